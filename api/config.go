@@ -8,6 +8,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
 	"testing"
 
 	stscreds "github.com/helix-tools/sdk-go/v2/credentials"
@@ -137,11 +138,24 @@ func (c TestConfig) RequireTestDatasetID(t *testing.T) {
 	}
 }
 
+// DefaultSSMPathPrefix is the SSM path under which customer credentials live
+// unless HELIX_SSM_CUSTOMER_PREFIX overrides it.
+const DefaultSSMPathPrefix = "/helix/production/customers"
+
 // LoadCredentialsFromSSM loads customer credentials from AWS SSM Parameter Store.
-// It uses the AWS helix profile and fetches:
-//   - /helix/production/customers/{customerID}/aws_access_key_id
-//   - /helix/production/customers/{customerID}/aws_secret_access_key
+// It uses the AWS helix profile and fetches, with {prefix} taken from
+// HELIX_SSM_CUSTOMER_PREFIX (default DefaultSSMPathPrefix):
+//   - {prefix}/{customerID}/aws_access_key_id
+//   - {prefix}/{customerID}/aws_secret_access_key
 func LoadCredentialsFromSSM(ctx context.Context, customerID string) (Credentials, error) {
+	return LoadCredentialsFromSSMPrefix(ctx, getEnvOrDefault("HELIX_SSM_CUSTOMER_PREFIX", DefaultSSMPathPrefix), customerID)
+}
+
+// LoadCredentialsFromSSMPrefix is LoadCredentialsFromSSM with an explicit SSM
+// path prefix, e.g. "/helix/staging/customers".
+func LoadCredentialsFromSSMPrefix(ctx context.Context, prefix, customerID string) (Credentials, error) {
+	prefix = strings.TrimRight(prefix, "/")
+
 	// Load AWS config with helix profile.
 	awsCfg, err := config.LoadDefaultConfig(ctx,
 		config.WithRegion(DefaultRegion),
@@ -154,7 +168,7 @@ func LoadCredentialsFromSSM(ctx context.Context, customerID string) (Credentials
 	ssmClient := ssm.NewFromConfig(awsCfg)
 
 	// Get access key ID.
-	accessKeyParam := fmt.Sprintf("/helix/production/customers/%s/aws_access_key_id", customerID)
+	accessKeyParam := fmt.Sprintf("%s/%s/aws_access_key_id", prefix, customerID)
 	accessKeyResp, err := ssmClient.GetParameter(ctx, &ssm.GetParameterInput{
 		Name:           aws.String(accessKeyParam),
 		WithDecryption: aws.Bool(true),
@@ -164,7 +178,7 @@ func LoadCredentialsFromSSM(ctx context.Context, customerID string) (Credentials
 	}
 
 	// Get secret access key.
-	secretKeyParam := fmt.Sprintf("/helix/production/customers/%s/aws_secret_access_key", customerID)
+	secretKeyParam := fmt.Sprintf("%s/%s/aws_secret_access_key", prefix, customerID)
 	secretKeyResp, err := ssmClient.GetParameter(ctx, &ssm.GetParameterInput{
 		Name:           aws.String(secretKeyParam),
 		WithDecryption: aws.Bool(true),
@@ -0,0 +1,427 @@
+// Package client is the SigV4-signed HTTP client for the Helix API. Producer
+// and Consumer send their requests through it, and it is the supported way
+// to call endpoints the SDK does not wrap yet, such as beta endpoints:
+//
+//	c := p.APIClient() // or client.New(endpoint, region, awsConfig)
+//	var out map[string]any
+//	err := c.Get(ctx, "/v1/new-endpoint", &out)
+//
+// A non-2xx response is returned as an *APIError. Idempotent requests that
+// fail transiently are retried according to Client.Retry.
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/helix-tools/sdk-go/v2/clientset"
+)
+
+// defaultTimeout is the HTTP timeout of a Client built by New.
+const defaultTimeout = 30 * time.Second
+
+// maxRetryAfter caps how long a Retry-After header can delay a retry.
+const maxRetryAfter = 30 * time.Second
+
+// DefaultRetryPolicy is the retry policy of Clients built by New and of the
+// Producer's and Consumer's API requests: 1 initial attempt + 2 retries.
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: 250 * time.Millisecond}
+
+// RetryPolicy retries requests that fail transiently: transport errors and
+// 429 or 5xx responses. Only idempotent requests are retried: GET, HEAD,
+// PUT, DELETE and OPTIONS, and any request with an Idempotency-Key header.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first.
+	// Less than 2 disables retries.
+	MaxAttempts int
+
+	// BaseDelay is the backoff before the first retry; it doubles for each
+	// later one, with +/-25% jitter. A Retry-After header on a 429 or 503
+	// response overrides it.
+	BaseDelay time.Duration
+}
+
+// delay returns the backoff before the attempt'th retry (attempt >= 1).
+func (p RetryPolicy) delay(attempt int, err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.retryAfter > 0 {
+		return apiErr.retryAfter
+	}
+	base := p.BaseDelay * time.Duration(int64(1)<<uint(attempt-1))
+	jitter := time.Duration((rand.Float64()*0.5 - 0.25) * float64(base)) //nolint:gosec // timing jitter, not security-sensitive
+	return base + jitter
+}
+
+// retryable reports whether a request failure is transient.
+func retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= 500
+	}
+
+	var requestErr *requestError
+	return errors.As(err, &requestErr)
+}
+
+type retryCounterKey struct{}
+
+// CountRetries returns a context under which every retry a Client makes
+// (after the first attempt of a request) is added to n, so a caller can
+// report how many retries an operation spanning several requests needed.
+func CountRetries(ctx context.Context, n *atomic.Int64) context.Context {
+	return context.WithValue(ctx, retryCounterKey{}, n)
+}
+
+// requestError marks a failure to send the request or read the response,
+// which is worth retrying; failures to build the request are not.
+type requestError struct{ err error }
+
+func (e *requestError) Error() string { return e.err.Error() }
+func (e *requestError) Unwrap() error { return e.err }
+
+// idempotent reports whether a request may be sent more than once.
+func idempotent(method string, headers http.Header) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return headers.Get("Idempotency-Key") != ""
+}
+
+// Limiter paces requests; *rate.Limiter from golang.org/x/time/rate
+// satisfies it.
+type Limiter interface {
+	Wait(ctx context.Context) error
+}
+
+// Client sends JSON requests to the Helix API, signed with SigV4. The zero
+// HTTPClient and Limiter are valid. A Client is safe for concurrent use.
+type Client struct {
+	Endpoint   string       // API base URL, e.g. "https://api-go.helix.tools"
+	Region     string       // SigV4 signing region
+	AWSConfig  aws.Config   // supplies the signing credentials
+	HTTPClient *http.Client // nil means http.DefaultClient
+	Limiter    Limiter      // when set, waited on before each request is signed
+	Retry      RetryPolicy  // the zero value sends each request once
+
+	// RequireBody makes a 2xx response with an empty body an error
+	// (ErrEmptyResponse) when the request has a result to decode into,
+	// instead of leaving the result untouched.
+	RequireBody bool
+}
+
+// ErrEmptyResponse is returned, with RequireBody set, for a 2xx response
+// with no body to decode into the request's result.
+var ErrEmptyResponse = errors.New("empty response body")
+
+// New returns a Client for endpoint with a 30-second HTTP timeout and
+// DefaultRetryPolicy.
+func New(endpoint, region string, awsConfig aws.Config) *Client {
+	return &Client{
+		Endpoint:   endpoint,
+		Region:     region,
+		AWSConfig:  awsConfig,
+		HTTPClient: NewHTTPClient(defaultTimeout, 0),
+		Retry:      DefaultRetryPolicy,
+	}
+}
+
+// APIError is a non-2xx response from the Helix API. Message is the
+// response's "error" or "message" field, when it has one.
+type APIError struct {
+	StatusCode int
+	Body       string
+	Message    string
+
+	retryAfter time.Duration // from a Retry-After header, capped at maxRetryAfter
+}
+
+func (e *APIError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("API error %d: %s", e.StatusCode, e.Message)
+	}
+
+	return fmt.Sprintf("API error %d: %s", e.StatusCode, e.Body)
+}
+
+// Request sends body (JSON-encoded, when non-nil) to method and path,
+// relative to Endpoint, and decodes a JSON response into result when result
+// is non-nil and the response has a body (see RequireBody).
+func (c *Client) Request(ctx context.Context, method, path string, body, result any) error {
+	return c.RequestWithHeaders(ctx, method, path, body, result, nil)
+}
+
+// RequestWithHeaders is Request with extra request headers, which are set
+// before signing.
+func (c *Client) RequestWithHeaders(ctx context.Context, method, path string, body, result any, headers http.Header) error {
+	apiURL, err := url.Parse(c.Endpoint + path)
+	if err != nil {
+		return fmt.Errorf("invalid API URL: %w", err)
+	}
+
+	var jsonData []byte
+	if body != nil {
+		jsonData, err = json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request body: %w", err)
+		}
+	}
+
+	return c.retry(ctx, method, headers, func() error {
+		return c.send(ctx, method, apiURL.String(), jsonData, result, headers)
+	})
+}
+
+// retry calls attempt until it succeeds, fails permanently, or c.Retry
+// gives up; only idempotent requests get more than one attempt.
+func (c *Client) retry(ctx context.Context, method string, headers http.Header, attempt func() error) error {
+	attempts := 1
+	if idempotent(method, headers) {
+		attempts = max(c.Retry.MaxAttempts, 1)
+	}
+
+	var err error
+	for n := 0; ; n++ {
+		if n > 0 {
+			select {
+			case <-ctx.Done():
+				return err // report the last failure, not the cancellation
+			case <-time.After(c.Retry.delay(n, err)):
+			}
+			if counter, ok := ctx.Value(retryCounterKey{}).(*atomic.Int64); ok {
+				counter.Add(1)
+			}
+		}
+
+		err = attempt()
+		if err == nil || n+1 >= attempts || !retryable(err) {
+			return err
+		}
+	}
+}
+
+// send makes one attempt at a request; jsonData is the encoded body, or nil.
+func (c *Client) send(ctx context.Context, method, apiURL string, jsonData []byte, result any, headers http.Header) error {
+	resp, err := c.do(ctx, method, apiURL, jsonData, headers, c.httpClient())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return apiError(resp)
+	}
+
+	respBody, err := readBody(resp)
+	if err != nil {
+		return &requestError{fmt.Errorf("failed to read response body: %w", err)}
+	}
+
+	if result != nil {
+		if len(bytes.TrimSpace(respBody)) == 0 {
+			if c.RequireBody {
+				return fmt.Errorf("failed to decode response: %w (status %d)", ErrEmptyResponse, resp.StatusCode)
+			}
+			return nil
+		}
+		if err := json.Unmarshal(respBody, result); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// do builds, signs and sends one request and returns the response with its
+// body unread, whatever its status.
+func (c *Client) do(ctx context.Context, method, apiURL string, jsonData []byte, headers http.Header, httpClient *http.Client) (*http.Response, error) {
+	var reqBody io.Reader
+	if jsonData != nil {
+		reqBody = bytes.NewReader(jsonData)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, apiURL, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if jsonData != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	for name, values := range headers {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+
+	// Wait for the rate limiter before signing so the signature is fresh.
+	if c.Limiter != nil {
+		if err := c.Limiter.Wait(ctx); err != nil {
+			return nil, fmt.Errorf("rate limiter: %w", err)
+		}
+	}
+
+	if err := clientset.SignRequest(ctx, c.AWSConfig, req, jsonData, c.Region); err != nil {
+		return nil, err
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, &requestError{fmt.Errorf("request failed: %w", err)}
+	}
+	return resp, nil
+}
+
+// httpClient returns HTTPClient, or http.DefaultClient when it is nil.
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient == nil {
+		return http.DefaultClient
+	}
+	return c.HTTPClient
+}
+
+// apiError reads the body of a non-2xx response into an *APIError. A body
+// that cannot be read is a retryable *requestError instead.
+func apiError(resp *http.Response) error {
+	respBody, err := readBody(resp)
+	if err != nil {
+		return &requestError{fmt.Errorf("failed to read response body: %w", err)}
+	}
+
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Body:       string(respBody),
+	}
+
+	var errResp struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(respBody, &errResp) == nil {
+		if errResp.Error != "" {
+			apiErr.Message = errResp.Error
+		} else {
+			apiErr.Message = errResp.Message
+		}
+	}
+
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		apiErr.retryAfter = min(time.Duration(seconds)*time.Second, maxRetryAfter)
+	}
+
+	return apiErr
+}
+
+// Stream sends a signed GET for path, with extra request headers, and
+// returns the response with its body unread, for endpoints that return raw
+// bytes rather than JSON. The caller must close the body. A 2xx or 304 Not
+// Modified response is returned; any other status is an *APIError, retried
+// as Request would. A redirect to another host, such as a presigned storage
+// URL, is followed without the request's signature.
+func (c *Client) Stream(ctx context.Context, path string, headers http.Header) (*http.Response, error) {
+	apiURL, err := url.Parse(c.Endpoint + path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid API URL: %w", err)
+	}
+
+	httpClient := *c.httpClient()
+	checkRedirect := httpClient.CheckRedirect
+	httpClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if req.URL.Host != via[0].URL.Host {
+			// The SigV4 signature must not reach another host. net/http
+			// keeps Authorization when only the port differs or the
+			// target is a subdomain, so drop it here too.
+			req.Header.Del("Authorization")
+			for name := range req.Header {
+				if strings.HasPrefix(http.CanonicalHeaderKey(name), "X-Amz-") {
+					req.Header.Del(name)
+				}
+			}
+		}
+		if checkRedirect != nil {
+			return checkRedirect(req, via)
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+
+	var resp *http.Response
+	err = c.retry(ctx, http.MethodGet, headers, func() error {
+		r, err := c.do(ctx, http.MethodGet, apiURL.String(), nil, headers, &httpClient)
+		if err != nil {
+			return err
+		}
+		if (r.StatusCode < 200 || r.StatusCode >= 300) && r.StatusCode != http.StatusNotModified {
+			defer r.Body.Close()
+			return apiError(r)
+		}
+		resp = r
+		return nil
+	})
+	return resp, err
+}
+
+// readBody reads a response body, inflating it if it is still gzipped. The
+// transport requests gzip and inflates responses itself unless the caller
+// set Accept-Encoding or the transport has compression disabled; this covers
+// those cases.
+func readBody(resp *http.Response) ([]byte, error) {
+	if resp.Uncompressed || !strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		return io.ReadAll(resp.Body)
+	}
+
+	gz, err := gzip.NewReader(resp.Body)
+	if errors.Is(err, io.EOF) {
+		return nil, nil // empty body
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	return io.ReadAll(gz)
+}
+
+// Get sends an authenticated GET request.
+func (c *Client) Get(ctx context.Context, path string, result any) error {
+	return c.Request(ctx, http.MethodGet, path, nil, result)
+}
+
+// Post sends an authenticated POST request.
+func (c *Client) Post(ctx context.Context, path string, body, result any) error {
+	return c.Request(ctx, http.MethodPost, path, body, result)
+}
+
+// Put sends an authenticated PUT request.
+func (c *Client) Put(ctx context.Context, path string, body, result any) error {
+	return c.Request(ctx, http.MethodPut, path, body, result)
+}
+
+// Patch sends an authenticated PATCH request.
+func (c *Client) Patch(ctx context.Context, path string, body, result any) error {
+	return c.Request(ctx, http.MethodPatch, path, body, result)
+}
+
+// Delete sends an authenticated DELETE request.
+func (c *Client) Delete(ctx context.Context, path string, result any) error {
+	return c.Request(ctx, http.MethodDelete, path, nil, result)
+}
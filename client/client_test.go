@@ -0,0 +1,231 @@
+package client
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+type countingLimiter struct{ waits int }
+
+func (l *countingLimiter) Wait(context.Context) error {
+	l.waits++
+	return nil
+}
+
+func TestClientRequest(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ") {
+			t.Errorf("%s %s not signed", r.Method, r.URL.Path)
+		}
+		switch r.URL.Path {
+		case "/v1/things":
+			body, _ := io.ReadAll(r.Body)
+			if r.Method != http.MethodPost || string(body) != `{"name":"a"}` || r.Header.Get("Content-Type") != "application/json" {
+				t.Errorf("request = %s %s %s", r.Method, r.Header.Get("Content-Type"), body)
+			}
+			_, _ = w.Write([]byte(`{"id":"thing-1"}`))
+		case "/v1/empty":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":"no such thing"}`))
+		}
+	}))
+	defer srv.Close()
+
+	limiter := &countingLimiter{}
+	c := New(srv.URL, "us-east-1", aws.Config{Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+		return aws.Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret"}, nil
+	})})
+	c.Limiter = limiter
+	ctx := context.Background()
+
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err := c.Post(ctx, "/v1/things", map[string]string{"name": "a"}, &created); err != nil || created.ID != "thing-1" {
+		t.Fatalf("Post = %+v, %v", created, err)
+	}
+
+	// An empty response body leaves result untouched.
+	if err := c.Delete(ctx, "/v1/empty", &created); err != nil {
+		t.Errorf("Delete: %v", err)
+	}
+
+	// ...unless the caller requires one; with no result it is still fine.
+	strict := *c
+	strict.RequireBody = true
+	if err := strict.Delete(ctx, "/v1/empty", &created); !errors.Is(err, ErrEmptyResponse) {
+		t.Errorf("Delete with RequireBody = %v, want ErrEmptyResponse", err)
+	}
+	if err := strict.Delete(ctx, "/v1/empty", nil); err != nil {
+		t.Errorf("Delete with RequireBody and no result: %v", err)
+	}
+
+	err := c.Get(ctx, "/v1/missing", nil)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || apiErr.Message != "no such thing" {
+		t.Errorf("err = %v, want a 404 *APIError with the error message", err)
+	}
+
+	if limiter.waits != 5 {
+		t.Errorf("limiter waited %d times, want 5", limiter.waits)
+	}
+}
+
+func TestClientRetry(t *testing.T) {
+	var (
+		mu                 sync.Mutex
+		gets, posts, keyed int
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == http.MethodGet:
+			gets++
+			if gets == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			_, _ = w.Write([]byte(`{}`))
+		case r.Header.Get("Idempotency-Key") != "":
+			keyed++
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			posts++
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, "us-east-1", aws.Config{Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+		return aws.Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret"}, nil
+	})})
+	c.Retry = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+	var retries atomic.Int64
+	ctx := CountRetries(context.Background(), &retries)
+
+	count := func(n *int) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return *n
+	}
+
+	if err := c.Get(ctx, "/v1/things", nil); err != nil || count(&gets) != 2 {
+		t.Errorf("Get = %v after %d attempts, want success on the second", err, count(&gets))
+	}
+
+	// A plain POST is not idempotent, so it is sent once.
+	if err := c.Post(ctx, "/v1/things", map[string]string{}, nil); err == nil || count(&posts) != 1 {
+		t.Errorf("Post = %v after %d attempts, want one failed attempt", err, count(&posts))
+	}
+
+	// With an idempotency key it is retried up to MaxAttempts.
+	headers := http.Header{"Idempotency-Key": {"key-1"}}
+	if err := c.RequestWithHeaders(ctx, http.MethodPost, "/v1/things", map[string]string{}, nil, headers); err == nil || count(&keyed) != 3 {
+		t.Errorf("keyed Post = %v after %d attempts, want 3 failed attempts", err, count(&keyed))
+	}
+
+	if n := retries.Load(); n != 3 {
+		t.Errorf("CountRetries counted %d retries, want 3 (1 for the Get, 2 for the keyed Post)", n)
+	}
+}
+
+func TestClientGzipResponses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			t.Errorf("Accept-Encoding = %q, want gzip requested", r.Header.Get("Accept-Encoding"))
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		_, _ = gz.Write([]byte(`{"datasets":[{"_id":"ds-1"}]}`))
+		_ = gz.Close()
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, "us-east-1", aws.Config{Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+		return aws.Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret"}, nil
+	})})
+
+	// The transport's transparent decompression, then the manual fallback
+	// used when the caller sets Accept-Encoding itself.
+	for _, headers := range []http.Header{nil, {"Accept-Encoding": {"gzip"}}} {
+		var out struct {
+			Datasets []struct {
+				ID string `json:"_id"`
+			} `json:"datasets"`
+		}
+		if err := c.RequestWithHeaders(context.Background(), http.MethodGet, "/v1/datasets", nil, &out, headers); err != nil || len(out.Datasets) != 1 {
+			t.Errorf("headers %v: got %+v, %v", headers, out, err)
+		}
+	}
+}
+
+func TestClientStream(t *testing.T) {
+	storage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, name := range []string{"Authorization", "X-Amz-Date", "X-Amz-Security-Token"} {
+			if r.Header.Get(name) != "" {
+				t.Errorf("redirected request carries %s", name)
+			}
+		}
+		_, _ = w.Write([]byte("stored bytes"))
+	}))
+	defer storage.Close()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/direct":
+			if r.Header.Get("Authorization") == "" {
+				t.Error("request is not signed")
+			}
+			_, _ = w.Write([]byte("stored bytes"))
+		case "/redirect":
+			http.Redirect(w, r, storage.URL+"/object", http.StatusFound)
+		case "/unchanged":
+			w.WriteHeader(http.StatusNotModified)
+		default:
+			http.Error(w, `{"error":"no such object"}`, http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, "us-east-1", aws.Config{Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+		return aws.Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret", SessionToken: "token"}, nil
+	})})
+
+	for _, path := range []string{"/direct", "/redirect"} {
+		resp, err := c.Stream(context.Background(), path, nil)
+		if err != nil {
+			t.Fatalf("Stream(%s): %v", path, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != "stored bytes" {
+			t.Errorf("Stream(%s) body = %q", path, body)
+		}
+	}
+
+	resp, err := c.Stream(context.Background(), "/unchanged", http.Header{"If-None-Match": {`"etag"`}})
+	if err != nil || resp.StatusCode != http.StatusNotModified {
+		t.Errorf("Stream(/unchanged) = %v, %v; want a 304 response", resp, err)
+	} else {
+		resp.Body.Close()
+	}
+
+	var apiErr *APIError
+	if _, err := c.Stream(context.Background(), "/missing", nil); !errors.As(err, &apiErr) || apiErr.Message != "no such object" {
+		t.Errorf("Stream(/missing) error = %v, want an *APIError", err)
+	}
+}
@@ -0,0 +1,67 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+// companyPollInterval is how often WaitForCompanyActive checks a company.
+var companyPollInterval = 5 * time.Second
+
+// ErrProvisioningFailed is wrapped by WaitForCompanyActive's error when the
+// company's setup failed and waiting longer will not make it active.
+var ErrProvisioningFailed = errors.New("company provisioning failed")
+
+// WaitForCompanyActive polls GET /v1/companies/{id} until the company is
+// active, so an onboarding flow can wait for the company's storage,
+// encryption key and notification queue before the first upload instead
+// of the producer failing on them. It returns the active company.
+//
+// A company whose status is provisioning_failed or onboarding_failed is
+// returned with an error wrapping ErrProvisioningFailed; one that is
+// inactive, suspended or being deprovisioned fails at once, as it will not
+// become active on its own. When timeout (if > 0) or ctx ends first, the
+// last company fetched is returned with the context's error. An API error
+// ends the wait.
+func (c *Client) WaitForCompanyActive(ctx context.Context, companyID string, timeout time.Duration) (*types.Company, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	path := "/v1/companies/" + url.PathEscape(companyID)
+	var last *types.Company
+	for {
+		var company types.Company
+		if err := c.Get(ctx, path, &company); err != nil {
+			// The deadline can land mid-request; that is still a timeout.
+			if last != nil && ctx.Err() != nil {
+				return last, fmt.Errorf("company %s still provisioning: %w", companyID, ctx.Err())
+			}
+			return nil, fmt.Errorf("failed to get company %s: %w", companyID, err)
+		}
+		last = &company
+
+		switch company.Status {
+		case types.CompanyStatusActive:
+			return &company, nil
+		case types.CompanyStatusProvisioning:
+		case types.CompanyStatusProvisioningFailed, types.CompanyStatusOnboardingFailed:
+			return &company, fmt.Errorf("company %s: %w (status %s)", companyID, ErrProvisioningFailed, company.Status)
+		default:
+			return &company, fmt.Errorf("company %s is %s, not provisioning", companyID, company.Status)
+		}
+
+		select {
+		case <-ctx.Done():
+			return &company, fmt.Errorf("company %s still provisioning: %w", companyID, ctx.Err())
+		case <-time.After(companyPollInterval):
+		}
+	}
+}
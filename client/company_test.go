@@ -0,0 +1,92 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestWaitForCompanyActive(t *testing.T) {
+	defer func(interval time.Duration) { companyPollInterval = interval }(companyPollInterval)
+	companyPollInterval = time.Millisecond
+
+	var (
+		mu       sync.Mutex
+		statuses []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/companies/company-1" {
+			http.NotFound(w, r)
+			return
+		}
+		mu.Lock()
+		status := statuses[0]
+		if len(statuses) > 1 {
+			statuses = statuses[1:]
+		}
+		mu.Unlock()
+		_, _ = fmt.Fprintf(w, `{"_id":"company-1","status":%q}`, status)
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, "us-east-1", aws.Config{Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+		return aws.Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret"}, nil
+	})})
+	wait := func(timeout time.Duration, sequence ...string) (string, error) {
+		mu.Lock()
+		statuses = sequence
+		mu.Unlock()
+		company, err := c.WaitForCompanyActive(context.Background(), "company-1", timeout)
+		if company == nil {
+			return "", err
+		}
+		return company.Status, err
+	}
+
+	if status, err := wait(time.Second, "provisioning", "provisioning", "active"); err != nil || status != "active" {
+		t.Errorf("provisioning then active: %s, %v", status, err)
+	}
+	if status, err := wait(time.Second, "provisioning", "provisioning_failed"); !errors.Is(err, ErrProvisioningFailed) || status != "provisioning_failed" {
+		t.Errorf("provisioning_failed: %s, %v; want ErrProvisioningFailed", status, err)
+	}
+	if _, err := wait(time.Second, "suspended"); err == nil {
+		t.Error("suspended company: no error")
+	}
+	if status, err := wait(20*time.Millisecond, "provisioning"); !errors.Is(err, context.DeadlineExceeded) || status != "provisioning" {
+		t.Errorf("timeout: %s, %v; want context.DeadlineExceeded", status, err)
+	}
+}
+
+func TestWaitForCompanyActiveDeadlineDuringRequest(t *testing.T) {
+	defer func(interval time.Duration) { companyPollInterval = interval }(companyPollInterval)
+	companyPollInterval = time.Millisecond
+
+	var gets atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if gets.Add(1) > 1 {
+			<-r.Context().Done() // hold the second GET past the deadline
+			return
+		}
+		_, _ = w.Write([]byte(`{"_id":"company-1","status":"provisioning"}`))
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, "us-east-1", aws.Config{Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+		return aws.Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret"}, nil
+	})})
+	company, err := c.WaitForCompanyActive(context.Background(), "company-1", 50*time.Millisecond)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if company == nil || company.Status != "provisioning" {
+		t.Errorf("company = %+v, want the last one fetched", company)
+	}
+}
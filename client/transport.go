@@ -0,0 +1,41 @@
+package client
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+// DefaultMaxIdleConnsPerHost is how many idle connections per host the SDK's
+// HTTP clients keep for reuse. net/http keeps only 2, so a batch upload
+// running more requests than that in parallel re-dials (and re-handshakes
+// TLS) for most of them; see BenchmarkBatchTransport.
+const DefaultMaxIdleConnsPerHost = 100
+
+// NewHTTPClient returns an *http.Client with the SDK's transport settings:
+// HTTP/2 attempted, the default proxy and dial settings, and up to
+// maxIdleConnsPerHost idle connections kept per host (< 1 means
+// DefaultMaxIdleConnsPerHost). timeout bounds each request; zero means none.
+func NewHTTPClient(timeout time.Duration, maxIdleConnsPerHost int) *http.Client {
+	if maxIdleConnsPerHost < 1 {
+		maxIdleConnsPerHost = DefaultMaxIdleConnsPerHost
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ForceAttemptHTTP2 = true
+	transport.MaxIdleConnsPerHost = maxIdleConnsPerHost
+	transport.MaxIdleConns = max(transport.MaxIdleConns, maxIdleConnsPerHost)
+
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// HTTPClientFor returns cfg.HTTPClient when set, else NewHTTPClient with
+// timeout and cfg.MaxIdleConnsPerHost. Producer and Consumer build their API
+// and upload client with it.
+func HTTPClientFor(cfg types.Config, timeout time.Duration) *http.Client {
+	if cfg.HTTPClient != nil {
+		return cfg.HTTPClient
+	}
+	return NewHTTPClient(timeout, cfg.MaxIdleConnsPerHost)
+}
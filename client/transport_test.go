@@ -0,0 +1,66 @@
+package client
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestNewHTTPClient(t *testing.T) {
+	transport := NewHTTPClient(0, 0).Transport.(*http.Transport)
+	if transport.MaxIdleConnsPerHost != DefaultMaxIdleConnsPerHost || !transport.ForceAttemptHTTP2 || transport.Proxy == nil {
+		t.Errorf("transport = %+v, want the default pool size, HTTP/2 and the environment proxy", transport)
+	}
+	if got := NewHTTPClient(0, 8).Transport.(*http.Transport).MaxIdleConnsPerHost; got != 8 {
+		t.Errorf("MaxIdleConnsPerHost = %d, want 8", got)
+	}
+}
+
+// BenchmarkBatchTransport sends batches of 32 parallel 64 KiB uploads over
+// TLS, as a batch upload does, with net/http's default idle pool and with
+// the SDK's. With 2 idle connections per host most requests of each batch
+// pay for a new TLS handshake.
+func BenchmarkBatchTransport(b *testing.B) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		_, _ = w.Write([]byte(`{"id":"ds-1"}`))
+	}))
+	defer srv.Close()
+	payload := strings.Repeat("x", 64<<10)
+
+	for _, tt := range []struct {
+		name        string
+		idlePerHost int
+	}{
+		{"net-http-default", http.DefaultMaxIdleConnsPerHost},
+		{"sdk-default", DefaultMaxIdleConnsPerHost},
+	} {
+		b.Run(tt.name, func(b *testing.B) {
+			transport := srv.Client().Transport.(*http.Transport).Clone()
+			transport.MaxIdleConnsPerHost = tt.idlePerHost
+			httpClient := &http.Client{Transport: transport}
+			defer httpClient.CloseIdleConnections()
+
+			for b.Loop() {
+				var wg sync.WaitGroup
+				for range 32 {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						resp, err := httpClient.Post(srv.URL, "application/octet-stream", strings.NewReader(payload))
+						if err != nil {
+							b.Error(err)
+							return
+						}
+						_, _ = io.Copy(io.Discard, resp.Body)
+						resp.Body.Close()
+					}()
+				}
+				wg.Wait()
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+// Package clientset builds one set of AWS clients that a Producer and a
+// Consumer in the same process can share, instead of each creating its own
+// credential provider, AWS config and connection pools.
+//
+//	cs, err := clientset.New(cfg)
+//	p, err := producer.NewProducerFromClientSet(cs)
+//	c, err := consumer.NewConsumerFromClientSet(cs)
+package clientset
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	stscreds "github.com/helix-tools/sdk-go/v2/credentials"
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+// awsHTTPTimeout bounds each AWS call. It must exceed the 20s SQS long-poll
+// wait used by Consumer.PollNotifications.
+const awsHTTPTimeout = 25 * time.Second
+
+// ClientSet holds the AWS config and clients shared by the producer and
+// consumer built from it.
+type ClientSet struct {
+	// Config is the configuration the set was built from, with APIEndpoint
+	// and Region defaulted.
+	Config types.Config
+
+	AWSConfig aws.Config
+	KMS       *kms.Client
+	S3        *s3.Client
+	SQS       *sqs.Client
+	SSM       *ssm.Client
+}
+
+// New resolves credentials for cfg, verifies them once with an STS
+// identity check (unless cfg.SkipCredentialValidation), and builds the
+// shared clients. APIEndpoint and Region
+// default as in producer.NewProducer and consumer.NewConsumer.
+func New(cfg types.Config) (*ClientSet, error) {
+	if cfg.APIEndpoint == "" {
+		if envEndpoint := strings.TrimSpace(os.Getenv("HELIX_API_ENDPOINT")); envEndpoint != "" {
+			cfg.APIEndpoint = envEndpoint
+		} else {
+			cfg.APIEndpoint = "https://api-go.helix.tools"
+		}
+	}
+
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+
+	credProvider, err := stscreds.SelectProvider(cfg.APIEndpoint, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select AWS credentials provider: %w", err)
+	}
+
+	endpointOpt, err := EndpointOption(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	awsCfg, err := config.LoadDefaultConfig(context.Background(),
+		config.WithRegion(cfg.Region),
+		config.WithCredentialsProvider(credProvider),
+		config.WithHTTPClient(&http.Client{Timeout: awsHTTPTimeout}),
+		endpointOpt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	if !cfg.SkipCredentialValidation {
+		if _, err := sts.NewFromConfig(awsCfg).GetCallerIdentity(
+			context.Background(),
+			&sts.GetCallerIdentityInput{},
+		); err != nil {
+			return nil, fmt.Errorf("invalid AWS credentials: %w", err)
+		}
+	}
+
+	return FromAWSConfig(cfg, awsCfg), nil
+}
+
+// FromAWSConfig builds a ClientSet from an already loaded AWS config,
+// without any credential check. cfg should carry the resolved APIEndpoint
+// and Region.
+func FromAWSConfig(cfg types.Config, awsCfg aws.Config) *ClientSet {
+	return &ClientSet{
+		Config:    cfg,
+		AWSConfig: awsCfg,
+		KMS:       kms.NewFromConfig(awsCfg),
+		S3:        s3.NewFromConfig(awsCfg, S3Options(cfg)),
+		SQS:       sqs.NewFromConfig(awsCfg),
+		SSM:       ssm.NewFromConfig(awsCfg),
+	}
+}
@@ -0,0 +1,63 @@
+package clientset
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/helix-tools/sdk-go/v2/internal/fips"
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+// EndpointOption returns the config.LoadDefaultConfig option that sends
+// every AWS client built from the loaded config to cfg.AWSEndpointURL, or
+// a no-op option when it is empty, and with cfg.FIPSMode selects the AWS
+// FIPS endpoints. It fails if the URL is not an absolute http or https
+// URL, or if FIPS mode is requested but the binary has no FIPS crypto
+// backend (see types.Config.FIPSMode), so every constructor fails fast.
+func EndpointOption(cfg types.Config) (func(*config.LoadOptions) error, error) {
+	var opts []func(*config.LoadOptions) error
+	if cfg.FIPSMode {
+		if err := fips.Check(); err != nil {
+			return nil, err
+		}
+		opts = append(opts, config.WithUseFIPSEndpoint(aws.FIPSEndpointStateEnabled))
+	}
+	if cfg.AWSEndpointURL != "" {
+		if err := validateEndpointURL(cfg.AWSEndpointURL); err != nil {
+			return nil, err
+		}
+		opts = append(opts, config.WithBaseEndpoint(cfg.AWSEndpointURL))
+	}
+	return func(o *config.LoadOptions) error {
+		for _, opt := range opts {
+			if err := opt(o); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, nil
+}
+
+// S3Options applies the S3-specific settings of cfg to an S3 client.
+func S3Options(cfg types.Config) func(*s3.Options) {
+	return func(o *s3.Options) {
+		o.UsePathStyle = cfg.S3ForcePathStyle
+	}
+}
+
+func validateEndpointURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid AWSEndpointURL %q: %w", raw, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid AWSEndpointURL %q: scheme must be http or https", raw)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("invalid AWSEndpointURL %q: missing host", raw)
+	}
+	return nil
+}
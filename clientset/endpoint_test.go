@@ -0,0 +1,97 @@
+package clientset
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/helix-tools/sdk-go/v2/internal/fips"
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+func TestEndpointOption(t *testing.T) {
+	for _, raw := range []string{"localhost:4566", "ftp://localhost:4566", "http://", "http://[::1"} {
+		if _, err := EndpointOption(types.Config{AWSEndpointURL: raw}); err == nil {
+			t.Errorf("EndpointOption(%q) succeeded, want error", raw)
+		}
+	}
+
+	for raw, want := range map[string]string{"": "", "http://localhost:4566": "http://localhost:4566"} {
+		opt, err := EndpointOption(types.Config{AWSEndpointURL: raw})
+		if err != nil {
+			t.Fatalf("EndpointOption(%q): %v", raw, err)
+		}
+		var o config.LoadOptions
+		if err := opt(&o); err != nil {
+			t.Fatal(err)
+		}
+		if o.BaseEndpoint != want {
+			t.Errorf("EndpointOption(%q) set BaseEndpoint %q, want %q", raw, o.BaseEndpoint, want)
+		}
+	}
+}
+
+func TestS3ForcePathStyle(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		paths []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.Host+r.URL.Path)
+		mu.Unlock()
+		w.Header().Set("ETag", `"etag"`)
+	}))
+	defer srv.Close()
+
+	awsCfg := aws.Config{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(srv.URL),
+		Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "test", SecretAccessKey: "test"}, nil
+		}),
+	}
+	client := s3.NewFromConfig(awsCfg, S3Options(types.Config{S3ForcePathStyle: true}))
+
+	if _, err := client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket: aws.String("helix-bucket"),
+		Key:    aws.String("datasets/data.json"),
+		Body:   strings.NewReader("{}"),
+	}); err != nil {
+		t.Fatalf("PutObject: %v", err)
+	}
+
+	want := strings.TrimPrefix(srv.URL, "http://") + "/helix-bucket/datasets/data.json"
+	mu.Lock()
+	defer mu.Unlock()
+	if len(paths) != 1 || paths[0] != want {
+		t.Errorf("requests = %q, want [%q]", paths, want)
+	}
+}
+
+func TestEndpointOptionFIPSMode(t *testing.T) {
+	opt, err := EndpointOption(types.Config{FIPSMode: true})
+	if fips.Check() != nil {
+		if !errors.Is(err, fips.ErrUnavailable) {
+			t.Errorf("EndpointOption without FIPS crypto = %v, want fips.ErrUnavailable", err)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("EndpointOption: %v", err)
+	}
+	var o config.LoadOptions
+	if err := opt(&o); err != nil {
+		t.Fatal(err)
+	}
+	if o.UseFIPSEndpoint != aws.FIPSEndpointStateEnabled {
+		t.Errorf("UseFIPSEndpoint = %v, want enabled", o.UseFIPSEndpoint)
+	}
+}
@@ -0,0 +1,38 @@
+package clientset
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestSignRequest(t *testing.T) {
+	awsConfig := aws.Config{Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+		return aws.Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret"}, nil
+	})}
+
+	sign := func(body []byte) string {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, "https://api.example.com/v1/things", nil)
+		if err := SignRequest(context.Background(), awsConfig, req, body, "eu-west-1"); err != nil {
+			t.Fatalf("SignRequest: %v", err)
+		}
+		return req.Header.Get("Authorization")
+	}
+
+	auth := sign(nil)
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") || !strings.Contains(auth, "/eu-west-1/execute-api/aws4_request") {
+		t.Errorf("Authorization = %q", auth)
+	}
+	// The body is part of the signature.
+	if sign([]byte("{}")) == auth {
+		t.Error("signature does not depend on the body")
+	}
+
+	if err := SignRequest(context.Background(), aws.Config{}, &http.Request{}, nil, "eu-west-1"); err == nil {
+		t.Error("SignRequest without credentials succeeded")
+	}
+}
@@ -0,0 +1,35 @@
+package consumer
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/helix-tools/sdk-go/v2/helixtest"
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+func TestNewConsumerWithAPI(t *testing.T) {
+	api := helixtest.NewMockAPI()
+	api.Handle(http.MethodGet, "/v1/subscriptions", http.StatusOK, map[string]any{
+		"subscriptions": []types.Subscription{{ID: "sub-1", ConsumerID: "company-1"}},
+		"count":         1,
+	})
+	api.Handle(http.MethodGet, "/v1/datasets/ds-1", http.StatusForbidden, "no subscription")
+
+	c := NewConsumerWithAPI(types.Config{CustomerID: "company-1"}, api)
+
+	subs, err := c.ListSubscriptions(context.Background(), &ListSubscriptionsOptions{Role: "consumer"})
+	if err != nil || len(subs) != 1 || subs[0].ID != "sub-1" {
+		t.Fatalf("ListSubscriptions = %+v, %v", subs, err)
+	}
+	if got := api.Calls()[0].Path; got != "/v1/subscriptions?role=consumer" {
+		t.Errorf("path = %q", got)
+	}
+
+	_, err = c.GetDataset(context.Background(), "ds-1")
+	if err == nil || !strings.Contains(err.Error(), "API request failed: 403 - no subscription") {
+		t.Errorf("GetDataset err = %v, want the usual API failure", err)
+	}
+}
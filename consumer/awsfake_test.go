@@ -0,0 +1,177 @@
+package consumer
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/helix-tools/sdk-go/v2/internal/awsfake"
+)
+
+// sealEnvelope encrypts data as the producer does:
+// [4-byte key length][wrapped data key][16-byte IV][16-byte tag][ciphertext].
+func sealEnvelope(t *testing.T, fakeKMS *awsfake.KMS, data []byte) []byte {
+	t.Helper()
+
+	dataKey, iv := make([]byte, 32), make([]byte, 16)
+	_, _ = rand.Read(dataKey)
+	_, _ = rand.Read(iv)
+
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed := gcm.Seal(nil, iv, data, nil)
+	ciphertext, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+
+	wrapped, err := fakeKMS.Encrypt(context.Background(), &kms.EncryptInput{KeyId: aws.String("test-key"), Plaintext: dataKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	_ = binary.Write(&out, binary.BigEndian, uint32(len(wrapped.CiphertextBlob)))
+	out.Write(wrapped.CiphertextBlob)
+	out.Write(iv)
+	out.Write(tag)
+	out.Write(ciphertext)
+	return out.Bytes()
+}
+
+// TestDownloadDatasetEncryptedWithFakes downloads an encrypted, compressed
+// dataset whose data key is unwrapped by the in-memory KMS fake, with and
+// without Config.ZeroizeBuffers (which must not clear the plaintext before
+// it is written).
+func TestDownloadDatasetEncryptedWithFakes(t *testing.T) {
+	for _, zeroize := range []bool{false, true} {
+		t.Run(fmt.Sprintf("zeroize=%v", zeroize), func(t *testing.T) {
+			payload := bytes.Repeat([]byte(`{"id":1}`+"\n"), 100)
+			fakeKMS := awsfake.NewKMS()
+
+			api := newFakeAPI(t)
+			metadata := api.dataset["metadata"].(map[string]any)
+			metadata["encryption_enabled"] = true
+			metadata["compression_enabled"] = true
+			api.s3Body = sealEnvelope(t, fakeKMS, gzipBytes(t, payload))
+
+			c := newTestConsumer(api.server.URL)
+			c.kmsClient = fakeKMS
+			c.zeroize = zeroize
+
+			out := filepath.Join(t.TempDir(), "data.ndjson")
+			if err := c.DownloadDataset(context.Background(), "ds-1", out); err != nil {
+				t.Fatalf("DownloadDataset: %v", err)
+			}
+			if got, _ := os.ReadFile(out); !bytes.Equal(got, payload) {
+				t.Errorf("output = %d bytes, want the %d-byte plaintext", len(got), len(payload))
+			}
+			if _, decrypts := fakeKMS.Calls(); decrypts != 1 {
+				t.Errorf("KMS Decrypt calls = %d, want 1", decrypts)
+			}
+		})
+	}
+}
+
+// TestPollNotificationsWithFakeSQS polls the in-memory SQS fake: manually
+// acknowledged messages are redelivered until deleted.
+func TestPollNotificationsWithFakeSQS(t *testing.T) {
+	fakeSQS := awsfake.NewSQS()
+	queueURL := fakeSQS.CreateQueue("123456789012", "consumer-queue", nil)
+	for _, id := range []string{"1", "2"} {
+		body := `{"event_type":"dataset_updated","dataset_id":"ds-` + id + `"}`
+		if _, err := fakeSQS.SendMessage(context.Background(), &sqs.SendMessageInput{
+			QueueUrl:    aws.String(queueURL),
+			MessageBody: aws.String(body),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := newTestConsumer("http://unused")
+	c.queueURL = aws.String(queueURL)
+	c.sqsClient = fakeSQS
+
+	manual := false
+	got, err := c.PollNotifications(context.Background(), PollNotificationsOptions{AutoAcknowledge: &manual})
+	if err != nil || len(got) != 2 {
+		t.Fatalf("PollNotifications = %d notifications, %v; want 2", len(got), err)
+	}
+	if err := c.DeleteNotification(context.Background(), got[0].ReceiptHandle); err != nil {
+		t.Fatalf("DeleteNotification: %v", err)
+	}
+	if n := fakeSQS.Len(queueURL); n != 1 {
+		t.Errorf("queue length = %d, want 1 unacknowledged message", n)
+	}
+
+	// The unacknowledged message stays hidden for its visibility timeout.
+	got, err = c.PollNotifications(context.Background(), PollNotificationsOptions{WaitTimeSeconds: 1})
+	if err != nil || len(got) != 0 {
+		t.Errorf("second poll = %d notifications, %v; want none while hidden", len(got), err)
+	}
+}
+
+// TestDeleteNotificationsWithFakeSQS deletes a poll's messages in one batch,
+// reporting the handle that was no longer valid.
+func TestDeleteNotificationsWithFakeSQS(t *testing.T) {
+	fakeSQS := awsfake.NewSQS()
+	queueURL := fakeSQS.CreateQueue("123456789012", "consumer-queue", nil)
+	for _, id := range []string{"1", "2", "3"} {
+		body := `{"event_type":"dataset_updated","dataset_id":"ds-` + id + `"}`
+		if _, err := fakeSQS.SendMessage(context.Background(), &sqs.SendMessageInput{
+			QueueUrl:    aws.String(queueURL),
+			MessageBody: aws.String(body),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := newTestConsumer("http://unused")
+	c.queueURL = aws.String(queueURL)
+	c.sqsClient = fakeSQS
+
+	manual := false
+	got, err := c.PollNotifications(context.Background(), PollNotificationsOptions{AutoAcknowledge: &manual})
+	if err != nil || len(got) != 3 {
+		t.Fatalf("PollNotifications = %d notifications, %v; want 3", len(got), err)
+	}
+
+	handles := []string{got[0].ReceiptHandle, got[1].ReceiptHandle, "stale-handle"}
+	err = c.DeleteNotifications(context.Background(), handles)
+	var deleteErr *NotificationDeleteError
+	if !errors.As(err, &deleteErr) || len(deleteErr.Failed) != 1 || deleteErr.Failed["stale-handle"] == nil {
+		t.Fatalf("DeleteNotifications err = %v, want only stale-handle failed", err)
+	}
+	if n := fakeSQS.Len(queueURL); n != 1 {
+		t.Errorf("queue length = %d, want 1 undeleted message", n)
+	}
+
+	// Made visible again, the last message is auto-acknowledged by the
+	// batch delete at the end of the poll.
+	if _, err := fakeSQS.ChangeMessageVisibility(context.Background(), &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:      aws.String(queueURL),
+		ReceiptHandle: aws.String(got[2].ReceiptHandle),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := c.PollNotifications(context.Background(), PollNotificationsOptions{}); err != nil || len(got) != 1 {
+		t.Fatalf("PollNotifications = %d notifications, %v; want 1", len(got), err)
+	}
+	if n := fakeSQS.Len(queueURL); n != 0 {
+		t.Errorf("queue length = %d, want 0 after auto-acknowledge", n)
+	}
+}
@@ -0,0 +1,25 @@
+package consumer
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/helix-tools/sdk-go/v2/clientset"
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+func TestNewConsumerFromClientSet(t *testing.T) {
+	cfg := types.Config{APIEndpoint: "https://api.test", CustomerID: "company-1", Region: "eu-west-1", TrackViews: true}
+	cs := clientset.FromAWSConfig(cfg, aws.Config{Region: "eu-west-1"})
+
+	c, err := NewConsumerFromClientSet(cs)
+	if err != nil {
+		t.Fatalf("NewConsumerFromClientSet: %v", err)
+	}
+	if c.APIEndpoint != "https://api.test" || c.CustomerID != "company-1" || c.Region != "eu-west-1" || !c.trackViews {
+		t.Errorf("consumer = %+v, want the ClientSet's config", c)
+	}
+	if c.kmsClient != cs.KMS || c.sqsClient != cs.SQS || c.ssmClient != cs.SSM {
+		t.Error("consumer should reuse the ClientSet's clients")
+	}
+}
@@ -0,0 +1,38 @@
+package consumer
+
+import (
+	"net/http"
+	"testing"
+
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// countingTransport records CloseIdleConnections calls.
+type countingTransport struct {
+	http.RoundTripper
+	closed int
+}
+
+func (t *countingTransport) CloseIdleConnections() { t.closed++ }
+
+func TestConsumerClose(t *testing.T) {
+	c := newTestConsumer("http://unused")
+	transport := &countingTransport{RoundTripper: http.DefaultTransport}
+	c.httpClient = &http.Client{Transport: transport}
+
+	queueURL, dlqURL := "https://queue", "https://dlq"
+	c.queueURL = &queueURL
+	c.deadLetterURL = &dlqURL
+	c.deadLetter = map[string]sqstypes.Message{"rh-1": {}}
+	c.rememberDownloadETag("ds-1", "out", `"etag"`)
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if transport.closed != 1 {
+		t.Errorf("CloseIdleConnections called %d times, want 1", transport.closed)
+	}
+	if c.queueURL != nil || c.deadLetterURL != nil || c.deadLetter != nil || c.etags != nil {
+		t.Error("Close should drop cached queue URLs, dead letters and ETags")
+	}
+}
@@ -0,0 +1,35 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// TestDownloadDatasetConcurrent shares one Consumer across goroutines; run
+// with -race to check the concurrency guarantee on Consumer.
+func TestDownloadDatasetConcurrent(t *testing.T) {
+	api := newFakeAPI(t)
+	c := newTestConsumer(api.server.URL)
+	dir := t.TempDir()
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			out := filepath.Join(dir, fmt.Sprintf("data-%d.ndjson", i))
+			if err := c.DownloadDataset(context.Background(), "ds-1", out); err != nil {
+				t.Errorf("download %d: %v", i, err)
+				return
+			}
+			if got, _ := os.ReadFile(out); string(got) != "hello world" {
+				t.Errorf("download %d wrote %q, want hello world", i, got)
+			}
+		}()
+	}
+	wg.Wait()
+}
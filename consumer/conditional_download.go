@@ -0,0 +1,58 @@
+package consumer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// ErrNotModified is returned by DownloadDataset when the dataset has not
+// changed since this Consumer last downloaded it to the same output path.
+// The existing output file is left untouched.
+var ErrNotModified = errors.New("dataset not modified")
+
+// Download URLs are presigned with only the host header signed, so the
+// storage service accepts (and honors) an unsigned If-None-Match header on
+// them. If a URL ever ignores the header it simply answers 200 with the full
+// object, and DownloadDataset falls back to an ordinary download.
+
+// etagKey identifies one (dataset, output file) pair.
+func etagKey(datasetID, outputPath string) string {
+	if abs, err := filepath.Abs(outputPath); err == nil {
+		outputPath = abs
+	}
+	return datasetID + "\x00" + outputPath
+}
+
+// downloadETag returns the ETag of the last download of datasetID to
+// outputPath, or "" when there is none or the file has since been removed.
+func (c *Consumer) downloadETag(datasetID, outputPath string) string {
+	c.etagMu.Lock()
+	etag := c.etags[etagKey(datasetID, outputPath)]
+	c.etagMu.Unlock()
+
+	if etag == "" {
+		return ""
+	}
+	if _, err := os.Stat(outputPath); err != nil {
+		return ""
+	}
+	return etag
+}
+
+// rememberDownloadETag records the ETag of a completed download. Responses
+// without an ETag clear any stale entry.
+func (c *Consumer) rememberDownloadETag(datasetID, outputPath, etag string) {
+	c.etagMu.Lock()
+	defer c.etagMu.Unlock()
+
+	key := etagKey(datasetID, outputPath)
+	if etag == "" {
+		delete(c.etags, key)
+		return
+	}
+	if c.etags == nil {
+		c.etags = make(map[string]string)
+	}
+	c.etags[key] = etag
+}
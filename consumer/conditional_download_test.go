@@ -0,0 +1,132 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// etagStore fakes the presigned object URL: it serves body with etag and,
+// unless ignoreConditional is set, answers a matching If-None-Match with 304.
+type etagStore struct {
+	*httptest.Server
+
+	mu                sync.Mutex
+	body, etag        string
+	ignoreConditional bool
+	ifNoneMatch       []string // If-None-Match of each request
+}
+
+func newETagStore(t *testing.T, api *fakeAPI) *etagStore {
+	t.Helper()
+
+	s := &etagStore{body: "v1", etag: `"etag-1"`}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.ifNoneMatch = append(s.ifNoneMatch, r.Header.Get("If-None-Match"))
+		if !s.ignoreConditional && r.Header.Get("If-None-Match") == s.etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", s.etag)
+		_, _ = w.Write([]byte(s.body))
+	}))
+	t.Cleanup(s.Close)
+
+	api.urlInfo = func() *DownloadURLInfo {
+		return &DownloadURLInfo{DownloadURL: s.URL + "/object", EventID: "evt-1"}
+	}
+	return s
+}
+
+func (s *etagStore) set(body, etag string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.body, s.etag = body, etag
+}
+
+func TestDownloadDataset_NotModified(t *testing.T) {
+	api := newFakeAPI(t)
+	store := newETagStore(t, api)
+	c := newTestConsumer(api.server.URL)
+	out := filepath.Join(t.TempDir(), "data.ndjson")
+
+	if err := c.DownloadDataset(context.Background(), "ds-1", out); err != nil {
+		t.Fatalf("first download: %v", err)
+	}
+
+	// Unchanged: 304 and the file is left alone.
+	if err := os.WriteFile(out, []byte("local edit"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.DownloadDataset(context.Background(), "ds-1", out); !errors.Is(err, ErrNotModified) {
+		t.Fatalf("second download err = %v, want ErrNotModified", err)
+	}
+	if got, _ := os.ReadFile(out); string(got) != "local edit" {
+		t.Errorf("output = %q, want the untouched file", got)
+	}
+
+	// Changed: downloaded again.
+	store.set("v2", `"etag-2"`)
+	if err := c.DownloadDataset(context.Background(), "ds-1", out); err != nil {
+		t.Fatalf("third download: %v", err)
+	}
+	if got, _ := os.ReadFile(out); string(got) != "v2" {
+		t.Errorf("output = %q, want v2", got)
+	}
+
+	want := []string{"", `"etag-1"`, `"etag-1"`}
+	if len(store.ifNoneMatch) != len(want) {
+		t.Fatalf("If-None-Match = %q, want %q", store.ifNoneMatch, want)
+	}
+	for i := range want {
+		if store.ifNoneMatch[i] != want[i] {
+			t.Errorf("request %d If-None-Match = %q, want %q", i, store.ifNoneMatch[i], want[i])
+		}
+	}
+}
+
+func TestDownloadDataset_ConditionalFallbacks(t *testing.T) {
+	t.Run("ignored header downloads normally", func(t *testing.T) {
+		api := newFakeAPI(t)
+		store := newETagStore(t, api)
+		store.ignoreConditional = true
+		c := newTestConsumer(api.server.URL)
+		out := filepath.Join(t.TempDir(), "data.ndjson")
+
+		for i := range 2 {
+			if err := c.DownloadDataset(context.Background(), "ds-1", out); err != nil {
+				t.Fatalf("download %d: %v", i, err)
+			}
+		}
+		if got, _ := os.ReadFile(out); string(got) != "v1" {
+			t.Errorf("output = %q, want v1", got)
+		}
+	})
+
+	t.Run("removed output is downloaded unconditionally", func(t *testing.T) {
+		api := newFakeAPI(t)
+		store := newETagStore(t, api)
+		c := newTestConsumer(api.server.URL)
+		out := filepath.Join(t.TempDir(), "data.ndjson")
+
+		if err := c.DownloadDataset(context.Background(), "ds-1", out); err != nil {
+			t.Fatalf("first download: %v", err)
+		}
+		if err := os.Remove(out); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.DownloadDataset(context.Background(), "ds-1", out); err != nil {
+			t.Fatalf("second download: %v", err)
+		}
+		if store.ifNoneMatch[1] != "" {
+			t.Errorf("If-None-Match = %q, want none for a missing output file", store.ifNoneMatch[1])
+		}
+	})
+}
@@ -0,0 +1,157 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultDrainTimeout bounds how long ConsumeNotifications waits for running
+// handlers after its context is cancelled.
+const defaultDrainTimeout = 30 * time.Second
+
+// NotificationHandler processes one notification. Returning nil acknowledges
+// (deletes) the message; an error leaves it on the queue for redelivery.
+type NotificationHandler func(ctx context.Context, n Notification) error
+
+// ConsumeNotificationsOptions configures ConsumeNotifications.
+type ConsumeNotificationsOptions struct {
+	// Poll configures each receive. AutoAcknowledge is ignored: a message is
+	// acknowledged only when its handler succeeds. MaxMessages defaults to
+	// Concurrency (at most 10).
+	Poll PollNotificationsOptions
+
+	// Concurrency is the number of handlers run in parallel (default: 1).
+	Concurrency int
+
+	// DrainTimeout is how long running handlers may continue after ctx is
+	// cancelled (default: 30s). Handlers still running then have their
+	// context cancelled and their messages are left for redelivery.
+	DrainTimeout time.Duration
+}
+
+// DrainError is returned by ConsumeNotifications once it stops because its
+// context was cancelled. It unwraps to the context's error.
+type DrainError struct {
+	// Completed is the number of handlers that were running at cancellation
+	// and finished within DrainTimeout.
+	Completed int
+
+	// Abandoned is the number of received messages left for redelivery:
+	// handlers still running at the drain deadline plus messages that had
+	// not been handed to a handler yet.
+	Abandoned int
+
+	Err error
+}
+
+func (e *DrainError) Error() string {
+	return fmt.Sprintf("notification consumer stopped: %d in-flight message(s) completed, %d abandoned for redelivery: %v",
+		e.Completed, e.Abandoned, e.Err)
+}
+
+func (e *DrainError) Unwrap() error {
+	return e.Err
+}
+
+// ConsumeNotifications polls the notification queue and runs handler for each
+// notification until ctx is cancelled.
+//
+// On cancellation it stops receiving, lets running handlers finish (and their
+// messages be acknowledged) for up to DrainTimeout, then returns a
+// *DrainError, so a rolling deployment doesn't abandon work mid-download. A
+// failure to poll the queue is returned as-is once running handlers finish.
+func (c *Consumer) ConsumeNotifications(ctx context.Context, opts ConsumeNotificationsOptions, handler NotificationHandler) error {
+	if handler == nil {
+		return errors.New("handler is required")
+	}
+
+	concurrency := max(opts.Concurrency, 1)
+	drainTimeout := opts.DrainTimeout
+	if drainTimeout <= 0 {
+		drainTimeout = defaultDrainTimeout
+	}
+
+	pollOpts := opts.Poll
+	manualAck := false
+	pollOpts.AutoAcknowledge = &manualAck
+	if pollOpts.MaxMessages == 0 {
+		pollOpts.MaxMessages = int32(min(concurrency, 10))
+	}
+
+	// Handlers outlive ctx so they can finish during the drain; their
+	// context is cancelled once the drain times out.
+	handlerCtx, cancelHandlers := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelHandlers()
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		inFlight  int
+		completed int // handlers finished after cancellation
+		unstarted int
+	)
+	slots := make(chan struct{}, concurrency)
+
+poll:
+	for ctx.Err() == nil {
+		notifications, err := c.PollNotifications(ctx, pollOpts)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			wg.Wait()
+			return err
+		}
+
+		for i, n := range notifications {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				unstarted = len(notifications) - i
+				break poll
+			}
+
+			mu.Lock()
+			inFlight++
+			mu.Unlock()
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-slots }()
+
+				if err := handler(handlerCtx, n); err != nil {
+					fmt.Printf("Warning: Handler failed for notification %s, leaving it for redelivery: %v\n", n.MessageID, err)
+				} else if err := c.DeleteNotification(handlerCtx, n.ReceiptHandle); err != nil {
+					fmt.Printf("Warning: Failed to acknowledge notification %s: %v\n", n.MessageID, err)
+				}
+
+				mu.Lock()
+				inFlight--
+				if ctx.Err() != nil {
+					completed++
+				}
+				mu.Unlock()
+			}()
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(drainTimeout):
+		cancelHandlers()
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	return &DrainError{Completed: completed, Abandoned: inFlight + unstarted, Err: ctx.Err()}
+}
@@ -0,0 +1,72 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestConsumeNotificationsDrain(t *testing.T) {
+	t.Run("running handler finishes and is acknowledged", func(t *testing.T) {
+		f := newFakeSQS(t, sqsMessage("1", "dataset_updated"))
+		ctx, cancel := context.WithCancel(context.Background())
+		started, release := make(chan struct{}), make(chan struct{})
+
+		errc := make(chan error, 1)
+		go func() {
+			errc <- f.consumer().ConsumeNotifications(ctx, ConsumeNotificationsOptions{DrainTimeout: 5 * time.Second},
+				func(ctx context.Context, n Notification) error {
+					close(started)
+					<-release
+					return ctx.Err() // the handler context is still live during the drain
+				})
+		}()
+
+		<-started
+		cancel()
+		close(release)
+
+		var drainErr *DrainError
+		err := <-errc
+		if !errors.As(err, &drainErr) || !errors.Is(err, context.Canceled) {
+			t.Fatalf("err = %v, want a *DrainError wrapping context.Canceled", err)
+		}
+		if drainErr.Completed != 1 || drainErr.Abandoned != 0 {
+			t.Errorf("DrainError = %+v, want 1 completed, 0 abandoned", drainErr)
+		}
+		if calls := f.takeCalls(); len(calls) != 1 || calls[0] != "DeleteMessage rh-1" {
+			t.Errorf("SQS calls = %v, want the message acknowledged", calls)
+		}
+	})
+
+	t.Run("handler past the drain timeout is abandoned", func(t *testing.T) {
+		f := newFakeSQS(t, sqsMessage("1", "dataset_updated"))
+		ctx, cancel := context.WithCancel(context.Background())
+		started := make(chan struct{})
+
+		errc := make(chan error, 1)
+		go func() {
+			errc <- f.consumer().ConsumeNotifications(ctx, ConsumeNotificationsOptions{DrainTimeout: 50 * time.Millisecond},
+				func(ctx context.Context, n Notification) error {
+					close(started)
+					<-ctx.Done()
+					return ctx.Err()
+				})
+		}()
+
+		<-started
+		cancel()
+
+		var drainErr *DrainError
+		if err := <-errc; !errors.As(err, &drainErr) {
+			t.Fatalf("err = %v, want *DrainError", err)
+		}
+		if drainErr.Completed != 0 || drainErr.Abandoned != 1 {
+			t.Errorf("DrainError = %+v, want 0 completed, 1 abandoned", drainErr)
+		}
+		if calls := f.takeCalls(); len(calls) != 0 {
+			t.Errorf("SQS calls = %v, want the abandoned message left unacknowledged", calls)
+		}
+	})
+}
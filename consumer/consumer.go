@@ -11,35 +11,40 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
-	"crypto/aes"
-	"crypto/cipher"
-	"crypto/sha256"
-	"encoding/binary"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/helix-tools/sdk-go/v2/client"
+	"github.com/helix-tools/sdk-go/v2/clientset"
 	stscreds "github.com/helix-tools/sdk-go/v2/credentials"
+	"github.com/helix-tools/sdk-go/v2/crypto"
+	"github.com/helix-tools/sdk-go/v2/internal/ratelimit"
+	"github.com/helix-tools/sdk-go/v2/internal/tempfile"
 	"github.com/helix-tools/sdk-go/v2/types"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/kms"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
 	"github.com/aws/aws-sdk-go-v2/service/ssm"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
+	smithy "github.com/aws/smithy-go"
 )
 
-// emptyPayloadHash is the SHA256 hash of an empty payload.
-const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
-
 // SDKVersion is the FALLBACK Go SDK version surfaced in download outcome
 // callbacks, used only when the real build version can't be resolved at
 // runtime (see effectiveSDKVersion / resolveSDKVersion in
@@ -89,6 +94,9 @@ const errorMessageMaxChars = 500
 // a slow observability API can't hold up an otherwise successful download.
 const outcomeCallbackTimeout = 5 * time.Second
 
+// maxDeleteBatchEntries is SQS's limit on messages per DeleteMessageBatch.
+const maxDeleteBatchEntries = 10
+
 // userPathPattern strips /Users/<name>/ paths from error_message before
 // sending so the producer dashboard never sees a consumer's home dir.
 var userPathPattern = regexp.MustCompile(`/Users/[^/\s]+`)
@@ -120,17 +128,69 @@ type RecordOutcomeRequest struct {
 }
 
 // Consumer handles downloading and managing datasets from Helix Connect platform.
+//
+// A Consumer is safe for concurrent use by multiple goroutines: its cached
+// queue URLs, dead letters and download ETags are guarded internally, and
+// the HTTP and AWS clients it holds are themselves safe for concurrent use.
 type Consumer struct {
 	APIEndpoint string
 	CustomerID  string
 	Region      string
 
-	awsConfig  aws.Config
-	httpClient *http.Client
-	kmsClient  *kms.Client
-	queueURL   *string // Cache for per-consumer queue URL.
-	sqsClient  *sqs.Client
-	ssmClient  *ssm.Client
+	api          types.APIDoer // when set, replaces the signed API round-trip
+	awsConfig    aws.Config
+	decryptKeyID string // Config.DecryptKMSKeyID
+	httpClient   *http.Client
+	kmsClient    kmsAPI
+	limiter      *ratelimit.Limiter // nil when Config.RequestsPerSecond is unset
+	maxInflate   int64              // Config.MaxDecompressedBytes; 0 means the default
+	queueMu      sync.Mutex
+	queueURL     *string // Cache for per-consumer queue URL; guarded by queueMu.
+	sqsClient    sqsAPI
+	ssmClient    *ssm.Client
+	tempDir      string // Config.TempDir
+	trackViews   bool   // Config.TrackViews
+	zeroize      bool   // Config.ZeroizeBuffers
+
+	// deadLetterURL caches the dead-letter queue URL; deadLetter holds the
+	// messages last listed from it, by receipt handle, for redrive. Both are
+	// guarded by deadLetterMu.
+	deadLetterMu  sync.Mutex
+	deadLetterURL *string
+	deadLetter    map[string]sqstypes.Message
+
+	// etags holds the ETag of each completed download, keyed by dataset
+	// and output path, for conditional re-downloads.
+	etagMu sync.Mutex
+	etags  map[string]string
+
+	// regionalKMS caches a KMS client per region for keys outside Region;
+	// guarded by regionalKMSMu.
+	regionalKMSMu sync.Mutex
+	regionalKMS   map[string]kmsAPI
+
+	// datasetCache caches dataset metadata for GetDatasetCached; nil when
+	// Config.DatasetCacheTTL is unset.
+	datasetCache *datasetCache
+}
+
+// kmsAPI is the subset of the KMS client the consumer calls.
+// *kms.Client satisfies it; tests substitute a fake.
+type kmsAPI interface {
+	Decrypt(ctx context.Context, params *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error)
+}
+
+// sqsAPI is the subset of the SQS client the consumer calls.
+// *sqs.Client satisfies it; tests substitute a fake.
+type sqsAPI interface {
+	ReceiveMessage(ctx context.Context, params *sqs.ReceiveMessageInput, optFns ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error)
+	DeleteMessage(ctx context.Context, params *sqs.DeleteMessageInput, optFns ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error)
+	DeleteMessageBatch(ctx context.Context, params *sqs.DeleteMessageBatchInput, optFns ...func(*sqs.Options)) (*sqs.DeleteMessageBatchOutput, error)
+	ChangeMessageVisibility(ctx context.Context, params *sqs.ChangeMessageVisibilityInput, optFns ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error)
+	SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error)
+	PurgeQueue(ctx context.Context, params *sqs.PurgeQueueInput, optFns ...func(*sqs.Options)) (*sqs.PurgeQueueOutput, error)
+	GetQueueAttributes(ctx context.Context, params *sqs.GetQueueAttributesInput, optFns ...func(*sqs.Options)) (*sqs.GetQueueAttributesOutput, error)
+	GetQueueUrl(ctx context.Context, params *sqs.GetQueueUrlInput, optFns ...func(*sqs.Options)) (*sqs.GetQueueUrlOutput, error)
 }
 
 // DownloadURLInfo contains information about a dataset download URL.
@@ -165,7 +225,9 @@ type Dataset struct {
 	} `json:"metadata"`
 }
 
-// Notification represents a dataset upload notification received from SQS.
+// Notification represents a dataset or subscription event received from SQS.
+// EventType says which (see the EventType constants); unknown event types
+// are passed through rather than dropped.
 type Notification struct {
 	DatasetID      string `json:"dataset_id"`
 	DatasetName    string `json:"dataset_name,omitempty"`
@@ -180,6 +242,10 @@ type Notification struct {
 	SubscriberID   string `json:"subscriber_id"`
 	SubscriptionID string `json:"subscription_id"`
 	Timestamp      string `json:"timestamp"`
+
+	// Attributes are the message attributes (e.g. producer_id, event_type)
+	// from the queue message or, for SNS-enveloped messages, the envelope.
+	Attributes map[string]string `json:"attributes,omitempty"`
 }
 
 // Subscription is an alias for types.Subscription for backward compatibility.
@@ -194,6 +260,19 @@ type ListSubscriptionsOptions struct {
 	Role string
 }
 
+// SubscriptionFilter narrows ListSubscriptionsFiltered. Empty fields don't
+// filter.
+type SubscriptionFilter struct {
+	// Status keeps subscriptions in this state, e.g. "active".
+	Status string
+
+	// ProducerID keeps subscriptions to this producer's datasets.
+	ProducerID string
+
+	// DatasetID keeps subscriptions covering this dataset.
+	DatasetID string
+}
+
 // PollNotificationsOptions contains options for polling notifications from SQS.
 type PollNotificationsOptions struct {
 	AutoAcknowledge *bool    // Automatically acknowledge (delete) messages after receiving (default: true)
@@ -204,6 +283,49 @@ type PollNotificationsOptions struct {
 	//
 	// TODO: Get pattern from AWS SSM.
 	WaitTimeSeconds int32
+
+	// EventTypes, when non-empty, returns only notifications whose event_type
+	// is listed (e.g. "dataset_updated"). Filtered-out messages are never
+	// returned, so the caller cannot acknowledge them: they are deleted from
+	// the queue even when AutoAcknowledge is false, or they would be
+	// redelivered forever. Set RequeueFilteredMessages to keep them instead.
+	EventTypes []string
+
+	// AttributeFilters, when non-empty, returns only notifications whose
+	// message attribute named by each key has one of the listed values (e.g.
+	// {"producer_id": {"company-1"}}). Queue message attributes are checked
+	// before the body is parsed, so non-matching messages skip the JSON
+	// decoding; otherwise the SNS envelope's attributes are used. A message
+	// lacking a filtered attribute is dropped. Dropped messages are
+	// acknowledged like those dropped by EventTypes.
+	AttributeFilters map[string][]string
+
+	// RequeueFilteredMessages makes messages dropped by EventTypes or
+	// AttributeFilters visible on the queue again immediately instead of
+	// deleting them, for queues shared with another poller that handles them.
+	RequeueFilteredMessages bool
+
+	// AcknowledgeParseFailures deletes messages whose body cannot be parsed,
+	// along with any auto-acknowledged notifications. By default they are
+	// left on the queue: they reappear after the visibility timeout and, on
+	// a queue with a redrive policy, eventually move to its dead-letter
+	// queue. Either way they are reported in PollResult.ParseFailures.
+	AcknowledgeParseFailures bool
+}
+
+// ParseFailure is a queue message whose body could not be parsed as a
+// notification.
+type ParseFailure struct {
+	MessageID     string
+	ReceiptHandle string
+	Body          string // raw message body
+	Err           error
+}
+
+// PollResult is the outcome of PollNotificationsResult.
+type PollResult struct {
+	Notifications []Notification
+	ParseFailures []ParseFailure
 }
 
 // NewConsumer creates a new Consumer instance.
@@ -241,36 +363,122 @@ func NewConsumer(cfg types.Config) (*Consumer, error) {
 		return nil, fmt.Errorf("failed to select AWS credentials provider: %w", err)
 	}
 
+	endpointOpt, err := clientset.EndpointOption(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	// Load AWS config.
 	awsCfg, err := config.LoadDefaultConfig(context.Background(),
 		config.WithRegion(cfg.Region),
 		config.WithCredentialsProvider(credProvider),
 		config.WithHTTPClient(awsHTTPClient),
+		endpointOpt,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load AWS config: %w", err)
 	}
 
-	// Validate credentials.
-	stsClient := sts.NewFromConfig(awsCfg)
-	if _, err = stsClient.GetCallerIdentity(
-		context.Background(),
-		&sts.GetCallerIdentityInput{},
-	); err != nil {
-		return nil, fmt.Errorf("invalid AWS credentials: %w", err)
+	// Validate credentials, unless the caller opted out.
+	if !cfg.SkipCredentialValidation {
+		stsClient := sts.NewFromConfig(awsCfg)
+		if _, err = stsClient.GetCallerIdentity(
+			context.Background(),
+			&sts.GetCallerIdentityInput{},
+		); err != nil {
+			return nil, fmt.Errorf("invalid AWS credentials: %w", err)
+		}
+	}
+
+	return newConsumer(cfg, awsCfg, kms.NewFromConfig(awsCfg), sqs.NewFromConfig(awsCfg), ssm.NewFromConfig(awsCfg)), nil
+}
+
+// NewConsumerFromClientSet creates a Consumer on the shared clients of cs,
+// so a process that also runs a Producer keeps a single credential provider
+// and connection pool. The credential check, if any, already ran in clientset.New.
+func NewConsumerFromClientSet(cs *clientset.ClientSet) (*Consumer, error) {
+	return newConsumer(cs.Config, cs.AWSConfig, cs.KMS, cs.SQS, cs.SSM), nil
+}
+
+// NewConsumerWithAPI creates a Consumer whose API requests go to api instead
+// of being signed and sent to the Helix API, so code built on it can be unit
+// tested without credentials (see package helixtest). It makes no calls
+// itself. Methods that also call AWS directly, such as PollNotifications or
+// decrypting a download, fail with a credentials error.
+func NewConsumerWithAPI(cfg types.Config, api types.APIDoer) *Consumer {
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
 	}
 
+	awsCfg := aws.Config{
+		Region: cfg.Region,
+		Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{}, errors.New("no AWS credentials: consumer was built with NewConsumerWithAPI")
+		}),
+	}
+
+	c := newConsumer(cfg, awsCfg, kms.NewFromConfig(awsCfg), sqs.NewFromConfig(awsCfg), ssm.NewFromConfig(awsCfg))
+	c.api = api
+	return c
+}
+
+func newConsumer(cfg types.Config, awsCfg aws.Config, kmsClient *kms.Client, sqsClient *sqs.Client, ssmClient *ssm.Client) *Consumer {
 	return &Consumer{
 		APIEndpoint: cfg.APIEndpoint,
 		CustomerID:  cfg.CustomerID,
 		Region:      cfg.Region,
 
-		awsConfig:  awsCfg,
-		httpClient: &http.Client{Timeout: defaultHTTPClientTimeout},
-		kmsClient:  kms.NewFromConfig(awsCfg),
-		sqsClient:  sqs.NewFromConfig(awsCfg),
-		ssmClient:  ssm.NewFromConfig(awsCfg),
-	}, nil
+		awsConfig:    awsCfg,
+		decryptKeyID: cfg.DecryptKMSKeyID,
+		httpClient:   client.HTTPClientFor(cfg, defaultHTTPClientTimeout),
+		kmsClient:    kmsClient,
+		limiter:      ratelimit.New(cfg.RequestsPerSecond, cfg.Burst),
+		maxInflate:   cfg.MaxDecompressedBytes,
+		sqsClient:    sqsClient,
+		ssmClient:    ssmClient,
+		tempDir:      cfg.TempDir,
+		trackViews:   cfg.TrackViews,
+		zeroize:      cfg.ZeroizeBuffers,
+		datasetCache: newDatasetCache(cfg.DatasetCacheTTL, cfg.DatasetCacheSize),
+	}
+}
+
+// Close releases the consumer's idle HTTP connections, both to the API and
+// to AWS, and drops cached state (queue URLs, listed dead-letter messages,
+// download ETags, dataset metadata). The consumer must not be used after Close. It always
+// returns nil; the error is there for future resources that can fail to
+// release.
+func (c *Consumer) Close() error {
+	for _, client := range []any{c.httpClient, c.awsConfig.HTTPClient} {
+		if cl, ok := client.(interface{ CloseIdleConnections() }); ok {
+			cl.CloseIdleConnections()
+		}
+	}
+
+	c.queueMu.Lock()
+	c.queueURL = nil
+	c.queueMu.Unlock()
+
+	c.deadLetterMu.Lock()
+	c.deadLetterURL = nil
+	c.deadLetter = nil
+	c.deadLetterMu.Unlock()
+
+	c.etagMu.Lock()
+	c.etags = nil
+	c.etagMu.Unlock()
+
+	c.regionalKMSMu.Lock()
+	c.regionalKMS = nil
+	c.regionalKMSMu.Unlock()
+
+	if c.datasetCache != nil {
+		c.datasetCache.mu.Lock()
+		clear(c.datasetCache.entries)
+		c.datasetCache.mu.Unlock()
+	}
+
+	return nil
 }
 
 // GetDataset retrieves metadata for a specific dataset.
@@ -336,7 +544,100 @@ func resolveEncryptCompress(dataset *types.Dataset) (isEncrypted, isCompressed b
 // message on failure, or status=success + bytes_downloaded + duration_ms
 // on success). The callback is best-effort — its failure NEVER affects
 // the caller's experience.
-func (c *Consumer) DownloadDataset(ctx context.Context, datasetID, outputPath string) (retErr error) {
+//
+// Conditional re-download: when this Consumer has already downloaded the
+// dataset to outputPath and the file is still there, the request carries
+// the stored ETag as If-None-Match. If the object is unchanged the method
+// returns ErrNotModified and leaves outputPath untouched (see
+// conditional_download.go).
+//
+// The file is written with mode 0644; use DownloadDatasetWithOptions to
+// choose another.
+func (c *Consumer) DownloadDataset(ctx context.Context, datasetID, outputPath string) error {
+	return c.DownloadDatasetWithOptions(ctx, datasetID, outputPath, DownloadOptions{})
+}
+
+// DownloadOptions configures DownloadDatasetWithOptions.
+type DownloadOptions struct {
+	// FileMode is the permission of the written file (default 0644). Use
+	// 0600 for sensitive data. A new file gets FileMode filtered by the
+	// process umask, as with os.WriteFile; an existing file is changed to
+	// exactly FileMode when it is set explicitly.
+	FileMode os.FileMode
+
+	// OnComplete, when set, is called with the download's stats after a
+	// successful download (not for ErrNotModified or failures).
+	OnComplete func(stats DownloadStats)
+
+	// Direct fetches the stored bytes through the signed API request
+	// instead of a presigned URL fetched separately (see
+	// DownloadDatasetDirect).
+	Direct bool
+
+	// VerifySize compares the number of bytes downloaded with the stored
+	// size the catalog records for the dataset and fails with
+	// ErrSizeMismatch, before decrypting, if they differ. It catches a
+	// transfer cut short behind a 200 response. Datasets with no recorded
+	// size are not checked; the parts of one stored as several are always
+	// checked against its manifest instead.
+	VerifySize bool
+}
+
+// DownloadStats describes a completed download, to log or alert on slow
+// networks and compare stored and plaintext sizes.
+type DownloadStats struct {
+	BytesDownloaded int64         // bytes fetched from storage, as stored
+	BytesWritten    int64         // bytes written to the output file
+	Duration        time.Duration // the whole download, metadata lookups included
+	ThroughputMBps  float64       // MiB/s of the fetch from storage alone
+	Decrypted       bool
+	Decompressed    bool
+
+	Timings DownloadTimings
+
+	// Retries is how many times API requests made for the download
+	// (metadata, download URL) were retried after a failure. The fetch
+	// from storage itself is not retried.
+	Retries int
+}
+
+// DownloadTimings is the wall-clock duration of each download stage, to
+// tell whether the network, decryption or the disk dominates a slow
+// download. Stages that did not run are zero.
+type DownloadTimings struct {
+	Metadata   time.Duration // dataset lookup, zero when cached
+	URLFetch   time.Duration // presigned URL request; zero for direct downloads
+	Download   time.Duration // fetch of the stored bytes
+	Decrypt    time.Duration // includes the KMS request for the data key
+	Decompress time.Duration
+	Write      time.Duration
+}
+
+// throughputMBps returns n bytes over d in MiB/s, or 0 for a zero d.
+func throughputMBps(n int64, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(n) / (1024 * 1024) / d.Seconds()
+}
+
+func (o DownloadOptions) fileMode() os.FileMode {
+	if o.FileMode == 0 {
+		return defaultDownloadFileMode
+	}
+	return o.FileMode.Perm()
+}
+
+// defaultDownloadFileMode is the permission DownloadDataset has always used.
+const defaultDownloadFileMode os.FileMode = 0o644
+
+// DownloadDatasetWithOptions is DownloadDataset with options. Missing parent
+// directories of outputPath are created before the download starts.
+//
+// A dataset stored as several parts (see producer.UploadShardedDataset) is
+// reassembled from its manifest: every part's records, in order, are
+// written to outputPath. Use DownloadDelta to fetch only newer parts.
+func (c *Consumer) DownloadDatasetWithOptions(ctx context.Context, datasetID, outputPath string, opts DownloadOptions) (retErr error) {
 	fmt.Printf("Downloading dataset %s...\n", datasetID)
 
 	start := time.Now()
@@ -350,8 +651,31 @@ func (c *Consumer) DownloadDataset(ctx context.Context, datasetID, outputPath st
 		eventID         string
 		bytesDownloaded int64
 		errorMessage    string
+		stats           DownloadStats
 	)
 
+	var retries atomic.Int64
+	ctx = client.CountRetries(ctx, &retries)
+
+	defer func() {
+		if retErr == nil && opts.OnComplete != nil {
+			stats.Duration = time.Since(start)
+			stats.Retries = int(retries.Load())
+			opts.OnComplete(stats)
+		}
+	}()
+
+	// plaintexts collects the buffers that hold dataset plaintext, to be
+	// zeroed on return, success or not, under Config.ZeroizeBuffers.
+	var plaintexts [][]byte
+	if c.zeroize {
+		defer func() {
+			for _, b := range plaintexts {
+				clear(b)
+			}
+		}()
+	}
+
 	// defer fires the outcome callback after the pipeline returns or
 	// panics. We capture variables by value into the deferred goroutine
 	// so a later mutation can't poison the payload, and use a fresh
@@ -369,7 +693,7 @@ func (c *Consumer) DownloadDataset(ctx context.Context, datasetID, outputPath st
 			SDKVersion:  effectiveSDKVersion(),
 			SDKLanguage: SDKLanguage,
 		}
-		if retErr == nil {
+		if retErr == nil || errors.Is(retErr, ErrNotModified) {
 			req.Status = "success"
 			req.BytesDownloaded = bytesDownloaded
 		} else {
@@ -387,15 +711,28 @@ func (c *Consumer) DownloadDataset(ctx context.Context, datasetID, outputPath st
 		}(req, datasetID)
 	}()
 
+	// Create the output directory up front so a bad path fails before any
+	// bytes are transferred.
+	if err := ensureOutputDir(outputPath); err != nil {
+		return err
+	}
+
 	// 1. Metadata fetch (BEFORE signed-url fetch — matches TS order so a
 	// metadata failure has no event_id captured yet and the callback
 	// becomes a no-op).
 	phase = ErrorCategoryMetadataFetch
-	dataset, err := c.GetDataset(ctx, datasetID)
+	stageStart := time.Now()
+	dataset, err := c.GetDatasetCached(ctx, datasetID)
 	if err != nil {
 		errorMessage = err.Error()
 		return fmt.Errorf("failed to get dataset metadata: %w", err)
 	}
+	stats.Timings.Metadata = time.Since(stageStart)
+
+	// A sharded dataset's stored object is its manifest, not its records.
+	if _, sharded := dataset.Metadata["part_count"]; sharded {
+		return c.downloadSharded(ctx, datasetID, dataset, outputPath, opts, &stats, &plaintexts)
+	}
 
 	// Decide whether to decrypt/decompress (see resolveEncryptCompress).
 	isEncrypted, isCompressed := resolveEncryptCompress(dataset)
@@ -403,31 +740,56 @@ func (c *Consumer) DownloadDataset(ctx context.Context, datasetID, outputPath st
 	fmt.Printf("   Compressed: %v\n", isCompressed)
 	fmt.Printf("   Encrypted: %v\n", isEncrypted)
 
-	// 2. Signed-URL fetch.
-	phase = ErrorCategorySignedURLFetch
-	urlInfo, err := c.GetDownloadURL(ctx, datasetID)
-	if err != nil {
-		errorMessage = err.Error()
-		return fmt.Errorf("failed to get download URL: %w", err)
-	}
-	// Capture event_id for the outcome callback. Absent against older
-	// API versions — the callback path becomes a no-op.
-	eventID = urlInfo.EventID
-
-	// 3. Network fetch.
-	phase = ErrorCategoryNetworkFetch
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlInfo.DownloadURL, nil)
-	if err != nil {
-		errorMessage = err.Error()
-		return fmt.Errorf("failed to build download request: %w", err)
-	}
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		errorMessage = err.Error()
-		return fmt.Errorf("failed to download: %w", err)
+	var (
+		resp       *http.Response
+		fetchStart time.Time
+	)
+	if opts.Direct {
+		// 2+3. Network fetch, the API streaming the object itself.
+		phase = ErrorCategoryNetworkFetch
+		fetchStart = time.Now()
+		resp, err = c.openDirectDownload(ctx, datasetID, c.downloadETag(datasetID, outputPath))
+		if err != nil {
+			errorMessage = err.Error()
+			return fmt.Errorf("failed to download: %w", err)
+		}
+	} else {
+		// 2. Signed-URL fetch.
+		phase = ErrorCategorySignedURLFetch
+		stageStart = time.Now()
+		urlInfo, err := c.GetDownloadURL(ctx, datasetID)
+		if err != nil {
+			errorMessage = err.Error()
+			return fmt.Errorf("failed to get download URL: %w", err)
+		}
+		stats.Timings.URLFetch = time.Since(stageStart)
+		// Capture event_id for the outcome callback. Absent against older
+		// API versions — the callback path becomes a no-op.
+		eventID = urlInfo.EventID
+
+		// 3. Network fetch.
+		phase = ErrorCategoryNetworkFetch
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlInfo.DownloadURL, nil)
+		if err != nil {
+			errorMessage = err.Error()
+			return fmt.Errorf("failed to build download request: %w", err)
+		}
+		if etag := c.downloadETag(datasetID, outputPath); etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		fetchStart = time.Now()
+		resp, err = c.httpClient.Do(req)
+		if err != nil {
+			errorMessage = err.Error()
+			return fmt.Errorf("failed to download: %w", err)
+		}
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotModified {
+		fmt.Printf("Dataset %s not modified; keeping %s\n", datasetID, outputPath)
+		return ErrNotModified
+	}
 	if resp.StatusCode != http.StatusOK {
 		errorMessage = fmt.Sprintf("download failed with status %d", resp.StatusCode)
 		return fmt.Errorf("%s", errorMessage)
@@ -438,10 +800,10 @@ func (c *Consumer) DownloadDataset(ctx context.Context, datasetID, outputPath st
 
 	if contentLength > largeFileThreshold {
 		// Large-file path: stream to temp, process, write final.
-		tempFile, terr := os.CreateTemp("", "helix-dataset-*")
+		tempFile, terr := tempfile.Create(c.tempDir, "helix-dataset-*")
 		if terr != nil {
 			errorMessage = terr.Error()
-			return fmt.Errorf("failed to create temp file: %w", terr)
+			return terr
 		}
 		defer os.Remove(tempFile.Name())
 		defer tempFile.Close()
@@ -452,38 +814,58 @@ func (c *Consumer) DownloadDataset(ctx context.Context, datasetID, outputPath st
 		written, cerr := io.Copy(tempFile, resp.Body)
 		if cerr != nil {
 			errorMessage = cerr.Error()
-			return fmt.Errorf("failed to stream to temp file: %w", cerr)
+			return fmt.Errorf("failed to stream to temp file: %w", tempfile.WriteError(c.tempDir, cerr))
 		}
 		tempFile.Close()
 		fmt.Printf("Downloaded %d bytes to temp file\n", written)
 		bytesDownloaded = written
+		stats.BytesDownloaded = written
+		stats.Timings.Download = time.Since(fetchStart)
+		stats.ThroughputMBps = throughputMBps(written, stats.Timings.Download)
+
+		if opts.VerifySize {
+			if err := verifyStoredSize(dataset, bytesDownloaded); err != nil {
+				errorMessage = err.Error()
+				return err
+			}
+		}
 
 		data, rerr := os.ReadFile(tempFile.Name())
 		if rerr != nil {
 			errorMessage = rerr.Error()
 			return fmt.Errorf("failed to read temp file: %w", rerr)
 		}
+		if !isEncrypted {
+			plaintexts = append(plaintexts, data)
+		}
 
 		if isEncrypted {
 			phase = ErrorCategoryKMSDecrypt
 			fmt.Printf("Decrypting %d bytes with KMS...\n", len(data))
-			data, err = c.decryptData(ctx, data)
+			stageStart = time.Now()
+			data, err = c.decryptData(ctx, data, datasetKeyRegion(dataset))
 			if err != nil {
 				errorMessage = err.Error()
-				return fmt.Errorf("decryption failed: %w", err)
+				return c.decryptionError(ctx, dataset, err)
 			}
+			plaintexts = append(plaintexts, data)
+			stats.Timings.Decrypt = time.Since(stageStart)
 			fmt.Printf("Decrypted to %d bytes\n", len(data))
+			stats.Decrypted = true
 			bytesDownloaded = int64(len(data))
 		}
 
 		if isCompressed {
 			phase = ErrorCategoryDecompress
 			fmt.Printf("Decompressing %d bytes...\n", len(data))
+			stageStart = time.Now()
 			data, err = c.decompressData(data)
 			if err != nil {
 				errorMessage = err.Error()
 				return fmt.Errorf("decompression failed: %w", err)
 			}
+			stats.Timings.Decompress = time.Since(stageStart)
+			plaintexts = append(plaintexts, data)
 			decompressedGB := float64(len(data)) / (1024 * 1024 * 1024)
 			if decompressedGB > 1 {
 				fmt.Printf("Decompressed to %.2f GB\n", decompressedGB)
@@ -494,11 +876,16 @@ func (c *Consumer) DownloadDataset(ctx context.Context, datasetID, outputPath st
 		}
 
 		phase = ErrorCategoryDiskWrite
-		if werr := os.WriteFile(outputPath, data, 0644); werr != nil {
+		stats.Decompressed = isCompressed
+		stats.BytesWritten = int64(len(data))
+		stageStart = time.Now()
+		if werr := writeOutputFile(outputPath, data, opts); werr != nil {
 			errorMessage = werr.Error()
 			return fmt.Errorf("failed to write file: %w", werr)
 		}
+		stats.Timings.Write = time.Since(stageStart)
 		fmt.Printf("Saved to %s\n", outputPath)
+		c.rememberDownloadETag(datasetID, outputPath, resp.Header.Get("ETag"))
 		return nil
 	}
 
@@ -511,27 +898,47 @@ func (c *Consumer) DownloadDataset(ctx context.Context, datasetID, outputPath st
 
 	fmt.Printf("Downloaded %d bytes\n", len(data))
 	bytesDownloaded = int64(len(data))
+	if !isEncrypted {
+		plaintexts = append(plaintexts, data)
+	}
+	stats.BytesDownloaded = bytesDownloaded
+	stats.Timings.Download = time.Since(fetchStart)
+	stats.ThroughputMBps = throughputMBps(bytesDownloaded, stats.Timings.Download)
+
+	if opts.VerifySize {
+		if err := verifyStoredSize(dataset, bytesDownloaded); err != nil {
+			errorMessage = err.Error()
+			return err
+		}
+	}
 
 	if isEncrypted {
 		phase = ErrorCategoryKMSDecrypt
 		fmt.Printf("Decrypting %d bytes with KMS...\n", len(data))
-		data, err = c.decryptData(ctx, data)
+		stageStart = time.Now()
+		data, err = c.decryptData(ctx, data, datasetKeyRegion(dataset))
 		if err != nil {
 			errorMessage = err.Error()
-			return fmt.Errorf("decryption failed: %w", err)
+			return c.decryptionError(ctx, dataset, err)
 		}
+		plaintexts = append(plaintexts, data)
+		stats.Timings.Decrypt = time.Since(stageStart)
 		fmt.Printf("Decrypted to %d bytes\n", len(data))
+		stats.Decrypted = true
 		bytesDownloaded = int64(len(data))
 	}
 
 	if isCompressed {
 		phase = ErrorCategoryDecompress
 		fmt.Printf("Decompressing %d bytes...\n", len(data))
+		stageStart = time.Now()
 		data, err = c.decompressData(data)
 		if err != nil {
 			errorMessage = err.Error()
 			return fmt.Errorf("decompression failed: %w", err)
 		}
+		stats.Timings.Decompress = time.Since(stageStart)
+		plaintexts = append(plaintexts, data)
 		decompressedGB := float64(len(data)) / (1024 * 1024 * 1024)
 		if decompressedGB > 1 {
 			fmt.Printf("Decompressed to %.2f GB\n", decompressedGB)
@@ -542,12 +949,109 @@ func (c *Consumer) DownloadDataset(ctx context.Context, datasetID, outputPath st
 	}
 
 	phase = ErrorCategoryDiskWrite
-	if err := os.WriteFile(outputPath, data, 0644); err != nil {
+	stats.Decompressed = isCompressed
+	stats.BytesWritten = int64(len(data))
+	stageStart = time.Now()
+	if err := writeOutputFile(outputPath, data, opts); err != nil {
 		errorMessage = err.Error()
 		return fmt.Errorf("failed to write file: %w", err)
 	}
+	stats.Timings.Write = time.Since(stageStart)
 	fmt.Printf("Saved to %s\n", outputPath)
+	c.rememberDownloadETag(datasetID, outputPath, resp.Header.Get("ETag"))
+
+	return nil
+}
+
+// downloadSharded is DownloadDatasetWithOptions for a dataset stored as
+// several parts: it fetches the manifest and writes every part's records,
+// in manifest order, to outputPath, as DownloadDelta does with no
+// sincePart. Each part is verified against the manifest, so VerifySize and
+// Direct do not apply, and the download is not conditional. The manifest
+// request counts as URLFetch and the parts as Download, decryption and
+// decompression included.
+func (c *Consumer) downloadSharded(ctx context.Context, datasetID string, dataset *types.Dataset, outputPath string, opts DownloadOptions, stats *DownloadStats, plaintexts *[][]byte) error {
+	stageStart := time.Now()
+	manifest, err := c.GetDownloadManifest(ctx, datasetID)
+	if err != nil {
+		return fmt.Errorf("failed to get manifest: %w", err)
+	}
+	stats.Timings.URLFetch = time.Since(stageStart)
 
+	fmt.Printf("Downloading %d parts...\n", len(manifest.Parts))
+	stageStart = time.Now()
+	records, stored, err := c.readParts(ctx, dataset, manifest.Parts)
+	if err != nil {
+		return err
+	}
+	*plaintexts = append(*plaintexts, records)
+	stats.Timings.Download = time.Since(stageStart)
+	stats.BytesDownloaded = stored
+	stats.ThroughputMBps = throughputMBps(stored, stats.Timings.Download)
+	stats.Decrypted, stats.Decompressed = resolveEncryptCompress(dataset)
+
+	stageStart = time.Now()
+	if err := writeOutputFile(outputPath, records, opts); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	stats.Timings.Write = time.Since(stageStart)
+	stats.BytesWritten = int64(len(records))
+	fmt.Printf("Saved %d bytes from %d parts to %s\n", len(records), len(manifest.Parts), outputPath)
+	return nil
+}
+
+// DownloadDatasetDirect is DownloadDataset with the API streaming the
+// stored bytes over the signed request (GET
+// /v1/datasets/{id}/download?redirect=false, or a redirect it answers with),
+// instead of issuing a presigned URL that is then fetched separately. It
+// saves a round trip and cannot race the URL's expiry, which suits small
+// and medium datasets; DownloadDataset remains the better choice for large
+// transfers, which the presigned URL sends straight from storage.
+func (c *Consumer) DownloadDatasetDirect(ctx context.Context, datasetID, outputPath string) error {
+	return c.DownloadDatasetWithOptions(ctx, datasetID, outputPath, DownloadOptions{Direct: true})
+}
+
+// openDirectDownload starts the signed download of datasetID's stored
+// object, conditional on etag when it is non-empty.
+func (c *Consumer) openDirectDownload(ctx context.Context, datasetID, etag string) (*http.Response, error) {
+	if c.api != nil {
+		return nil, errors.New("direct downloads need the signed API client, not the APIDoer the consumer was built with")
+	}
+
+	headers := http.Header{}
+	if etag != "" {
+		headers.Set("If-None-Match", etag)
+	}
+	path := fmt.Sprintf("/v1/datasets/%s/download?redirect=false", url.PathEscape(datasetID))
+	resp, err := c.APIClient().Stream(ctx, path, headers)
+	var apiErr *client.APIError
+	if errors.As(err, &apiErr) {
+		return nil, &types.StatusError{StatusCode: apiErr.StatusCode, Body: apiErr.Body}
+	}
+	return resp, err
+}
+
+// ensureOutputDir creates the missing parent directories of outputPath.
+func ensureOutputDir(outputPath string) error {
+	dir := filepath.Dir(outputPath)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create output directory %s: %w", dir, err)
+	}
+	return nil
+}
+
+// writeOutputFile writes a downloaded dataset, applying opts.FileMode.
+func writeOutputFile(outputPath string, data []byte, opts DownloadOptions) error {
+	_, statErr := os.Stat(outputPath)
+	mode := opts.fileMode()
+	if err := os.WriteFile(outputPath, data, mode); err != nil {
+		return err
+	}
+
+	// os.WriteFile keeps the mode of an existing file.
+	if statErr == nil && opts.FileMode != 0 {
+		return os.Chmod(outputPath, mode)
+	}
 	return nil
 }
 
@@ -592,81 +1096,210 @@ func sanitizeErrorMessage(msg string) string {
 	return scrubbed
 }
 
-// decryptData decrypts data using envelope decryption.
-func (c *Consumer) decryptData(ctx context.Context, data []byte) ([]byte, error) {
-	buf := bytes.NewReader(data)
+// decryptData decrypts data sealed by a producer, unwrapping its data key
+// with KMS (see package crypto for the format). keyRegion is the region of
+// the producer's key, from the dataset's kms_key_region metadata; the
+// region of a DecryptKMSKeyID ARN takes precedence, and with neither the
+// consumer's own region is used.
+func (c *Consumer) decryptData(ctx context.Context, data []byte, keyRegion string) ([]byte, error) {
+	kmsClient := c.kmsClientFor(crypto.KeyRegion(c.decryptKeyID, keyRegion))
+	plaintext, err := crypto.Open(ctx, kmsClient, data, crypto.OpenOptions{KeyID: c.decryptKeyID})
+	if err != nil {
+		return nil, explainKMSError(err)
+	}
+	return plaintext, nil
+}
 
-	// Read encrypted key length.
-	var keyLen uint32
-	if err := binary.Read(buf, binary.BigEndian, &keyLen); err != nil {
-		return nil, err
+// kmsClientFor returns a KMS client for region: the consumer's own for its
+// region (or an empty one), else one built from the consumer's AWS config
+// and cached for later downloads.
+func (c *Consumer) kmsClientFor(region string) kmsAPI {
+	if region == "" || region == c.Region {
+		return c.kmsClient
 	}
 
-	// Read encrypted data key.
-	encryptedKey := make([]byte, keyLen)
-	if _, err := buf.Read(encryptedKey); err != nil {
-		return nil, err
+	c.regionalKMSMu.Lock()
+	defer c.regionalKMSMu.Unlock()
+
+	if client, ok := c.regionalKMS[region]; ok {
+		return client
+	}
+	client := kms.NewFromConfig(c.awsConfig, func(o *kms.Options) { o.Region = region })
+	if c.regionalKMS == nil {
+		c.regionalKMS = make(map[string]kmsAPI)
 	}
+	c.regionalKMS[region] = client
+	return client
+}
 
-	// Read IV (16 bytes).
-	iv := make([]byte, 16)
-	if _, err := buf.Read(iv); err != nil {
-		return nil, err
+// datasetKeyRegion returns the kms_key_region the producer recorded for
+// dataset, or "" for datasets uploaded before it was recorded.
+func datasetKeyRegion(dataset *types.Dataset) string {
+	region, _ := dataset.Metadata["kms_key_region"].(string)
+	return region
+}
+
+// ErrKMSAccessDenied is returned (wrapped) when KMS refuses to decrypt a
+// dataset's data key, which almost always means the subscription has no
+// working decryption grant.
+var ErrKMSAccessDenied = errors.New("KMS access denied — your subscription may be missing a decryption grant; contact the producer or re-subscribe")
+
+// decryptionError wraps a decryptData failure for DownloadDataset. For
+// ErrKMSAccessDenied it looks up the subscription covering dataset and
+// names it and its kms_grant_id, so the caller knows which grant to chase;
+// the lookup is best-effort.
+func (c *Consumer) decryptionError(ctx context.Context, dataset *types.Dataset, err error) error {
+	if !errors.Is(err, ErrKMSAccessDenied) {
+		return fmt.Errorf("%w: %w", types.ErrDecryptionFailed, err)
+	}
+
+	subs, lerr := c.ListSubscriptions(ctx, nil)
+	if lerr != nil {
+		return fmt.Errorf("%w: %w", types.ErrDecryptionFailed, err)
+	}
+	sub := subscriptionFor(subs, dataset)
+	switch {
+	case sub == nil:
+		return fmt.Errorf("%w (no subscription covers dataset %s): %w", types.ErrDecryptionFailed, dataset.ID, err)
+	case sub.KMSGrantID == nil || *sub.KMSGrantID == "":
+		return fmt.Errorf("%w (subscription %s has no kms_grant_id): %w", types.ErrDecryptionFailed, sub.ID, err)
+	default:
+		return fmt.Errorf("%w (subscription %s, kms_grant_id %s): %w", types.ErrDecryptionFailed, sub.ID, *sub.KMSGrantID, err)
 	}
+}
 
-	// Read auth tag (16 bytes).
-	authTag := make([]byte, 16)
-	if _, err := buf.Read(authTag); err != nil {
-		return nil, err
+// subscriptionFor returns the subscription that grants access to dataset:
+// one for the dataset itself, else an all-datasets subscription to its
+// producer. It returns nil when there is none.
+func subscriptionFor(subs []Subscription, dataset *types.Dataset) *Subscription {
+	var producerWide *Subscription
+	for i := range subs {
+		sub := &subs[i]
+		switch {
+		case sub.DatasetID != nil && *sub.DatasetID == dataset.ID:
+			return sub
+		case sub.DatasetID == nil && sub.ProducerID == dataset.ProducerID && producerWide == nil:
+			producerWide = sub
+		}
 	}
+	return producerWide
+}
 
-	// Remaining bytes are encrypted data.
-	encryptedData, err := io.ReadAll(buf)
-	if err != nil {
-		return nil, err
+// explainKMSError turns the KMS failures a consumer can act on into
+// actionable errors: both usually mean the subscription's KMS grant
+// (kms_grant_id) is missing, revoked or points at a key the producer has
+// since disabled. Other errors are returned unchanged.
+func explainKMSError(err error) error {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+	switch apiErr.ErrorCode() {
+	case "AccessDeniedException":
+		return fmt.Errorf("%w: %w", ErrKMSAccessDenied, err)
+	case "KMSInvalidStateException":
+		return fmt.Errorf("%w (the dataset's key is disabled or pending deletion: the subscription's kms_grant_id no longer grants a usable key, contact the producer)", err)
 	}
+	return err
+}
 
-	// Decrypt data key with KMS.
-	decryptOut, err := c.kmsClient.Decrypt(ctx, &kms.DecryptInput{
-		CiphertextBlob: encryptedKey,
-	})
+// decompressData decompresses data using gzip.
+func (c *Consumer) decompressData(data []byte) ([]byte, error) {
+	gr, err := gzip.NewReader(bytes.NewReader(data))
 	if err != nil {
-		return nil, fmt.Errorf("KMS decrypt failed: %w", err)
+		return nil, corruptCompressedData(err)
 	}
 
-	// Decrypt data with AES-256-GCM.
-	block, err := aes.NewCipher(decryptOut.Plaintext)
-	if err != nil {
-		return nil, err
+	defer gr.Close()
+
+	limit := c.maxInflate
+	if limit <= 0 {
+		limit = defaultMaxDecompressedBytes
 	}
 
-	// Use 16-byte nonce (Python uses os.urandom(16) for IV).
-	aesGCM, err := cipher.NewGCMWithNonceSize(block, 16)
+	// io.ReadAll reaches the gzip trailer, so a CRC or length mismatch
+	// (gzip.ErrChecksum) or a cut-off stream (io.ErrUnexpectedEOF) surfaces
+	// here rather than as silently partial data. Reading one byte past the
+	// limit tells an oversize stream apart from one exactly at it.
+	out, err := io.ReadAll(io.LimitReader(gr, limit+1))
 	if err != nil {
-		return nil, err
+		return nil, corruptCompressedData(err)
+	}
+	if int64(len(out)) > limit {
+		return nil, fmt.Errorf("%w (%d bytes); raise Config.MaxDecompressedBytes if the dataset is expected to be this large",
+			ErrDecompressedSizeExceeded, limit)
 	}
 
-	// Append auth tag to encrypted data for GCM.
-	ciphertext := append(encryptedData, authTag...)
+	return out, nil
+}
 
-	plaintext, err := aesGCM.Open(nil, iv, ciphertext, nil)
-	if err != nil {
-		return nil, fmt.Errorf("AES-GCM decrypt failed: %w", err)
+// defaultMaxDecompressedBytes is the decompressed size limit used when
+// Config.MaxDecompressedBytes is unset.
+const defaultMaxDecompressedBytes int64 = 2 << 30
+
+// ErrDecompressedSizeExceeded is returned (wrapped) by DownloadDataset when
+// a compressed dataset expands past Config.MaxDecompressedBytes.
+var ErrDecompressedSizeExceeded = errors.New("decompressed size exceeds limit")
+
+// ErrSizeMismatch is returned (wrapped) by DownloadDatasetWithOptions with
+// DownloadOptions.VerifySize when the download is not the size the catalog
+// records for the stored object. Retrying the download usually helps.
+var ErrSizeMismatch = errors.New("downloaded size does not match the catalog")
+
+// verifyStoredSize checks n downloaded bytes against dataset's stored size:
+// its encrypted_size_bytes or compressed_size_bytes metadata, whichever
+// describes the stored form, or else its size_bytes. A dataset stored as
+// parts has each part verified against its manifest instead.
+func verifyStoredSize(dataset *types.Dataset, n int64) error {
+	if _, sharded := dataset.Metadata["part_count"]; sharded {
+		return nil
 	}
 
-	return plaintext, nil
+	isEncrypted, isCompressed := resolveEncryptCompress(dataset)
+	var want int64
+	switch {
+	case isEncrypted:
+		want = metadataInt64(dataset.Metadata, "encrypted_size_bytes")
+	case isCompressed:
+		want = metadataInt64(dataset.Metadata, "compressed_size_bytes")
+	}
+	if want == 0 {
+		want = dataset.SizeBytes
+	}
+	if want == 0 {
+		fmt.Printf("Warning: dataset %s records no stored size; download size not verified\n", dataset.ID)
+		return nil
+	}
+	if n != want {
+		return fmt.Errorf("%w: downloaded %d bytes, catalog records %d", ErrSizeMismatch, n, want)
+	}
+	return nil
 }
 
-// decompressData decompresses data using gzip.
-func (c *Consumer) decompressData(data []byte) ([]byte, error) {
-	gr, err := gzip.NewReader(bytes.NewReader(data))
-	if err != nil {
-		return nil, err
-	}
+// metadataInt64 returns the number stored under key in metadata, or 0.
+func metadataInt64(metadata map[string]any, key string) int64 {
+	switch v := metadata[key].(type) {
+	case float64:
+		return int64(v)
+	case int64:
+		return v
+	case int:
+		return int64(v)
+	}
+	return 0
+}
 
-	defer gr.Close()
+// ErrCorruptCompressedData is returned (wrapped) by DownloadDataset when the
+// downloaded data is not a complete, valid gzip stream: truncated, failing
+// its checksum, or not gzip at all. Retrying the download usually helps.
+var ErrCorruptCompressedData = errors.New("corrupt compressed data")
 
-	return io.ReadAll(gr)
+func corruptCompressedData(err error) error {
+	if errors.Is(err, io.EOF) {
+		// An empty input fails gzip.NewReader with a bare io.EOF.
+		err = io.ErrUnexpectedEOF
+	}
+	return fmt.Errorf("%w: %w", ErrCorruptCompressedData, err)
 }
 
 // ListDatasets lists all available datasets.
@@ -722,6 +1355,28 @@ func (c *Consumer) ListSubscriptions(ctx context.Context, opts *ListSubscription
 	return response.Subscriptions, nil
 }
 
+// ListSubscriptionsFiltered is ListSubscriptions filtered server side by
+// opts, so consumers with many subscriptions fetch only those they need.
+//
+// GET /v1/subscriptions?status=&producer_id=&dataset_id=
+func (c *Consumer) ListSubscriptionsFiltered(ctx context.Context, opts SubscriptionFilter) ([]Subscription, error) {
+	q := url.Values{}
+	for key, value := range map[string]string{"status": opts.Status, "producer_id": opts.ProducerID, "dataset_id": opts.DatasetID} {
+		if value != "" {
+			q.Set(key, value)
+		}
+	}
+
+	var response struct {
+		Subscriptions []Subscription `json:"subscriptions"`
+	}
+	if err := c.makeAPIRequest(ctx, http.MethodGet, listPath("/v1/subscriptions", q), nil, &response); err != nil {
+		return nil, err
+	}
+
+	return response.Subscriptions, nil
+}
+
 // CreateSubscriptionRequest creates a subscription request to access a producer's datasets.
 // The producer must approve the request before the consumer gains access.
 //
@@ -757,70 +1412,35 @@ func (c *Consumer) CreateSubscriptionRequest(ctx context.Context, input types.Cr
 
 // makeAPIRequest makes an authenticated API request.
 func (c *Consumer) makeAPIRequest(ctx context.Context, method, path string, body interface{}, result interface{}) error {
-	reqURL := c.APIEndpoint + path
-
-	var (
-		reqBody  io.Reader
-		jsonData []byte
-	)
-
-	if body != nil {
-		var err error
-		jsonData, err = json.Marshal(body)
-		if err != nil {
-			return err
-		}
-
-		reqBody = bytes.NewReader(jsonData)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, method, reqURL, reqBody)
-	if err != nil {
-		return err
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-
-	// Sign request with AWS SigV4
-	creds, err := c.awsConfig.Credentials.Retrieve(ctx)
-	if err != nil {
-		return err
-	}
-
-	// Calculate payload hash for SigV4
-	payloadHash := emptyPayloadHash
-	if body != nil {
-		// Hash the actual JSON body
-		h := sha256.New()
-		h.Write(jsonData)
-		payloadHash = fmt.Sprintf("%x", h.Sum(nil))
-	}
-
-	signer := v4.NewSigner()
-	if err := signer.SignHTTP(ctx, creds, req, payloadHash, "execute-api", c.Region, time.Now()); err != nil {
-		return err
-	}
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return err
+	if c.api != nil {
+		return c.api.Do(ctx, method, path, body, result)
 	}
 
-	defer resp.Body.Close()
-
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-
-		return fmt.Errorf("API request failed: %d - %s", resp.StatusCode, string(bodyBytes))
+	// A result to decode but no body is an error, as it has always been
+	// for the consumer's own requests.
+	apiClient := c.APIClient()
+	apiClient.RequireBody = true
+	err := apiClient.Request(ctx, method, path, body, result)
+	var apiErr *client.APIError
+	if errors.As(err, &apiErr) {
+		return &types.StatusError{StatusCode: apiErr.StatusCode, Body: apiErr.Body}
 	}
+	return err
+}
 
-	if result != nil {
-		if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
-			return err
-		}
+// APIClient returns a client for the Helix API that signs with the
+// consumer's credentials and shares its HTTP client and rate limit, for
+// calling endpoints the SDK does not wrap. It always talks to APIEndpoint,
+// even for a Consumer built with NewConsumerWithAPI.
+func (c *Consumer) APIClient() *client.Client {
+	return &client.Client{
+		Endpoint:   c.APIEndpoint,
+		Region:     c.Region,
+		AWSConfig:  c.awsConfig,
+		HTTPClient: c.httpClient,
+		Limiter:    c.limiter,
+		Retry:      client.DefaultRetryPolicy,
 	}
-
-	return nil
 }
 
 // PollNotifications polls the per-consumer SQS queue for dataset upload notifications.
@@ -832,7 +1452,28 @@ func (c *Consumer) makeAPIRequest(ctx context.Context, method, path string, body
 // Messages are automatically acknowledged (deleted) by default after retrieval.
 // This prevents duplicate processing and simplifies the developer experience.
 // Set opts.AutoAcknowledge to false if you need manual control over message deletion.
+//
+// Messages that cannot be parsed are skipped with a warning; use
+// PollNotificationsResult to receive them.
+//
+// When the queue cannot be found the error matches ErrNoActiveSubscriptions,
+// ErrQueueNotProvisioned or ErrLegacySubscription.
 func (c *Consumer) PollNotifications(ctx context.Context, opts PollNotificationsOptions) ([]Notification, error) {
+	result, err := c.PollNotificationsResult(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, failure := range result.ParseFailures {
+		fmt.Printf("Warning: Skipping message %s: %v\n", failure.MessageID, failure.Err)
+	}
+	return result.Notifications, nil
+}
+
+// PollNotificationsResult is PollNotifications that also returns the
+// messages it could not parse, so callers can route them to a dead-letter
+// queue or alert on them. Whether those messages are deleted is set by
+// opts.AcknowledgeParseFailures.
+func (c *Consumer) PollNotificationsResult(ctx context.Context, opts PollNotificationsOptions) (*PollResult, error) {
 	// Apply defaults
 	if opts.MaxMessages == 0 {
 		opts.MaxMessages = 10
@@ -857,51 +1498,11 @@ func (c *Consumer) PollNotifications(ctx context.Context, opts PollNotifications
 		autoAcknowledge = *opts.AutoAcknowledge
 	}
 
-	// Get per-consumer queue URL from active subscriptions where this customer is the consumer.
-	if c.queueURL == nil {
-		// For "both" customers, explicitly request consumer subscriptions to disambiguate.
-		// This ensures we get the queue where WE are the consumer, not producer.
-		subscriptions, err := c.ListSubscriptions(ctx, &ListSubscriptionsOptions{Role: "consumer"})
-		if err != nil {
-			return nil, fmt.Errorf("failed to get subscriptions: %w", err)
-		}
-
-		if len(subscriptions) == 0 {
-			return nil, fmt.Errorf("no active subscriptions found. Create a subscription first using CreateSubscriptionRequest()")
-		}
-
-		// Filter to only subscriptions where WE are the consumer.
-		// This handles edge cases and ensures we get the correct queue.
-		var myConsumerSubs []Subscription
-		for _, sub := range subscriptions {
-			if sub.ConsumerID == c.CustomerID {
-				myConsumerSubs = append(myConsumerSubs, sub)
-			}
-		}
-
-		if len(myConsumerSubs) == 0 {
-			return nil, fmt.Errorf("no subscriptions found where you are the consumer")
-		}
-
-		// Get queue URL from our own subscription (all consumer subscriptions share same queue).
-		var queueURL *string
-		for _, sub := range myConsumerSubs {
-			if sub.SQSQueueURL != nil {
-				queueURL = sub.SQSQueueURL
-				break
-			}
-		}
-
-		if queueURL == nil {
-			return nil, fmt.Errorf("per-consumer queue not provisioned. This may be a legacy subscription. " +
-				"Please contact support or create a new subscription to get a dedicated queue.")
-		}
-
-		c.queueURL = queueURL
+	queueURL, err := c.resolveQueueURL(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	queueURL := aws.ToString(c.queueURL)
-
 	// Poll SQS for messages.
 	receiveOutput, err := c.sqsClient.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
 		MaxNumberOfMessages:   opts.MaxMessages,
@@ -914,63 +1515,59 @@ func (c *Consumer) PollNotifications(ctx context.Context, opts PollNotifications
 		return nil, fmt.Errorf("failed to poll SQS queue: %w", err)
 	}
 
-	var notifications []Notification
+	var (
+		result      PollResult
+		acknowledge []string // receipt handles to delete once the batch is parsed
+	)
 
 	for _, message := range receiveOutput.Messages {
-		// Parse message body - handle both SNS-wrapped and raw message formats.
-		// SNS-wrapped messages have a "Message" field containing the stringified notification.
-		// Raw messages contain the notification fields directly (event_type, producer_id, etc.).
-
-		messageBody := aws.ToString(message.Body)
-
-		// First, try to parse as a generic JSON to determine format.
-		var parsedBody map[string]any
-		if err := json.Unmarshal([]byte(messageBody), &parsedBody); err != nil {
-			fmt.Printf("Warning: Failed to parse message body: %v\n", err)
+		attributes := messageAttributes(message)
+		if match, decided := matchAttributes(opts.AttributeFilters, attributes); decided && !match {
+			c.releaseFilteredMessage(ctx, message, opts.RequeueFilteredMessages)
 			continue
 		}
 
-		// Notification data structure.
-		var notificationData struct {
-			DatasetID      string `json:"dataset_id"`
-			DatasetName    string `json:"dataset_name"`
-			EventType      string `json:"event_type"`
-			ProducerID     string `json:"producer_id"`
-			S3Bucket       string `json:"s3_bucket"`
-			S3Key          string `json:"s3_key"`
-			SizeBytes      int64  `json:"size_bytes"`
-			SubscriberID   string `json:"subscriber_id"`
-			SubscriptionID string `json:"subscription_id"`
-			Timestamp      string `json:"timestamp"`
+		parsed, err := ParseNotification(aws.ToString(message.Body))
+		if err != nil {
+			result.ParseFailures = append(result.ParseFailures, ParseFailure{
+				MessageID:     aws.ToString(message.MessageId),
+				ReceiptHandle: aws.ToString(message.ReceiptHandle),
+				Body:          aws.ToString(message.Body),
+				Err:           err,
+			})
+			if opts.AcknowledgeParseFailures {
+				acknowledge = append(acknowledge, aws.ToString(message.ReceiptHandle))
+			}
+			continue
 		}
+		c.invalidateCachedDataset(parsed)
 
-		// Determine message format and parse notification data.
-		if snsMessage, hasSNSWrapper := parsedBody["Message"].(string); hasSNSWrapper {
-			// SNS-wrapped format: { "Type": "Notification", "Message": "{...}", ... }
-			if err := json.Unmarshal([]byte(snsMessage), &notificationData); err != nil {
-				fmt.Printf("Warning: Failed to parse notification payload from SNS wrapper: %v\n", err)
-				continue
-			}
-		} else if _, hasEventType := parsedBody["event_type"]; hasEventType {
-			// Raw notification payload format (raw_message_delivery = true or direct SQS).
-			if err := json.Unmarshal([]byte(messageBody), &notificationData); err != nil {
-				fmt.Printf("Warning: Failed to parse raw notification payload: %v\n", err)
-				continue
+		if len(attributes) > 0 {
+			if parsed.Attributes == nil {
+				parsed.Attributes = attributes
+			} else {
+				maps.Copy(parsed.Attributes, attributes)
 			}
-		} else {
-			fmt.Printf("Warning: Unknown message format for %s, skipping\n", aws.ToString(message.MessageId))
+		}
+		if match, _ := matchAttributes(opts.AttributeFilters, parsed.Attributes); !match {
+			c.releaseFilteredMessage(ctx, message, opts.RequeueFilteredMessages)
 			continue
 		}
 
 		// SNS filter policy ensures only messages for this consumer reach this queue
 		// No need for subscriber_id filtering - it's already guaranteed by SNS.
 
+		if len(opts.EventTypes) > 0 && !slices.Contains(opts.EventTypes, parsed.EventType) {
+			c.releaseFilteredMessage(ctx, message, opts.RequeueFilteredMessages)
+			continue
+		}
+
 		// Optional filter by subscription IDs if provided (advanced use case).
 		if len(opts.SubscriptionIDs) > 0 {
 			found := false
 
 			for _, subID := range opts.SubscriptionIDs {
-				if subID == notificationData.SubscriptionID {
+				if subID == parsed.SubscriptionID {
 					found = true
 
 					break
@@ -981,46 +1578,180 @@ func (c *Consumer) PollNotifications(ctx context.Context, opts PollNotifications
 			}
 		}
 
-		notification := Notification{
-			DatasetID:      notificationData.DatasetID,
-			DatasetName:    notificationData.DatasetName,
-			EventType:      notificationData.EventType,
-			MessageID:      aws.ToString(message.MessageId),
-			ProducerID:     notificationData.ProducerID,
-			RawMessage:     aws.ToString(message.Body),
-			ReceiptHandle:  aws.ToString(message.ReceiptHandle),
-			S3Bucket:       notificationData.S3Bucket,
-			S3Key:          notificationData.S3Key,
-			SizeBytes:      notificationData.SizeBytes,
-			SubscriberID:   notificationData.SubscriberID,
-			SubscriptionID: notificationData.SubscriptionID,
-			Timestamp:      notificationData.Timestamp,
-		}
+		notification := *parsed
+		notification.MessageID = aws.ToString(message.MessageId)
+		notification.ReceiptHandle = aws.ToString(message.ReceiptHandle)
 
-		notifications = append(notifications, notification)
+		result.Notifications = append(result.Notifications, notification)
 
 		// Auto-acknowledge (delete) message by default.
 		if autoAcknowledge {
-			if err := c.DeleteNotification(ctx, notification.ReceiptHandle); err != nil {
-				fmt.Printf("Warning: Failed to auto-acknowledge notification %s: %v\n", notification.MessageID, err)
-			}
+			acknowledge = append(acknowledge, notification.ReceiptHandle)
+		}
+	}
+
+	if err := c.DeleteNotifications(ctx, acknowledge); err != nil {
+		fmt.Printf("Warning: Failed to auto-acknowledge notifications: %v\n", err)
+	}
+
+	return &result, nil
+}
+
+// resolveQueueURL returns the per-consumer queue URL, taken from an active
+// subscription where this customer is the consumer, and caches it. The lock
+// is held across the lookup so concurrent first calls share a single
+// subscription request.
+func (c *Consumer) resolveQueueURL(ctx context.Context) (string, error) {
+	c.queueMu.Lock()
+	defer c.queueMu.Unlock()
+
+	if c.queueURL != nil {
+		return *c.queueURL, nil
+	}
+
+	// For "both" customers, explicitly request consumer subscriptions to disambiguate.
+	// This ensures we get the queue where WE are the consumer, not producer.
+	subscriptions, err := c.ListSubscriptions(ctx, &ListSubscriptionsOptions{Role: "consumer"})
+	if err != nil {
+		return "", fmt.Errorf("failed to get subscriptions: %w", err)
+	}
+
+	if len(subscriptions) == 0 {
+		return "", fmt.Errorf("%w. Create a subscription first using CreateSubscriptionRequest()", ErrNoActiveSubscriptions)
+	}
+
+	// Filter to only subscriptions where WE are the consumer.
+	// This handles edge cases and ensures we get the correct queue.
+	var myConsumerSubs []Subscription
+	for _, sub := range subscriptions {
+		if sub.ConsumerID == c.CustomerID {
+			myConsumerSubs = append(myConsumerSubs, sub)
+		}
+	}
+
+	if len(myConsumerSubs) == 0 {
+		return "", fmt.Errorf("%w: none where you are the consumer", ErrNoActiveSubscriptions)
+	}
+
+	// Get queue URL from our own subscription (all consumer subscriptions share same queue).
+	var queueURL *string
+	for _, sub := range myConsumerSubs {
+		if sub.SQSQueueURL != nil {
+			queueURL = sub.SQSQueueURL
+			break
+		}
+	}
+
+	if queueURL == nil {
+		if legacySubscriptions(myConsumerSubs) {
+			return "", fmt.Errorf("%w: it predates per-consumer queues. "+
+				"Please contact support or create a new subscription to get a dedicated queue.", ErrLegacySubscription)
+		}
+		return "", fmt.Errorf("%w yet; the queue is created shortly after a subscription is approved, so retry later", ErrQueueNotProvisioned)
+	}
+
+	c.queueURL = queueURL
+	return *queueURL, nil
+}
+
+// Errors returned by PollNotifications (and everything else that needs the
+// notification queue) when it cannot find the queue. Match them with
+// errors.Is to tell the user what to do next.
+var (
+	// ErrNoActiveSubscriptions means the consumer has no subscriptions to
+	// be notified about: it needs to subscribe first.
+	ErrNoActiveSubscriptions = errors.New("no active subscriptions found")
+
+	// ErrQueueNotProvisioned means the consumer is subscribed but its
+	// notification queue is still being set up: wait and retry.
+	ErrQueueNotProvisioned = errors.New("per-consumer queue not provisioned")
+
+	// ErrLegacySubscription means the consumer's subscriptions were set up
+	// before per-consumer queues and will never get one: re-subscribe or
+	// contact support.
+	ErrLegacySubscription = errors.New("legacy subscription has no per-consumer queue")
+)
+
+// legacySubscriptions reports whether subs, none of which has a queue, are
+// fully set up under the older model: each is already subscribed to its
+// notification topic, which a subscription still being provisioned is not.
+func legacySubscriptions(subs []Subscription) bool {
+	for _, sub := range subs {
+		if sub.SNSSubscriptionARN == nil || *sub.SNSSubscriptionARN == "" {
+			return false
+		}
+	}
+	return len(subs) > 0
+}
+
+// cachedQueueURL returns the queue URL cached by resolveQueueURL, or nil.
+func (c *Consumer) cachedQueueURL() *string {
+	c.queueMu.Lock()
+	defer c.queueMu.Unlock()
+	return c.queueURL
+}
+
+// messageAttributes returns the string-valued attributes of a queue message.
+func messageAttributes(message sqstypes.Message) map[string]string {
+	if len(message.MessageAttributes) == 0 {
+		return nil
+	}
+	attributes := make(map[string]string, len(message.MessageAttributes))
+	for name, value := range message.MessageAttributes {
+		if value.StringValue != nil {
+			attributes[name] = *value.StringValue
+		}
+	}
+	return attributes
+}
+
+// matchAttributes checks attributes against filters. decided is false when
+// an attribute a filter needs is absent, so the answer may change once more
+// attributes are known; match is then false.
+func matchAttributes(filters map[string][]string, attributes map[string]string) (match, decided bool) {
+	decided = true
+	for name, allowed := range filters {
+		value, ok := attributes[name]
+		if !ok {
+			decided = false
+			continue
 		}
+		if !slices.Contains(allowed, value) {
+			return false, true
+		}
+	}
+	return decided, decided
+}
+
+// releaseFilteredMessage deletes a message dropped by a filter,
+// or with requeue makes it visible again right away.
+func (c *Consumer) releaseFilteredMessage(ctx context.Context, message sqstypes.Message, requeue bool) {
+	if requeue {
+		if _, err := c.sqsClient.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+			QueueUrl:          c.cachedQueueURL(),
+			ReceiptHandle:     message.ReceiptHandle,
+			VisibilityTimeout: 0,
+		}); err != nil {
+			fmt.Printf("Warning: Failed to requeue filtered notification %s: %v\n", aws.ToString(message.MessageId), err)
+		}
+		return
 	}
 
-	return notifications, nil
+	if err := c.DeleteNotification(ctx, aws.ToString(message.ReceiptHandle)); err != nil {
+		fmt.Printf("Warning: Failed to acknowledge filtered notification %s: %v\n", aws.ToString(message.MessageId), err)
+	}
 }
 
 // DeleteNotification deletes a notification message from the SQS queue after processing.
 func (c *Consumer) DeleteNotification(ctx context.Context, receiptHandle string) error {
-	if c.queueURL == nil {
+	queueURL := c.cachedQueueURL()
+	if queueURL == nil {
 		return fmt.Errorf("queue URL not available. Call PollNotifications() first to initialize the queue URL")
 	}
 
-	queueURL := aws.ToString(c.queueURL)
-
 	// Delete message.
 	if _, err := c.sqsClient.DeleteMessage(ctx, &sqs.DeleteMessageInput{
-		QueueUrl:      aws.String(queueURL),
+		QueueUrl:      queueURL,
 		ReceiptHandle: aws.String(receiptHandle),
 	}); err != nil {
 		return fmt.Errorf("failed to delete notification: %w", err)
@@ -1029,6 +1760,69 @@ func (c *Consumer) DeleteNotification(ctx context.Context, receiptHandle string)
 	return nil
 }
 
+// NotificationDeleteError is returned by DeleteNotifications when some
+// messages were not deleted. The others were.
+type NotificationDeleteError struct {
+	Failed map[string]error // by receipt handle
+}
+
+func (e *NotificationDeleteError) Error() string {
+	for _, err := range e.Failed {
+		if len(e.Failed) == 1 {
+			return err.Error()
+		}
+		return fmt.Sprintf("%d notifications not deleted, e.g.: %v", len(e.Failed), err)
+	}
+	return "no notifications failed to delete"
+}
+
+// DeleteNotifications deletes many notification messages from the SQS queue,
+// up to 10 per SQS request. When some could not be deleted it returns a
+// *NotificationDeleteError with each failed receipt handle's error.
+func (c *Consumer) DeleteNotifications(ctx context.Context, receiptHandles []string) error {
+	if len(receiptHandles) == 0 {
+		return nil
+	}
+	queueURL := c.cachedQueueURL()
+	if queueURL == nil {
+		return fmt.Errorf("queue URL not available. Call PollNotifications() first to initialize the queue URL")
+	}
+
+	failed := make(map[string]error)
+	for batch := range slices.Chunk(receiptHandles, maxDeleteBatchEntries) {
+		entries := make([]sqstypes.DeleteMessageBatchRequestEntry, len(batch))
+		for i, handle := range batch {
+			entries[i] = sqstypes.DeleteMessageBatchRequestEntry{
+				Id:            aws.String(strconv.Itoa(i)),
+				ReceiptHandle: aws.String(handle),
+			}
+		}
+
+		output, err := c.sqsClient.DeleteMessageBatch(ctx, &sqs.DeleteMessageBatchInput{
+			QueueUrl: queueURL,
+			Entries:  entries,
+		})
+		if err != nil {
+			for _, handle := range batch {
+				failed[handle] = fmt.Errorf("failed to delete notification: %w", err)
+			}
+			continue
+		}
+		for _, entry := range output.Failed {
+			i, err := strconv.Atoi(aws.ToString(entry.Id))
+			if err != nil || i < 0 || i >= len(batch) {
+				continue
+			}
+			failed[batch[i]] = fmt.Errorf("failed to delete notification: %s: %s", aws.ToString(entry.Code), aws.ToString(entry.Message))
+		}
+	}
+
+	if len(failed) > 0 {
+		return &NotificationDeleteError{Failed: failed}
+	}
+	return nil
+}
+
 // ListSubscriptionRequests lists the consumer's own subscription requests.
 // Allows consumers to track the status of their pending, approved, or rejected requests.
 //
@@ -1091,15 +1885,21 @@ func (c *Consumer) CancelSubscription(ctx context.Context, subscriptionID string
 	return c.makeAPIRequest(ctx, "DELETE", fmt.Sprintf("/v1/subscriptions/%s", url.PathEscape(subscriptionID)), nil, nil)
 }
 
-// GetSubscription retrieves a specific subscription by ID.
+// GetSubscription retrieves a specific subscription by ID, e.g. to read
+// the SQSQueueURL and KMSGrantID of a freshly approved one.
 //
 // Parameters:
 //   - subscriptionID: The subscription ID to retrieve.
 //
-// Returns the subscription details or an error if not found.
+// Returns the subscription details, or a *types.NotFoundError if the API
+// doesn't know the subscription.
 func (c *Consumer) GetSubscription(ctx context.Context, subscriptionID string) (*types.Subscription, error) {
 	var sub types.Subscription
 	if err := c.makeAPIRequest(ctx, "GET", fmt.Sprintf("/v1/subscriptions/%s", url.PathEscape(subscriptionID)), nil, &sub); err != nil {
+		var statusErr *types.StatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+			return nil, &types.NotFoundError{Resource: "subscription", ID: subscriptionID, Err: err}
+		}
 		return nil, err
 	}
 	return &sub, nil
@@ -1112,48 +1912,11 @@ func (c *Consumer) GetSubscription(ctx context.Context, subscriptionID string) (
 // IMPORTANT: AWS limits PurgeQueue to once every 60 seconds per queue.
 // Calling this method more frequently will result in an error.
 func (c *Consumer) ClearQueue(ctx context.Context) error {
-	// Initialize queue URL if not already set.
-	if c.queueURL == nil {
-		subscriptions, err := c.ListSubscriptions(ctx, &ListSubscriptionsOptions{Role: "consumer"})
-		if err != nil {
-			return fmt.Errorf("failed to get subscriptions: %w", err)
-		}
-
-		if len(subscriptions) == 0 {
-			return fmt.Errorf("no active subscriptions found. Cannot determine queue URL")
-		}
-
-		// Filter to only subscriptions where WE are the consumer
-		var myConsumerSubs []Subscription
-		for _, sub := range subscriptions {
-			if sub.ConsumerID == c.CustomerID {
-				myConsumerSubs = append(myConsumerSubs, sub)
-			}
-		}
-
-		if len(myConsumerSubs) == 0 {
-			return fmt.Errorf("no subscriptions found where you are the consumer")
-		}
-
-		// Get queue URL from our own subscription
-		var queueURL *string
-		for _, sub := range myConsumerSubs {
-			if sub.SQSQueueURL != nil {
-				queueURL = sub.SQSQueueURL
-				break
-			}
-		}
-
-		if queueURL == nil {
-			return fmt.Errorf("per-consumer queue not provisioned. This may be a legacy subscription. " +
-				"Please contact support or create a new subscription to get a dedicated queue.")
-		}
-
-		c.queueURL = queueURL
+	queueURL, err := c.resolveQueueURL(ctx)
+	if err != nil {
+		return err
 	}
 
-	queueURL := aws.ToString(c.queueURL)
-
 	// Purge queue.
 	if _, err := c.sqsClient.PurgeQueue(ctx, &sqs.PurgeQueueInput{
 		QueueUrl: aws.String(queueURL),
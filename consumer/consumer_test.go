@@ -100,49 +100,9 @@ func TestSubscriptionUnmarshalPreservesExtendedFields(t *testing.T) {
 	}
 }
 
-// notificationData mirrors the struct used in PollNotifications for testing.
-type notificationData struct {
-	DatasetID      string `json:"dataset_id"`
-	DatasetName    string `json:"dataset_name"`
-	EventType      string `json:"event_type"`
-	ProducerID     string `json:"producer_id"`
-	S3Bucket       string `json:"s3_bucket"`
-	S3Key          string `json:"s3_key"`
-	SizeBytes      int64  `json:"size_bytes"`
-	SubscriberID   string `json:"subscriber_id"`
-	SubscriptionID string `json:"subscription_id"`
-	Timestamp      string `json:"timestamp"`
-}
-
-// parseNotificationMessage simulates the notification parsing logic from consumer.go.
-func parseNotificationMessage(messageBody string) (*notificationData, error) {
-	var parsedBody map[string]any
-	if err := json.Unmarshal([]byte(messageBody), &parsedBody); err != nil {
-		return nil, err
-	}
-
-	var data notificationData
-
-	if snsMessage, hasSNSWrapper := parsedBody["Message"].(string); hasSNSWrapper {
-		// SNS-wrapped format
-		if err := json.Unmarshal([]byte(snsMessage), &data); err != nil {
-			return nil, err
-		}
-	} else if _, hasEventType := parsedBody["event_type"]; hasEventType {
-		// Raw notification payload format
-		if err := json.Unmarshal([]byte(messageBody), &data); err != nil {
-			return nil, err
-		}
-	} else {
-		return nil, errors.New("unknown message format")
-	}
-
-	return &data, nil
-}
-
 // TestNotificationParsingSnSWrapped tests parsing of SNS-wrapped messages.
 func TestNotificationParsingSNSWrapped(t *testing.T) {
-	notificationPayload := notificationData{
+	notificationPayload := Notification{
 		EventType:      "dataset_updated",
 		ProducerID:     "company-123",
 		DatasetID:      "dataset-456",
@@ -165,7 +125,7 @@ func TestNotificationParsingSNSWrapped(t *testing.T) {
 
 	messageBody, _ := json.Marshal(snsWrapped)
 
-	result, err := parseNotificationMessage(string(messageBody))
+	result, err := ParseNotification(string(messageBody))
 	if err != nil {
 		t.Fatalf("failed to parse SNS-wrapped message: %v", err)
 	}
@@ -185,7 +145,7 @@ func TestNotificationParsingSNSWrapped(t *testing.T) {
 
 // TestNotificationParsingRaw tests parsing of raw notification messages.
 func TestNotificationParsingRaw(t *testing.T) {
-	rawPayload := notificationData{
+	rawPayload := Notification{
 		EventType:      "dataset_updated",
 		ProducerID:     "company-123",
 		DatasetID:      "dataset-456",
@@ -200,7 +160,7 @@ func TestNotificationParsingRaw(t *testing.T) {
 
 	messageBody, _ := json.Marshal(rawPayload)
 
-	result, err := parseNotificationMessage(string(messageBody))
+	result, err := ParseNotification(string(messageBody))
 	if err != nil {
 		t.Fatalf("failed to parse raw message: %v", err)
 	}
@@ -227,7 +187,7 @@ func TestNotificationParsingUnknownFormat(t *testing.T) {
 
 	messageBody, _ := json.Marshal(unknownMessage)
 
-	_, err := parseNotificationMessage(string(messageBody))
+	_, err := ParseNotification(string(messageBody))
 	if err == nil {
 		t.Fatal("expected error for unknown message format, got nil")
 	}
@@ -235,6 +195,10 @@ func TestNotificationParsingUnknownFormat(t *testing.T) {
 	if err.Error() != "unknown message format" {
 		t.Errorf("expected 'unknown message format' error, got '%s'", err.Error())
 	}
+
+	if !errors.Is(err, ErrUnknownMessageFormat) {
+		t.Errorf("expected ErrUnknownMessageFormat, got %v", err)
+	}
 }
 
 // TestNotificationParsingPreviouslyFailingCase tests the original bug case.
@@ -257,7 +221,7 @@ func TestNotificationParsingPreviouslyFailingCase(t *testing.T) {
 
 	messageBody, _ := json.Marshal(rawPayload)
 
-	result, err := parseNotificationMessage(string(messageBody))
+	result, err := ParseNotification(string(messageBody))
 	if err != nil {
 		t.Fatalf("failed to parse raw message (previously failing case): %v", err)
 	}
@@ -0,0 +1,119 @@
+package consumer
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+// defaultDatasetCacheSize is how many datasets the metadata cache holds
+// when Config.DatasetCacheSize is zero.
+const defaultDatasetCacheSize = 1000
+
+// datasetCache holds GetDataset results by dataset ID until they expire,
+// for Config.DatasetCacheTTL. Only successful lookups are cached.
+type datasetCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	size    int
+	entries map[string]cachedDataset
+	now     func() time.Time
+}
+
+type cachedDataset struct {
+	dataset *types.Dataset
+	expires time.Time
+}
+
+// newDatasetCache returns a cache for ttl and size, or nil when ttl <= 0
+// disables caching. size <= 0 means defaultDatasetCacheSize.
+func newDatasetCache(ttl time.Duration, size int) *datasetCache {
+	if ttl <= 0 {
+		return nil
+	}
+	if size <= 0 {
+		size = defaultDatasetCacheSize
+	}
+	return &datasetCache{ttl: ttl, size: size, entries: make(map[string]cachedDataset), now: time.Now}
+}
+
+func (c *datasetCache) get(datasetID string) (*types.Dataset, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[datasetID]
+	if !ok || !c.now().Before(entry.expires) {
+		return nil, false
+	}
+	return entry.dataset, true
+}
+
+// put caches dataset, evicting expired entries and then the entry closest
+// to expiry when the cache is full.
+func (c *datasetCache) put(datasetID string, dataset *types.Dataset) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if _, ok := c.entries[datasetID]; !ok && len(c.entries) >= c.size {
+		var oldest string
+		for id, entry := range c.entries {
+			if !now.Before(entry.expires) {
+				delete(c.entries, id)
+			} else if oldest == "" || entry.expires.Before(c.entries[oldest].expires) {
+				oldest = id
+			}
+		}
+		if len(c.entries) >= c.size {
+			delete(c.entries, oldest)
+		}
+	}
+	c.entries[datasetID] = cachedDataset{dataset: dataset, expires: now.Add(c.ttl)}
+}
+
+func (c *datasetCache) invalidate(datasetID string) {
+	c.mu.Lock()
+	delete(c.entries, datasetID)
+	c.mu.Unlock()
+}
+
+// GetDatasetCached is GetDataset answered from the consumer's metadata
+// cache (Config.DatasetCacheTTL) while an earlier result for datasetID is
+// unexpired. DownloadDataset uses it for the dataset's compression and
+// encryption flags. Polling a dataset_updated or dataset_deleted
+// notification drops that dataset from the cache, since the flags belong
+// to a version. The returned dataset may be shared with other callers and
+// must not be modified. Without a TTL configured it is GetDataset.
+func (c *Consumer) GetDatasetCached(ctx context.Context, datasetID string) (*types.Dataset, error) {
+	if c.datasetCache == nil {
+		return c.GetDataset(ctx, datasetID)
+	}
+	if dataset, ok := c.datasetCache.get(datasetID); ok {
+		return dataset, nil
+	}
+
+	dataset, err := c.GetDataset(ctx, datasetID)
+	if err != nil {
+		return nil, err
+	}
+	c.cacheDataset(datasetID, dataset)
+	return dataset, nil
+}
+
+// cacheDataset caches a dataset just fetched with GetDataset, so a download
+// that follows uses its flags rather than those of an older version cached
+// earlier.
+func (c *Consumer) cacheDataset(datasetID string, dataset *types.Dataset) {
+	if c.datasetCache != nil {
+		c.datasetCache.put(datasetID, dataset)
+	}
+}
+
+// invalidateCachedDataset drops the cached metadata of the dataset a
+// notification announces a new version or the deletion of.
+func (c *Consumer) invalidateCachedDataset(n *Notification) {
+	if c.datasetCache != nil && (n.IsDatasetUpdated() || n.IsDatasetDeleted()) {
+		c.datasetCache.invalidate(n.DatasetID)
+	}
+}
@@ -0,0 +1,60 @@
+package consumer
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/helix-tools/sdk-go/v2/helixtest"
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+func TestGetDatasetCached(t *testing.T) {
+	api := helixtest.NewMockAPI()
+	api.Handle(http.MethodGet, "/v1/datasets/ds-1", http.StatusOK, types.Dataset{ID: "ds-1"})
+	api.Handle(http.MethodGet, "/v1/datasets/ds-2", http.StatusOK, types.Dataset{ID: "ds-2"})
+	c := NewConsumerWithAPI(types.Config{DatasetCacheTTL: time.Minute, DatasetCacheSize: 1}, api)
+	now := time.Now()
+	c.datasetCache.now = func() time.Time { return now }
+
+	get := func(id string, wantCalls int) {
+		t.Helper()
+		dataset, err := c.GetDatasetCached(context.Background(), id)
+		if err != nil || dataset.ID != id {
+			t.Fatalf("GetDatasetCached(%s) = %+v, %v", id, dataset, err)
+		}
+		if calls := len(api.Calls()); calls != wantCalls {
+			t.Fatalf("after GetDatasetCached(%s): %d API calls, want %d", id, calls, wantCalls)
+		}
+	}
+
+	get("ds-1", 1)
+	get("ds-1", 1) // cached
+
+	now = now.Add(time.Minute)
+	get("ds-1", 2) // expired
+
+	c.invalidateCachedDataset(&Notification{DatasetID: "ds-1", EventType: EventTypeSubscriptionRevoked})
+	get("ds-1", 2) // not a dataset event
+	c.invalidateCachedDataset(&Notification{DatasetID: "ds-1", EventType: EventTypeDatasetUpdated})
+	get("ds-1", 3) // new version announced
+
+	get("ds-2", 4) // evicts ds-1 from the one-entry cache
+	get("ds-1", 5)
+}
+
+func TestGetDatasetCachedDisabled(t *testing.T) {
+	api := helixtest.NewMockAPI()
+	api.Handle(http.MethodGet, "/v1/datasets/ds-1", http.StatusOK, types.Dataset{ID: "ds-1"})
+	c := NewConsumerWithAPI(types.Config{}, api)
+
+	for range 2 {
+		if _, err := c.GetDatasetCached(context.Background(), "ds-1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls := len(api.Calls()); calls != 2 {
+		t.Errorf("%d API calls, want 2 without a cache TTL", calls)
+	}
+}
@@ -0,0 +1,87 @@
+package consumer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// detailsServer answers GET .../details and reports each POST .../views path
+// on views.
+func detailsServer(t *testing.T, viewStatus int) (*httptest.Server, chan string) {
+	t.Helper()
+
+	views := make(chan string, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/details"):
+			_, _ = w.Write([]byte(`{"dataset":{"_id":"dataset-1"},"reviews":[],"related_datasets":[],"subscription_info":{}}`))
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/views"):
+			views <- r.URL.EscapedPath()
+			w.WriteHeader(viewStatus)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server, views
+}
+
+func TestRecordDatasetView(t *testing.T) {
+	server, views := detailsServer(t, http.StatusNoContent)
+
+	if err := newTestConsumer(server.URL).RecordDatasetView(context.Background(), "data set"); err != nil {
+		t.Fatalf("RecordDatasetView: %v", err)
+	}
+	if got := <-views; got != "/v1/datasets/data%20set/views" {
+		t.Errorf("escaped path = %q, want /v1/datasets/data%%20set/views", got)
+	}
+}
+
+func TestRecordDatasetView_Error(t *testing.T) {
+	server, _ := detailsServer(t, http.StatusInternalServerError)
+
+	err := newTestConsumer(server.URL).RecordDatasetView(context.Background(), "dataset-1")
+	if err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("err = %v, want the API failure", err)
+	}
+}
+
+func TestGetDatasetDetails_TrackViews(t *testing.T) {
+	t.Run("enabled records a view in the background", func(t *testing.T) {
+		// A failing view endpoint must not fail the detail fetch.
+		server, views := detailsServer(t, http.StatusInternalServerError)
+		c := newTestConsumer(server.URL)
+		c.trackViews = true
+
+		if _, err := c.GetDatasetDetails(context.Background(), "dataset-1"); err != nil {
+			t.Fatalf("GetDatasetDetails: %v", err)
+		}
+		select {
+		case got := <-views:
+			if got != "/v1/datasets/dataset-1/views" {
+				t.Errorf("view path = %q, want /v1/datasets/dataset-1/views", got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("expected a view to be recorded")
+		}
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		server, views := detailsServer(t, http.StatusNoContent)
+
+		if _, err := newTestConsumer(server.URL).GetDatasetDetails(context.Background(), "dataset-1"); err != nil {
+			t.Fatalf("GetDatasetDetails: %v", err)
+		}
+		select {
+		case got := <-views:
+			t.Errorf("unexpected view recorded: %s", got)
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+}
@@ -0,0 +1,177 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// deadLetterVisibilityTimeout hides listed dead-letter messages from other
+// readers long enough to redrive them with the returned receipt handle.
+const deadLetterVisibilityTimeout = 300
+
+// DeadLetterNotification is a notification parked on the dead-letter queue
+// after repeatedly failing processing (typically under manual acknowledge).
+type DeadLetterNotification struct {
+	// Notification holds the parsed fields when the body parses; MessageID,
+	// ReceiptHandle (for RedriveNotification), and RawMessage are always set.
+	Notification
+
+	// ReceiveCount is how many times the message was received, across the
+	// main queue and the dead-letter queue.
+	ReceiveCount int
+
+	// ParseError is set when the body is not a valid notification, which is
+	// often why the message was dead-lettered.
+	ParseError error
+}
+
+// ListDeadLetterNotifications returns up to maxMessages (1-10, default 10)
+// messages from the dead-letter queue of the per-consumer notification queue,
+// found through the main queue's redrive policy.
+//
+// Listed messages stay on the dead-letter queue but are hidden from other
+// readers for 5 minutes; call RedriveNotification within that window to move
+// one back to the main queue.
+func (c *Consumer) ListDeadLetterNotifications(ctx context.Context, maxMessages int32) ([]DeadLetterNotification, error) {
+	if maxMessages <= 0 || maxMessages > 10 {
+		maxMessages = 10 // AWS limit.
+	}
+
+	dlqURL, err := c.resolveDeadLetterURL(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := c.sqsClient.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:                    aws.String(dlqURL),
+		MaxNumberOfMessages:         maxMessages,
+		MessageAttributeNames:       []string{"All"},
+		MessageSystemAttributeNames: []sqstypes.MessageSystemAttributeName{sqstypes.MessageSystemAttributeNameApproximateReceiveCount},
+		VisibilityTimeout:           deadLetterVisibilityTimeout,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead-letter queue: %w", err)
+	}
+
+	c.deadLetterMu.Lock()
+	defer c.deadLetterMu.Unlock()
+	if c.deadLetter == nil {
+		c.deadLetter = make(map[string]sqstypes.Message)
+	}
+
+	notifications := make([]DeadLetterNotification, 0, len(out.Messages))
+	for _, message := range out.Messages {
+		dl := DeadLetterNotification{}
+		if parsed, err := ParseNotification(aws.ToString(message.Body)); err != nil {
+			dl.ParseError = err
+		} else {
+			dl.Notification = *parsed
+		}
+		dl.MessageID = aws.ToString(message.MessageId)
+		dl.ReceiptHandle = aws.ToString(message.ReceiptHandle)
+		dl.RawMessage = aws.ToString(message.Body)
+		dl.ReceiveCount, _ = strconv.Atoi(message.Attributes[string(sqstypes.MessageSystemAttributeNameApproximateReceiveCount)])
+
+		c.deadLetter[dl.ReceiptHandle] = message
+		notifications = append(notifications, dl)
+	}
+
+	return notifications, nil
+}
+
+// RedriveNotification moves a message listed by ListDeadLetterNotifications
+// back to the main notification queue (with its body and attributes) for
+// reprocessing, then deletes it from the dead-letter queue.
+func (c *Consumer) RedriveNotification(ctx context.Context, receiptHandle string) error {
+	c.deadLetterMu.Lock()
+	message, ok := c.deadLetter[receiptHandle]
+	deadLetterURL := c.deadLetterURL
+	c.deadLetterMu.Unlock()
+	if !ok {
+		return fmt.Errorf("unknown receipt handle: list the message with ListDeadLetterNotifications first")
+	}
+
+	if _, err := c.sqsClient.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:          c.cachedQueueURL(),
+		MessageBody:       message.Body,
+		MessageAttributes: message.MessageAttributes,
+	}); err != nil {
+		return fmt.Errorf("failed to requeue message %s: %w", aws.ToString(message.MessageId), err)
+	}
+
+	if _, err := c.sqsClient.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+		QueueUrl:      deadLetterURL,
+		ReceiptHandle: aws.String(receiptHandle),
+	}); err != nil {
+		return fmt.Errorf("message %s requeued but not removed from the dead-letter queue (it may be processed twice): %w",
+			aws.ToString(message.MessageId), err)
+	}
+
+	c.deadLetterMu.Lock()
+	delete(c.deadLetter, receiptHandle)
+	c.deadLetterMu.Unlock()
+
+	return nil
+}
+
+// resolveDeadLetterURL caches the dead-letter queue URL, read from the
+// deadLetterTargetArn of the main queue's RedrivePolicy.
+func (c *Consumer) resolveDeadLetterURL(ctx context.Context) (string, error) {
+	c.deadLetterMu.Lock()
+	cached := c.deadLetterURL
+	c.deadLetterMu.Unlock()
+	if cached != nil {
+		return *cached, nil
+	}
+
+	queueURL, err := c.resolveQueueURL(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	attrs, err := c.sqsClient.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl:       aws.String(queueURL),
+		AttributeNames: []sqstypes.QueueAttributeName{sqstypes.QueueAttributeNameRedrivePolicy},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to read queue redrive policy: %w", err)
+	}
+
+	policy := attrs.Attributes[string(sqstypes.QueueAttributeNameRedrivePolicy)]
+	if policy == "" {
+		return "", fmt.Errorf("notification queue has no dead-letter queue configured")
+	}
+
+	var redrive struct {
+		DeadLetterTargetArn string `json:"deadLetterTargetArn"`
+	}
+	if err := json.Unmarshal([]byte(policy), &redrive); err != nil {
+		return "", fmt.Errorf("failed to parse queue redrive policy: %w", err)
+	}
+
+	// arn:aws:sqs:<region>:<account>:<queue-name>
+	parts := strings.Split(redrive.DeadLetterTargetArn, ":")
+	if len(parts) != 6 || parts[5] == "" {
+		return "", fmt.Errorf("malformed dead-letter queue ARN %q", redrive.DeadLetterTargetArn)
+	}
+
+	out, err := c.sqsClient.GetQueueUrl(ctx, &sqs.GetQueueUrlInput{
+		QueueName:              aws.String(parts[5]),
+		QueueOwnerAWSAccountId: aws.String(parts[4]),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve dead-letter queue URL: %w", err)
+	}
+
+	c.deadLetterMu.Lock()
+	c.deadLetterURL = out.QueueUrl
+	c.deadLetterMu.Unlock()
+	return aws.ToString(out.QueueUrl), nil
+}
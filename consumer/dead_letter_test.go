@@ -0,0 +1,44 @@
+package consumer
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestDeadLetterNotifications(t *testing.T) {
+	f := newFakeSQS(t)
+	good := sqsMessage("1", "dataset_updated")
+	good["Attributes"] = map[string]string{"ApproximateReceiveCount": "6"}
+	f.deadLetter = []map[string]any{
+		good,
+		{"MessageId": "2", "ReceiptHandle": "rh-2", "Body": "poison"},
+	}
+	c := f.consumer()
+
+	parked, err := c.ListDeadLetterNotifications(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("ListDeadLetterNotifications: %v", err)
+	}
+	if len(parked) != 2 {
+		t.Fatalf("parked = %+v, want 2", parked)
+	}
+	if parked[0].DatasetID != "ds-1" || parked[0].ReceiveCount != 6 || parked[0].ParseError != nil {
+		t.Errorf("parked[0] = %+v, want ds-1 received 6 times", parked[0])
+	}
+	if parked[1].ParseError == nil || parked[1].RawMessage != "poison" || parked[1].ReceiptHandle != "rh-2" {
+		t.Errorf("parked[1] = %+v, want the unparseable body with its receipt handle", parked[1])
+	}
+
+	if err := c.RedriveNotification(context.Background(), "rh-1"); err != nil {
+		t.Fatalf("RedriveNotification: %v", err)
+	}
+	calls := f.takeCalls()
+	if len(calls) != 2 || !strings.HasPrefix(calls[0], "SendMessage /queue/test {") || calls[1] != "DeleteMessage rh-1" {
+		t.Errorf("SQS calls = %v, want a send to the main queue then a delete from the dead-letter queue", calls)
+	}
+
+	if err := c.RedriveNotification(context.Background(), "rh-1"); err == nil {
+		t.Error("expected an error redriving an already redriven message")
+	}
+}
@@ -0,0 +1,103 @@
+package consumer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	if _, err := gw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecompressDataIntegrity(t *testing.T) {
+	payload := bytes.Repeat([]byte(`{"id":1,"name":"row"}`+"\n"), 200)
+	valid := gzipBytes(t, payload)
+
+	corruptCRC := bytes.Clone(valid)
+	corruptCRC[len(corruptCRC)-5] ^= 0xff // last byte of the CRC-32 trailer
+
+	tests := []struct {
+		name  string
+		input []byte
+		cause error
+	}{
+		{"truncated stream", valid[:len(valid)/2], io.ErrUnexpectedEOF},
+		{"missing trailer", valid[:len(valid)-4], io.ErrUnexpectedEOF},
+		{"checksum mismatch", corruptCRC, gzip.ErrChecksum},
+		{"not gzip", []byte("plain text"), gzip.ErrHeader},
+		{"empty", nil, io.ErrUnexpectedEOF},
+	}
+	c := &Consumer{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := c.decompressData(tt.input)
+			if !errors.Is(err, ErrCorruptCompressedData) || !errors.Is(err, tt.cause) {
+				t.Fatalf("err = %v, want ErrCorruptCompressedData wrapping %v", err, tt.cause)
+			}
+			if out != nil {
+				t.Errorf("got %d bytes of partial output, want none", len(out))
+			}
+		})
+	}
+
+	out, err := c.decompressData(valid)
+	if err != nil || !bytes.Equal(out, payload) {
+		t.Errorf("valid stream: %d bytes, %v", len(out), err)
+	}
+}
+
+// TestDownloadDatasetTruncatedGzip pins that a truncated download fails
+// with ErrCorruptCompressedData and writes no output file.
+func TestDownloadDatasetTruncatedGzip(t *testing.T) {
+	api := newFakeAPI(t)
+	api.dataset["metadata"].(map[string]any)["compression_enabled"] = true
+	full := gzipBytes(t, bytes.Repeat([]byte("row\n"), 1000))
+	api.s3Body = full[:len(full)-10]
+
+	out := filepath.Join(t.TempDir(), "data.ndjson")
+	err := newTestConsumer(api.server.URL).DownloadDataset(context.Background(), "ds-1", out)
+	if !errors.Is(err, ErrCorruptCompressedData) {
+		t.Fatalf("err = %v, want ErrCorruptCompressedData", err)
+	}
+	if _, statErr := os.Stat(out); !os.IsNotExist(statErr) {
+		t.Errorf("output file written despite corrupt data (stat err = %v)", statErr)
+	}
+}
+
+func TestDecompressDataSizeLimit(t *testing.T) {
+	payload := bytes.Repeat([]byte("a"), 1000)
+	compressed := gzipBytes(t, payload)
+
+	c := &Consumer{maxInflate: 999}
+	out, err := c.decompressData(compressed)
+	if !errors.Is(err, ErrDecompressedSizeExceeded) {
+		t.Fatalf("err = %v, want ErrDecompressedSizeExceeded", err)
+	}
+	if errors.Is(err, ErrCorruptCompressedData) {
+		t.Errorf("oversize stream reported as corrupt: %v", err)
+	}
+	if out != nil {
+		t.Errorf("got %d bytes of output, want none", len(out))
+	}
+
+	c.maxInflate = 1000
+	if out, err := c.decompressData(compressed); err != nil || len(out) != 1000 {
+		t.Errorf("at the limit: %d bytes, %v; want 1000, nil", len(out), err)
+	}
+}
@@ -0,0 +1,179 @@
+package consumer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	smithy "github.com/aws/smithy-go"
+	"github.com/helix-tools/sdk-go/v2/helixtest"
+	"github.com/helix-tools/sdk-go/v2/internal/envelope"
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+// identityKMS "unwraps" a data key by returning it unchanged.
+type identityKMS struct{}
+
+func (identityKMS) Decrypt(_ context.Context, in *kms.DecryptInput, _ ...func(*kms.Options)) (*kms.DecryptOutput, error) {
+	return &kms.DecryptOutput{Plaintext: in.CiphertextBlob}, nil
+}
+
+func TestDecryptDataRoundTrip(t *testing.T) {
+	c := &Consumer{kmsClient: identityKMS{}}
+	identity := func(_ context.Context, key []byte) ([]byte, error) { return key, nil }
+
+	for _, data := range [][]byte{{}, {0x42}, bytes.Repeat([]byte("row\n"), 1<<18)} {
+		sealed, err := envelope.Seal(context.Background(), nil, data, identity)
+		if err != nil {
+			t.Fatal(err)
+		}
+		opened, err := c.decryptData(context.Background(), sealed, "")
+		if err != nil || !bytes.Equal(opened, data) {
+			t.Errorf("round trip of %d bytes: got %d bytes, %v", len(data), len(opened), err)
+		}
+	}
+
+	sealed, _ := envelope.Seal(context.Background(), nil, []byte("x"), identity)
+	sealed[len(sealed)-1] ^= 1
+	if _, err := c.decryptData(context.Background(), sealed, ""); err == nil || !strings.Contains(err.Error(), "AES-GCM decrypt failed") {
+		t.Errorf("tampered data err = %v, want an AES-GCM failure", err)
+	}
+}
+
+// keyCheckingKMS records the KeyId of each Decrypt call and fails with err.
+type keyCheckingKMS struct {
+	keyIDs []string
+	err    error
+}
+
+func (k *keyCheckingKMS) Decrypt(_ context.Context, in *kms.DecryptInput, _ ...func(*kms.Options)) (*kms.DecryptOutput, error) {
+	k.keyIDs = append(k.keyIDs, aws.ToString(in.KeyId))
+	if k.err != nil {
+		return nil, k.err
+	}
+	return &kms.DecryptOutput{Plaintext: in.CiphertextBlob}, nil
+}
+
+func TestDecryptDataKeyIDOverride(t *testing.T) {
+	identity := func(_ context.Context, key []byte) ([]byte, error) { return key, nil }
+	sealed, err := envelope.Seal(context.Background(), nil, []byte("x"), identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake := &keyCheckingKMS{}
+	if _, err := (&Consumer{kmsClient: fake}).decryptData(context.Background(), sealed, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (&Consumer{kmsClient: fake, decryptKeyID: "alias/consumer"}).decryptData(context.Background(), sealed, ""); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"", "alias/consumer"}; !slices.Equal(fake.keyIDs, want) {
+		t.Errorf("KeyId = %q, want %q", fake.keyIDs, want)
+	}
+}
+
+func TestDecryptDataExplainsKMSErrors(t *testing.T) {
+	identity := func(_ context.Context, key []byte) ([]byte, error) { return key, nil }
+	sealed, _ := envelope.Seal(context.Background(), nil, []byte("x"), identity)
+
+	for code, hint := range map[string]string{
+		"AccessDeniedException":    "missing a decryption grant",
+		"KMSInvalidStateException": "disabled or pending deletion",
+		"ThrottlingException":      "",
+	} {
+		kmsErr := &smithy.GenericAPIError{Code: code, Message: "nope"}
+		_, err := (&Consumer{kmsClient: &keyCheckingKMS{err: kmsErr}}).decryptData(context.Background(), sealed, "")
+		if !errors.Is(err, kmsErr) {
+			t.Errorf("%s: err = %v, want it to wrap the KMS error", code, err)
+			continue
+		}
+		if hint != "" && !strings.Contains(err.Error(), hint) {
+			t.Errorf("%s: err = %v, want a hint mentioning %q", code, err, hint)
+		}
+		if hint == "" && strings.Contains(err.Error(), "grant") {
+			t.Errorf("%s: err = %v, want no grant hint", code, err)
+		}
+		if got := errors.Is(err, ErrKMSAccessDenied); got != (code == "AccessDeniedException") {
+			t.Errorf("%s: errors.Is(err, ErrKMSAccessDenied) = %v", code, got)
+		}
+	}
+}
+
+func TestDecryptionErrorNamesSubscription(t *testing.T) {
+	denied := fmt.Errorf("%w: %w", ErrKMSAccessDenied, &smithy.GenericAPIError{Code: "AccessDeniedException"})
+	grant := "grant-1"
+	dsID := "ds-1"
+
+	tests := []struct {
+		name string
+		subs []types.Subscription
+		want string
+	}{
+		{"dataset subscription with grant", []types.Subscription{
+			{ID: "sub-all", ProducerID: "company-p"},
+			{ID: "sub-1", DatasetID: &dsID, ProducerID: "company-p", KMSGrantID: &grant},
+		}, "subscription sub-1, kms_grant_id grant-1"},
+		{"producer-wide subscription without grant", []types.Subscription{
+			{ID: "sub-other", ProducerID: "company-q"},
+			{ID: "sub-all", ProducerID: "company-p"},
+		}, "subscription sub-all has no kms_grant_id"},
+		{"no subscription", nil, "no subscription covers dataset ds-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := helixtest.NewMockAPI()
+			api.Handle(http.MethodGet, "/v1/subscriptions", http.StatusOK, map[string]any{"subscriptions": tt.subs})
+			c := NewConsumerWithAPI(types.Config{CustomerID: "company-1"}, api)
+
+			err := c.decryptionError(context.Background(), &types.Dataset{ID: dsID, ProducerID: "company-p"}, denied)
+			if !errors.Is(err, ErrKMSAccessDenied) || !errors.Is(err, types.ErrDecryptionFailed) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want ErrKMSAccessDenied mentioning %q", err, tt.want)
+			}
+		})
+	}
+
+	// Other failures skip the lookup.
+	api := helixtest.NewMockAPI()
+	err := NewConsumerWithAPI(types.Config{}, api).decryptionError(context.Background(), &types.Dataset{ID: dsID}, errors.New("boom"))
+	if err == nil || err.Error() != "decryption failed: boom" || !errors.Is(err, types.ErrDecryptionFailed) || len(api.Calls()) != 0 {
+		t.Errorf("err = %v after %d calls, want a plain wrap and no lookup", err, len(api.Calls()))
+	}
+}
+
+func TestDecryptDataKeyRegion(t *testing.T) {
+	identity := func(_ context.Context, key []byte) ([]byte, error) { return key, nil }
+	sealed, _ := envelope.Seal(context.Background(), nil, []byte("x"), identity)
+
+	home, euWest, apSouth := &keyCheckingKMS{}, &keyCheckingKMS{}, &keyCheckingKMS{}
+	c := &Consumer{
+		Region:      "us-east-1",
+		kmsClient:   home,
+		regionalKMS: map[string]kmsAPI{"eu-west-1": euWest, "ap-south-1": apSouth},
+	}
+	for _, region := range []string{"", "us-east-1", "eu-west-1"} {
+		if _, err := c.decryptData(context.Background(), sealed, region); err != nil {
+			t.Fatalf("region %q: %v", region, err)
+		}
+	}
+	// A DecryptKMSKeyID ARN names the region itself.
+	c.decryptKeyID = "arn:aws:kms:ap-south-1:111122223333:key/mrk-1"
+	if _, err := c.decryptData(context.Background(), sealed, "eu-west-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(home.keyIDs) != 2 || len(euWest.keyIDs) != 1 || len(apSouth.keyIDs) != 1 {
+		t.Errorf("decrypts per client: home %d, eu-west-1 %d, ap-south-1 %d; want 2, 1, 1",
+			len(home.keyIDs), len(euWest.keyIDs), len(apSouth.keyIDs))
+	}
+	if c.kmsClientFor("eu-central-1") != c.kmsClientFor("eu-central-1") {
+		t.Error("regional KMS client should be cached")
+	}
+}
@@ -0,0 +1,150 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+// partKeyRegex matches the S3 key of one part of a sharded dataset,
+// datasets/{name}/part-NNNN.ndjson.gz.
+var partKeyRegex = regexp.MustCompile(`^datasets/[^/]+/part-\d{4,}\.ndjson\.gz$`)
+
+// PartKey returns the part of a sharded dataset this notification is about,
+// or "" when it is about a whole dataset. For an append, it names the part
+// that was added; once DownloadDelta has fetched it, pass it as the next
+// sincePart.
+func (n Notification) PartKey() string {
+	if partKeyRegex.MatchString(n.S3Key) {
+		return n.S3Key
+	}
+	return ""
+}
+
+// DownloadDelta downloads the parts of datasetID, a sharded dataset, that
+// come after sincePart in its manifest, and writes their records,
+// concatenated in manifest order, to out. sincePart is the S3 key of the
+// last part already downloaded (see Notification.PartKey), or "" for every
+// part. Each part is verified against the manifest before it is decrypted
+// and decompressed.
+//
+// A sincePart the manifest does not list is an error rather than a full
+// download. When no part is newer, ErrNotModified is returned and out is
+// left untouched. Parts appended while DownloadDelta runs are included only
+// if the manifest already listed them when it was fetched.
+func (c *Consumer) DownloadDelta(ctx context.Context, datasetID string, sincePart string, out string) error {
+	if err := ensureOutputDir(out); err != nil {
+		return err
+	}
+
+	dataset, err := c.GetDatasetCached(ctx, datasetID)
+	if err != nil {
+		return fmt.Errorf("failed to get dataset metadata: %w", err)
+	}
+	manifest, err := c.GetDownloadManifest(ctx, datasetID)
+	if err != nil {
+		return fmt.Errorf("failed to get manifest: %w", err)
+	}
+
+	parts, err := partsAfter(manifest, sincePart)
+	if err != nil {
+		return fmt.Errorf("dataset %s: %w", datasetID, err)
+	}
+	if len(parts) == 0 {
+		fmt.Printf("Dataset %s has no parts after %s; keeping %s\n", datasetID, sincePart, out)
+		return ErrNotModified
+	}
+
+	fmt.Printf("Downloading %d new parts of dataset %s...\n", len(parts), datasetID)
+	records, _, err := c.readParts(ctx, dataset, parts)
+	if err != nil {
+		return err
+	}
+
+	if err := writeOutputFile(out, records, DownloadOptions{}); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	fmt.Printf("Saved %d bytes from %d parts to %s\n", len(records), len(parts), out)
+	return nil
+}
+
+// readParts downloads parts of dataset and returns their records,
+// decrypted, decompressed and concatenated in order, along with the number
+// of stored bytes fetched.
+func (c *Consumer) readParts(ctx context.Context, dataset *types.Dataset, parts []types.ManifestPart) ([]byte, int64, error) {
+	isEncrypted, isCompressed := resolveEncryptCompress(dataset)
+
+	var (
+		records []byte
+		stored  int64
+	)
+	for _, part := range parts {
+		data, err := c.downloadPart(ctx, part)
+		if err != nil {
+			return nil, 0, err
+		}
+		stored += int64(len(data))
+		if isEncrypted {
+			if data, err = c.decryptData(ctx, data, datasetKeyRegion(dataset)); err != nil {
+				return nil, 0, c.decryptionError(ctx, dataset, err)
+			}
+		}
+		if isCompressed {
+			if data, err = c.decompressData(data); err != nil {
+				return nil, 0, fmt.Errorf("part %s: decompression failed: %w", part.S3Key, err)
+			}
+		}
+		records = append(records, data...)
+		// Shards need not end in a newline; keep records on separate lines.
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			records = append(records, '\n')
+		}
+	}
+	return records, stored, nil
+}
+
+// partsAfter returns the parts manifest lists after sincePart, or all of
+// them when sincePart is empty.
+func partsAfter(manifest *types.Manifest, sincePart string) ([]types.ManifestPart, error) {
+	if sincePart == "" {
+		return manifest.Parts, nil
+	}
+	for i, part := range manifest.Parts {
+		if part.S3Key == sincePart {
+			return manifest.Parts[i+1:], nil
+		}
+	}
+	return nil, fmt.Errorf("part %s is not in the manifest", sincePart)
+}
+
+// downloadPart fetches a part from its presigned URL and verifies it.
+func (c *Consumer) downloadPart(ctx context.Context, part types.ManifestPart) ([]byte, error) {
+	if part.DownloadURL == "" {
+		return nil, fmt.Errorf("part %s has no download URL", part.S3Key)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, part.DownloadURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build download request: %w", err)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download part %s: %w", part.S3Key, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download of part %s failed with status %d", part.S3Key, resp.StatusCode)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read part %s: %w", part.S3Key, err)
+	}
+	if err := part.Verify(data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
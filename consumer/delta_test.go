@@ -0,0 +1,120 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+// newDeltaServer serves an unencrypted, gzipped sharded dataset ds-1 whose
+// parts hold records.
+func newDeltaServer(t *testing.T, records ...string) *httptest.Server {
+	t.Helper()
+
+	stored := make([][]byte, len(records))
+	for i, r := range records {
+		stored[i] = gzipBytes(t, []byte(r))
+	}
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/datasets/ds-1":
+			_ = json.NewEncoder(w).Encode(types.Dataset{ID: "ds-1", Metadata: map[string]any{"compression_enabled": true, "part_count": len(stored)}})
+		case "/v1/datasets/ds-1/manifest":
+			manifest := types.Manifest{ManifestVersion: types.ManifestVersion, DatasetName: "sales"}
+			for i, data := range stored {
+				part := types.NewManifestPart(fmt.Sprintf("datasets/sales/part-%04d.ndjson.gz", i), data)
+				part.DownloadURL = fmt.Sprintf("%s/parts/%d", server.URL, i)
+				manifest.Parts = append(manifest.Parts, part)
+			}
+			_ = json.NewEncoder(w).Encode(manifest)
+		default:
+			var i int
+			if _, err := fmt.Sscanf(r.URL.Path, "/parts/%d", &i); err != nil || i >= len(stored) {
+				http.NotFound(w, r)
+				return
+			}
+			_, _ = w.Write(stored[i])
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDownloadDelta(t *testing.T) {
+	server := newDeltaServer(t, "{\"id\":1}\n", "{\"id\":2}", "{\"id\":3}\n")
+	c := newTestConsumer(server.URL)
+	ctx := context.Background()
+	out := filepath.Join(t.TempDir(), "delta", "records.ndjson")
+
+	tests := []struct {
+		sincePart string
+		want      string
+	}{
+		{"", "{\"id\":1}\n{\"id\":2}\n{\"id\":3}\n"},
+		{"datasets/sales/part-0000.ndjson.gz", "{\"id\":2}\n{\"id\":3}\n"},
+		{"datasets/sales/part-0001.ndjson.gz", "{\"id\":3}\n"},
+	}
+	for _, tt := range tests {
+		if err := c.DownloadDelta(ctx, "ds-1", tt.sincePart, out); err != nil {
+			t.Fatalf("DownloadDelta(%q): %v", tt.sincePart, err)
+		}
+		if got, _ := os.ReadFile(out); string(got) != tt.want {
+			t.Errorf("DownloadDelta(%q) wrote %q, want %q", tt.sincePart, got, tt.want)
+		}
+	}
+
+	if err := c.DownloadDelta(ctx, "ds-1", "datasets/sales/part-0002.ndjson.gz", out); !errors.Is(err, ErrNotModified) {
+		t.Errorf("DownloadDelta from the last part = %v, want ErrNotModified", err)
+	}
+	if got, _ := os.ReadFile(out); string(got) != "{\"id\":3}\n" {
+		t.Errorf("output changed to %q with nothing new", got)
+	}
+	if err := c.DownloadDelta(ctx, "ds-1", "datasets/sales/part-0009.ndjson.gz", out); err == nil {
+		t.Error("expected an error for a part the manifest does not list")
+	}
+}
+
+func TestDownloadDatasetSharded(t *testing.T) {
+	server := newDeltaServer(t, "{\"id\":1}\n", "{\"id\":2}")
+	c := newTestConsumer(server.URL)
+	out := filepath.Join(t.TempDir(), "records.ndjson")
+
+	var stats DownloadStats
+	opts := DownloadOptions{FileMode: 0o600, OnComplete: func(s DownloadStats) { stats = s }}
+	if err := c.DownloadDatasetWithOptions(context.Background(), "ds-1", out, opts); err != nil {
+		t.Fatalf("DownloadDatasetWithOptions: %v", err)
+	}
+	if got, _ := os.ReadFile(out); string(got) != "{\"id\":1}\n{\"id\":2}\n" {
+		t.Errorf("wrote %q, want both parts' records", got)
+	}
+	if info, err := os.Stat(out); err != nil {
+		t.Fatal(err)
+	} else if info.Mode().Perm() != 0o600 {
+		t.Errorf("output mode = %v, want 0600", info.Mode().Perm())
+	}
+	if !stats.Decompressed || stats.Decrypted || stats.BytesWritten != int64(len("{\"id\":1}\n{\"id\":2}\n")) || stats.BytesDownloaded == 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestNotificationPartKey(t *testing.T) {
+	for key, want := range map[string]string{
+		"datasets/sales/part-0003.ndjson.gz":  "datasets/sales/part-0003.ndjson.gz",
+		"datasets/sales/part-12345.ndjson.gz": "datasets/sales/part-12345.ndjson.gz",
+		"datasets/sales/data.ndjson.gz":       "",
+		"datasets/sales/manifest.json":        "",
+	} {
+		if got := (Notification{S3Key: key}).PartKey(); got != want {
+			t.Errorf("PartKey(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// extensionsByContentType maps the content types producers record to the
+// extension DownloadDatasetAuto gives the output file.
+var extensionsByContentType = map[string]string{
+	"application/x-ndjson":           ".ndjson",
+	"application/jsonl":              ".jsonl",
+	"application/json":               ".json",
+	"text/csv":                       ".csv",
+	"text/tab-separated-values":      ".tsv",
+	"application/vnd.apache.parquet": ".parquet",
+}
+
+var unsafeFileNameRegex = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// DownloadDatasetAuto downloads a dataset into outputDir, deriving the file
+// name from the dataset name and the extension from the content type the
+// producer recorded in metadata (".ndjson" when absent or unknown). It
+// returns the path written.
+func (c *Consumer) DownloadDatasetAuto(ctx context.Context, datasetID, outputDir string) (string, error) {
+	dataset, err := c.GetDataset(ctx, datasetID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get dataset metadata: %w", err)
+	}
+	c.cacheDataset(datasetID, dataset)
+
+	name := dataset.Name
+	if name == "" {
+		name = datasetID
+	}
+
+	var contentType string
+	if dataset.Metadata != nil {
+		contentType, _ = dataset.Metadata["content_type"].(string)
+	}
+
+	outputPath := filepath.Join(outputDir, outputFileName(name, contentType))
+	if err := c.DownloadDataset(ctx, datasetID, outputPath); err != nil {
+		return "", err
+	}
+
+	return outputPath, nil
+}
+
+// outputFileName builds a filesystem-safe file name from a dataset name and
+// content type.
+func outputFileName(name, contentType string) string {
+	base := strings.Trim(unsafeFileNameRegex.ReplaceAllString(name, "_"), "._")
+	if base == "" {
+		base = "dataset"
+	}
+
+	return base + extensionForContentType(contentType)
+}
+
+func extensionForContentType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ".ndjson"
+	}
+	if ext, ok := extensionsByContentType[mediaType]; ok {
+		return ext
+	}
+	if exts, err := mime.ExtensionsByType(mediaType); err == nil && len(exts) > 0 {
+		return exts[0]
+	}
+	return ".ndjson"
+}
@@ -0,0 +1,53 @@
+package consumer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOutputFileName(t *testing.T) {
+	tests := []struct {
+		name, datasetName, contentType, want string
+	}{
+		{"ndjson", "Daily Sales", "application/x-ndjson", "Daily_Sales.ndjson"},
+		{"csv with params", "sales", "text/csv; charset=utf-8", "sales.csv"},
+		{"parquet", "sales", "application/vnd.apache.parquet", "sales.parquet"},
+		{"missing type", "sales", "", "sales.ndjson"},
+		{"unknown type", "sales", "application/x-helix-unknown", "sales.ndjson"},
+		{"path traversal", "../../etc/passwd", "text/csv", "etc_passwd.csv"},
+		{"nothing safe left", "///", "text/csv", "dataset.csv"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := outputFileName(tt.datasetName, tt.contentType); got != tt.want {
+				t.Errorf("outputFileName(%q, %q) = %q, want %q", tt.datasetName, tt.contentType, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDownloadDatasetAuto drives the full download into a directory and pins
+// the derived path.
+func TestDownloadDatasetAuto(t *testing.T) {
+	api := newFakeAPI(t)
+	api.dataset["metadata"].(map[string]any)["content_type"] = "text/csv"
+
+	dir := t.TempDir()
+	path, err := newTestConsumer(api.server.URL).DownloadDatasetAuto(context.Background(), "ds-1", dir)
+	if err != nil {
+		t.Fatalf("DownloadDatasetAuto: %v", err)
+	}
+
+	if want := filepath.Join(dir, "Test_Dataset.csv"); path != want {
+		t.Errorf("path = %q, want %q", path, want)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	if string(got) != "hello world" {
+		t.Errorf("unexpected content %q", got)
+	}
+}
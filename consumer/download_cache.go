@@ -0,0 +1,169 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// cacheTempPrefix marks in-progress downloads inside a cache directory;
+// PruneDownloadCache leaves them alone.
+const cacheTempPrefix = ".download-"
+
+// DownloadDatasetCached downloads a dataset into an on-disk cache under
+// cacheDir and returns the cached file's path. When the cache already holds
+// the catalog's current version (and content_sha256, when the producer
+// recorded one) it returns that path without downloading; otherwise it
+// downloads the new version and removes the dataset's older cached versions.
+//
+// A cache hit refreshes the file's modification time, so PruneDownloadCache
+// evicts the least recently used entries first.
+func (c *Consumer) DownloadDatasetCached(ctx context.Context, datasetID, cacheDir string) (string, error) {
+	dataset, err := c.GetDataset(ctx, datasetID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get dataset metadata: %w", err)
+	}
+	c.cacheDataset(datasetID, dataset)
+
+	var contentSHA256 string
+	if dataset.Metadata != nil {
+		contentSHA256, _ = dataset.Metadata["content_sha256"].(string)
+	}
+
+	datasetDir := filepath.Join(cacheDir, cacheFileName(datasetID))
+	cachedPath := filepath.Join(datasetDir, cacheEntryName(dataset.Version, contentSHA256))
+
+	if info, err := os.Stat(cachedPath); err == nil && info.Mode().IsRegular() {
+		now := time.Now()
+		_ = os.Chtimes(cachedPath, now, now)
+		fmt.Printf("Using cached dataset %s (version %s)\n", datasetID, dataset.Version)
+		return cachedPath, nil
+	}
+
+	if err := os.MkdirAll(datasetDir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	// Download next to the final path and rename, so an interrupted
+	// download never leaves a partial file that looks like a cache hit.
+	tempFile, err := os.CreateTemp(datasetDir, cacheTempPrefix+"*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create cache file: %w", err)
+	}
+	tempPath := tempFile.Name()
+	tempFile.Close()
+	defer os.Remove(tempPath)
+
+	if err := c.DownloadDataset(ctx, datasetID, tempPath); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tempPath, cachedPath); err != nil {
+		return "", fmt.Errorf("failed to store cached dataset: %w", err)
+	}
+
+	removeStaleCacheEntries(datasetDir, filepath.Base(cachedPath))
+
+	return cachedPath, nil
+}
+
+// PruneDownloadCache evicts entries from a DownloadDatasetCached cache
+// directory: first every file not used for longer than maxAge, then the
+// least recently used files until the cache holds at most maxBytes. A zero
+// maxAge or maxBytes disables that limit. It returns the number of files
+// removed.
+func PruneDownloadCache(cacheDir string, maxBytes int64, maxAge time.Duration) (int, error) {
+	type entry struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+
+	var entries []entry
+	err := filepath.WalkDir(cacheDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || strings.HasPrefix(d.Name(), cacheTempPrefix) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry{path: path, size: info.Size(), modTime: info.ModTime()})
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to scan cache directory: %w", err)
+	}
+
+	// Oldest first, so size eviction drops the least recently used.
+	slices.SortFunc(entries, func(a, b entry) int { return a.modTime.Compare(b.modTime) })
+
+	var total int64
+	for _, e := range entries {
+		total += e.size
+	}
+
+	removed := 0
+	cutoff := time.Now().Add(-maxAge)
+	for _, e := range entries {
+		expired := maxAge > 0 && e.modTime.Before(cutoff)
+		oversize := maxBytes > 0 && total > maxBytes
+		if !expired && !oversize {
+			continue
+		}
+		if err := os.Remove(e.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return removed, fmt.Errorf("failed to evict %s: %w", e.path, err)
+		}
+		total -= e.size
+		removed++
+	}
+
+	return removed, nil
+}
+
+// removeStaleCacheEntries deletes every cached version in datasetDir except
+// keep. Failures are logged: a stale entry only costs disk space.
+func removeStaleCacheEntries(datasetDir, keep string) {
+	entries, err := os.ReadDir(datasetDir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if e.Name() == keep || !e.Type().IsRegular() || strings.HasPrefix(e.Name(), cacheTempPrefix) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(datasetDir, e.Name())); err != nil {
+			fmt.Printf("Warning: Failed to remove stale cache entry %s: %v\n", e.Name(), err)
+		}
+	}
+}
+
+// cacheEntryName keys a cached file by dataset version and, when known,
+// content checksum.
+func cacheEntryName(version, contentSHA256 string) string {
+	name := cacheFileName(version)
+	if contentSHA256 != "" {
+		name += "-" + cacheFileName(contentSHA256)
+	}
+	return name + ".data"
+}
+
+// cacheFileName makes an ID or version safe to use as a single path element.
+func cacheFileName(s string) string {
+	name := strings.Trim(unsafeFileNameRegex.ReplaceAllString(s, "_"), "._")
+	if name == "" {
+		return "unversioned"
+	}
+	return name
+}
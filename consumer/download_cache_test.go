@@ -0,0 +1,126 @@
+package consumer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// s3Fetches counts the signed-URL fetches the fake API has served.
+func (f *fakeAPI) s3Fetches() int {
+	n := 0
+	for _, call := range f.takeCaptured() {
+		if strings.HasPrefix(call.Path, "/s3-mock/") {
+			n++
+		}
+	}
+	return n
+}
+
+func TestDownloadDatasetCached(t *testing.T) {
+	api := newFakeAPI(t)
+	api.dataset["version"] = "1.0.0"
+	c := newTestConsumer(api.server.URL)
+	dir := t.TempDir()
+
+	first, err := c.DownloadDatasetCached(context.Background(), "ds-1", dir)
+	if err != nil {
+		t.Fatalf("DownloadDatasetCached: %v", err)
+	}
+	if want := filepath.Join(dir, "ds-1", "1.0.0.data"); first != want {
+		t.Errorf("path = %q, want %q", first, want)
+	}
+	if got, _ := os.ReadFile(first); string(got) != "hello world" {
+		t.Errorf("cached content = %q, want hello world", got)
+	}
+
+	// Same version: served from the cache.
+	second, err := c.DownloadDatasetCached(context.Background(), "ds-1", dir)
+	if err != nil || second != first {
+		t.Fatalf("second call = %q, %v; want cache hit %q", second, err, first)
+	}
+	if n := api.s3Fetches(); n != 1 {
+		t.Errorf("signed-URL fetches = %d, want 1", n)
+	}
+
+	// New version: downloaded again and the old entry removed.
+	api.dataset["version"] = "1.1.0"
+	api.dataset["metadata"].(map[string]any)["content_sha256"] = "abc123"
+	api.s3Body = []byte("new data")
+	third, err := c.DownloadDatasetCached(context.Background(), "ds-1", dir)
+	if err != nil {
+		t.Fatalf("DownloadDatasetCached after version bump: %v", err)
+	}
+	if want := filepath.Join(dir, "ds-1", "1.1.0-abc123.data"); third != want {
+		t.Errorf("path = %q, want %q", third, want)
+	}
+	if got, _ := os.ReadFile(third); string(got) != "new data" {
+		t.Errorf("cached content = %q, want new data", got)
+	}
+	if n := api.s3Fetches(); n != 2 {
+		t.Errorf("signed-URL fetches = %d, want 2", n)
+	}
+	if _, err := os.Stat(first); !os.IsNotExist(err) {
+		t.Errorf("stale version should be removed, stat err = %v", err)
+	}
+}
+
+func TestDownloadDatasetCached_FailureLeavesNoEntry(t *testing.T) {
+	api := newFakeAPI(t)
+	api.dataset["version"] = "1.0.0"
+	api.s3Status = 500
+	dir := t.TempDir()
+
+	if _, err := newTestConsumer(api.server.URL).DownloadDatasetCached(context.Background(), "ds-1", dir); err == nil {
+		t.Fatal("expected download error")
+	}
+	entries, _ := os.ReadDir(filepath.Join(dir, "ds-1"))
+	if len(entries) != 0 {
+		t.Errorf("failed download left %d cache files", len(entries))
+	}
+}
+
+func TestPruneDownloadCache(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	write := func(name string, size int, age time.Duration) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	expired := write("a/1.data", 10, 48*time.Hour)
+	oldest := write("b/1.data", 40, 3*time.Hour)
+	newer := write("c/1.data", 40, time.Hour)
+	newest := write("d/1.data", 40, time.Minute)
+	temp := write("d/"+cacheTempPrefix+"123", 100, 72*time.Hour)
+
+	removed, err := PruneDownloadCache(dir, 80, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("PruneDownloadCache: %v", err)
+	}
+	if removed != 2 {
+		t.Errorf("removed = %d, want 2", removed)
+	}
+	for path, want := range map[string]bool{expired: false, oldest: false, newer: true, newest: true, temp: true} {
+		if _, err := os.Stat(path); (err == nil) != want {
+			t.Errorf("%s exists = %v, want %v", path, err == nil, want)
+		}
+	}
+
+	if removed, err := PruneDownloadCache(filepath.Join(dir, "missing"), 1, 0); err != nil || removed != 0 {
+		t.Errorf("missing dir = %d, %v; want 0, nil", removed, err)
+	}
+}
@@ -0,0 +1,72 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestDownloadDatasetHonorsContext pins that the signed-URL fetch is bound
+// to the caller's context: a deadline aborts a stalled download promptly.
+func TestDownloadDatasetHonorsContext(t *testing.T) {
+	release := make(chan struct{})
+	stalled := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer stalled.Close()
+	defer close(release)
+
+	api := newFakeAPI(t)
+	api.urlInfo = func() *DownloadURLInfo {
+		return &DownloadURLInfo{DownloadURL: stalled.URL + "/object"}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := newTestConsumer(api.server.URL).DownloadDataset(ctx, "ds-1", filepath.Join(t.TempDir(), "out"))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("download took %s to notice the deadline", elapsed)
+	}
+}
+
+// TestDownloadDatasetCancelMidBody pins that cancelling the context while
+// the body is streaming aborts the read instead of waiting for the rest.
+func TestDownloadDatasetCancelMidBody(t *testing.T) {
+	release := make(chan struct{})
+	partial := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "1024")
+		_, _ = w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer partial.Close()
+	defer close(release)
+
+	api := newFakeAPI(t)
+	api.urlInfo = func() *DownloadURLInfo {
+		return &DownloadURLInfo{DownloadURL: partial.URL + "/object"}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	err := newTestConsumer(api.server.URL).DownloadDataset(ctx, "ds-1", filepath.Join(t.TempDir(), "out"))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+}
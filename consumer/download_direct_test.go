@@ -0,0 +1,78 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+// TestDownloadDatasetDirect downloads through the signed download endpoint,
+// without a presigned URL or an outcome callback.
+func TestDownloadDatasetDirect(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls = append(calls, r.Method+" "+r.URL.RequestURI())
+		mu.Unlock()
+
+		switch r.URL.RequestURI() {
+		case "/v1/datasets/ds-1":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"_id":"ds-1","metadata":{"compression_enabled":false,"encryption_enabled":false}}`))
+		case "/v1/datasets/ds-1/download?redirect=false":
+			if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ") {
+				t.Errorf("download request is not signed: Authorization = %q", r.Header.Get("Authorization"))
+			}
+			w.Header().Set("Content-Type", "application/octet-stream")
+			_, _ = w.Write([]byte("hello world"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	out := filepath.Join(t.TempDir(), "data.ndjson")
+	if err := newTestConsumer(srv.URL).DownloadDatasetDirect(context.Background(), "ds-1", out); err != nil {
+		t.Fatalf("DownloadDatasetDirect: %v", err)
+	}
+	if got, _ := os.ReadFile(out); string(got) != "hello world" {
+		t.Errorf("output = %q, want %q", got, "hello world")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"GET /v1/datasets/ds-1", "GET /v1/datasets/ds-1/download?redirect=false"}
+	if strings.Join(calls, ", ") != strings.Join(want, ", ") {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
+
+// TestDownloadDatasetDirectNotFound returns the API error of the download
+// endpoint as a *types.StatusError.
+func TestDownloadDatasetDirectNotFound(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/datasets/ds-1" {
+			_, _ = w.Write([]byte(`{"_id":"ds-1"}`))
+			return
+		}
+		http.Error(w, `{"error":"dataset object not found"}`, http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	err := newTestConsumer(srv.URL).DownloadDatasetDirect(context.Background(), "ds-1", filepath.Join(t.TempDir(), "out"))
+	var statusErr *types.StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Errorf("err = %v, want a *types.StatusError with status 404", err)
+	}
+}
@@ -0,0 +1,137 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+func TestDownloadDatasetWithOptions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix permission bits")
+	}
+
+	api := newFakeAPI(t)
+	out := filepath.Join(t.TempDir(), "nested", "dir", "data.ndjson")
+
+	err := newTestConsumer(api.server.URL).DownloadDatasetWithOptions(context.Background(), "ds-1", out, DownloadOptions{FileMode: 0o600})
+	if err != nil {
+		t.Fatalf("DownloadDatasetWithOptions: %v", err)
+	}
+	info, err := os.Stat(out)
+	if err != nil {
+		t.Fatalf("output not written: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm&0o077 != 0 {
+		t.Errorf("mode = %v, want no group/other access", perm)
+	}
+}
+
+func TestWriteOutputFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix permission bits")
+	}
+
+	t.Run("default mode", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "data")
+		if err := writeOutputFile(path, []byte("x"), DownloadOptions{}); err != nil {
+			t.Fatalf("writeOutputFile: %v", err)
+		}
+		info, _ := os.Stat(path)
+		if perm := info.Mode().Perm(); perm&^defaultDownloadFileMode != 0 {
+			t.Errorf("mode = %v, want at most %v", perm, defaultDownloadFileMode)
+		}
+	})
+
+	t.Run("existing file is tightened", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "data")
+		if err := os.WriteFile(path, []byte("old"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(path, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := writeOutputFile(path, []byte("new"), DownloadOptions{FileMode: 0o600}); err != nil {
+			t.Fatalf("writeOutputFile: %v", err)
+		}
+		info, _ := os.Stat(path)
+		if perm := info.Mode().Perm(); perm != 0o600 {
+			t.Errorf("mode = %v, want 0600", perm)
+		}
+		if got, _ := os.ReadFile(path); string(got) != "new" {
+			t.Errorf("content = %q, want new", got)
+		}
+	})
+}
+
+// TestDownloadDataset_OutputDirFailsFast pins that an output directory that
+// cannot be created fails before anything is downloaded, with an error that
+// names the directory rather than the file write.
+func TestDownloadDataset_OutputDirFailsFast(t *testing.T) {
+	parent := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(parent, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	api := newFakeAPI(t)
+	err := newTestConsumer(api.server.URL).DownloadDataset(context.Background(), "ds-1", filepath.Join(parent, "sub", "data"))
+	if err == nil || !strings.Contains(err.Error(), "failed to create output directory") {
+		t.Fatalf("err = %v, want an output directory error", err)
+	}
+	if calls := api.takeCaptured(); len(calls) != 0 {
+		t.Errorf("expected no requests before the directory check, got %v", calls)
+	}
+}
+
+// TestDownloadVerifySize pins that VerifySize fails a download shorter than
+// the catalog's size_bytes before anything is written, and passes one that
+// matches.
+func TestDownloadVerifySize(t *testing.T) {
+	for _, tc := range []struct {
+		sizeBytes int
+		wantErr   bool
+	}{{11, false}, {20, true}} {
+		api := newFakeAPI(t)
+		api.dataset["size_bytes"] = tc.sizeBytes
+		out := filepath.Join(t.TempDir(), "data")
+
+		err := newTestConsumer(api.server.URL).DownloadDatasetWithOptions(context.Background(), "ds-1", out, DownloadOptions{VerifySize: true})
+		if tc.wantErr {
+			if !errors.Is(err, ErrSizeMismatch) || !strings.Contains(err.Error(), "downloaded 11 bytes, catalog records 20") {
+				t.Errorf("size_bytes %d: err = %v, want ErrSizeMismatch", tc.sizeBytes, err)
+			}
+			if _, serr := os.Stat(out); !os.IsNotExist(serr) {
+				t.Errorf("size_bytes %d: output written despite the mismatch", tc.sizeBytes)
+			}
+		} else if err != nil {
+			t.Errorf("size_bytes %d: %v", tc.sizeBytes, err)
+		}
+	}
+}
+
+func TestVerifyStoredSize(t *testing.T) {
+	encrypted := &types.Dataset{SizeBytes: 100, Metadata: map[string]any{
+		"encryption_enabled": true, "compression_enabled": true,
+		"compressed_size_bytes": float64(80), "encrypted_size_bytes": float64(90),
+	}}
+	if err := verifyStoredSize(encrypted, 90); err != nil {
+		t.Errorf("encrypted_size_bytes match: %v", err)
+	}
+	if err := verifyStoredSize(encrypted, 100); !errors.Is(err, ErrSizeMismatch) {
+		t.Errorf("size_bytes instead of encrypted_size_bytes: err = %v, want ErrSizeMismatch", err)
+	}
+
+	sharded := &types.Dataset{SizeBytes: 100, Metadata: map[string]any{"part_count": float64(2)}}
+	if err := verifyStoredSize(sharded, 10); err != nil {
+		t.Errorf("sharded dataset checked against its parts' total: %v", err)
+	}
+	if err := verifyStoredSize(&types.Dataset{}, 10); err != nil {
+		t.Errorf("dataset with no recorded size: %v", err)
+	}
+}
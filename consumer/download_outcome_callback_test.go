@@ -451,15 +451,14 @@ func TestDownloadOutcome_NetworkFetchError(t *testing.T) {
 
 // TestDownloadOutcome_DiskWriteError verifies that a disk-write failure
 // tags the callback with error_category=disk_write. We force the
-// failure by pointing outputPath at an unwritable directory (a path
-// with a NUL byte is portable across OSes — os.WriteFile rejects it).
+// failure by pointing outputPath at an existing directory: its parent
+// exists, so the up-front directory check passes, and os.WriteFile
+// rejects it only at the write.
 func TestDownloadOutcome_DiskWriteError(t *testing.T) {
 	f := newFakeAPI(t)
 	c := newTestConsumer(f.server.URL)
 
-	// A path under a non-existent directory triggers os.WriteFile's
-	// "no such file or directory" error — exercises ErrorCategoryDiskWrite.
-	out := filepath.Join(t.TempDir(), "nonexistent-dir", "out.bin")
+	out := t.TempDir()
 	err := c.DownloadDataset(context.Background(), "ds-1", out)
 	if err == nil {
 		t.Fatal("expected DownloadDataset to fail on disk write")
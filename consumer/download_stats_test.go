@@ -0,0 +1,61 @@
+package consumer
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDownloadOnComplete(t *testing.T) {
+	api := newFakeAPI(t)
+	api.dataset["metadata"].(map[string]any)["compression_enabled"] = true
+	api.s3Body = gzipBytes(t, []byte("hello world, hello world"))
+	c := newTestConsumer(api.server.URL)
+
+	var stats []DownloadStats
+	opts := DownloadOptions{OnComplete: func(s DownloadStats) { stats = append(stats, s) }}
+	if err := c.DownloadDatasetWithOptions(context.Background(), "ds-1", filepath.Join(t.TempDir(), "out"), opts); err != nil {
+		t.Fatalf("DownloadDatasetWithOptions: %v", err)
+	}
+
+	if len(stats) != 1 {
+		t.Fatalf("OnComplete calls = %d, want 1", len(stats))
+	}
+	got := stats[0]
+	if got.BytesDownloaded != int64(len(api.s3Body)) || got.BytesWritten != 24 {
+		t.Errorf("sizes = %d downloaded, %d written; want %d, 24", got.BytesDownloaded, got.BytesWritten, len(api.s3Body))
+	}
+	if got.Decrypted || !got.Decompressed || got.Duration <= 0 || got.ThroughputMBps <= 0 {
+		t.Errorf("stats = %+v", got)
+	}
+	tm := got.Timings
+	if tm.Metadata <= 0 || tm.URLFetch <= 0 || tm.Download <= 0 || tm.Decompress <= 0 || tm.Write <= 0 {
+		t.Errorf("timings = %+v, want every stage that ran to be timed", tm)
+	}
+	if tm.Decrypt != 0 {
+		t.Errorf("Decrypt = %v for an unencrypted dataset, want 0", tm.Decrypt)
+	}
+	if sum := tm.Metadata + tm.URLFetch + tm.Download + tm.Decompress + tm.Write; sum > got.Duration {
+		t.Errorf("stage timings add up to %v, more than Duration %v", sum, got.Duration)
+	}
+	if got.Retries != 0 {
+		t.Errorf("Retries = %d, want 0", got.Retries)
+	}
+
+	// Failures don't report.
+	api.s3Status = 500
+	_ = c.DownloadDatasetWithOptions(context.Background(), "ds-1", filepath.Join(t.TempDir(), "out"), opts)
+	if len(stats) != 1 {
+		t.Errorf("OnComplete calls after a failure = %d, want 1", len(stats))
+	}
+}
+
+func TestThroughputMBps(t *testing.T) {
+	if got := throughputMBps(10<<20, 2*time.Second); got != 5 {
+		t.Errorf("throughputMBps = %v, want 5", got)
+	}
+	if got := throughputMBps(1, 0); got != 0 {
+		t.Errorf("throughputMBps with zero duration = %v, want 0", got)
+	}
+}
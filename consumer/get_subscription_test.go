@@ -0,0 +1,51 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/helix-tools/sdk-go/v2/helixtest"
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+func TestConsumerGetSubscription(t *testing.T) {
+	api := helixtest.NewMockAPI()
+	api.Handle(http.MethodGet, "/v1/subscriptions/sub-1", http.StatusOK, Subscription{
+		ID:          "sub-1",
+		KMSGrantID:  aws.String("grant-1"),
+		SQSQueueURL: aws.String("https://queue.example/consumer-1"),
+	})
+	api.Handle(http.MethodGet, "/v1/subscriptions/missing", http.StatusNotFound, map[string]string{"error": "not found"})
+	c := NewConsumerWithAPI(types.Config{CustomerID: "consumer-1"}, api)
+
+	sub, err := c.GetSubscription(context.Background(), "sub-1")
+	if err != nil || aws.ToString(sub.KMSGrantID) != "grant-1" || aws.ToString(sub.SQSQueueURL) == "" {
+		t.Fatalf("GetSubscription = %+v, %v", sub, err)
+	}
+
+	_, err = c.GetSubscription(context.Background(), "missing")
+	var notFound *types.NotFoundError
+	if !errors.As(err, &notFound) || notFound.ID != "missing" {
+		t.Errorf("err = %v, want a *types.NotFoundError for missing", err)
+	}
+}
+
+// TestConsumerGetSubscriptionNotFoundHTTP covers the signed HTTP path,
+// whose non-2xx responses are *types.StatusError like an APIDoer's.
+func TestConsumerGetSubscriptionNotFoundHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"subscription not found"}`, http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	_, err := newTestConsumer(srv.URL).GetSubscription(context.Background(), "sub-1")
+	var notFound *types.NotFoundError
+	var statusErr *types.StatusError
+	if !errors.As(err, &notFound) || !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Errorf("err = %v, want a *types.NotFoundError wrapping the 404", err)
+	}
+}
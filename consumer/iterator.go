@@ -0,0 +1,358 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+// ListOptions configures the listing iterators (DatasetIterator,
+// SubscriptionIterator, SubscriptionRequestIterator). Fields that don't
+// apply to a listing are ignored.
+type ListOptions struct {
+	// ProducerID filters datasets by producer, as in ListDatasets.
+	ProducerID string
+
+	// Category filters datasets by category, as in ListDatasetsByCategory.
+	Category string
+
+	// Marketplace, when set, makes DatasetIterator walk the public
+	// marketplace (BrowseMarketplace) with these filters instead of the
+	// datasets available to the consumer. Its Page is ignored.
+	Marketplace *types.MarketplaceBrowseParams
+
+	// Role filters subscriptions, as ListSubscriptionsOptions.Role does.
+	Role string
+
+	// Status filters subscription requests, as in ListSubscriptionRequests.
+	Status string
+
+	// PerPage is the page size to request. Zero leaves it to the API.
+	PerPage int
+
+	// Prefetch fetches the next page in the background while the caller
+	// works through the current one (default: true).
+	Prefetch *bool
+}
+
+// prefetch resolves Prefetch, defaulting to true when unset.
+func (o ListOptions) prefetch() bool {
+	return o.Prefetch == nil || *o.Prefetch
+}
+
+// listPage is one response of a paged listing, in whichever paging style
+// the API uses: NextCursor for cursor paging, Pagination for page numbers,
+// neither for an unpaged listing.
+type listPage[T any] struct {
+	Items      []T
+	NextCursor string
+	Pagination *types.MarketplacePagination
+}
+
+type pageResult[T any] struct {
+	page *listPage[T]
+	err  error
+}
+
+// pager fetches the pages of one listing on demand. It detects the API's
+// paging style from each response and follows next_cursor or page numbers
+// accordingly, so callers are unaffected when the API switches from one to
+// the other.
+type pager[T any] struct {
+	ctx      context.Context
+	query    url.Values // the listing's filters
+	fetch    func(ctx context.Context, q url.Values) (*listPage[T], error)
+	prefetch bool
+
+	buf     []T
+	current T
+	cursor  string // next cursor, in cursor paging
+	page    int    // last page fetched, in page paging
+	done    bool   // no pages left to fetch
+	err     error
+	pending chan pageResult[T] // prefetched next page
+}
+
+func newPager[T any](ctx context.Context, opts ListOptions, query url.Values, fetch func(context.Context, url.Values) (*listPage[T], error)) *pager[T] {
+	if opts.PerPage > 0 {
+		query.Set("per_page", strconv.Itoa(opts.PerPage))
+	}
+	return &pager[T]{ctx: ctx, query: query, fetch: fetch, prefetch: opts.prefetch()}
+}
+
+// next advances to the next item, fetching the next page when the current
+// one is used up.
+func (p *pager[T]) next() bool {
+	for len(p.buf) == 0 {
+		if p.done || p.err != nil {
+			return false
+		}
+
+		var result pageResult[T]
+		if p.pending != nil {
+			result = <-p.pending
+			p.pending = nil
+		} else {
+			result.page, result.err = p.fetch(p.ctx, p.nextQuery())
+		}
+		if result.err != nil {
+			p.err = result.err
+			return false
+		}
+		p.advance(result.page)
+
+		if p.prefetch && !p.done && p.err == nil {
+			// Buffered, so an abandoned iterator doesn't strand the goroutine.
+			pending := make(chan pageResult[T], 1)
+			go func(q url.Values) {
+				page, err := p.fetch(p.ctx, q)
+				pending <- pageResult[T]{page, err}
+			}(p.nextQuery())
+			p.pending = pending
+		}
+	}
+
+	p.current, p.buf = p.buf[0], p.buf[1:]
+	return true
+}
+
+// nextQuery is the query for the page after the last one fetched.
+func (p *pager[T]) nextQuery() url.Values {
+	q := maps.Clone(p.query)
+	switch {
+	case p.cursor != "":
+		q.Set("cursor", p.cursor)
+	case p.page > 0:
+		q.Set("page", strconv.Itoa(p.page+1))
+	}
+	return q
+}
+
+// advance takes in a fetched page and works out where the one after it
+// comes from.
+func (p *pager[T]) advance(page *listPage[T]) {
+	p.buf = page.Items
+
+	switch {
+	case page.NextCursor != "":
+		if page.NextCursor == p.cursor {
+			p.err = fmt.Errorf("API returned the same cursor %q twice", p.cursor)
+			return
+		}
+		p.cursor = page.NextCursor
+	case p.cursor == "" && page.Pagination != nil && len(page.Items) > 0:
+		p.page++
+		p.done = p.page >= page.Pagination.TotalPages
+	default:
+		p.done = true
+	}
+}
+
+// listPath joins a listing path and its query.
+func listPath(path string, q url.Values) string {
+	if len(q) == 0 {
+		return path
+	}
+	return path + "?" + q.Encode()
+}
+
+// datasetPage is one response of GET /v1/datasets or the marketplace.
+type datasetPage[T any] struct {
+	Datasets   []T                          `json:"datasets"`
+	NextCursor string                       `json:"next_cursor,omitempty"`
+	Pagination *types.MarketplacePagination `json:"pagination,omitempty"`
+}
+
+// ListDatasetsCursor fetches one page of datasets by cursor: pass "" for
+// the first page and the returned nextCursor for each following one. An
+// empty nextCursor means there are no more pages, or that the API does not
+// page by cursor; DatasetIterator handles both paging styles.
+func (c *Consumer) ListDatasetsCursor(ctx context.Context, cursor string) (datasets []Dataset, nextCursor string, err error) {
+	q := url.Values{}
+	if cursor != "" {
+		q.Set("cursor", cursor)
+	}
+
+	var page datasetPage[Dataset]
+	if err := c.makeAPIRequest(ctx, http.MethodGet, listPath("/v1/datasets", q), nil, &page); err != nil {
+		return nil, "", err
+	}
+	return page.Datasets, page.NextCursor, nil
+}
+
+// DatasetIterator walks every dataset of a listing, fetching pages as
+// needed:
+//
+//	it := consumer.DatasetIterator(ctx, consumer.ListOptions{})
+//	for it.Next() {
+//		dataset := it.Dataset()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type DatasetIterator struct {
+	p *pager[types.Dataset]
+}
+
+// DatasetIterator returns an iterator over the datasets available to the
+// consumer (GET /v1/datasets), or over the marketplace with
+// opts.Marketplace. No request is made until the first Next; ctx governs
+// every page request.
+func (c *Consumer) DatasetIterator(ctx context.Context, opts ListOptions) *DatasetIterator {
+	return &DatasetIterator{datasetPager[types.Dataset](ctx, c, opts)}
+}
+
+// datasetPager pages through the listing DatasetIterator walks, decoding
+// each dataset as T.
+func datasetPager[T any](ctx context.Context, c *Consumer, opts ListOptions) *pager[T] {
+	path, q := "/v1/datasets", url.Values{}
+	if m := opts.Marketplace; m != nil {
+		path = "/v1/datasets/marketplace"
+		for key, value := range map[string]string{"search": m.Search, "category": m.Category, "sort": m.Sort} {
+			if value != "" {
+				q.Set(key, value)
+			}
+		}
+	} else {
+		if opts.ProducerID != "" {
+			q.Set("producer_id", opts.ProducerID)
+		}
+		if opts.Category != "" {
+			q.Set("category", opts.Category)
+		}
+	}
+
+	return newPager(ctx, opts, q, func(ctx context.Context, q url.Values) (*listPage[T], error) {
+		var page datasetPage[T]
+		if err := c.makeAPIRequest(ctx, http.MethodGet, listPath(path, q), nil, &page); err != nil {
+			return nil, err
+		}
+		return &listPage[T]{Items: page.Datasets, NextCursor: page.NextCursor, Pagination: page.Pagination}, nil
+	})
+}
+
+// ListDatasetsByCategory lists every dataset available to the consumer in
+// category, following pagination to the last page.
+func (c *Consumer) ListDatasetsByCategory(ctx context.Context, category string) ([]Dataset, error) {
+	if strings.TrimSpace(category) == "" {
+		return nil, fmt.Errorf("category is required")
+	}
+
+	noPrefetch := false
+	p := datasetPager[Dataset](ctx, c, ListOptions{Category: category, Prefetch: &noPrefetch})
+	var datasets []Dataset
+	for p.next() {
+		datasets = append(datasets, p.current)
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return datasets, nil
+}
+
+// Next advances to the next dataset. It returns false when the listing is
+// exhausted or a request failed; Err tells which.
+func (it *DatasetIterator) Next() bool {
+	return it.p.next()
+}
+
+// Dataset returns the dataset Next advanced to.
+func (it *DatasetIterator) Dataset() types.Dataset {
+	return it.p.current
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *DatasetIterator) Err() error {
+	return it.p.err
+}
+
+// SubscriptionIterator is DatasetIterator for the consumer's subscriptions.
+type SubscriptionIterator struct {
+	p *pager[Subscription]
+}
+
+// SubscriptionIterator returns an iterator over the consumer's
+// subscriptions (GET /v1/subscriptions), filtered by opts.Role.
+func (c *Consumer) SubscriptionIterator(ctx context.Context, opts ListOptions) *SubscriptionIterator {
+	q := url.Values{}
+	if opts.Role != "" {
+		q.Set("role", opts.Role)
+	}
+
+	return &SubscriptionIterator{newPager(ctx, opts, q, func(ctx context.Context, q url.Values) (*listPage[Subscription], error) {
+		var page struct {
+			Subscriptions []Subscription               `json:"subscriptions"`
+			NextCursor    string                       `json:"next_cursor,omitempty"`
+			Pagination    *types.MarketplacePagination `json:"pagination,omitempty"`
+		}
+		if err := c.makeAPIRequest(ctx, http.MethodGet, listPath("/v1/subscriptions", q), nil, &page); err != nil {
+			return nil, err
+		}
+		return &listPage[Subscription]{Items: page.Subscriptions, NextCursor: page.NextCursor, Pagination: page.Pagination}, nil
+	})}
+}
+
+// Next advances to the next subscription.
+func (it *SubscriptionIterator) Next() bool {
+	return it.p.next()
+}
+
+// Subscription returns the subscription Next advanced to.
+func (it *SubscriptionIterator) Subscription() Subscription {
+	return it.p.current
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *SubscriptionIterator) Err() error {
+	return it.p.err
+}
+
+// SubscriptionRequestIterator is DatasetIterator for the consumer's
+// subscription requests.
+type SubscriptionRequestIterator struct {
+	p *pager[types.SubscriptionRequest]
+}
+
+// SubscriptionRequestIterator returns an iterator over the consumer's
+// subscription requests (GET /v1/subscription-requests), filtered by
+// opts.Status.
+func (c *Consumer) SubscriptionRequestIterator(ctx context.Context, opts ListOptions) *SubscriptionRequestIterator {
+	q := url.Values{}
+	if opts.Status != "" {
+		q.Set("status", opts.Status)
+	}
+
+	return &SubscriptionRequestIterator{newPager(ctx, opts, q, func(ctx context.Context, q url.Values) (*listPage[types.SubscriptionRequest], error) {
+		var page struct {
+			Requests   []types.SubscriptionRequest  `json:"requests"`
+			NextCursor string                       `json:"next_cursor,omitempty"`
+			Pagination *types.MarketplacePagination `json:"pagination,omitempty"`
+		}
+		if err := c.makeAPIRequest(ctx, http.MethodGet, listPath("/v1/subscription-requests", q), nil, &page); err != nil {
+			return nil, err
+		}
+		return &listPage[types.SubscriptionRequest]{Items: page.Requests, NextCursor: page.NextCursor, Pagination: page.Pagination}, nil
+	})}
+}
+
+// Next advances to the next subscription request.
+func (it *SubscriptionRequestIterator) Next() bool {
+	return it.p.next()
+}
+
+// SubscriptionRequest returns the subscription request Next advanced to.
+func (it *SubscriptionRequestIterator) SubscriptionRequest() types.SubscriptionRequest {
+	return it.p.current
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *SubscriptionRequestIterator) Err() error {
+	return it.p.err
+}
@@ -0,0 +1,196 @@
+package consumer
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/helix-tools/sdk-go/v2/helixtest"
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+func datasetIDs(t *testing.T, it *DatasetIterator) []string {
+	t.Helper()
+	var ids []string
+	for it.Next() {
+		ids = append(ids, it.Dataset().ID)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("iteration failed after %v: %v", ids, err)
+	}
+	return ids
+}
+
+func TestDatasetIteratorCursorPaging(t *testing.T) {
+	api := helixtest.NewMockAPI()
+	api.Handle(http.MethodGet, "/v1/datasets?producer_id=company-1", http.StatusOK, map[string]any{
+		"datasets":    []types.Dataset{{ID: "ds-1"}, {ID: "ds-2"}},
+		"next_cursor": "c1",
+	})
+	api.Handle(http.MethodGet, "/v1/datasets?cursor=c1&producer_id=company-1", http.StatusOK, map[string]any{
+		"datasets": []types.Dataset{{ID: "ds-3"}},
+	})
+	c := NewConsumerWithAPI(types.Config{CustomerID: "consumer-1"}, api)
+
+	it := c.DatasetIterator(context.Background(), ListOptions{ProducerID: "company-1"})
+	if got := datasetIDs(t, it); !slices.Equal(got, []string{"ds-1", "ds-2", "ds-3"}) {
+		t.Errorf("datasets = %v, want ds-1..ds-3", got)
+	}
+	if calls := api.Calls(); len(calls) != 2 {
+		t.Errorf("calls = %v, want 2 pages", calls)
+	}
+}
+
+func TestDatasetIteratorPagePaging(t *testing.T) {
+	api := helixtest.NewMockAPI()
+	api.Handle(http.MethodGet, "/v1/datasets?per_page=1", http.StatusOK, map[string]any{
+		"datasets":   []types.Dataset{{ID: "ds-1"}},
+		"pagination": types.MarketplacePagination{Page: 1, PerPage: 1, TotalPages: 2, Total: 2},
+	})
+	api.Handle(http.MethodGet, "/v1/datasets?page=2&per_page=1", http.StatusOK, map[string]any{
+		"datasets":   []types.Dataset{{ID: "ds-2"}},
+		"pagination": types.MarketplacePagination{Page: 2, PerPage: 1, TotalPages: 2, Total: 2},
+	})
+	c := NewConsumerWithAPI(types.Config{CustomerID: "consumer-1"}, api)
+
+	noPrefetch := false
+	for _, opts := range []ListOptions{{PerPage: 1}, {PerPage: 1, Prefetch: &noPrefetch}} {
+		if got := datasetIDs(t, c.DatasetIterator(context.Background(), opts)); !slices.Equal(got, []string{"ds-1", "ds-2"}) {
+			t.Errorf("datasets (prefetch %v) = %v, want ds-1, ds-2", opts.prefetch(), got)
+		}
+	}
+
+	// An unpaged listing is a single page.
+	api.Handle(http.MethodGet, "/v1/datasets", http.StatusOK, map[string]any{"datasets": []types.Dataset{{ID: "ds-9"}}})
+	if got := datasetIDs(t, c.DatasetIterator(context.Background(), ListOptions{})); !slices.Equal(got, []string{"ds-9"}) {
+		t.Errorf("unpaged datasets = %v, want ds-9", got)
+	}
+}
+
+func TestDatasetIteratorMarketplace(t *testing.T) {
+	api := helixtest.NewMockAPI()
+	api.Handle(http.MethodGet, "/v1/datasets/marketplace?category=finance", http.StatusOK, types.MarketplaceBrowseResponse{
+		Datasets:   []types.Dataset{{ID: "ds-1"}},
+		Pagination: types.MarketplacePagination{Page: 1, TotalPages: 2},
+	})
+	api.Handle(http.MethodGet, "/v1/datasets/marketplace?category=finance&page=2", http.StatusOK, types.MarketplaceBrowseResponse{
+		Datasets:   []types.Dataset{{ID: "ds-2"}},
+		Pagination: types.MarketplacePagination{Page: 2, TotalPages: 2},
+	})
+	c := NewConsumerWithAPI(types.Config{CustomerID: "consumer-1"}, api)
+
+	it := c.DatasetIterator(context.Background(), ListOptions{Marketplace: &types.MarketplaceBrowseParams{Category: "finance", Page: 7}})
+	if got := datasetIDs(t, it); !slices.Equal(got, []string{"ds-1", "ds-2"}) {
+		t.Errorf("marketplace datasets = %v, want ds-1, ds-2", got)
+	}
+}
+
+func TestDatasetIteratorErrors(t *testing.T) {
+	api := helixtest.NewMockAPI()
+	api.Handle(http.MethodGet, "/v1/datasets", http.StatusOK, map[string]any{
+		"datasets":    []types.Dataset{{ID: "ds-1"}},
+		"next_cursor": "c1",
+	})
+	api.Handle(http.MethodGet, "/v1/datasets?cursor=c1", http.StatusOK, map[string]any{
+		"datasets":    []types.Dataset{{ID: "ds-2"}},
+		"next_cursor": "c1",
+	})
+	c := NewConsumerWithAPI(types.Config{CustomerID: "consumer-1"}, api)
+
+	it := c.DatasetIterator(context.Background(), ListOptions{})
+	var n int
+	for it.Next() {
+		n++
+	}
+	if it.Err() == nil || n != 2 {
+		t.Errorf("repeated cursor: %d datasets, err = %v; want 2 and an error", n, it.Err())
+	}
+
+	api.Handle(http.MethodGet, "/v1/datasets?cursor=c1", http.StatusInternalServerError, "boom")
+	it = c.DatasetIterator(context.Background(), ListOptions{})
+	for it.Next() {
+	}
+	if it.Err() == nil {
+		t.Error("a failed page request should stop the iteration with an error")
+	}
+}
+
+func TestSubscriptionIterators(t *testing.T) {
+	api := helixtest.NewMockAPI()
+	api.Handle(http.MethodGet, "/v1/subscriptions?role=consumer", http.StatusOK, map[string]any{
+		"subscriptions": []Subscription{{ID: "sub-1"}},
+		"next_cursor":   "s1",
+	})
+	api.Handle(http.MethodGet, "/v1/subscriptions?cursor=s1&role=consumer", http.StatusOK, map[string]any{
+		"subscriptions": []Subscription{{ID: "sub-2"}},
+	})
+	api.Handle(http.MethodGet, "/v1/subscription-requests?status=pending", http.StatusOK, map[string]any{
+		"requests":   []types.SubscriptionRequest{{ID: "req-1"}, {ID: "req-2"}},
+		"pagination": types.MarketplacePagination{Page: 1, TotalPages: 1},
+	})
+	c := NewConsumerWithAPI(types.Config{CustomerID: "consumer-1"}, api)
+	ctx := context.Background()
+
+	var subs []string
+	for it := c.SubscriptionIterator(ctx, ListOptions{Role: "consumer"}); it.Next(); {
+		subs = append(subs, it.Subscription().ID)
+	}
+	if !slices.Equal(subs, []string{"sub-1", "sub-2"}) {
+		t.Errorf("subscriptions = %v, want sub-1, sub-2", subs)
+	}
+
+	var requests []string
+	it := c.SubscriptionRequestIterator(ctx, ListOptions{Status: "pending"})
+	for it.Next() {
+		requests = append(requests, it.SubscriptionRequest().ID)
+	}
+	if it.Err() != nil || !slices.Equal(requests, []string{"req-1", "req-2"}) {
+		t.Errorf("subscription requests = %v, %v; want req-1, req-2", requests, it.Err())
+	}
+}
+
+func TestListDatasetsCursor(t *testing.T) {
+	api := helixtest.NewMockAPI()
+	api.Handle(http.MethodGet, "/v1/datasets", http.StatusOK, map[string]any{
+		"datasets":    []Dataset{{ID: "ds-1"}},
+		"next_cursor": "c1",
+	})
+	api.Handle(http.MethodGet, "/v1/datasets?cursor=c1", http.StatusOK, map[string]any{
+		"datasets": []Dataset{{ID: "ds-2"}},
+	})
+	c := NewConsumerWithAPI(types.Config{CustomerID: "consumer-1"}, api)
+
+	datasets, next, err := c.ListDatasetsCursor(context.Background(), "")
+	if err != nil || len(datasets) != 1 || next != "c1" {
+		t.Fatalf("first page = %v, %q, %v", datasets, next, err)
+	}
+	datasets, next, err = c.ListDatasetsCursor(context.Background(), next)
+	if err != nil || len(datasets) != 1 || datasets[0].ID != "ds-2" || next != "" {
+		t.Errorf("last page = %v, %q, %v; want ds-2 and no cursor", datasets, next, err)
+	}
+}
+
+func TestListDatasetsByCategory(t *testing.T) {
+	api := helixtest.NewMockAPI()
+	api.Handle(http.MethodGet, "/v1/datasets?category=Real+Estate+%26+Land", http.StatusOK, map[string]any{
+		"datasets":    []types.Dataset{{ID: "ds-1"}},
+		"next_cursor": "c1",
+	})
+	api.Handle(http.MethodGet, "/v1/datasets?category=Real+Estate+%26+Land&cursor=c1", http.StatusOK, map[string]any{
+		"datasets": []types.Dataset{{ID: "ds-2"}},
+	})
+	c := NewConsumerWithAPI(types.Config{CustomerID: "consumer-1"}, api)
+
+	datasets, err := c.ListDatasetsByCategory(context.Background(), "Real Estate & Land")
+	if err != nil {
+		t.Fatalf("ListDatasetsByCategory: %v", err)
+	}
+	if len(datasets) != 2 || datasets[0].ID != "ds-1" || datasets[1].ID != "ds-2" {
+		t.Errorf("datasets = %+v, want ds-1, ds-2", datasets)
+	}
+
+	if _, err := c.ListDatasetsByCategory(context.Background(), " "); err == nil {
+		t.Error("ListDatasetsByCategory with a blank category succeeded")
+	}
+}
@@ -0,0 +1,27 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+// GetDownloadManifest returns the manifest of a dataset stored as several
+// parts, with a presigned DownloadURL on each part. Verify each downloaded
+// part with ManifestPart.Verify. Datasets stored as a single object have no
+// manifest; download those with DownloadDataset.
+//
+// GET /v1/datasets/:id/manifest
+func (c *Consumer) GetDownloadManifest(ctx context.Context, datasetID string) (*types.Manifest, error) {
+	path := fmt.Sprintf("/v1/datasets/%s/manifest", url.PathEscape(datasetID))
+
+	var manifest types.Manifest
+	if err := c.makeAPIRequest(ctx, http.MethodGet, path, nil, &manifest); err != nil {
+		return nil, err
+	}
+
+	return &manifest, nil
+}
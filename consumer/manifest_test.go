@@ -0,0 +1,46 @@
+package consumer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetDownloadManifest(t *testing.T) {
+	var gotPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.EscapedPath()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"manifest_version":1,"dataset_name":"sales","total_size_bytes":5,"parts":[
+			{"s3_key":"datasets/sales/part-0000.ndjson.gz","size_bytes":5,
+			 "sha256":"2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+			 "download_url":"https://example.invalid/part-0000"}]}`))
+	}))
+	defer server.Close()
+
+	manifest, err := newTestConsumer(server.URL).GetDownloadManifest(context.Background(), "ds 1")
+	if err != nil {
+		t.Fatalf("GetDownloadManifest: %v", err)
+	}
+	if gotPath != "/v1/datasets/ds%201/manifest" {
+		t.Errorf("path = %q, want /v1/datasets/ds%%201/manifest", gotPath)
+	}
+	if len(manifest.Parts) != 1 || manifest.Parts[0].DownloadURL != "https://example.invalid/part-0000" {
+		t.Fatalf("manifest = %+v, want one part with its download URL", manifest)
+	}
+	if err := manifest.Parts[0].Verify([]byte("hello")); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+}
+
+func TestGetDownloadManifest_NotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"dataset has no manifest"}`, http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	if _, err := newTestConsumer(server.URL).GetDownloadManifest(context.Background(), "ds-1"); err == nil {
+		t.Error("expected an error for a single-object dataset")
+	}
+}
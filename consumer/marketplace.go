@@ -66,9 +66,31 @@ func (c *Consumer) GetDatasetDetails(ctx context.Context, datasetID string) (*ty
 		return nil, err
 	}
 
+	if c.trackViews {
+		// Fire-and-forget, like the download outcome callback: the view
+		// count must never slow down or fail the detail fetch.
+		go func() {
+			viewCtx, cancel := context.WithTimeout(context.Background(), outcomeCallbackTimeout)
+			defer cancel()
+			if err := c.RecordDatasetView(viewCtx, datasetID); err != nil {
+				fmt.Printf("Warning: Failed to record view of dataset %s: %v\n", datasetID, err)
+			}
+		}()
+	}
+
 	return &resp, nil
 }
 
+// RecordDatasetView records that this consumer viewed a dataset, counted in
+// the dataset's view_count stat.
+//
+// POST /v1/datasets/:id/views. Called automatically (in the background) by
+// GetDatasetDetails when Config.TrackViews is set.
+func (c *Consumer) RecordDatasetView(ctx context.Context, datasetID string) error {
+	path := fmt.Sprintf("/v1/datasets/%s/views", url.PathEscape(datasetID))
+	return c.makeAPIRequest(ctx, http.MethodPost, path, nil, nil)
+}
+
 // CreateSubscriptionCheckout creates a Stripe Checkout session to subscribe to a
 // PAID marketplace dataset and returns the hosted Checkout URL.
 //
@@ -0,0 +1,166 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+// EventType is a Notification's event_type.
+type EventType = string
+
+// Event types the SDK understands. A notification with any other event type
+// is still delivered as is, so switches on Notification.EventType should
+// keep a default case; KnownEventType reports whether it is one of these.
+const (
+	// EventTypeDatasetUpdated: a new version of the dataset was uploaded.
+	// Download it again to pick up the change.
+	EventTypeDatasetUpdated EventType = "dataset_updated"
+
+	// EventTypeDatasetDeleted: the producer deleted the dataset. DatasetID
+	// names it, so local copies and cache entries can be purged; it can no
+	// longer be downloaded.
+	EventTypeDatasetDeleted EventType = "dataset_deleted"
+
+	// EventTypeSubscriptionRevoked: the subscription named by
+	// SubscriptionID was revoked, ending access to its datasets.
+	EventTypeSubscriptionRevoked EventType = "subscription_revoked"
+)
+
+// knownEventTypes lists the EventType constants.
+var knownEventTypes = []EventType{EventTypeDatasetUpdated, EventTypeDatasetDeleted, EventTypeSubscriptionRevoked}
+
+// KnownEventType reports whether the notification's event type is one of the
+// EventType constants.
+func (n *Notification) KnownEventType() bool {
+	return slices.Contains(knownEventTypes, n.EventType)
+}
+
+// IsDatasetUpdated reports whether the notification announces a new version
+// of dataset DatasetID.
+func (n *Notification) IsDatasetUpdated() bool {
+	return n.EventType == EventTypeDatasetUpdated
+}
+
+// IsDatasetDeleted reports whether the notification announces that dataset
+// DatasetID was deleted.
+func (n *Notification) IsDatasetDeleted() bool {
+	return n.EventType == EventTypeDatasetDeleted
+}
+
+// IsSubscriptionRevoked reports whether the notification announces that
+// subscription SubscriptionID was revoked.
+func (n *Notification) IsSubscriptionRevoked() bool {
+	return n.EventType == EventTypeSubscriptionRevoked
+}
+
+// ErrUnknownMessageFormat is returned by ParseNotification for a JSON body
+// that is neither an SNS envelope nor a raw notification payload.
+var ErrUnknownMessageFormat = errors.New("unknown message format")
+
+// ParseNotification decodes a dataset notification message body. It accepts
+// both formats the notification topic delivers: the SNS envelope
+// ({"Type": "Notification", "Message": "{...}"}) and the raw payload (raw
+// message delivery, or a direct publish), recognized by its event_type.
+//
+// PollNotifications uses it for every message; consumers receiving
+// notifications through their own endpoint or queue can call it directly.
+// MessageID and ReceiptHandle are left empty; RawMessage is messageBody, and
+// Attributes holds the SNS envelope's message attributes, if any.
+func ParseNotification(messageBody string) (*Notification, error) {
+	var parsedBody map[string]any
+	if err := json.Unmarshal([]byte(messageBody), &parsedBody); err != nil {
+		return nil, fmt.Errorf("failed to parse message body: %w", err)
+	}
+
+	var notification Notification
+	if snsMessage, hasSNSWrapper := parsedBody["Message"].(string); hasSNSWrapper {
+		if err := json.Unmarshal([]byte(snsMessage), &notification); err != nil {
+			return nil, fmt.Errorf("failed to parse notification payload from SNS wrapper: %w", err)
+		}
+	} else if _, hasEventType := parsedBody["event_type"]; hasEventType {
+		if err := json.Unmarshal([]byte(messageBody), &notification); err != nil {
+			return nil, fmt.Errorf("failed to parse raw notification payload: %w", err)
+		}
+	} else {
+		return nil, ErrUnknownMessageFormat
+	}
+
+	// The payload carries no transport fields; never trust them from it.
+	notification.MessageID = ""
+	notification.ReceiptHandle = ""
+	notification.RawMessage = messageBody
+	notification.Attributes = envelopeAttributes(parsedBody)
+
+	return &notification, nil
+}
+
+// envelopeAttributes returns the string-valued MessageAttributes of an SNS
+// envelope ({"MessageAttributes": {"name": {"Type": "String", "Value": "..."}}}).
+func envelopeAttributes(envelope map[string]any) map[string]string {
+	raw, _ := envelope["MessageAttributes"].(map[string]any)
+	if len(raw) == 0 {
+		return nil
+	}
+	attributes := make(map[string]string, len(raw))
+	for name, attr := range raw {
+		if fields, ok := attr.(map[string]any); ok {
+			if value, ok := fields["Value"].(string); ok {
+				attributes[name] = value
+			}
+		}
+	}
+	return attributes
+}
+
+// GetSubscriptionTopicARN returns the ARN of the SNS topic that delivers a
+// subscription's notifications, derived from its SNS subscription ARN
+// (the topic ARN plus a trailing ":<subscription-id>"). Advanced consumers
+// can subscribe their own HTTPS endpoint or queue to it instead of calling
+// PollNotifications; use NotificationFilterPolicy to receive only their
+// notifications.
+func (c *Consumer) GetSubscriptionTopicARN(ctx context.Context, subscriptionID string) (string, error) {
+	sub, err := c.GetSubscription(ctx, subscriptionID)
+	if err != nil {
+		return "", err
+	}
+
+	if sub.SNSSubscriptionARN == nil || *sub.SNSSubscriptionARN == "" {
+		return "", fmt.Errorf("subscription %s has no notification topic", subscriptionID)
+	}
+
+	arn := *sub.SNSSubscriptionARN
+	if !strings.HasPrefix(arn, "arn:") || strings.Count(arn, ":") < 6 {
+		return "", fmt.Errorf("subscription %s has malformed SNS subscription ARN %q", subscriptionID, arn)
+	}
+
+	return arn[:strings.LastIndex(arn, ":")], nil
+}
+
+// NotificationFilterPolicy returns the SNS filter policy (JSON) matching
+// sub's notifications: those addressed to its consumer and, for a
+// single-dataset subscription, that dataset. Attach it to your own
+// subscription on the topic from GetSubscriptionTopicARN.
+func NotificationFilterPolicy(sub *types.Subscription) (string, error) {
+	if sub == nil || sub.ConsumerID == "" {
+		return "", errors.New("subscription with a consumer ID is required")
+	}
+
+	policy := map[string][]string{
+		"subscriber_id": {sub.ConsumerID},
+	}
+	if sub.DatasetID != nil && *sub.DatasetID != "" {
+		policy["dataset_id"] = []string{*sub.DatasetID}
+	}
+
+	b, err := json.Marshal(policy)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
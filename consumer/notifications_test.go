@@ -0,0 +1,110 @@
+package consumer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+func TestParseNotificationSetsRawMessage(t *testing.T) {
+	body := `{"Type":"Notification","Message":"{\"event_type\":\"dataset_updated\",\"dataset_id\":\"ds-1\",\"receipt_handle\":\"spoofed\"}"}`
+
+	n, err := ParseNotification(body)
+	if err != nil {
+		t.Fatalf("ParseNotification: %v", err)
+	}
+	if n.DatasetID != "ds-1" || n.EventType != "dataset_updated" {
+		t.Errorf("notification = %+v", n)
+	}
+	if n.RawMessage != body {
+		t.Errorf("RawMessage = %q, want the message body", n.RawMessage)
+	}
+	if n.ReceiptHandle != "" {
+		t.Errorf("ReceiptHandle = %q, want empty (transport field, not taken from the payload)", n.ReceiptHandle)
+	}
+
+	if _, err := ParseNotification("not json"); err == nil {
+		t.Error("expected an error for a non-JSON body")
+	}
+}
+
+func TestNotificationEventTypes(t *testing.T) {
+	tests := []struct {
+		body    string
+		known   bool
+		deleted bool
+		revoked bool
+	}{
+		{`{"event_type":"dataset_updated","dataset_id":"ds-1"}`, true, false, false},
+		{`{"event_type":"dataset_deleted","dataset_id":"ds-1"}`, true, true, false},
+		{`{"event_type":"subscription_revoked","subscription_id":"sub-1"}`, true, false, true},
+		// Unknown event types are passed through, not rejected.
+		{`{"event_type":"dataset_archived","dataset_id":"ds-1"}`, false, false, false},
+	}
+	for _, tt := range tests {
+		n, err := ParseNotification(tt.body)
+		if err != nil {
+			t.Fatalf("ParseNotification(%s): %v", tt.body, err)
+		}
+		if n.KnownEventType() != tt.known || n.IsDatasetDeleted() != tt.deleted || n.IsSubscriptionRevoked() != tt.revoked {
+			t.Errorf("%s: known=%v deleted=%v revoked=%v", n.EventType, n.KnownEventType(), n.IsDatasetDeleted(), n.IsSubscriptionRevoked())
+		}
+		if n.IsDatasetUpdated() != (n.EventType == EventTypeDatasetUpdated) {
+			t.Errorf("%s: IsDatasetUpdated = %v", n.EventType, n.IsDatasetUpdated())
+		}
+	}
+}
+
+func TestGetSubscriptionTopicARN(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/subscriptions/sub-1":
+			_, _ = w.Write([]byte(`{"_id":"sub-1","sns_subscription_arn":"arn:aws:sns:us-east-1:123456789012:dataset-updates:0f1e2d3c"}`))
+		case "/v1/subscriptions/sub-legacy":
+			_, _ = w.Write([]byte(`{"_id":"sub-legacy"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	c := newTestConsumer(server.URL)
+
+	arn, err := c.GetSubscriptionTopicARN(context.Background(), "sub-1")
+	if err != nil {
+		t.Fatalf("GetSubscriptionTopicARN: %v", err)
+	}
+	if arn != "arn:aws:sns:us-east-1:123456789012:dataset-updates" {
+		t.Errorf("topic ARN = %q", arn)
+	}
+
+	if _, err := c.GetSubscriptionTopicARN(context.Background(), "sub-legacy"); err == nil || !strings.Contains(err.Error(), "no notification topic") {
+		t.Errorf("err = %v, want no notification topic", err)
+	}
+}
+
+func TestNotificationFilterPolicy(t *testing.T) {
+	datasetID := "ds-1"
+
+	policy, err := NotificationFilterPolicy(&types.Subscription{ConsumerID: "company-9", DatasetID: &datasetID})
+	if err != nil {
+		t.Fatalf("NotificationFilterPolicy: %v", err)
+	}
+	if policy != `{"dataset_id":["ds-1"],"subscriber_id":["company-9"]}` {
+		t.Errorf("policy = %s", policy)
+	}
+
+	policy, _ = NotificationFilterPolicy(&types.Subscription{ConsumerID: "company-9"})
+	if policy != `{"subscriber_id":["company-9"]}` {
+		t.Errorf("all-datasets policy = %s", policy)
+	}
+
+	if _, err := NotificationFilterPolicy(&types.Subscription{}); err == nil {
+		t.Error("expected an error without a consumer ID")
+	}
+}
@@ -0,0 +1,247 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+// fakeSQS serves the SQS JSON protocol (routed by X-Amz-Target) from a fixed
+// set of messages and records the calls made against them.
+type fakeSQS struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	messages   []map[string]any // next ReceiveMessage result for the main queue
+	deadLetter []map[string]any // ReceiveMessage result for the dead-letter queue
+	calls      []string         // "<Action> <ReceiptHandle or queue>" per call
+}
+
+func newFakeSQS(t *testing.T, messages ...map[string]any) *fakeSQS {
+	t.Helper()
+
+	f := &fakeSQS{messages: messages}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		action := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "AmazonSQS.")
+		var in map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&in)
+
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+		switch action {
+		case "ReceiveMessage":
+			// Main-queue messages are delivered once; an empty receive
+			// briefly stands in for a long poll.
+			messages := f.messages
+			if strings.HasSuffix(in["QueueUrl"].(string), "/dlq") {
+				messages = f.deadLetter
+			} else {
+				f.messages = nil
+			}
+			if len(messages) == 0 {
+				time.Sleep(10 * time.Millisecond)
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"Messages": messages})
+		case "DeleteMessage", "ChangeMessageVisibility":
+			f.calls = append(f.calls, action+" "+in["ReceiptHandle"].(string))
+			_, _ = w.Write([]byte(`{}`))
+		case "DeleteMessageBatch":
+			var handles []string
+			var successful []map[string]any
+			for _, entry := range in["Entries"].([]any) {
+				entry := entry.(map[string]any)
+				handles = append(handles, entry["ReceiptHandle"].(string))
+				successful = append(successful, map[string]any{"Id": entry["Id"]})
+			}
+			f.calls = append(f.calls, action+" "+strings.Join(handles, ","))
+			_ = json.NewEncoder(w).Encode(map[string]any{"Successful": successful})
+		case "SendMessage":
+			f.calls = append(f.calls, action+" "+in["QueueUrl"].(string)[len(f.URL):]+" "+in["MessageBody"].(string))
+			_, _ = w.Write([]byte(`{"MessageId":"new"}`))
+		case "GetQueueAttributes":
+			_, _ = w.Write([]byte(`{"Attributes":{"RedrivePolicy":"{\"deadLetterTargetArn\":\"arn:aws:sqs:us-east-1:123456789012:test-dlq\",\"maxReceiveCount\":5}"}}`))
+		case "GetQueueUrl":
+			if in["QueueName"] != "test-dlq" || in["QueueOwnerAWSAccountId"] != "123456789012" {
+				t.Errorf("GetQueueUrl input = %v", in)
+			}
+			_ = json.NewEncoder(w).Encode(map[string]string{"QueueUrl": f.URL + "/queue/dlq"})
+		default:
+			t.Errorf("unexpected SQS action %q", action)
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(f.Close)
+
+	return f
+}
+
+func (f *fakeSQS) takeCalls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.calls...)
+}
+
+// consumer returns a Consumer whose SQS client talks to f, with the queue
+// URL already resolved so no subscription lookup is needed.
+func (f *fakeSQS) consumer() *Consumer {
+	c := newTestConsumer("http://unused")
+	c.queueURL = aws.String(f.URL + "/queue/test")
+	c.sqsClient = sqs.NewFromConfig(c.awsConfig, func(o *sqs.Options) {
+		o.BaseEndpoint = aws.String(f.URL)
+	})
+	return c
+}
+
+func sqsMessage(id, eventType string) map[string]any {
+	body, _ := json.Marshal(map[string]any{"event_type": eventType, "dataset_id": "ds-" + id})
+	return map[string]any{"MessageId": id, "ReceiptHandle": "rh-" + id, "Body": string(body)}
+}
+
+func TestPollNotificationsEventTypes(t *testing.T) {
+	messages := []map[string]any{
+		sqsMessage("1", "dataset_updated"),
+		sqsMessage("2", "dataset_deleted"),
+	}
+
+	t.Run("filtered messages are acknowledged even without auto-ack", func(t *testing.T) {
+		f := newFakeSQS(t, messages...)
+		manual := false
+
+		got, err := f.consumer().PollNotifications(context.Background(), PollNotificationsOptions{
+			AutoAcknowledge: &manual,
+			EventTypes:      []string{"dataset_updated"},
+		})
+		if err != nil {
+			t.Fatalf("PollNotifications: %v", err)
+		}
+		if len(got) != 1 || got[0].DatasetID != "ds-1" || got[0].ReceiptHandle != "rh-1" {
+			t.Fatalf("notifications = %+v, want only ds-1", got)
+		}
+		if calls := f.takeCalls(); len(calls) != 1 || calls[0] != "DeleteMessage rh-2" {
+			t.Errorf("SQS calls = %v, want only the filtered message deleted", calls)
+		}
+	})
+
+	t.Run("RequeueFilteredMessages releases instead of deleting", func(t *testing.T) {
+		f := newFakeSQS(t, messages...)
+
+		got, err := f.consumer().PollNotifications(context.Background(), PollNotificationsOptions{
+			EventTypes:              []string{"dataset_updated"},
+			RequeueFilteredMessages: true,
+		})
+		if err != nil {
+			t.Fatalf("PollNotifications: %v", err)
+		}
+		if len(got) != 1 {
+			t.Fatalf("notifications = %+v, want one", got)
+		}
+		calls := f.takeCalls()
+		want := []string{"ChangeMessageVisibility rh-2", "DeleteMessageBatch rh-1"}
+		if strings.Join(calls, ",") != strings.Join(want, ",") {
+			t.Errorf("SQS calls = %v, want %v", calls, want)
+		}
+	})
+
+	t.Run("no filter returns every message", func(t *testing.T) {
+		f := newFakeSQS(t, messages...)
+
+		got, err := f.consumer().PollNotifications(context.Background(), PollNotificationsOptions{})
+		if err != nil {
+			t.Fatalf("PollNotifications: %v", err)
+		}
+		if len(got) != 2 {
+			t.Errorf("notifications = %+v, want both", got)
+		}
+	})
+}
+
+func TestPollNotificationsAttributeFilters(t *testing.T) {
+	fromQueue := sqsMessage("1", "dataset_updated")
+	fromQueue["MessageAttributes"] = map[string]any{
+		"producer_id": map[string]any{"DataType": "String", "StringValue": "company-1"},
+	}
+	// Attribute says another producer; the body is not even valid JSON, so
+	// it can only be dropped without parsing.
+	otherProducer := map[string]any{
+		"MessageId": "2", "ReceiptHandle": "rh-2", "Body": "not parsed",
+		"MessageAttributes": map[string]any{
+			"producer_id": map[string]any{"DataType": "String", "StringValue": "company-2"},
+		},
+	}
+	// SNS envelope: attributes only inside the body.
+	envelope, _ := json.Marshal(map[string]any{
+		"Type":              "Notification",
+		"Message":           `{"event_type":"dataset_updated","dataset_id":"ds-3"}`,
+		"MessageAttributes": map[string]any{"producer_id": map[string]any{"Type": "String", "Value": "company-1"}},
+	})
+	enveloped := map[string]any{"MessageId": "3", "ReceiptHandle": "rh-3", "Body": string(envelope)}
+	noAttributes := sqsMessage("4", "dataset_updated")
+
+	f := newFakeSQS(t, fromQueue, otherProducer, enveloped, noAttributes)
+	manual := false
+	got, err := f.consumer().PollNotifications(context.Background(), PollNotificationsOptions{
+		AutoAcknowledge:  &manual,
+		AttributeFilters: map[string][]string{"producer_id": {"company-1"}},
+	})
+	if err != nil {
+		t.Fatalf("PollNotifications: %v", err)
+	}
+
+	if len(got) != 2 || got[0].DatasetID != "ds-1" || got[1].DatasetID != "ds-3" {
+		t.Fatalf("notifications = %+v, want ds-1 and ds-3", got)
+	}
+	if got[0].Attributes["producer_id"] != "company-1" || got[1].Attributes["producer_id"] != "company-1" {
+		t.Errorf("Attributes = %v, %v, want producer_id on both", got[0].Attributes, got[1].Attributes)
+	}
+	if calls := f.takeCalls(); strings.Join(calls, ",") != "DeleteMessage rh-2,DeleteMessage rh-4" {
+		t.Errorf("SQS calls = %v, want the two filtered messages acknowledged", calls)
+	}
+}
+
+func TestPollNotificationsResultParseFailures(t *testing.T) {
+	malformed := map[string]any{"MessageId": "2", "ReceiptHandle": "rh-2", "Body": "not json"}
+
+	t.Run("left on the queue by default", func(t *testing.T) {
+		f := newFakeSQS(t, sqsMessage("1", "dataset_updated"), malformed)
+
+		result, err := f.consumer().PollNotificationsResult(context.Background(), PollNotificationsOptions{})
+		if err != nil {
+			t.Fatalf("PollNotificationsResult: %v", err)
+		}
+		if len(result.Notifications) != 1 || result.Notifications[0].DatasetID != "ds-1" {
+			t.Errorf("Notifications = %+v, want ds-1", result.Notifications)
+		}
+		if len(result.ParseFailures) != 1 {
+			t.Fatalf("ParseFailures = %+v, want one", result.ParseFailures)
+		}
+		failure := result.ParseFailures[0]
+		if failure.MessageID != "2" || failure.ReceiptHandle != "rh-2" || failure.Body != "not json" || failure.Err == nil {
+			t.Errorf("ParseFailure = %+v", failure)
+		}
+		if calls := f.takeCalls(); strings.Join(calls, ",") != "DeleteMessageBatch rh-1" {
+			t.Errorf("SQS calls = %v, want only the parsed message acknowledged", calls)
+		}
+	})
+
+	t.Run("AcknowledgeParseFailures deletes them", func(t *testing.T) {
+		f := newFakeSQS(t, sqsMessage("1", "dataset_updated"), malformed)
+
+		result, err := f.consumer().PollNotificationsResult(context.Background(), PollNotificationsOptions{AcknowledgeParseFailures: true})
+		if err != nil || len(result.ParseFailures) != 1 {
+			t.Fatalf("PollNotificationsResult = %+v, %v", result, err)
+		}
+		if calls := f.takeCalls(); strings.Join(calls, ",") != "DeleteMessageBatch rh-1,rh-2" {
+			t.Errorf("SQS calls = %v, want both messages deleted in one batch", calls)
+		}
+	})
+}
@@ -0,0 +1,45 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+// GetProducerProfile fetches the public profile of the company publishing a
+// dataset (GET /v1/companies/{id}).
+//
+// Only public fields are returned: ID, CompanyName, CustomerType, Status,
+// Tier, and CreatedAt. Everything else the API may include (contact and
+// billing details, users, settings, infrastructure such as KMSKeyID or
+// S3Bucket) is dropped, since it belongs to another customer.
+func (c *Consumer) GetProducerProfile(ctx context.Context, producerID string) (*types.Company, error) {
+	if producerID == "" {
+		return nil, fmt.Errorf("producer ID is required")
+	}
+
+	path := fmt.Sprintf("/v1/companies/%s", url.PathEscape(producerID))
+
+	var company types.Company
+	if err := c.makeAPIRequest(ctx, http.MethodGet, path, nil, &company); err != nil {
+		return nil, err
+	}
+
+	return publicCompanyProfile(company), nil
+}
+
+// publicCompanyProfile copies the allowlisted public fields of company, so
+// fields added to types.Company later stay hidden until listed here.
+func publicCompanyProfile(company types.Company) *types.Company {
+	return &types.Company{
+		ID:           company.ID,
+		CompanyName:  company.CompanyName,
+		CustomerType: company.CustomerType,
+		Status:       company.Status,
+		Tier:         company.Tier,
+		CreatedAt:    company.CreatedAt,
+	}
+}
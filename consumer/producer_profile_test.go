@@ -0,0 +1,55 @@
+package consumer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetProducerProfile(t *testing.T) {
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.EscapedPath()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"_id": "company-1/x",
+			"company_name": "Acme Data",
+			"business_email": "ops@acme.example",
+			"customer_type": "producer",
+			"status": "active",
+			"tier": "free",
+			"s3_bucket": "acme-bucket",
+			"kms_key_id": "key-123",
+			"sns_topic_arn": "topic",
+			"infrastructure": {"iam_user_arn": "user", "kms_key_id": "key-123"},
+			"settings": {"webhook_url": "https://acme.example/hook"},
+			"users": [{"_id": "u1", "email": "a@acme.example"}],
+			"created_at": "2026-01-01T00:00:00Z"
+		}`))
+	}))
+	defer server.Close()
+
+	company, err := newTestConsumer(server.URL).GetProducerProfile(context.Background(), "company-1/x")
+	if err != nil {
+		t.Fatalf("GetProducerProfile: %v", err)
+	}
+
+	if path != "/v1/companies/company-1%2Fx" {
+		t.Errorf("path = %q, want the escaped producer ID", path)
+	}
+	if company.ID != "company-1/x" || company.CompanyName != "Acme Data" || company.Status != "active" ||
+		company.CustomerType != "producer" || company.Tier != "free" || company.CreatedAt == "" {
+		t.Errorf("public fields not kept: %+v", company)
+	}
+	if company.KMSKeyID != "" || company.S3Bucket != "" || company.SNSTopicARN != "" || company.Infrastructure != nil ||
+		company.Settings != nil || company.Users != nil || company.BusinessEmail != "" {
+		t.Errorf("private fields leaked: %+v", company)
+	}
+}
+
+func TestGetProducerProfileRequiresID(t *testing.T) {
+	if _, err := newTestConsumer("http://unused").GetProducerProfile(context.Background(), ""); err == nil {
+		t.Fatal("expected an error for an empty producer ID")
+	}
+}
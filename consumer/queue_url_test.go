@@ -0,0 +1,84 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+// TestPollNotificationsConcurrent polls from several goroutines on a fresh
+// Consumer; run with -race. The queue URL must be looked up exactly once.
+func TestPollNotificationsConcurrent(t *testing.T) {
+	f := newFakeSQS(t)
+
+	var lookups atomic.Int32
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/subscriptions" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		lookups.Add(1)
+		time.Sleep(20 * time.Millisecond) // widen the window for a racing second lookup
+		_, _ = w.Write([]byte(`{"subscriptions":[{"consumer_id":"test-customer","sqs_queue_url":"` + f.URL + `/queue/test"}],"count":1}`))
+	}))
+	t.Cleanup(api.Close)
+
+	c := newTestConsumer(api.URL)
+	c.sqsClient = sqs.NewFromConfig(c.awsConfig, func(o *sqs.Options) {
+		o.BaseEndpoint = aws.String(f.URL)
+	})
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.PollNotifications(context.Background(), PollNotificationsOptions{WaitTimeSeconds: 1}); err != nil {
+				t.Errorf("PollNotifications: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := lookups.Load(); n != 1 {
+		t.Errorf("subscription lookups = %d, want 1", n)
+	}
+	if got := aws.ToString(c.cachedQueueURL()); got != f.URL+"/queue/test" {
+		t.Errorf("cached queue URL = %q", got)
+	}
+}
+
+func TestPollNotificationsQueueErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		subscriptions string
+		want          error
+	}{
+		{"no subscriptions", `[]`, ErrNoActiveSubscriptions},
+		{"only as producer", `[{"consumer_id":"someone-else","sqs_queue_url":"https://queue"}]`, ErrNoActiveSubscriptions},
+		{"queue still provisioning", `[{"consumer_id":"test-customer"}]`, ErrQueueNotProvisioned},
+		{"legacy subscription", `[{"consumer_id":"test-customer","sns_subscription_arn":"arn:aws:sns:us-east-1:1:topic:sub"}]`, ErrLegacySubscription},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(`{"subscriptions":` + tt.subscriptions + `}`))
+			}))
+			t.Cleanup(api.Close)
+
+			_, err := newTestConsumer(api.URL).PollNotifications(context.Background(), PollNotificationsOptions{})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("PollNotifications = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package consumer
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/helix-tools/sdk-go/v2/helixtest"
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+func TestListSubscriptionsFiltered(t *testing.T) {
+	api := helixtest.NewMockAPI()
+	api.Handle(http.MethodGet, "/v1/subscriptions", http.StatusOK, map[string]any{
+		"subscriptions": []Subscription{{ID: "sub-1"}},
+	})
+	c := NewConsumerWithAPI(types.Config{CustomerID: "consumer-1"}, api)
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		filter SubscriptionFilter
+		path   string
+	}{
+		{SubscriptionFilter{}, "/v1/subscriptions"},
+		{SubscriptionFilter{Status: "active"}, "/v1/subscriptions?status=active"},
+		{
+			SubscriptionFilter{Status: "active", ProducerID: "company 1", DatasetID: "ds-1"},
+			"/v1/subscriptions?dataset_id=ds-1&producer_id=company+1&status=active",
+		},
+	} {
+		subs, err := c.ListSubscriptionsFiltered(ctx, tc.filter)
+		if err != nil || len(subs) != 1 || subs[0].ID != "sub-1" {
+			t.Errorf("ListSubscriptionsFiltered(%+v) = %v, %v", tc.filter, subs, err)
+		}
+		calls := api.Calls()
+		if got := calls[len(calls)-1].Path; got != tc.path {
+			t.Errorf("filter %+v requested %s, want %s", tc.filter, got, tc.path)
+		}
+	}
+}
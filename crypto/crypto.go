@@ -0,0 +1,114 @@
+// Package crypto seals and opens the SDK's envelope-encrypted payloads:
+// data encrypted under a fresh data key, which is itself encrypted with a
+// KMS key and stored alongside it. The producer seals what it uploads and
+// the consumer opens what it downloads, both through this package, so the
+// wire format has a single implementation.
+package crypto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/helix-tools/sdk-go/v2/internal/envelope"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+)
+
+// KMSEncrypter is the KMS call Seal makes. *kms.Client satisfies it.
+type KMSEncrypter interface {
+	Encrypt(ctx context.Context, params *kms.EncryptInput, optFns ...func(*kms.Options)) (*kms.EncryptOutput, error)
+}
+
+// KMSDecrypter is the KMS call Open makes. *kms.Client satisfies it.
+type KMSDecrypter interface {
+	Decrypt(ctx context.Context, params *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error)
+}
+
+// KMSReEncrypter is the KMS call Rewrap makes. *kms.Client satisfies it.
+type KMSReEncrypter interface {
+	ReEncrypt(ctx context.Context, params *kms.ReEncryptInput, optFns ...func(*kms.Options)) (*kms.ReEncryptOutput, error)
+}
+
+// SealOptions configures Seal. The zero value is ready to use.
+type SealOptions struct {
+	// Rand is the source of the data key and IV; nil means crypto/rand.
+	// Set it only to make test output reproducible.
+	Rand io.Reader
+}
+
+// Seal encrypts plaintext under a fresh data key and encrypts that key with
+// keyID through kmsClient, returning the payload Open reads.
+func Seal(ctx context.Context, kmsClient KMSEncrypter, keyID string, plaintext []byte, opts SealOptions) ([]byte, error) {
+	if keyID == "" {
+		return nil, errors.New("KMS key not configured, cannot encrypt data")
+	}
+
+	return envelope.Seal(ctx, opts.Rand, plaintext, func(ctx context.Context, dataKey []byte) ([]byte, error) {
+		out, err := kmsClient.Encrypt(ctx, &kms.EncryptInput{
+			KeyId:     aws.String(keyID),
+			Plaintext: dataKey,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("KMS encryption failed: %w", err)
+		}
+		return out.CiphertextBlob, nil
+	})
+}
+
+// OpenOptions configures Open. The zero value is ready to use.
+type OpenOptions struct {
+	// KeyID, when set, names the KMS key the data key must be decrypted
+	// with; otherwise KMS infers it from the ciphertext.
+	KeyID string
+}
+
+// Open decrypts a payload made by Seal, decrypting its data key through
+// kmsClient. A truncated payload fails with an error wrapping
+// io.ErrUnexpectedEOF; a tampered one fails authentication.
+func Open(ctx context.Context, kmsClient KMSDecrypter, ciphertext []byte, opts OpenOptions) ([]byte, error) {
+	return envelope.Open(ctx, ciphertext, func(ctx context.Context, wrappedKey []byte) ([]byte, error) {
+		in := &kms.DecryptInput{CiphertextBlob: wrappedKey}
+		if opts.KeyID != "" {
+			in.KeyId = aws.String(opts.KeyID)
+		}
+		out, err := kmsClient.Decrypt(ctx, in)
+		if err != nil {
+			return nil, fmt.Errorf("KMS decrypt failed: %w", err)
+		}
+		return out.Plaintext, nil
+	})
+}
+
+// Rewrap moves a payload made by Seal to newKeyID: KMS re-encrypts its data
+// key server side, so neither the data key nor the data is ever decrypted
+// here, and the bulk ciphertext is copied unchanged.
+func Rewrap(ctx context.Context, kmsClient KMSReEncrypter, ciphertext []byte, newKeyID string) ([]byte, error) {
+	if newKeyID == "" {
+		return nil, errors.New("new KMS key not configured, cannot re-encrypt data")
+	}
+
+	return envelope.Rewrap(ctx, ciphertext, func(ctx context.Context, wrappedKey []byte) ([]byte, error) {
+		out, err := kmsClient.ReEncrypt(ctx, &kms.ReEncryptInput{
+			CiphertextBlob:   wrappedKey,
+			DestinationKeyId: aws.String(newKeyID),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("KMS re-encrypt failed: %w", err)
+		}
+		return out.CiphertextBlob, nil
+	})
+}
+
+// KeyRegion returns the region of the KMS key keyID names: the region of a
+// key or alias ARN, else fallback, since a bare key ID or alias name is
+// resolved in the caller's own region.
+func KeyRegion(keyID, fallback string) string {
+	if parsed, err := arn.Parse(keyID); err == nil && parsed.Service == "kms" && parsed.Region != "" {
+		return parsed.Region
+	}
+	return fallback
+}
@@ -0,0 +1,123 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/helix-tools/sdk-go/v2/internal/awsfake"
+)
+
+func TestSealOpen(t *testing.T) {
+	ctx := context.Background()
+	fakeKMS := awsfake.NewKMS()
+	plaintext := bytes.Repeat([]byte(`{"id":1}`+"\n"), 1000)
+
+	sealed, err := Seal(ctx, fakeKMS, "key-1", plaintext, SealOptions{})
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	opened, err := Open(ctx, fakeKMS, sealed, OpenOptions{})
+	if err != nil || !bytes.Equal(opened, plaintext) {
+		t.Fatalf("Open = %d bytes, %v; want the plaintext", len(opened), err)
+	}
+
+	if _, err := Open(ctx, awsfake.NewKMS(), sealed, OpenOptions{}); err == nil || !strings.Contains(err.Error(), "KMS decrypt failed") {
+		t.Errorf("Open with another KMS err = %v, want a KMS decrypt failure", err)
+	}
+	if _, err := Open(ctx, fakeKMS, sealed[:len(sealed)/2], OpenOptions{}); err == nil {
+		t.Error("Open accepted a truncated payload")
+	}
+	if opened, err := Open(ctx, fakeKMS, sealed, OpenOptions{KeyID: "key-1"}); err != nil || !bytes.Equal(opened, plaintext) {
+		t.Errorf("Open with the right KeyID = %d bytes, %v", len(opened), err)
+	}
+	if _, err := Open(ctx, fakeKMS, sealed, OpenOptions{KeyID: "key-2"}); err == nil {
+		t.Error("Open with the wrong KeyID should fail")
+	}
+	if _, err := Seal(ctx, fakeKMS, "", plaintext, SealOptions{}); err == nil {
+		t.Error("Seal without a key ID should fail")
+	}
+}
+
+// zeroReader makes Seal's data key and IV deterministic.
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+func TestSealOptionsRand(t *testing.T) {
+	ctx := context.Background()
+	fakeKMS := awsfake.NewKMS()
+	opts := SealOptions{Rand: zeroReader{}}
+
+	a, err := Seal(ctx, fakeKMS, "key-1", []byte("same"), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Seal(ctx, fakeKMS, "key-1", []byte("same"), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only the wrapped key (a fresh KMS handle each call) may differ; the
+	// trailing IV, tag and 4-byte ciphertext must repeat.
+	if tail := 16 + 16 + 4; !bytes.Equal(a[len(a)-tail:], b[len(b)-tail:]) {
+		t.Error("IV, tag and ciphertext should repeat with a fixed Rand")
+	}
+
+	short := SealOptions{Rand: io.LimitReader(zeroReader{}, 8)}
+	if _, err := Seal(ctx, fakeKMS, "key-1", []byte("x"), short); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("short Rand err = %v, want io.ErrUnexpectedEOF", err)
+	}
+}
+
+func TestKeyRegion(t *testing.T) {
+	for keyID, want := range map[string]string{
+		"arn:aws:kms:eu-west-1:111122223333:key/mrk-1234abcd": "eu-west-1",
+		"arn:aws:kms:ap-south-1:111122223333:alias/datasets":  "ap-south-1",
+		"1234abcd-12ab-34cd-56ef-1234567890ab":                "us-east-1",
+		"alias/datasets":                                      "us-east-1",
+		"arn:aws:s3:::bucket":                                 "us-east-1",
+		"":                                                    "us-east-1",
+	} {
+		if got := KeyRegion(keyID, "us-east-1"); got != want {
+			t.Errorf("KeyRegion(%q) = %q, want %q", keyID, got, want)
+		}
+	}
+}
+
+func TestRewrap(t *testing.T) {
+	ctx := context.Background()
+	fakeKMS := awsfake.NewKMS()
+	plaintext := []byte(`{"id":1}` + "\n")
+
+	sealed, err := Seal(ctx, fakeKMS, "old-key", plaintext, SealOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rewrapped, err := Rewrap(ctx, fakeKMS, sealed, "new-key")
+	if err != nil {
+		t.Fatalf("Rewrap: %v", err)
+	}
+	if _, decrypts := fakeKMS.Calls(); decrypts != 0 || fakeKMS.ReEncrypts() != 1 {
+		t.Errorf("decrypts = %d, re-encrypts = %d; want 0, 1", decrypts, fakeKMS.ReEncrypts())
+	}
+
+	if opened, err := Open(ctx, fakeKMS, rewrapped, OpenOptions{KeyID: "new-key"}); err != nil || !bytes.Equal(opened, plaintext) {
+		t.Errorf("Open under the new key = %q, %v", opened, err)
+	}
+	if _, err := Open(ctx, fakeKMS, rewrapped, OpenOptions{KeyID: "old-key"}); err == nil {
+		t.Error("Open under the old key should fail")
+	}
+
+	if _, err := Rewrap(ctx, awsfake.NewKMS(), sealed, "new-key"); err == nil || !strings.Contains(err.Error(), "KMS re-encrypt failed") {
+		t.Errorf("Rewrap with another KMS err = %v, want a KMS re-encrypt failure", err)
+	}
+	if _, err := Rewrap(ctx, fakeKMS, sealed, ""); err == nil {
+		t.Error("Rewrap without a key ID should fail")
+	}
+}
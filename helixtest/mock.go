@@ -0,0 +1,124 @@
+// Package helixtest provides an in-memory Helix API for unit tests of code
+// built on the producer and consumer packages.
+//
+//	api := helixtest.NewMockAPI()
+//	api.Handle(http.MethodGet, "/v1/datasets/ds-1", http.StatusOK, types.Dataset{ID: "ds-1"})
+//	p := producer.NewProducerWithAPI(types.Config{CustomerID: "company-1"}, api)
+//	dataset, err := p.GetDataset(ctx, "ds-1")
+package helixtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+// Call is one request made through a MockAPI.
+type Call struct {
+	Method string
+	Path   string
+	Body   json.RawMessage // JSON request body; nil when there was none
+}
+
+// MockAPI is a types.APIDoer that answers from registered responses and
+// records every call. It is safe for concurrent use.
+type MockAPI struct {
+	mu        sync.Mutex
+	responses map[string]response
+	calls     []Call
+}
+
+type response struct {
+	status int
+	body   any
+}
+
+// NewMockAPI returns a MockAPI with no responses registered.
+func NewMockAPI() *MockAPI {
+	return &MockAPI{responses: make(map[string]response)}
+}
+
+// Handle registers the response to method and path. path is matched with
+// its query string first, then without, so "/v1/datasets" answers any
+// listing while "/v1/datasets?page=2" answers only that page. A 2xx status
+// returns body JSON-encoded to the caller; any other status fails the call
+// with a *types.StatusError whose Body is body as JSON (or as is, for a
+// string). A later Handle for the same method and path replaces the earlier
+// one.
+func (m *MockAPI) Handle(method, path string, status int, body any) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.responses[method+" "+path] = response{status: status, body: body}
+}
+
+// Calls returns the calls made so far, oldest first.
+func (m *MockAPI) Calls() []Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Call(nil), m.calls...)
+}
+
+// Do implements types.APIDoer. A request with no registered response fails
+// with an error naming it.
+func (m *MockAPI) Do(ctx context.Context, method, path string, body, result any) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	call := Call{Method: method, Path: path}
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request body: %w", err)
+		}
+		call.Body = data
+	}
+
+	m.mu.Lock()
+	m.calls = append(m.calls, call)
+	resp, ok := m.responses[method+" "+path]
+	if !ok {
+		base, _, _ := strings.Cut(path, "?")
+		resp, ok = m.responses[method+" "+base]
+	}
+	m.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("helixtest: no response registered for %s %s", method, path)
+	}
+
+	if resp.status < 200 || resp.status >= 300 {
+		return &types.StatusError{StatusCode: resp.status, Body: errorBody(resp.body)}
+	}
+
+	if result == nil || resp.body == nil {
+		return nil
+	}
+	// Round-trip through JSON, as the real client decodes the response.
+	data, err := json.Marshal(resp.body)
+	if err != nil {
+		return fmt.Errorf("helixtest: failed to encode response for %s %s: %w", method, path, err)
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+func errorBody(body any) string {
+	switch b := body.(type) {
+	case nil:
+		return ""
+	case string:
+		return b
+	default:
+		data, _ := json.Marshal(b)
+		return string(data)
+	}
+}
+
+var _ types.APIDoer = (*MockAPI)(nil)
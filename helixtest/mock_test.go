@@ -0,0 +1,56 @@
+package helixtest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+func TestMockAPI(t *testing.T) {
+	api := NewMockAPI()
+	api.Handle(http.MethodGet, "/v1/datasets/ds-1", http.StatusOK, types.Dataset{ID: "ds-1", Name: "Weather"})
+	api.Handle(http.MethodGet, "/v1/datasets", http.StatusOK, map[string]any{"count": 0})
+	api.Handle(http.MethodGet, "/v1/datasets?page=2", http.StatusOK, map[string]any{"count": 2})
+	api.Handle(http.MethodDelete, "/v1/datasets/ds-1", http.StatusConflict, "in use")
+
+	ctx := context.Background()
+
+	var dataset types.Dataset
+	if err := api.Do(ctx, http.MethodGet, "/v1/datasets/ds-1", nil, &dataset); err != nil || dataset.Name != "Weather" {
+		t.Errorf("dataset = %+v, %v", dataset, err)
+	}
+
+	var list types.DatasetListResponse
+	if err := api.Do(ctx, http.MethodGet, "/v1/datasets?page=1", nil, &list); err != nil || list.Count != 0 {
+		t.Errorf("page 1 = %+v, %v; want the query-less response", list, err)
+	}
+	if err := api.Do(ctx, http.MethodGet, "/v1/datasets?page=2", nil, &list); err != nil || list.Count != 2 {
+		t.Errorf("page 2 = %+v, %v; want the exact-match response", list, err)
+	}
+
+	var statusErr *types.StatusError
+	err := api.Do(ctx, http.MethodDelete, "/v1/datasets/ds-1", nil, nil)
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusConflict || statusErr.Body != "in use" {
+		t.Errorf("err = %v, want StatusError 409 in use", err)
+	}
+
+	if err := api.Do(ctx, http.MethodPost, "/v1/datasets", map[string]string{"name": "x"}, nil); err == nil ||
+		!strings.Contains(err.Error(), "no response registered for POST /v1/datasets") {
+		t.Errorf("unregistered err = %v", err)
+	}
+
+	calls := api.Calls()
+	if len(calls) != 5 {
+		t.Fatalf("calls = %d, want 5", len(calls))
+	}
+	if last := calls[4]; last.Method != http.MethodPost || string(last.Body) != `{"name":"x"}` {
+		t.Errorf("last call = %+v", last)
+	}
+	if calls[0].Body != nil {
+		t.Errorf("GET body = %s, want nil", calls[0].Body)
+	}
+}
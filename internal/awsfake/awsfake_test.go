@@ -0,0 +1,221 @@
+package awsfake
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	kmstypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+func TestKMS(t *testing.T) {
+	ctx := context.Background()
+	k := NewKMS()
+
+	enc, err := k.Encrypt(ctx, &kms.EncryptInput{KeyId: aws.String("key-1"), Plaintext: []byte("secret")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec, err := k.Decrypt(ctx, &kms.DecryptInput{CiphertextBlob: enc.CiphertextBlob})
+	if err != nil || string(dec.Plaintext) != "secret" || aws.ToString(dec.KeyId) != "key-1" {
+		t.Errorf("Decrypt = %+v, %v", dec, err)
+	}
+
+	var invalid *kmstypes.InvalidCiphertextException
+	if _, err := NewKMS().Decrypt(ctx, &kms.DecryptInput{CiphertextBlob: enc.CiphertextBlob}); !errors.As(err, &invalid) {
+		t.Errorf("foreign ciphertext err = %v, want InvalidCiphertextException", err)
+	}
+}
+
+func TestS3(t *testing.T) {
+	ctx := context.Background()
+	f := NewS3()
+	in := &s3.HeadObjectInput{Bucket: aws.String("b"), Key: aws.String("k")}
+
+	var respErr *awshttp.ResponseError
+	if _, err := f.HeadObject(ctx, in); !errors.As(err, &respErr) || respErr.HTTPStatusCode() != http.StatusNotFound {
+		t.Fatalf("missing HeadObject err = %v, want a 404 response error", err)
+	}
+
+	if _, err := f.PutObject(ctx, &s3.PutObjectInput{Bucket: in.Bucket, Key: in.Key, Body: strings.NewReader("data")}); err != nil {
+		t.Fatal(err)
+	}
+	if head, err := f.HeadObject(ctx, in); err != nil || aws.ToInt64(head.ContentLength) != 4 {
+		t.Errorf("HeadObject = %+v, %v", head, err)
+	}
+	if body, ok := f.Object("b", "k"); !ok || string(body) != "data" {
+		t.Errorf("Object = %q, %v", body, ok)
+	}
+
+	if _, err := f.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:            aws.String("dst"),
+		Key:               aws.String("copy"),
+		CopySource:        aws.String("b/k"),
+		CopySourceIfMatch: aws.String("stale"),
+	}); !errors.As(err, &respErr) || respErr.HTTPStatusCode() != http.StatusPreconditionFailed {
+		t.Errorf("CopyObject with a stale ETag err = %v, want a 412 response error", err)
+	}
+	if _, err := f.CopyObject(ctx, &s3.CopyObjectInput{Bucket: aws.String("dst"), Key: aws.String("copy"), CopySource: aws.String("b/k")}); err != nil {
+		t.Fatal(err)
+	}
+	if body, ok := f.Object("dst", "copy"); !ok || string(body) != "data" {
+		t.Errorf("copied Object = %q, %v", body, ok)
+	}
+
+	if _, err := f.DeleteObject(ctx, &s3.DeleteObjectInput{Bucket: in.Bucket, Key: in.Key}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := f.Object("b", "k"); ok {
+		t.Error("object still stored after DeleteObject")
+	}
+}
+
+func TestS3RangeAndMultipart(t *testing.T) {
+	ctx := context.Background()
+	f := NewS3()
+	b, k := aws.String("b"), aws.String("k")
+	src := bytes.Repeat([]byte("0123456789"), minPartSize/10+1)
+	put, err := f.PutObject(ctx, &s3.PutObjectInput{Bucket: b, Key: k, Body: bytes.NewReader(src)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := f.GetObject(ctx, &s3.GetObjectInput{Bucket: b, Key: k, Range: aws.String("bytes=2-5")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := io.ReadAll(got.Body); string(body) != "2345" || aws.ToString(got.ContentRange) != fmt.Sprintf("bytes 2-5/%d", len(src)) {
+		t.Errorf("ranged GetObject = %q, %q", body, aws.ToString(got.ContentRange))
+	}
+	var respErr *awshttp.ResponseError
+	if _, err := f.GetObject(ctx, &s3.GetObjectInput{Bucket: b, Key: k, IfMatch: aws.String(`"stale"`)}); !errors.As(err, &respErr) || respErr.HTTPStatusCode() != http.StatusPreconditionFailed {
+		t.Errorf("stale IfMatch err = %v, want a 412", err)
+	}
+
+	// Replace the first 10 bytes, copying the rest server side.
+	up, err := f.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{Bucket: b, Key: k})
+	if err != nil {
+		t.Fatal(err)
+	}
+	head := append([]byte("abcdefghij"), src[10:minPartSize]...)
+	p1, err := f.UploadPart(ctx, &s3.UploadPartInput{Bucket: b, Key: k, UploadId: up.UploadId, PartNumber: aws.Int32(1), Body: bytes.NewReader(head)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	copyIn := &s3.UploadPartCopyInput{
+		Bucket: b, Key: k, UploadId: up.UploadId, PartNumber: aws.Int32(2),
+		CopySource:        aws.String("b/k"),
+		CopySourceRange:   aws.String(fmt.Sprintf("bytes=%d-%d", minPartSize, len(src)-1)),
+		CopySourceIfMatch: aws.String(`"stale"`),
+	}
+	if _, err := f.UploadPartCopy(ctx, copyIn); !errors.As(err, &respErr) || respErr.HTTPStatusCode() != http.StatusPreconditionFailed {
+		t.Errorf("stale CopySourceIfMatch err = %v, want a 412", err)
+	}
+	copyIn.CopySourceIfMatch = put.ETag
+	p2, err := f.UploadPartCopy(ctx, copyIn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket: b, Key: k, UploadId: up.UploadId,
+		MultipartUpload: &s3types.CompletedMultipartUpload{Parts: []s3types.CompletedPart{
+			{PartNumber: aws.Int32(2), ETag: p2.CopyPartResult.ETag},
+			{PartNumber: aws.Int32(1), ETag: p1.ETag},
+		}},
+	}); err != nil {
+		t.Fatalf("CompleteMultipartUpload: %v", err)
+	}
+	if body, _ := f.Object("b", "k"); !bytes.Equal(body, append([]byte("abcdefghij"), src[10:]...)) || f.Uploads() != 0 {
+		t.Errorf("completed object has %d bytes, %d uploads left", len(body), f.Uploads())
+	}
+
+	// A small non-final part is rejected, as by S3.
+	up, _ = f.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{Bucket: b, Key: k})
+	small, _ := f.UploadPart(ctx, &s3.UploadPartInput{Bucket: b, Key: k, UploadId: up.UploadId, PartNumber: aws.Int32(1), Body: strings.NewReader("tiny")})
+	last, _ := f.UploadPart(ctx, &s3.UploadPartInput{Bucket: b, Key: k, UploadId: up.UploadId, PartNumber: aws.Int32(2), Body: strings.NewReader("end")})
+	if _, err := f.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket: b, Key: k, UploadId: up.UploadId,
+		MultipartUpload: &s3types.CompletedMultipartUpload{Parts: []s3types.CompletedPart{
+			{PartNumber: aws.Int32(1), ETag: small.ETag}, {PartNumber: aws.Int32(2), ETag: last.ETag},
+		}},
+	}); err == nil {
+		t.Error("a small non-final part should be rejected")
+	}
+	if _, err := f.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{Bucket: b, Key: k, UploadId: up.UploadId}); err != nil || f.Uploads() != 0 {
+		t.Errorf("AbortMultipartUpload = %v, %d uploads left", err, f.Uploads())
+	}
+}
+
+func TestSQSVisibility(t *testing.T) {
+	ctx := context.Background()
+	f := NewSQS()
+	queueURL := f.CreateQueue("123456789012", "q", map[string]string{"RedrivePolicy": "{}"})
+	if _, err := f.SendMessage(ctx, &sqs.SendMessageInput{QueueUrl: aws.String(queueURL), MessageBody: aws.String("hello")}); err != nil {
+		t.Fatal(err)
+	}
+
+	receive := func() []sqstypes.Message {
+		t.Helper()
+		out, err := f.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{QueueUrl: aws.String(queueURL), MaxNumberOfMessages: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out.Messages
+	}
+
+	first := receive()
+	if len(first) != 1 || aws.ToString(first[0].Body) != "hello" {
+		t.Fatalf("first receive = %+v", first)
+	}
+	if again := receive(); len(again) != 0 {
+		t.Errorf("hidden message received again: %+v", again)
+	}
+
+	// Releasing it redelivers with a new receipt handle and receive count.
+	if _, err := f.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+		QueueUrl: aws.String(queueURL), ReceiptHandle: first[0].ReceiptHandle,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	second := receive()
+	if len(second) != 1 || aws.ToString(second[0].ReceiptHandle) == aws.ToString(first[0].ReceiptHandle) ||
+		second[0].Attributes["ApproximateReceiveCount"] != "2" {
+		t.Fatalf("redelivery = %+v", second)
+	}
+
+	// The stale handle no longer deletes; the current one does.
+	var invalid *sqstypes.ReceiptHandleIsInvalid
+	if _, err := f.DeleteMessage(ctx, &sqs.DeleteMessageInput{QueueUrl: aws.String(queueURL), ReceiptHandle: first[0].ReceiptHandle}); !errors.As(err, &invalid) {
+		t.Errorf("stale handle delete err = %v, want ReceiptHandleIsInvalid", err)
+	}
+	if _, err := f.DeleteMessage(ctx, &sqs.DeleteMessageInput{QueueUrl: aws.String(queueURL), ReceiptHandle: second[0].ReceiptHandle}); err != nil {
+		t.Fatal(err)
+	}
+	if n := f.Len(queueURL); n != 0 {
+		t.Errorf("Len = %d after delete, want 0", n)
+	}
+
+	url, err := f.GetQueueUrl(ctx, &sqs.GetQueueUrlInput{QueueName: aws.String("q"), QueueOwnerAWSAccountId: aws.String("123456789012")})
+	if err != nil || aws.ToString(url.QueueUrl) != queueURL {
+		t.Errorf("GetQueueUrl = %v, %v", url, err)
+	}
+	attrs, err := f.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl:       aws.String(queueURL),
+		AttributeNames: []sqstypes.QueueAttributeName{sqstypes.QueueAttributeNameRedrivePolicy},
+	})
+	if err != nil || attrs.Attributes["RedrivePolicy"] != "{}" {
+		t.Errorf("GetQueueAttributes = %v, %v", attrs, err)
+	}
+}
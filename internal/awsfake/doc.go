@@ -0,0 +1,12 @@
+// Package awsfake provides in-memory stand-ins for the narrow subset of the
+// KMS, S3 and SQS APIs the producer and consumer call, so their encryption,
+// upload and notification paths can be unit tested without AWS.
+//
+// Each fake has the same method signatures as the corresponding AWS SDK
+// client, so it can be assigned to the producer's or consumer's client
+// fields in tests. All fakes are safe for concurrent use.
+//
+// To run against LocalStack instead, point the real clients at it with the
+// standard AWS_ENDPOINT_URL environment variable, which the SDK's default
+// config loading honors.
+package awsfake
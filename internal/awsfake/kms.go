@@ -0,0 +1,162 @@
+package awsfake
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	kmstypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+// KMS fakes KMS Encrypt, Decrypt, ReEncrypt and DescribeKey. Its ciphertexts
+// are opaque handles that only the same KMS value can decrypt.
+type KMS struct {
+	mu       sync.Mutex
+	keys     map[string]kmsEntry // by ciphertext
+	states   map[string]kmstypes.KeyState
+	encrypts int
+	decrypts int
+	reencs   int
+}
+
+type kmsEntry struct {
+	keyID     string
+	plaintext []byte
+}
+
+// NewKMS returns an empty KMS fake.
+func NewKMS() *KMS {
+	return &KMS{keys: make(map[string]kmsEntry), states: make(map[string]kmstypes.KeyState)}
+}
+
+// Encrypt returns a ciphertext handle for in.Plaintext under in.KeyId.
+func (k *KMS) Encrypt(_ context.Context, in *kms.EncryptInput, _ ...func(*kms.Options)) (*kms.EncryptOutput, error) {
+	if aws.ToString(in.KeyId) == "" {
+		return nil, errors.New("awsfake: Encrypt requires a KeyId")
+	}
+
+	blob, err := newHandle()
+	if err != nil {
+		return nil, err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.encrypts++
+	k.keys[string(blob)] = kmsEntry{keyID: aws.ToString(in.KeyId), plaintext: bytes.Clone(in.Plaintext)}
+
+	return &kms.EncryptOutput{CiphertextBlob: blob, KeyId: in.KeyId}, nil
+}
+
+// ReEncrypt returns a new ciphertext handle for the plaintext of
+// in.CiphertextBlob under in.DestinationKeyId, failing like Decrypt for a
+// ciphertext it did not make.
+func (k *KMS) ReEncrypt(_ context.Context, in *kms.ReEncryptInput, _ ...func(*kms.Options)) (*kms.ReEncryptOutput, error) {
+	if aws.ToString(in.DestinationKeyId) == "" {
+		return nil, errors.New("awsfake: ReEncrypt requires a DestinationKeyId")
+	}
+	blob, err := newHandle()
+	if err != nil {
+		return nil, err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.reencs++
+
+	entry, ok := k.keys[string(in.CiphertextBlob)]
+	if !ok {
+		return nil, &kmstypes.InvalidCiphertextException{Message: aws.String("awsfake: unknown ciphertext")}
+	}
+	k.keys[string(blob)] = kmsEntry{keyID: aws.ToString(in.DestinationKeyId), plaintext: entry.plaintext}
+
+	return &kms.ReEncryptOutput{
+		CiphertextBlob: blob,
+		KeyId:          in.DestinationKeyId,
+		SourceKeyId:    aws.String(entry.keyID),
+	}, nil
+}
+
+// newHandle returns a fresh opaque ciphertext handle.
+func newHandle() ([]byte, error) {
+	handle := make([]byte, 16)
+	if _, err := rand.Read(handle); err != nil {
+		return nil, err
+	}
+	return []byte("awsfake-kms:" + hex.EncodeToString(handle)), nil
+}
+
+// Decrypt returns the plaintext for a ciphertext made by Encrypt, or an
+// InvalidCiphertextException for any other input. When in.KeyId is set it
+// must name the key the ciphertext was made with (IncorrectKeyException).
+func (k *KMS) Decrypt(_ context.Context, in *kms.DecryptInput, _ ...func(*kms.Options)) (*kms.DecryptOutput, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.decrypts++
+
+	entry, ok := k.keys[string(in.CiphertextBlob)]
+	if !ok {
+		return nil, &kmstypes.InvalidCiphertextException{Message: aws.String("awsfake: unknown ciphertext")}
+	}
+	if in.KeyId != nil && aws.ToString(in.KeyId) != entry.keyID {
+		return nil, &kmstypes.IncorrectKeyException{Message: aws.String("awsfake: ciphertext was not made with " + aws.ToString(in.KeyId))}
+	}
+	return &kms.DecryptOutput{Plaintext: bytes.Clone(entry.plaintext), KeyId: aws.String(entry.keyID)}, nil
+}
+
+// SetKeyState makes DescribeKey report keyID in state; keys default to
+// Enabled.
+func (k *KMS) SetKeyState(keyID string, state kmstypes.KeyState) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.states[keyID] = state
+}
+
+// DescribeKey describes any key ID as an existing key, in the state set by
+// SetKeyState and with a us-east-1 ARN unless the ID already is an ARN. The
+// key ID "missing" fails with a NotFoundException.
+func (k *KMS) DescribeKey(_ context.Context, in *kms.DescribeKeyInput, _ ...func(*kms.Options)) (*kms.DescribeKeyOutput, error) {
+	keyID := aws.ToString(in.KeyId)
+	if keyID == "" || keyID == "missing" {
+		return nil, &kmstypes.NotFoundException{Message: aws.String("awsfake: key " + keyID + " not found")}
+	}
+
+	k.mu.Lock()
+	state, ok := k.states[keyID]
+	k.mu.Unlock()
+	if !ok {
+		state = kmstypes.KeyStateEnabled
+	}
+
+	arn := keyID
+	if !strings.HasPrefix(arn, "arn:") {
+		arn = "arn:aws:kms:us-east-1:000000000000:key/" + keyID
+	}
+	return &kms.DescribeKeyOutput{KeyMetadata: &kmstypes.KeyMetadata{
+		KeyId:    aws.String(keyID),
+		Arn:      aws.String(arn),
+		Enabled:  state == kmstypes.KeyStateEnabled,
+		KeyState: state,
+		KeyUsage: kmstypes.KeyUsageTypeEncryptDecrypt,
+	}}, nil
+}
+
+// Calls returns how many Encrypt and Decrypt calls were made.
+func (k *KMS) Calls() (encrypts, decrypts int) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.encrypts, k.decrypts
+}
+
+// ReEncrypts returns how many ReEncrypt calls were made.
+func (k *KMS) ReEncrypts() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.reencs
+}
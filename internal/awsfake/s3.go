@@ -0,0 +1,381 @@
+package awsfake
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// S3 fakes S3 object storage: PutObject, GetObject (with Range and
+// IfMatch), HeadObject, HeadBucket, DeleteObject, CopyObject and multipart
+// uploads, including UploadPartCopy. Missing objects fail with the same 404
+// response error the real client returns, failed preconditions with a 412.
+// Every bucket exists except one named "missing".
+type S3 struct {
+	mu      sync.Mutex
+	objects map[string]s3Object // by "bucket/key"
+	uploads map[string]*s3Upload
+}
+
+// minPartSize is S3's minimum size for every part but the last.
+const minPartSize = 5 << 20
+
+type s3Upload struct {
+	key   string // "bucket/key"
+	attrs s3Object
+	parts map[int32][]byte
+}
+
+type s3Object struct {
+	body         []byte
+	contentType  string
+	metadata     map[string]string
+	etag         string
+	storageClass string
+}
+
+// NewS3 returns an empty S3 fake.
+func NewS3() *S3 {
+	return &S3{objects: make(map[string]s3Object), uploads: make(map[string]*s3Upload)}
+}
+
+// PutObject stores the object, replacing any existing one. With in.IfMatch
+// the existing object must have that ETag; with in.IfNoneMatch "*" there
+// must be none.
+func (f *S3) PutObject(_ context.Context, in *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	var body []byte
+	if in.Body != nil {
+		var err error
+		if body, err = io.ReadAll(in.Body); err != nil {
+			return nil, err
+		}
+	}
+
+	obj := s3Object{
+		body:        body,
+		contentType: aws.ToString(in.ContentType),
+		metadata:    maps.Clone(in.Metadata),
+		etag:        etagOf(body),
+	}
+	obj.storageClass = string(in.StorageClass)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if in.IfMatch != nil && f.objects[objectKey(in.Bucket, in.Key)].etag != aws.ToString(in.IfMatch) {
+		return nil, preconditionFailed()
+	}
+	if _, exists := f.objects[objectKey(in.Bucket, in.Key)]; exists && aws.ToString(in.IfNoneMatch) == "*" {
+		return nil, preconditionFailed()
+	}
+	f.objects[objectKey(in.Bucket, in.Key)] = obj
+
+	return &s3.PutObjectOutput{ETag: aws.String(obj.etag)}, nil
+}
+
+// GetObject returns a stored object, or the "bytes=first-last" slice of it
+// in.Range names.
+func (f *S3) GetObject(_ context.Context, in *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	obj, ok := f.lookup(in.Bucket, in.Key)
+	if !ok {
+		return nil, notFound(&s3types.NoSuchKey{Message: aws.String("awsfake: no such key")})
+	}
+	if in.IfMatch != nil && aws.ToString(in.IfMatch) != obj.etag {
+		return nil, preconditionFailed()
+	}
+
+	out := &s3.GetObjectOutput{
+		ContentType:  aws.String(obj.contentType),
+		ETag:         aws.String(obj.etag),
+		Metadata:     maps.Clone(obj.metadata),
+		StorageClass: s3types.StorageClass(obj.storageClass),
+	}
+	body := obj.body
+	if in.Range != nil {
+		first, last, err := parseRange(aws.ToString(in.Range), len(obj.body))
+		if err != nil {
+			return nil, err
+		}
+		body = obj.body[first : last+1]
+		out.ContentRange = aws.String(fmt.Sprintf("bytes %d-%d/%d", first, last, len(obj.body)))
+	}
+	out.Body = io.NopCloser(bytes.NewReader(body))
+	out.ContentLength = aws.Int64(int64(len(body)))
+	return out, nil
+}
+
+// HeadBucket succeeds for any bucket but "missing", which is not found.
+func (f *S3) HeadBucket(_ context.Context, in *s3.HeadBucketInput, _ ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	if aws.ToString(in.Bucket) == "missing" {
+		return nil, notFound(&s3types.NotFound{Message: aws.String("awsfake: no such bucket")})
+	}
+	return &s3.HeadBucketOutput{}, nil
+}
+
+// HeadObject returns a stored object's attributes.
+func (f *S3) HeadObject(_ context.Context, in *s3.HeadObjectInput, _ ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	obj, ok := f.lookup(in.Bucket, in.Key)
+	if !ok {
+		return nil, notFound(&s3types.NotFound{Message: aws.String("awsfake: not found")})
+	}
+	return &s3.HeadObjectOutput{
+		ContentLength: aws.Int64(int64(len(obj.body))),
+		ContentType:   aws.String(obj.contentType),
+		ETag:          aws.String(obj.etag),
+		Metadata:      maps.Clone(obj.metadata),
+	}, nil
+}
+
+// DeleteObject removes an object. Like S3, deleting a missing object
+// succeeds.
+func (f *S3) DeleteObject(_ context.Context, in *s3.DeleteObjectInput, _ ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.objects, objectKey(in.Bucket, in.Key))
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+// CopyObject copies in.CopySource ("bucket/key", URL-encoded) to in.Bucket
+// and in.Key. The copy keeps the source's content type and metadata unless
+// in.MetadataDirective is REPLACE.
+func (f *S3) CopyObject(_ context.Context, in *s3.CopyObjectInput, _ ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	source, err := url.PathUnescape(strings.TrimPrefix(aws.ToString(in.CopySource), "/"))
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	obj, ok := f.objects[source]
+	if !ok {
+		return nil, notFound(&s3types.NoSuchKey{Message: aws.String("awsfake: no such copy source")})
+	}
+	if in.CopySourceIfMatch != nil && aws.ToString(in.CopySourceIfMatch) != obj.etag {
+		return nil, preconditionFailed()
+	}
+
+	obj.body = bytes.Clone(obj.body)
+	if in.MetadataDirective == s3types.MetadataDirectiveReplace {
+		obj.contentType = aws.ToString(in.ContentType)
+		obj.metadata = maps.Clone(in.Metadata)
+	}
+	obj.storageClass = string(in.StorageClass)
+	f.objects[objectKey(in.Bucket, in.Key)] = obj
+	return &s3.CopyObjectOutput{CopyObjectResult: &s3types.CopyObjectResult{ETag: aws.String(obj.etag)}}, nil
+}
+
+// CreateMultipartUpload starts a multipart upload to in.Bucket and in.Key.
+func (f *S3) CreateMultipartUpload(_ context.Context, in *s3.CreateMultipartUploadInput, _ ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	uploadID := hex.EncodeToString(id)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.uploads[uploadID] = &s3Upload{
+		key: objectKey(in.Bucket, in.Key),
+		attrs: s3Object{
+			contentType:  aws.ToString(in.ContentType),
+			metadata:     maps.Clone(in.Metadata),
+			storageClass: string(in.StorageClass),
+		},
+		parts: make(map[int32][]byte),
+	}
+	return &s3.CreateMultipartUploadOutput{Bucket: in.Bucket, Key: in.Key, UploadId: aws.String(uploadID)}, nil
+}
+
+// UploadPart stores one part of a multipart upload.
+func (f *S3) UploadPart(_ context.Context, in *s3.UploadPartInput, _ ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	body, err := io.ReadAll(in.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	upload, err := f.upload(in.UploadId, in.Bucket, in.Key)
+	if err != nil {
+		return nil, err
+	}
+	upload.parts[aws.ToInt32(in.PartNumber)] = body
+	return &s3.UploadPartOutput{ETag: aws.String(etagOf(body))}, nil
+}
+
+// UploadPartCopy stores a part copied from in.CopySource ("bucket/key",
+// URL-encoded), or the in.CopySourceRange slice of it.
+func (f *S3) UploadPartCopy(_ context.Context, in *s3.UploadPartCopyInput, _ ...func(*s3.Options)) (*s3.UploadPartCopyOutput, error) {
+	source, err := url.PathUnescape(strings.TrimPrefix(aws.ToString(in.CopySource), "/"))
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	upload, err := f.upload(in.UploadId, in.Bucket, in.Key)
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := f.objects[source]
+	if !ok {
+		return nil, notFound(&s3types.NoSuchKey{Message: aws.String("awsfake: no such copy source")})
+	}
+	if in.CopySourceIfMatch != nil && aws.ToString(in.CopySourceIfMatch) != obj.etag {
+		return nil, preconditionFailed()
+	}
+
+	body := obj.body
+	if in.CopySourceRange != nil {
+		first, last, err := parseRange(aws.ToString(in.CopySourceRange), len(obj.body))
+		if err != nil {
+			return nil, err
+		}
+		body = obj.body[first : last+1]
+	}
+	body = bytes.Clone(body)
+	upload.parts[aws.ToInt32(in.PartNumber)] = body
+	return &s3.UploadPartCopyOutput{CopyPartResult: &s3types.CopyPartResult{ETag: aws.String(etagOf(body))}}, nil
+}
+
+// CompleteMultipartUpload joins the listed parts into the object,
+// replacing any existing one (which must have the ETag in.IfMatch, when
+// set). Every part but the last must be at least 5 MiB, as in S3.
+func (f *S3) CompleteMultipartUpload(_ context.Context, in *s3.CompleteMultipartUploadInput, _ ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	upload, err := f.upload(in.UploadId, in.Bucket, in.Key)
+	if err != nil {
+		return nil, err
+	}
+	if in.MultipartUpload == nil || len(in.MultipartUpload.Parts) == 0 {
+		return nil, errors.New("awsfake: CompleteMultipartUpload needs parts")
+	}
+	if in.IfMatch != nil && f.objects[upload.key].etag != aws.ToString(in.IfMatch) {
+		return nil, preconditionFailed()
+	}
+
+	parts := slices.Clone(in.MultipartUpload.Parts)
+	slices.SortFunc(parts, func(a, b s3types.CompletedPart) int {
+		return cmp.Compare(aws.ToInt32(a.PartNumber), aws.ToInt32(b.PartNumber))
+	})
+
+	var body []byte
+	for i, part := range parts {
+		data, ok := upload.parts[aws.ToInt32(part.PartNumber)]
+		if !ok || aws.ToString(part.ETag) != etagOf(data) {
+			return nil, fmt.Errorf("awsfake: invalid part %d", aws.ToInt32(part.PartNumber))
+		}
+		if i < len(parts)-1 && len(data) < minPartSize {
+			return nil, fmt.Errorf("awsfake: part %d is %d bytes, below the %d minimum", aws.ToInt32(part.PartNumber), len(data), minPartSize)
+		}
+		body = append(body, data...)
+	}
+
+	obj := upload.attrs
+	obj.body = body
+	obj.etag = etagOf(body)
+	f.objects[upload.key] = obj
+	delete(f.uploads, aws.ToString(in.UploadId))
+	return &s3.CompleteMultipartUploadOutput{Bucket: in.Bucket, Key: in.Key, ETag: aws.String(obj.etag)}, nil
+}
+
+// AbortMultipartUpload discards a multipart upload and its parts.
+func (f *S3) AbortMultipartUpload(_ context.Context, in *s3.AbortMultipartUploadInput, _ ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, err := f.upload(in.UploadId, in.Bucket, in.Key); err != nil {
+		return nil, err
+	}
+	delete(f.uploads, aws.ToString(in.UploadId))
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
+// Uploads returns how many multipart uploads are in progress.
+func (f *S3) Uploads() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.uploads)
+}
+
+// upload returns the in-progress upload uploadID to bucket and key. f.mu
+// must be held.
+func (f *S3) upload(uploadID, bucket, key *string) (*s3Upload, error) {
+	upload, ok := f.uploads[aws.ToString(uploadID)]
+	if !ok || upload.key != objectKey(bucket, key) {
+		return nil, notFound(&s3types.NoSuchUpload{Message: aws.String("awsfake: no such upload")})
+	}
+	return upload, nil
+}
+
+// Object returns the body stored at bucket and key, for assertions.
+func (f *S3) Object(bucket, key string) ([]byte, bool) {
+	obj, ok := f.lookup(aws.String(bucket), aws.String(key))
+	return obj.body, ok
+}
+
+func (f *S3) lookup(bucket, key *string) (s3Object, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	obj, ok := f.objects[objectKey(bucket, key)]
+	return obj, ok
+}
+
+func objectKey(bucket, key *string) string {
+	return aws.ToString(bucket) + "/" + aws.ToString(key)
+}
+
+// etagOf returns the quoted MD5 ETag S3 gives a single-part body.
+func etagOf(body []byte) string {
+	sum := md5.Sum(body)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// parseRange parses a "bytes=first-last" range over size bytes, clamping
+// last to the end as S3 does.
+func parseRange(header string, size int) (first, last int, err error) {
+	if _, err := fmt.Sscanf(header, "bytes=%d-%d", &first, &last); err != nil {
+		return 0, 0, fmt.Errorf("awsfake: unsupported range %q", header)
+	}
+	if first < 0 || first >= size || last < first {
+		return 0, 0, &awshttp.ResponseError{ResponseError: &smithyhttp.ResponseError{
+			Response: &smithyhttp.Response{Response: &http.Response{StatusCode: http.StatusRequestedRangeNotSatisfiable}},
+			Err:      fmt.Errorf("awsfake: range %q not satisfiable for %d bytes", header, size),
+		}}
+	}
+	return first, min(last, size-1), nil
+}
+
+// preconditionFailed returns the 412 response error of a failed If-Match
+// or If-None-Match.
+func preconditionFailed() error {
+	return &awshttp.ResponseError{ResponseError: &smithyhttp.ResponseError{
+		Response: &smithyhttp.Response{Response: &http.Response{StatusCode: http.StatusPreconditionFailed}},
+		Err:      errors.New("awsfake: precondition failed"),
+	}}
+}
+
+// notFound wraps err in the 404 response error the real client returns.
+func notFound(err error) error {
+	return &awshttp.ResponseError{ResponseError: &smithyhttp.ResponseError{
+		Response: &smithyhttp.Response{Response: &http.Response{StatusCode: http.StatusNotFound}},
+		Err:      err,
+	}}
+}
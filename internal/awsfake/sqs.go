@@ -0,0 +1,284 @@
+package awsfake
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// defaultVisibilityTimeout is SQS's default for queues that don't set one.
+const defaultVisibilityTimeout = 30 * time.Second
+
+// SQS fakes the SQS queue operations the consumer uses. Queues are created
+// with CreateQueue; received messages stay hidden for the visibility
+// timeout and are redelivered unless deleted, as with SQS.
+type SQS struct {
+	mu     sync.Mutex
+	queues map[string]*queue // by URL
+	seq    int
+}
+
+type queue struct {
+	account    string
+	name       string
+	attributes map[string]string
+	messages   []*message
+}
+
+type message struct {
+	id             string
+	body           string
+	attributes     map[string]sqstypes.MessageAttributeValue
+	receiptHandle  string
+	receives       int
+	invisibleUntil time.Time
+}
+
+// NewSQS returns an SQS fake with no queues.
+func NewSQS() *SQS {
+	return &SQS{queues: make(map[string]*queue)}
+}
+
+// CreateQueue adds a queue and returns its URL. attributes, such as a
+// RedrivePolicy, are returned by GetQueueAttributes.
+func (f *SQS) CreateQueue(account, name string, attributes map[string]string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	queueURL := fmt.Sprintf("https://sqs.awsfake/%s/%s", account, name)
+	f.queues[queueURL] = &queue{account: account, name: name, attributes: maps.Clone(attributes)}
+	return queueURL
+}
+
+// Len returns the number of messages on a queue, visible or not.
+func (f *SQS) Len(queueURL string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if q, ok := f.queues[queueURL]; ok {
+		return len(q.messages)
+	}
+	return 0
+}
+
+// SendMessage appends a message to the queue.
+func (f *SQS) SendMessage(_ context.Context, in *sqs.SendMessageInput, _ ...func(*sqs.Options)) (*sqs.SendMessageOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	q, err := f.queue(in.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	f.seq++
+	m := &message{
+		id:         "msg-" + strconv.Itoa(f.seq),
+		body:       aws.ToString(in.MessageBody),
+		attributes: maps.Clone(in.MessageAttributes),
+	}
+	q.messages = append(q.messages, m)
+
+	return &sqs.SendMessageOutput{MessageId: aws.String(m.id)}, nil
+}
+
+// ReceiveMessage returns up to MaxNumberOfMessages visible messages and
+// hides them for the visibility timeout. With none visible it waits up to
+// WaitTimeSeconds for one, like a long poll.
+func (f *SQS) ReceiveMessage(ctx context.Context, in *sqs.ReceiveMessageInput, _ ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error) {
+	deadline := time.Now().Add(time.Duration(in.WaitTimeSeconds) * time.Second)
+	for {
+		out, err := f.receive(in)
+		if err != nil || len(out.Messages) > 0 || !time.Now().Before(deadline) {
+			return out, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+}
+
+func (f *SQS) receive(in *sqs.ReceiveMessageInput) (*sqs.ReceiveMessageOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	q, err := f.queue(in.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := max(int(in.MaxNumberOfMessages), 1)
+	visibility := defaultVisibilityTimeout
+	if in.VisibilityTimeout > 0 {
+		visibility = time.Duration(in.VisibilityTimeout) * time.Second
+	}
+
+	now := time.Now()
+	out := &sqs.ReceiveMessageOutput{}
+	for _, m := range q.messages {
+		if len(out.Messages) == limit {
+			break
+		}
+		if now.Before(m.invisibleUntil) {
+			continue
+		}
+		f.seq++
+		m.receives++
+		m.receiptHandle = "rh-" + strconv.Itoa(f.seq)
+		m.invisibleUntil = now.Add(visibility)
+
+		received := sqstypes.Message{
+			MessageId:     aws.String(m.id),
+			ReceiptHandle: aws.String(m.receiptHandle),
+			Body:          aws.String(m.body),
+			Attributes: map[string]string{
+				string(sqstypes.MessageSystemAttributeNameApproximateReceiveCount): strconv.Itoa(m.receives),
+			},
+		}
+		if len(in.MessageAttributeNames) > 0 {
+			received.MessageAttributes = maps.Clone(m.attributes)
+		}
+		out.Messages = append(out.Messages, received)
+	}
+	return out, nil
+}
+
+// DeleteMessage removes the message last received with the receipt handle.
+func (f *SQS) DeleteMessage(_ context.Context, in *sqs.DeleteMessageInput, _ ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	q, i, err := f.received(in.QueueUrl, in.ReceiptHandle)
+	if err != nil {
+		return nil, err
+	}
+	q.messages = slices.Delete(q.messages, i, i+1)
+	return &sqs.DeleteMessageOutput{}, nil
+}
+
+// DeleteMessageBatch deletes each entry as DeleteMessage would, reporting
+// unknown receipt handles as failed entries.
+func (f *SQS) DeleteMessageBatch(_ context.Context, in *sqs.DeleteMessageBatchInput, _ ...func(*sqs.Options)) (*sqs.DeleteMessageBatchOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(in.Entries) == 0 {
+		return nil, &sqstypes.EmptyBatchRequest{Message: aws.String("awsfake: no entries")}
+	}
+	if len(in.Entries) > 10 {
+		return nil, &sqstypes.TooManyEntriesInBatchRequest{Message: aws.String("awsfake: more than 10 entries")}
+	}
+	if _, err := f.queue(in.QueueUrl); err != nil {
+		return nil, err
+	}
+
+	out := &sqs.DeleteMessageBatchOutput{}
+	for _, entry := range in.Entries {
+		q, i, err := f.received(in.QueueUrl, entry.ReceiptHandle)
+		if err != nil {
+			out.Failed = append(out.Failed, sqstypes.BatchResultErrorEntry{
+				Id:          entry.Id,
+				Code:        aws.String("ReceiptHandleIsInvalid"),
+				Message:     aws.String(err.Error()),
+				SenderFault: true,
+			})
+			continue
+		}
+		q.messages = slices.Delete(q.messages, i, i+1)
+		out.Successful = append(out.Successful, sqstypes.DeleteMessageBatchResultEntry{Id: entry.Id})
+	}
+	return out, nil
+}
+
+// ChangeMessageVisibility hides a received message for VisibilityTimeout
+// seconds from now; zero makes it visible again right away.
+func (f *SQS) ChangeMessageVisibility(_ context.Context, in *sqs.ChangeMessageVisibilityInput, _ ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	q, i, err := f.received(in.QueueUrl, in.ReceiptHandle)
+	if err != nil {
+		return nil, err
+	}
+	q.messages[i].invisibleUntil = time.Now().Add(time.Duration(in.VisibilityTimeout) * time.Second)
+	return &sqs.ChangeMessageVisibilityOutput{}, nil
+}
+
+// PurgeQueue deletes every message on the queue.
+func (f *SQS) PurgeQueue(_ context.Context, in *sqs.PurgeQueueInput, _ ...func(*sqs.Options)) (*sqs.PurgeQueueOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	q, err := f.queue(in.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	q.messages = nil
+	return &sqs.PurgeQueueOutput{}, nil
+}
+
+// GetQueueAttributes returns the requested attributes given to CreateQueue.
+func (f *SQS) GetQueueAttributes(_ context.Context, in *sqs.GetQueueAttributesInput, _ ...func(*sqs.Options)) (*sqs.GetQueueAttributesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	q, err := f.queue(in.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	attributes := make(map[string]string)
+	for _, name := range in.AttributeNames {
+		if name == sqstypes.QueueAttributeNameAll {
+			maps.Copy(attributes, q.attributes)
+			continue
+		}
+		if value, ok := q.attributes[string(name)]; ok {
+			attributes[string(name)] = value
+		}
+	}
+	return &sqs.GetQueueAttributesOutput{Attributes: attributes}, nil
+}
+
+// GetQueueUrl looks a queue up by name and, when given, owner account.
+func (f *SQS) GetQueueUrl(_ context.Context, in *sqs.GetQueueUrlInput, _ ...func(*sqs.Options)) (*sqs.GetQueueUrlOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for queueURL, q := range f.queues {
+		if q.name == aws.ToString(in.QueueName) &&
+			(in.QueueOwnerAWSAccountId == nil || q.account == aws.ToString(in.QueueOwnerAWSAccountId)) {
+			return &sqs.GetQueueUrlOutput{QueueUrl: aws.String(queueURL)}, nil
+		}
+	}
+	return nil, &sqstypes.QueueDoesNotExist{Message: aws.String("awsfake: no queue named " + aws.ToString(in.QueueName))}
+}
+
+func (f *SQS) queue(queueURL *string) (*queue, error) {
+	q, ok := f.queues[aws.ToString(queueURL)]
+	if !ok {
+		return nil, &sqstypes.QueueDoesNotExist{Message: aws.String("awsfake: no queue at " + aws.ToString(queueURL))}
+	}
+	return q, nil
+}
+
+// received finds the message on a queue last received with receiptHandle.
+func (f *SQS) received(queueURL, receiptHandle *string) (*queue, int, error) {
+	q, err := f.queue(queueURL)
+	if err != nil {
+		return nil, 0, err
+	}
+	handle := aws.ToString(receiptHandle)
+	i := slices.IndexFunc(q.messages, func(m *message) bool { return m.receiptHandle == handle })
+	if handle == "" || i < 0 {
+		return nil, 0, &sqstypes.ReceiptHandleIsInvalid{Message: aws.String("awsfake: unknown receipt handle " + handle)}
+	}
+	return q, i, nil
+}
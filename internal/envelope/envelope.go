@@ -0,0 +1,171 @@
+// Package envelope implements the envelope encryption format the producer
+// writes and the consumer reads:
+//
+//	[4 bytes: wrapped key length, big-endian][wrapped data key][16 bytes: IV][16 bytes: GCM tag][ciphertext]
+//
+// Data is encrypted with AES-256-GCM under a fresh random data key, using a
+// 16-byte nonce to match the Python SDK's os.urandom(16). Wrapping the data
+// key (normally with KMS) is left to the caller.
+package envelope
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+const (
+	dataKeySize = 32 // AES-256
+	nonceSize   = 16
+	tagSize     = 16
+)
+
+// Seal encrypts data under a fresh data key and returns the envelope. The
+// data key and IV are read from random, or crypto/rand when it is nil.
+// wrapKey encrypts the data key; its error is returned as is. The data key
+// is zeroed before Seal returns, so wrapKey must not keep it.
+func Seal(ctx context.Context, random io.Reader, data []byte, wrapKey func(ctx context.Context, dataKey []byte) ([]byte, error)) ([]byte, error) {
+	if random == nil {
+		random = rand.Reader
+	}
+
+	dataKey := make([]byte, dataKeySize)
+	defer clear(dataKey)
+	if _, err := io.ReadFull(random, dataKey); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	iv := make([]byte, nonceSize)
+	if _, err := io.ReadFull(random, iv); err != nil {
+		return nil, fmt.Errorf("failed to generate IV: %w", err)
+	}
+
+	aesGCM, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// Seal appends the auth tag; the format stores it before the ciphertext.
+	sealed := aesGCM.Seal(nil, iv, data, nil)
+	ciphertext, authTag := sealed[:len(sealed)-tagSize], sealed[len(sealed)-tagSize:]
+
+	wrappedKey, err := wrapKey(ctx, dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var result bytes.Buffer
+	result.Grow(4 + len(wrappedKey) + nonceSize + tagSize + len(ciphertext))
+	_ = binary.Write(&result, binary.BigEndian, uint32(len(wrappedKey)))
+	result.Write(wrappedKey)
+	result.Write(iv)
+	result.Write(authTag)
+	result.Write(ciphertext)
+
+	return result.Bytes(), nil
+}
+
+// Open decrypts an envelope made by Seal. unwrapKey decrypts the data key;
+// its error is returned as is. The key it returns is zeroed before Open
+// returns, so it must be a copy the caller does not otherwise use.
+func Open(ctx context.Context, data []byte, unwrapKey func(ctx context.Context, wrappedKey []byte) ([]byte, error)) ([]byte, error) {
+	buf := bytes.NewReader(data)
+
+	var keyLen uint32
+	if err := binary.Read(buf, binary.BigEndian, &keyLen); err != nil {
+		return nil, fmt.Errorf("failed to read key length: %w", truncated(err))
+	}
+	if int64(keyLen) > int64(buf.Len()) {
+		return nil, fmt.Errorf("wrapped key length %d exceeds the data: %w", keyLen, io.ErrUnexpectedEOF)
+	}
+
+	wrappedKey := make([]byte, keyLen)
+	iv := make([]byte, nonceSize)
+	authTag := make([]byte, tagSize)
+	for _, part := range []struct {
+		name string
+		dst  []byte
+	}{{"wrapped key", wrappedKey}, {"IV", iv}, {"auth tag", authTag}} {
+		if _, err := io.ReadFull(buf, part.dst); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", part.name, truncated(err))
+		}
+	}
+
+	// Remaining bytes are the ciphertext; GCM expects the tag after it.
+	ciphertext := make([]byte, buf.Len(), buf.Len()+tagSize)
+	_, _ = buf.Read(ciphertext)
+	ciphertext = append(ciphertext, authTag...)
+
+	dataKey, err := unwrapKey(ctx, wrappedKey)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(dataKey)
+
+	aesGCM, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := aesGCM.Open(nil, iv, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("AES-GCM decrypt failed: %w", err)
+	}
+
+	return plaintext, nil
+}
+
+// Rewrap replaces the wrapped data key of an envelope made by Seal,
+// leaving the IV, tag and ciphertext untouched, so a payload can move to a
+// new wrapping key without decrypting the data. rewrapKey turns the old
+// wrapped key into the new one; its error is returned as is.
+func Rewrap(ctx context.Context, data []byte, rewrapKey func(ctx context.Context, wrappedKey []byte) ([]byte, error)) ([]byte, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("failed to read key length: %w", io.ErrUnexpectedEOF)
+	}
+	keyLen := int64(binary.BigEndian.Uint32(data))
+	rest := int64(len(data)) - 4
+	if keyLen+nonceSize+tagSize > rest {
+		return nil, fmt.Errorf("wrapped key length %d exceeds the data: %w", keyLen, io.ErrUnexpectedEOF)
+	}
+
+	wrappedKey := data[4 : 4+keyLen]
+	body := data[4+keyLen:]
+
+	newKey, err := rewrapKey(ctx, bytes.Clone(wrappedKey))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]byte, 4, 4+len(newKey)+len(body))
+	binary.BigEndian.PutUint32(result, uint32(len(newKey)))
+	result = append(result, newKey...)
+	result = append(result, body...)
+	return result, nil
+}
+
+func newGCM(dataKey []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	aesGCM, err := cipher.NewGCMWithNonceSize(block, nonceSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+	return aesGCM, nil
+}
+
+// truncated reports a short read of the envelope as io.ErrUnexpectedEOF.
+func truncated(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,206 @@
+package envelope
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+)
+
+// identity stands in for KMS: the "wrapped" key is the data key itself.
+func identity(_ context.Context, key []byte) ([]byte, error) { return key, nil }
+
+func TestSealOpenRoundTrip(t *testing.T) {
+	large := make([]byte, 5<<20+3)
+	for i := range large {
+		large[i] = byte(i * 31)
+	}
+
+	for _, size := range []int{0, 1, 15, 16, 17, 4096} {
+		data := bytes.Repeat([]byte{0xa5}, size)
+		t.Run(fmt.Sprintf("%d bytes", size), func(t *testing.T) { roundTrip(t, data) })
+	}
+	t.Run("large", func(t *testing.T) { roundTrip(t, large) })
+}
+
+func roundTrip(t *testing.T, data []byte) {
+	t.Helper()
+
+	sealed, err := Seal(context.Background(), nil, data, identity)
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	if want := 4 + dataKeySize + nonceSize + tagSize + len(data); len(sealed) != want {
+		t.Errorf("envelope = %d bytes, want %d", len(sealed), want)
+	}
+	if keyLen := binary.BigEndian.Uint32(sealed); keyLen != dataKeySize {
+		t.Errorf("key length prefix = %d, want %d", keyLen, dataKeySize)
+	}
+
+	opened, err := Open(context.Background(), sealed, identity)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if !bytes.Equal(opened, data) {
+		t.Errorf("Open(Seal(%d bytes)) differs from the input", len(data))
+	}
+}
+
+// TestOpenFixedLayout decrypts an envelope assembled by hand with a 16-byte
+// GCM nonce, as the Python SDK writes it, so the layout can't drift in step
+// on both sides.
+func TestOpenFixedLayout(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	iv := bytes.Repeat([]byte{2}, 16)
+	block, _ := aes.NewCipher(key)
+	gcm, _ := cipher.NewGCMWithNonceSize(block, 16)
+	sealed := gcm.Seal(nil, iv, []byte("interop"), nil)
+
+	var env bytes.Buffer
+	_ = binary.Write(&env, binary.BigEndian, uint32(len(key)))
+	env.Write(key)
+	env.Write(iv)
+	env.Write(sealed[len(sealed)-16:]) // tag before ciphertext
+	env.Write(sealed[:len(sealed)-16])
+
+	got, err := Open(context.Background(), env.Bytes(), identity)
+	if err != nil || string(got) != "interop" {
+		t.Errorf("Open = %q, %v; want interop", got, err)
+	}
+}
+
+func TestOpenRejectsBadInput(t *testing.T) {
+	sealed, err := Seal(context.Background(), nil, []byte("payload"), identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := bytes.Clone(sealed)
+	tampered[len(tampered)-1] ^= 1
+
+	hugeKeyLen := bytes.Clone(sealed)
+	binary.BigEndian.PutUint32(hugeKeyLen, 1<<30)
+
+	for name, tc := range map[string]struct {
+		data []byte
+		want error
+	}{
+		"empty":              {nil, io.ErrUnexpectedEOF},
+		"cut in key length":  {sealed[:2], io.ErrUnexpectedEOF},
+		"cut in auth tag":    {sealed[:4+dataKeySize+nonceSize+4], io.ErrUnexpectedEOF},
+		"key length too big": {hugeKeyLen, io.ErrUnexpectedEOF},
+		"tampered":           {tampered, nil},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := Open(context.Background(), tc.data, identity)
+			if err == nil {
+				t.Fatal("Open succeeded on bad input")
+			}
+			if tc.want != nil && !errors.Is(err, tc.want) {
+				t.Errorf("err = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestKeyWrapErrorsPassThrough(t *testing.T) {
+	wrapErr := errors.New("KMS encryption failed: denied")
+	if _, err := Seal(context.Background(), nil, []byte("x"), func(context.Context, []byte) ([]byte, error) {
+		return nil, wrapErr
+	}); err != wrapErr {
+		t.Errorf("Seal err = %v, want the wrap error as is", err)
+	}
+
+	sealed, _ := Seal(context.Background(), nil, []byte("x"), identity)
+	unwrapErr := errors.New("KMS decrypt failed: denied")
+	if _, err := Open(context.Background(), sealed, func(context.Context, []byte) ([]byte, error) {
+		return nil, unwrapErr
+	}); err != unwrapErr {
+		t.Errorf("Open err = %v, want the unwrap error as is", err)
+	}
+}
+
+func TestRewrap(t *testing.T) {
+	data := []byte("rows that must survive re-wrapping")
+	xor := func(key []byte, b byte) []byte {
+		out := make([]byte, len(key))
+		for i := range key {
+			out[i] = key[i] ^ b
+		}
+		return out
+	}
+	wrapOld := func(_ context.Context, key []byte) ([]byte, error) { return xor(key, 0x5a), nil }
+	// The new wrapping is longer than the old, as a different key's may be.
+	unwrapNew := func(_ context.Context, wrapped []byte) ([]byte, error) { return xor(wrapped[3:], 0x0f), nil }
+
+	sealed, err := Seal(context.Background(), nil, data, wrapOld)
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := bytes.Clone(sealed)
+
+	rewrapped, err := Rewrap(context.Background(), sealed, func(_ context.Context, wrapped []byte) ([]byte, error) {
+		return append([]byte("v2:"), xor(xor(wrapped, 0x5a), 0x0f)...), nil
+	})
+	if err != nil {
+		t.Fatalf("Rewrap: %v", err)
+	}
+	if !bytes.Equal(sealed, original) {
+		t.Error("Rewrap modified its input")
+	}
+	tail := nonceSize + tagSize + len(data)
+	if !bytes.Equal(rewrapped[len(rewrapped)-tail:], sealed[len(sealed)-tail:]) {
+		t.Error("Rewrap changed the IV, tag or ciphertext")
+	}
+
+	opened, err := Open(context.Background(), rewrapped, unwrapNew)
+	if err != nil || !bytes.Equal(opened, data) {
+		t.Fatalf("Open after Rewrap = %q, %v", opened, err)
+	}
+	if _, err := Open(context.Background(), rewrapped, wrapOld); err == nil {
+		t.Error("the old wrapping should no longer open the envelope")
+	}
+
+	for _, bad := range [][]byte{nil, {0, 0}, sealed[:4+dataKeySize+nonceSize]} {
+		if _, err := Rewrap(context.Background(), bad, identity); !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("Rewrap of %d bytes: err = %v, want io.ErrUnexpectedEOF", len(bad), err)
+		}
+	}
+	boom := errors.New("boom")
+	if _, err := Rewrap(context.Background(), sealed, func(context.Context, []byte) ([]byte, error) { return nil, boom }); err != boom {
+		t.Errorf("rewrap error = %v, want it returned as is", err)
+	}
+}
+
+// TestDataKeyZeroed pins that Seal and Open zero the data key once the
+// AES operation is done, rather than leaving it for the garbage collector.
+func TestDataKeyZeroed(t *testing.T) {
+	var sealKey []byte
+	sealed, err := Seal(context.Background(), nil, []byte("secret"), func(_ context.Context, key []byte) ([]byte, error) {
+		sealKey = key
+		return bytes.Clone(key), nil
+	})
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	if !bytes.Equal(sealKey, make([]byte, dataKeySize)) {
+		t.Error("Seal left the data key in memory")
+	}
+
+	var openKey []byte
+	opened, err := Open(context.Background(), sealed, func(_ context.Context, wrapped []byte) ([]byte, error) {
+		openKey = bytes.Clone(wrapped)
+		return openKey, nil
+	})
+	if err != nil || string(opened) != "secret" {
+		t.Fatalf("Open = %q, %v", opened, err)
+	}
+	if !bytes.Equal(openKey, make([]byte, dataKeySize)) {
+		t.Error("Open left the data key in memory")
+	}
+}
@@ -0,0 +1,7 @@
+//go:build boringcrypto
+
+package fips
+
+import "crypto/boring"
+
+func boringEnabled() bool { return boring.Enabled() }
@@ -0,0 +1,36 @@
+// Package fips checks that the binary can honor Config.FIPSMode: that its
+// crypto runs in a FIPS 140 validated module, through which the standard
+// crypto/aes and crypto/cipher packages the envelope uses are then routed.
+package fips
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/fips140"
+	"errors"
+	"fmt"
+)
+
+// ErrUnavailable is returned (wrapped) by Check when the binary was not
+// built or run with a FIPS 140 crypto backend.
+var ErrUnavailable = errors.New("FIPS mode requested but the binary has no FIPS 140 crypto backend")
+
+// Check reports whether FIPS-validated crypto is active: Go's FIPS 140-3
+// module (GODEBUG=fips140=on, or a binary built with GOFIPS140) or
+// BoringCrypto (GOEXPERIMENT=boringcrypto). It also fails under
+// GODEBUG=fips140=only, which rejects the caller-chosen 16-byte GCM IV of
+// the SDK's envelope format.
+func Check() error {
+	if !fips140.Enabled() && !boringEnabled() {
+		return fmt.Errorf("%w: run with GODEBUG=fips140=on, build with GOFIPS140=latest, or build with GOEXPERIMENT=boringcrypto", ErrUnavailable)
+	}
+
+	block, err := aes.NewCipher(make([]byte, 32))
+	if err != nil {
+		return fmt.Errorf("FIPS mode: %w", err)
+	}
+	if _, err := cipher.NewGCMWithNonceSize(block, 16); err != nil {
+		return fmt.Errorf("FIPS mode: the envelope format's 16-byte GCM IV is not allowed (use GODEBUG=fips140=on rather than only): %w", err)
+	}
+	return nil
+}
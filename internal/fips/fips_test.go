@@ -0,0 +1,59 @@
+package fips
+
+import (
+	"crypto/fips140"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	err := Check()
+	if fips140.Enabled() || boringEnabled() {
+		if err != nil && !strings.Contains(os.Getenv("GODEBUG"), "fips140=only") {
+			t.Errorf("Check with FIPS crypto active = %v, want nil", err)
+		}
+		return
+	}
+	if !errors.Is(err, ErrUnavailable) || !strings.Contains(err.Error(), "GODEBUG=fips140=on") {
+		t.Errorf("Check = %v, want ErrUnavailable with build guidance", err)
+	}
+}
+
+// TestCheckModes reruns TestCheck in a child process under each fips140
+// GODEBUG setting.
+func TestCheckModes(t *testing.T) {
+	if os.Getenv("HELIX_FIPS_CHILD") != "" {
+		t.Skip("child process")
+	}
+	if testing.Short() {
+		t.Skip("starts child processes")
+	}
+	if boringEnabled() {
+		t.Skip("fips140 GODEBUG settings are incompatible with BoringCrypto")
+	}
+	for mode, wantErr := range map[string]string{"on": "", "only": "16-byte GCM IV"} {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestCheckChild$", "-test.v")
+		cmd.Env = append(os.Environ(), "GODEBUG=fips140="+mode, "HELIX_FIPS_CHILD=1", "HELIX_FIPS_WANT_ERR="+wantErr)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Errorf("fips140=%s: %v\n%s", mode, err, out)
+		}
+	}
+}
+
+func TestCheckChild(t *testing.T) {
+	if os.Getenv("HELIX_FIPS_CHILD") == "" {
+		t.Skip("run by TestCheckModes")
+	}
+	want := os.Getenv("HELIX_FIPS_WANT_ERR")
+	err := Check()
+	if want == "" && err != nil {
+		t.Errorf("Check = %v, want nil", err)
+	}
+	if want != "" && (err == nil || !strings.Contains(err.Error(), want)) {
+		t.Errorf("Check = %v, want an error about %q", err, want)
+	}
+}
@@ -0,0 +1,5 @@
+//go:build !boringcrypto
+
+package fips
+
+func boringEnabled() bool { return false }
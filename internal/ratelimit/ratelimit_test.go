@@ -0,0 +1,75 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNewDisabled(t *testing.T) {
+	if l := New(0, 10); l != nil {
+		t.Fatalf("New(0, 10) = %v, want nil", l)
+	}
+	var l *Limiter
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatalf("nil Limiter Wait: %v", err)
+	}
+}
+
+func TestReserveBurstThenRefill(t *testing.T) {
+	clock := time.Unix(0, 0)
+	l := New(2, 3)
+	l.now = func() time.Time { return clock }
+
+	for i := range 3 {
+		if d := l.reserve(); d != 0 {
+			t.Fatalf("burst token %d delayed %v", i, d)
+		}
+	}
+	if d := l.reserve(); d != 500*time.Millisecond {
+		t.Fatalf("delay after burst = %v, want 500ms", d)
+	}
+
+	clock = clock.Add(500 * time.Millisecond)
+	if d := l.reserve(); d != 0 {
+		t.Fatalf("delay after refill = %v, want 0", d)
+	}
+
+	// Idle time refills up to burst, never beyond.
+	clock = clock.Add(time.Hour)
+	for i := range 3 {
+		if d := l.reserve(); d != 0 {
+			t.Fatalf("token %d after idle delayed %v", i, d)
+		}
+	}
+	if d := l.reserve(); d == 0 {
+		t.Fatal("bucket refilled past burst")
+	}
+}
+
+func TestWaitHonorsContext(t *testing.T) {
+	l := New(0.001, 1)
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatalf("first Wait: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestWaitPaces(t *testing.T) {
+	l := New(100, 1)
+	start := time.Now()
+	for range 3 {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatalf("Wait: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 15*time.Millisecond {
+		t.Fatalf("3 requests at 100/s burst 1 took %v, want >= ~20ms", elapsed)
+	}
+}
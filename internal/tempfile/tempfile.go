@@ -0,0 +1,48 @@
+// Package tempfile creates the temporary files and directories the Consumer
+// and Producer spill data to, in Config.TempDir, and explains the failures
+// that usually mean that directory is the wrong place for them.
+package tempfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// Create creates a new file in dir (os.TempDir() when empty) as
+// os.CreateTemp does. The file is readable and writable by its owner
+// only, since it may hold decrypted data; the caller removes it.
+func Create(dir, pattern string) (*os.File, error) {
+	f, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create a temporary file in %s (set Config.TempDir to a writable directory): %w", name(dir), err)
+	}
+	return f, nil
+}
+
+// MkdirTemp creates a new directory in dir (os.TempDir() when empty) as
+// os.MkdirTemp does, accessible by its owner only; the caller removes it.
+func MkdirTemp(dir, pattern string) (string, error) {
+	path, err := os.MkdirTemp(dir, pattern)
+	if err != nil {
+		return "", fmt.Errorf("cannot create a temporary directory in %s (set Config.TempDir to a writable directory): %w", name(dir), err)
+	}
+	return path, nil
+}
+
+// WriteError explains err, from writing a file Create or MkdirTemp made in
+// dir, when it means dir is full; other errors are returned as is.
+func WriteError(dir string, err error) error {
+	if errors.Is(err, syscall.ENOSPC) {
+		return fmt.Errorf("temporary directory %s is full (set Config.TempDir to a larger volume): %w", name(dir), err)
+	}
+	return err
+}
+
+func name(dir string) string {
+	if dir == "" {
+		return os.TempDir()
+	}
+	return dir
+}
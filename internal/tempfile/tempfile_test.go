@@ -0,0 +1,53 @@
+package tempfile
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	f, err := Create(dir, "helix-*")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	if filepath.Dir(f.Name()) != dir {
+		t.Errorf("file %s not in %s", f.Name(), dir)
+	}
+	if runtime.GOOS != "windows" {
+		info, _ := f.Stat()
+		if perm := info.Mode().Perm(); perm != 0o600 {
+			t.Errorf("mode = %v, want 0600", perm)
+		}
+	}
+}
+
+func TestCreateUnusableDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "missing")
+	if _, err := Create(dir, "helix-*"); !errors.Is(err, fs.ErrNotExist) || !strings.Contains(err.Error(), "Config.TempDir") {
+		t.Errorf("Create = %v, want an error naming Config.TempDir", err)
+	}
+	if _, err := MkdirTemp(dir, "helix-*"); !errors.Is(err, fs.ErrNotExist) || !strings.Contains(err.Error(), dir) {
+		t.Errorf("MkdirTemp = %v, want an error naming %s", err, dir)
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	full := &fs.PathError{Op: "write", Path: "f", Err: syscall.ENOSPC}
+	if err := WriteError("/data", full); !errors.Is(err, syscall.ENOSPC) || !strings.Contains(err.Error(), "/data is full") {
+		t.Errorf("WriteError = %v, want it to say /data is full", err)
+	}
+	other := errors.New("boom")
+	if err := WriteError("/data", other); err != other {
+		t.Errorf("WriteError = %v, want the error as is", err)
+	}
+}
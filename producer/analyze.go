@@ -2,30 +2,131 @@ package producer
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"sort"
 	"strings"
+
+	"github.com/helix-tools/sdk-go/v2/types"
 )
 
 // AnalysisResult contains dataset analysis results.
 type AnalysisResult struct {
-	Schema         map[string]any     `json:"schema"`
-	FieldEmptiness map[string]float64 `json:"field_emptiness"`
-	RecordCount    int                `json:"record_count"`
-	AnalysisErrors int                `json:"analysis_errors"`
+	Schema         map[string]any `json:"schema"`
+	FieldEmptiness FieldEmptiness `json:"field_emptiness"`
+	RecordCount    int            `json:"record_count"`
+	AnalysisErrors int            `json:"analysis_errors"`
+
+	// TruncatedRecords counts lines longer than AnalysisOptions.MaxLineBytes.
+	// They are skipped, and not counted in AnalysisErrors.
+	TruncatedRecords int `json:"truncated_records"`
+
+	// ErrorSamples holds the first AnalysisOptions.MaxErrorSamples bad lines
+	// (invalid JSON or truncated), in file order.
+	ErrorSamples []ParseError `json:"error_samples,omitempty"`
+}
+
+// FieldEmptinessEntry is the emptiness of one field: the percentage of
+// records (or array elements, for "x[].f" fields) where it is missing or empty.
+type FieldEmptinessEntry struct {
+	Field   string  `json:"field"`
+	Percent float64 `json:"percent"`
+}
+
+// FieldEmptiness lists field emptiness highest-first, ties broken by field
+// name, so iteration and JSON output are stable. It marshals as a JSON
+// object ({"field": percent, ...}) in that order, the shape the catalog
+// metadata has always used.
+type FieldEmptiness []FieldEmptinessEntry
+
+// Map returns the emptiness keyed by field, for lookups.
+func (fe FieldEmptiness) Map() map[string]float64 {
+	m := make(map[string]float64, len(fe))
+	for _, entry := range fe {
+		m[entry.Field] = entry.Percent
+	}
+	return m
+}
+
+// Get returns the emptiness of field and whether it was discovered.
+func (fe FieldEmptiness) Get(field string) (float64, bool) {
+	for _, entry := range fe {
+		if entry.Field == field {
+			return entry.Percent, true
+		}
+	}
+	return 0, false
+}
+
+// MarshalJSON encodes fe as a JSON object, preserving order.
+func (fe FieldEmptiness) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, entry := range fe {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(entry.Field)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(entry.Percent)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
 }
 
+// UnmarshalJSON decodes a JSON object of field percentages, sorting it the
+// same way analysis does (JSON object order is not preserved by decoding).
+func (fe *FieldEmptiness) UnmarshalJSON(data []byte) error {
+	var m map[string]float64
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	*fe = sortByValueDesc(m)
+	return nil
+}
+
+// ParseError describes one line the analysis could not use.
+type ParseError struct {
+	LineNumber int    `json:"line_number"`       // 1-based
+	Snippet    string `json:"snippet,omitempty"` // first ~200 chars of the line; empty for truncated lines
+	Message    string `json:"message"`
+}
+
+// errorSnippetRunes caps ParseError.Snippet.
+const errorSnippetRunes = 200
+
+// defaultMaxErrorSamples is the default AnalysisOptions.MaxErrorSamples.
+const defaultMaxErrorSamples = 10
+
+// defaultMaxLineBytes is the default AnalysisOptions.MaxLineBytes.
+const defaultMaxLineBytes = 10 * 1024 * 1024
+
 // AnalysisOptions configures the analysis behavior.
 type AnalysisOptions struct {
 	SchemaSampleLimit int // Default: 1000, 0 = all records
+	MaxLineBytes      int // Default: 10MB; longer lines count as TruncatedRecords
+	MaxErrorSamples   int // Default: 10, negative = none
+	MaxRecords        int // Default: 0 = unlimited; more records abort with *types.LimitError
 }
 
 // DefaultAnalysisOptions returns default analysis options.
 func DefaultAnalysisOptions() AnalysisOptions {
 	return AnalysisOptions{
 		SchemaSampleLimit: 1000,
+		MaxLineBytes:      defaultMaxLineBytes,
+		MaxErrorSamples:   defaultMaxErrorSamples,
 	}
 }
 
@@ -38,49 +139,110 @@ func DefaultAnalysisOptions() AnalysisOptions {
 // Memory efficiency is achieved by processing line-by-line rather than
 // loading the entire file into memory.
 func (p *Producer) analyzeData(filePath string, opts AnalysisOptions) (*AnalysisResult, error) {
-	if opts.SchemaSampleLimit == 0 {
-		opts.SchemaSampleLimit = 0 // 0 means all records
-	}
-
 	file, err := os.Open(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
 	defer file.Close()
 
+	return p.analyzeReader(file, opts)
+}
+
+// analyzeFiles analyzes the NDJSON files as one dataset, as if they were
+// concatenated in order: record counts, schema and field emptiness cover
+// every file. Error sample line numbers count across the files.
+func (p *Producer) analyzeFiles(filePaths []string, opts AnalysisOptions) (*AnalysisResult, error) {
+	if len(filePaths) == 1 {
+		return p.analyzeData(filePaths[0], opts)
+	}
+
+	readers := make([]io.Reader, 0, 2*len(filePaths))
+	for _, filePath := range filePaths {
+		file, err := os.Open(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open file: %w", err)
+		}
+		defer file.Close()
+		// A file without a trailing newline must not run into the next.
+		readers = append(readers, file, strings.NewReader("\n"))
+	}
+
+	return p.analyzeReader(io.MultiReader(readers...), opts)
+}
+
+// analyzeReader is analyzeData over NDJSON read from r.
+func (p *Producer) analyzeReader(r io.Reader, opts AnalysisOptions) (*AnalysisResult, error) {
+	if opts.SchemaSampleLimit == 0 {
+		opts.SchemaSampleLimit = 0 // 0 means all records
+	}
+
+	if opts.MaxLineBytes <= 0 {
+		opts.MaxLineBytes = defaultMaxLineBytes
+	}
+
+	if opts.MaxErrorSamples == 0 {
+		opts.MaxErrorSamples = defaultMaxErrorSamples
+	}
+
 	var (
 		allFields         = make(map[string]bool)
 		fieldPresentCount = make(map[string]int)
+		elementSlots      = make(map[string]int) // array path "x[]" -> elements seen
+		elementPresent    = make(map[string]int) // "x[].f" -> elements where f is non-empty
 		schemaBuilder     = newSchemaBuilder()
 		recordCount       = 0
 		analysisErrors    = 0
+		truncatedRecords  = 0
+		errorSamples      []ParseError
 	)
 
+	addErrorSample := func(lineNum int, snippet []byte, message string) {
+		if len(errorSamples) < opts.MaxErrorSamples {
+			errorSamples = append(errorSamples, ParseError{
+				LineNumber: lineNum,
+				Snippet:    truncateRunes(string(snippet), errorSnippetRunes),
+				Message:    message,
+			})
+		}
+	}
+
 	fmt.Println("📊 Analyzing dataset for schema and field statistics...")
 
-	scanner := bufio.NewScanner(file)
-	// Increase buffer size for large lines (default is 64KB)
-	buf := make([]byte, 0, 1024*1024) // 1MB buffer
-	scanner.Buffer(buf, 10*1024*1024) // 10MB max line size
+	reader := bufio.NewReaderSize(r, 1024*1024) // 1MB buffer
 
 	lineNum := 0
-	for scanner.Scan() {
+	for {
+		raw, err := readLine(reader, opts.MaxLineBytes)
+		if err == io.EOF {
+			break
+		}
+
 		lineNum++
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
+		if errors.Is(err, bufio.ErrTooLong) {
+			truncatedRecords++
+			addErrorSample(lineNum, nil, fmt.Sprintf("line exceeds %d bytes", opts.MaxLineBytes))
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading file: %w", err)
+		}
+
+		line := bytes.TrimSpace(raw)
+		if len(line) == 0 {
 			continue
 		}
 
 		var record map[string]any
-		if err := json.Unmarshal([]byte(line), &record); err != nil {
+		if err := json.Unmarshal(line, &record); err != nil {
 			analysisErrors++
-			if analysisErrors <= 5 {
-				fmt.Printf("  Warning: Failed to parse line %d: %v\n", lineNum, err)
-			}
+			addErrorSample(lineNum, line, err.Error())
 			continue
 		}
 
 		recordCount++
+		if opts.MaxRecords > 0 && recordCount > opts.MaxRecords {
+			return nil, &types.LimitError{Observed: int64(recordCount), Allowed: int64(opts.MaxRecords), Err: types.ErrTooManyRecords}
+		}
 
 		// Infer schema from first N records for complete type coverage
 		if opts.SchemaSampleLimit == 0 || recordCount <= opts.SchemaSampleLimit {
@@ -99,26 +261,29 @@ func (p *Producer) analyzeData(filePath string, opts AnalysisOptions) (*Analysis
 		for field := range present {
 			fieldPresentCount[field]++
 		}
-	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading file: %w", err)
+		// Array-element fields are counted per element, not per record
+		countArrayElements(record, "", elementSlots, elementPresent)
 	}
 
-	// Calculate emptiness: % of records where field is missing OR empty
+	// Calculate emptiness: % of records where field is missing OR empty.
+	// Array-element fields ("x[].f") use the elements of their array instead,
+	// so a field missing from half the elements is 50% empty.
 	fieldEmptiness := make(map[string]float64)
 	for field := range allFields {
-		present := fieldPresentCount[field]
-		missingOrEmpty := recordCount - present
+		total, present := recordCount, fieldPresentCount[field]
+		if container := arrayContainer(field); container != "" {
+			total, present = elementSlots[container], elementPresent[field]
+		}
 		var percentage float64
-		if recordCount > 0 {
-			percentage = float64(missingOrEmpty) / float64(recordCount) * 100
+		if total > 0 {
+			percentage = float64(total-present) / float64(total) * 100
 		}
 		fieldEmptiness[field] = roundTo2Decimals(percentage)
 	}
 
 	// Sort by emptiness percentage (highest first)
-	fieldEmptiness = sortByValueDesc(fieldEmptiness)
+	sortedEmptiness := sortByValueDesc(fieldEmptiness)
 
 	// Build the final schema
 	var schema map[string]any
@@ -156,15 +321,66 @@ func (p *Producer) analyzeData(filePath string, opts AnalysisOptions) (*Analysis
 	if analysisErrors > 0 {
 		fmt.Printf("  Parse errors: %d\n", analysisErrors)
 	}
+	if truncatedRecords > 0 {
+		fmt.Printf("  Truncated records (over %d bytes): %d\n", opts.MaxLineBytes, truncatedRecords)
+	}
 
 	return &AnalysisResult{
-		Schema:         schema,
-		FieldEmptiness: fieldEmptiness,
-		RecordCount:    recordCount,
-		AnalysisErrors: analysisErrors,
+		Schema:           schema,
+		FieldEmptiness:   sortedEmptiness,
+		RecordCount:      recordCount,
+		AnalysisErrors:   analysisErrors,
+		TruncatedRecords: truncatedRecords,
+		ErrorSamples:     errorSamples,
 	}, nil
 }
 
+// truncateRunes returns s cut to at most n runes.
+func truncateRunes(s string, n int) string {
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
+	}
+	return s
+}
+
+// readLine reads the next line from r. A line longer than maxBytes
+// (excluding the line ending) is consumed and discarded, and
+// bufio.ErrTooLong is returned so the caller can count it and move on.
+// io.EOF is returned only when no line is left.
+func readLine(r *bufio.Reader, maxBytes int) ([]byte, error) {
+	var (
+		line    []byte
+		tooLong bool
+	)
+
+	for {
+		chunk, err := r.ReadSlice('\n')
+		if !tooLong {
+			line = append(line, chunk...)
+			if len(bytes.TrimRight(line, "\r\n")) > maxBytes {
+				tooLong, line = true, nil
+			}
+		}
+
+		switch {
+		case err == bufio.ErrBufferFull:
+			continue
+		case err == io.EOF && (len(line) > 0 || tooLong):
+			// Last line without a trailing newline.
+		case err != nil:
+			return nil, err
+		}
+
+		if tooLong {
+			return nil, bufio.ErrTooLong
+		}
+		return line, nil
+	}
+}
+
 // isEmptyValue checks if a value is considered empty.
 // Empty values include: nil, empty string, empty slice, empty map,
 // and strings containing only whitespace.
@@ -219,19 +435,17 @@ func getFieldStatus(obj map[string]any, prefix string) (allFields, presentFields
 				for f := range nestedPresent {
 					presentFields[f] = true
 				}
-			} else if arr, ok := value.([]any); ok && len(arr) > 0 {
-				// For arrays of objects, analyze each item
-				if firstItem, ok := arr[0].(map[string]any); ok {
-					_ = firstItem // Type check passed
-					for _, item := range arr {
-						if itemMap, ok := item.(map[string]any); ok {
-							nestedAll, nestedPresent := getFieldStatus(itemMap, fieldPath+"[]")
-							for f := range nestedAll {
-								allFields[f] = true
-							}
-							for f := range nestedPresent {
-								presentFields[f] = true
-							}
+			} else if arr, ok := value.([]any); ok {
+				// Every object element contributes, even when earlier
+				// elements are scalars or lack some fields
+				for _, item := range arr {
+					if itemMap, ok := item.(map[string]any); ok {
+						nestedAll, nestedPresent := getFieldStatus(itemMap, fieldPath+"[]")
+						for f := range nestedAll {
+							allFields[f] = true
+						}
+						for f := range nestedPresent {
+							presentFields[f] = true
 						}
 					}
 				}
@@ -242,6 +456,51 @@ func getFieldStatus(obj map[string]any, prefix string) (allFields, presentFields
 	return allFields, presentFields
 }
 
+// countArrayElements walks obj and, for every array under it, counts its
+// elements in slots (keyed "x[]") and, per element field path, the elements
+// where that field is non-empty in present. Non-object elements count as
+// elements with no fields.
+func countArrayElements(obj map[string]any, prefix string, slots, present map[string]int) {
+	for key, value := range obj {
+		fieldPath := key
+		if prefix != "" {
+			fieldPath = prefix + "." + key
+		}
+
+		switch val := value.(type) {
+		case map[string]any:
+			countArrayElements(val, fieldPath, slots, present)
+		case []any:
+			container := fieldPath + "[]"
+			for _, item := range val {
+				slots[container]++
+				itemMap, ok := item.(map[string]any)
+				if !ok {
+					continue
+				}
+				_, itemPresent := getFieldStatus(itemMap, container)
+				for f := range itemPresent {
+					// Fields of nested arrays are counted by the recursion below
+					if arrayContainer(f) == container {
+						present[f]++
+					}
+				}
+				countArrayElements(itemMap, container, slots, present)
+			}
+		}
+	}
+}
+
+// arrayContainer returns the innermost array path ("x[]") a field path
+// belongs to, or "" for fields counted per record.
+func arrayContainer(fieldPath string) string {
+	i := strings.LastIndex(fieldPath, "[]")
+	if i < 0 {
+		return ""
+	}
+	return fieldPath[:i+2]
+}
+
 // schemaBuilder builds a JSON schema from sample records.
 type schemaBuilder struct {
 	properties map[string]*propertySchema
@@ -272,29 +531,32 @@ func (sb *schemaBuilder) addProperties(obj map[string]any, props map[string]*pro
 			}
 		}
 
-		prop := props[key]
-		prop.types[inferType(value)] = true
+		sb.addValue(props[key], value)
+	}
+}
 
-		// Handle nested objects
-		if nestedMap, ok := value.(map[string]any); ok {
-			sb.addProperties(nestedMap, prop.properties)
-		}
+// addValue records value's type on prop and merges its structure: object
+// properties, and for arrays every element (including nested arrays) into
+// a single items schema.
+func (sb *schemaBuilder) addValue(prop *propertySchema, value any) {
+	prop.types[inferType(value)] = true
 
-		// Handle arrays
-		if arr, ok := value.([]any); ok && len(arr) > 0 {
-			if prop.items == nil {
-				prop.items = &propertySchema{
-					types:      make(map[string]bool),
-					properties: make(map[string]*propertySchema),
-				}
-			}
-			for _, item := range arr {
-				prop.items.types[inferType(item)] = true
-				if itemMap, ok := item.(map[string]any); ok {
-					sb.addProperties(itemMap, prop.items.properties)
-				}
+	switch val := value.(type) {
+	case map[string]any:
+		sb.addProperties(val, prop.properties)
+	case []any:
+		if len(val) == 0 {
+			return
+		}
+		if prop.items == nil {
+			prop.items = &propertySchema{
+				types:      make(map[string]bool),
+				properties: make(map[string]*propertySchema),
 			}
 		}
+		for _, item := range val {
+			sb.addValue(prop.items, item)
+		}
 	}
 }
 
@@ -310,52 +572,39 @@ func (sb *schemaBuilder) propertiesToSchema(props map[string]*propertySchema) ma
 	result := make(map[string]any)
 
 	for name, prop := range props {
-		propSchema := make(map[string]any)
-
-		// Get types
-		types := make([]string, 0, len(prop.types))
-		for t := range prop.types {
-			types = append(types, t)
-		}
-		sort.Strings(types)
-
-		if len(types) == 1 {
-			propSchema["type"] = types[0]
-		} else if len(types) > 1 {
-			propSchema["type"] = types
-		}
+		result[name] = sb.propertyToSchema(prop)
+	}
 
-		// Handle nested object properties
-		if len(prop.properties) > 0 {
-			propSchema["properties"] = sb.propertiesToSchema(prop.properties)
-		}
+	return result
+}
 
-		// Handle array items
-		if prop.items != nil {
-			itemSchema := make(map[string]any)
-			itemTypes := make([]string, 0, len(prop.items.types))
-			for t := range prop.items.types {
-				itemTypes = append(itemTypes, t)
-			}
-			sort.Strings(itemTypes)
+func (sb *schemaBuilder) propertyToSchema(prop *propertySchema) map[string]any {
+	propSchema := make(map[string]any)
 
-			if len(itemTypes) == 1 {
-				itemSchema["type"] = itemTypes[0]
-			} else if len(itemTypes) > 1 {
-				itemSchema["type"] = itemTypes
-			}
+	// Get types
+	types := make([]string, 0, len(prop.types))
+	for t := range prop.types {
+		types = append(types, t)
+	}
+	sort.Strings(types)
 
-			if len(prop.items.properties) > 0 {
-				itemSchema["properties"] = sb.propertiesToSchema(prop.items.properties)
-			}
+	if len(types) == 1 {
+		propSchema["type"] = types[0]
+	} else if len(types) > 1 {
+		propSchema["type"] = types
+	}
 
-			propSchema["items"] = itemSchema
-		}
+	// Handle nested object properties
+	if len(prop.properties) > 0 {
+		propSchema["properties"] = sb.propertiesToSchema(prop.properties)
+	}
 
-		result[name] = propSchema
+	// Handle array items (recursively, for arrays of arrays)
+	if prop.items != nil {
+		propSchema["items"] = sb.propertyToSchema(prop.items)
 	}
 
-	return result
+	return propSchema
 }
 
 // inferType returns the JSON schema type for a value.
@@ -387,9 +636,9 @@ func roundTo2Decimals(f float64) float64 {
 	return float64(int(f*100+0.5)) / 100
 }
 
-// sortByValueDesc returns a new map sorted by value in descending order.
-// Note: Go maps are unordered, so this returns a new map for consistency.
-func sortByValueDesc(m map[string]float64) map[string]float64 {
+// sortByValueDesc returns the entries of m sorted by value in descending
+// order, then by key ascending for stability.
+func sortByValueDesc(m map[string]float64) FieldEmptiness {
 	// Create a sorted slice of keys
 	keys := make([]string, 0, len(m))
 	for k := range m {
@@ -404,10 +653,9 @@ func sortByValueDesc(m map[string]float64) map[string]float64 {
 		return keys[i] < keys[j]
 	})
 
-	// Create a new map (note: iteration order is still undefined in Go)
-	result := make(map[string]float64, len(m))
+	result := make(FieldEmptiness, 0, len(keys))
 	for _, k := range keys {
-		result[k] = m[k]
+		result = append(result, FieldEmptinessEntry{Field: k, Percent: m[k]})
 	}
 
 	return result
@@ -1,10 +1,13 @@
 package producer
 
 import (
+	"bufio"
 	"encoding/json"
+	"io"
 	"math"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -118,6 +121,17 @@ func TestGetFieldStatus(t *testing.T) {
 		}
 	})
 
+	t.Run("array whose first element is not an object", func(t *testing.T) {
+		obj := map[string]any{
+			"items": []any{nil, map[string]any{"id": float64(1)}},
+		}
+		allFields, present := getFieldStatus(obj, "")
+
+		if !allFields["items[].id"] || !present["items[].id"] {
+			t.Error("later object elements should still be analyzed")
+		}
+	})
+
 	t.Run("empty array", func(t *testing.T) {
 		obj := map[string]any{"items": []any{}}
 		allFields, present := getFieldStatus(obj, "")
@@ -169,7 +183,7 @@ func TestAnalyzeDataSingleRecord(t *testing.T) {
 	}
 
 	// All fields should be 0% empty
-	for field, emptiness := range result.FieldEmptiness {
+	for field, emptiness := range result.FieldEmptiness.Map() {
 		if emptiness != 0.0 {
 			t.Errorf("Field %s should be 0%% empty, got %.2f%%", field, emptiness)
 		}
@@ -203,7 +217,7 @@ func TestAnalyzeDataMissingFields(t *testing.T) {
 		t.Errorf("RecordCount = %d, want 3", result.RecordCount)
 	}
 
-	emptiness := result.FieldEmptiness
+	emptiness := result.FieldEmptiness.Map()
 
 	// name: present in all 3 records
 	if emptiness["name"] != 0.0 {
@@ -233,7 +247,7 @@ func TestAnalyzeDataEmptyValues(t *testing.T) {
 		t.Errorf("RecordCount = %d, want 3", result.RecordCount)
 	}
 
-	emptiness := result.FieldEmptiness
+	emptiness := result.FieldEmptiness.Map()
 
 	// name: all 3 have names
 	if emptiness["name"] != 0.0 {
@@ -263,7 +277,7 @@ func TestAnalyzeDataNestedObjects(t *testing.T) {
 		t.Errorf("RecordCount = %d, want 3", result.RecordCount)
 	}
 
-	emptiness := result.FieldEmptiness
+	emptiness := result.FieldEmptiness.Map()
 
 	// Check nested field paths exist
 	expectedFields := []string{"user", "user.name", "user.address", "user.address.city", "user.address.zip"}
@@ -301,7 +315,7 @@ func TestAnalyzeDataArrays(t *testing.T) {
 		t.Errorf("RecordCount = %d, want 3", result.RecordCount)
 	}
 
-	emptiness := result.FieldEmptiness
+	emptiness := result.FieldEmptiness.Map()
 
 	// items: 1 record has empty array = 33.33% empty
 	if !approxEqual(emptiness["items"], 33.33, 0.01) {
@@ -354,13 +368,13 @@ func TestAnalyzeDataSchemaSampling(t *testing.T) {
 	}
 
 	// But field_emptiness should have all fields (full scan)
-	if _, exists := result.FieldEmptiness["a"]; !exists {
+	if _, exists := result.FieldEmptiness.Get("a"); !exists {
 		t.Error("FieldEmptiness should have field 'a'")
 	}
-	if _, exists := result.FieldEmptiness["b"]; !exists {
+	if _, exists := result.FieldEmptiness.Get("b"); !exists {
 		t.Error("FieldEmptiness should have field 'b'")
 	}
-	if _, exists := result.FieldEmptiness["c"]; !exists {
+	if _, exists := result.FieldEmptiness.Get("c"); !exists {
 		t.Error("FieldEmptiness should have field 'c'")
 	}
 }
@@ -382,7 +396,7 @@ func TestAnalyzeDataMalformedJSON(t *testing.T) {
 	}
 
 	// Valid records should still be analyzed
-	emptiness := result.FieldEmptiness
+	emptiness := result.FieldEmptiness.Map()
 	if _, exists := emptiness["valid"]; !exists {
 		t.Error("FieldEmptiness should have field 'valid'")
 	}
@@ -432,8 +446,8 @@ func TestAnalyzeDataLargeFile(t *testing.T) {
 	}
 
 	// email: 50% empty (every other record)
-	if result.FieldEmptiness["email"] != 50.0 {
-		t.Errorf("email should be 50%% empty, got %.2f%%", result.FieldEmptiness["email"])
+	if got, _ := result.FieldEmptiness.Get("email"); got != 50.0 {
+		t.Errorf("email should be 50%% empty, got %.2f%%", got)
 	}
 }
 
@@ -459,3 +473,210 @@ func TestInferType(t *testing.T) {
 		}
 	}
 }
+
+// TestAnalyzeDataMaxLineBytes tests that over-long lines are skipped and
+// counted as truncated records rather than parse errors.
+func TestAnalyzeDataMaxLineBytes(t *testing.T) {
+	wide := `{"blob":"` + strings.Repeat("x", 200) + `"}`
+	path := filepath.Join(t.TempDir(), "wide.ndjson")
+	content := `{"id":1}` + "\n" + wide + "\n" + `{"id":2}` + "\n" + `not json` + "\n" + wide
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+
+	p := &Producer{}
+
+	opts := DefaultAnalysisOptions()
+	opts.MaxLineBytes = 100
+	result, err := p.analyzeData(path, opts)
+	if err != nil {
+		t.Fatalf("analyzeData failed: %v", err)
+	}
+	if result.RecordCount != 2 {
+		t.Errorf("RecordCount = %d, want 2", result.RecordCount)
+	}
+	if result.TruncatedRecords != 2 {
+		t.Errorf("TruncatedRecords = %d, want 2", result.TruncatedRecords)
+	}
+	if result.AnalysisErrors != 1 {
+		t.Errorf("AnalysisErrors = %d, want 1 (only the invalid JSON line)", result.AnalysisErrors)
+	}
+
+	// Raising the limit lets the wide records through.
+	opts.MaxLineBytes = 1024
+	result, err = p.analyzeData(path, opts)
+	if err != nil {
+		t.Fatalf("analyzeData failed: %v", err)
+	}
+	if result.RecordCount != 4 || result.TruncatedRecords != 0 {
+		t.Errorf("RecordCount = %d, TruncatedRecords = %d, want 4 and 0", result.RecordCount, result.TruncatedRecords)
+	}
+}
+
+// TestReadLine tests line splitting across buffer boundaries and endings.
+func TestReadLine(t *testing.T) {
+	long := strings.Repeat("a", 40) // longer than the 16-byte reader buffer
+	r := bufio.NewReaderSize(strings.NewReader(long+"\r\nshort\n"+long+"x\nlast"), 16)
+
+	want := []struct {
+		line string
+		err  error
+	}{
+		{long + "\r\n", nil},
+		{"short\n", nil},
+		{"", bufio.ErrTooLong},
+		{"last", nil},
+		{"", io.EOF},
+	}
+	for i, w := range want {
+		line, err := readLine(r, 40)
+		if string(line) != w.line || err != w.err {
+			t.Errorf("line %d: got (%q, %v), want (%q, %v)", i, line, err, w.line, w.err)
+		}
+	}
+}
+
+// TestAnalyzeDataErrorSamples tests that bad lines are returned
+// structurally, capped at MaxErrorSamples.
+func TestAnalyzeDataErrorSamples(t *testing.T) {
+	p := &Producer{}
+
+	result, err := p.analyzeData(testdataPath("malformed.ndjson"), DefaultAnalysisOptions())
+	if err != nil {
+		t.Fatalf("analyzeData failed: %v", err)
+	}
+	if len(result.ErrorSamples) != result.AnalysisErrors {
+		t.Fatalf("expected one sample per parse error, got %d samples for %d errors", len(result.ErrorSamples), result.AnalysisErrors)
+	}
+	for _, sample := range result.ErrorSamples {
+		if sample.LineNumber < 1 || sample.Snippet == "" || sample.Message == "" {
+			t.Errorf("incomplete sample: %+v", sample)
+		}
+	}
+
+	// Many bad lines: capped, in file order, with truncated snippets.
+	var b strings.Builder
+	for range 20 {
+		b.WriteString("{bad " + strings.Repeat("é", 300) + "\n")
+	}
+	path := filepath.Join(t.TempDir(), "bad.ndjson")
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+
+	opts := DefaultAnalysisOptions()
+	opts.MaxErrorSamples = 3
+	result, err = p.analyzeData(path, opts)
+	if err != nil {
+		t.Fatalf("analyzeData failed: %v", err)
+	}
+	if result.AnalysisErrors != 20 || len(result.ErrorSamples) != 3 {
+		t.Fatalf("AnalysisErrors = %d, samples = %d, want 20 and 3", result.AnalysisErrors, len(result.ErrorSamples))
+	}
+	for i, sample := range result.ErrorSamples {
+		if sample.LineNumber != i+1 {
+			t.Errorf("sample %d LineNumber = %d, want %d", i, sample.LineNumber, i+1)
+		}
+		if n := len([]rune(sample.Snippet)); n != errorSnippetRunes {
+			t.Errorf("sample %d snippet has %d runes, want %d", i, n, errorSnippetRunes)
+		}
+	}
+
+	opts.MaxErrorSamples = -1
+	if result, _ = p.analyzeData(path, opts); len(result.ErrorSamples) != 0 {
+		t.Errorf("negative MaxErrorSamples should disable samples, got %d", len(result.ErrorSamples))
+	}
+}
+
+// TestAnalyzeDataArrayUnion tests that every array element contributes to
+// the schema and that element fields are counted per element: element 1
+// has fields a,b and element 2 has b,c.
+func TestAnalyzeDataArrayUnion(t *testing.T) {
+	p := &Producer{}
+	result, err := p.analyzeData(testdataPath("array_union.ndjson"), DefaultAnalysisOptions())
+	if err != nil {
+		t.Fatalf("analyzeData failed: %v", err)
+	}
+
+	items := result.Schema["properties"].(map[string]any)["items"].(map[string]any)["items"].(map[string]any)
+	itemProps := items["properties"].(map[string]any)
+	for _, field := range []string{"a", "b", "c", "tags"} {
+		if _, ok := itemProps[field]; !ok {
+			t.Errorf("items schema should contain %q, got %v", field, itemProps)
+		}
+	}
+	if got, ok := items["type"].([]string); !ok || len(got) != 2 || got[0] != "null" || got[1] != "object" {
+		t.Errorf("items type should union null and object, got %v", items["type"])
+	}
+
+	// Arrays of arrays merge every inner element too
+	tagItems := itemProps["tags"].(map[string]any)["items"].(map[string]any)
+	inner, ok := tagItems["items"].(map[string]any)
+	if !ok {
+		t.Fatalf("tags items should describe the inner arrays, got %v", tagItems)
+	}
+	if got, ok := inner["type"].([]string); !ok || len(got) != 2 || got[0] != "number" || got[1] != "string" {
+		t.Errorf("inner array type should union number and string, got %v", inner["type"])
+	}
+
+	// 4 elements in total (one of them null)
+	emptiness := result.FieldEmptiness.Map()
+	want := map[string]float64{
+		"items[].a": 75, // 1 of 4 elements
+		"items[].b": 25, // 3 of 4
+		"items[].c": 50, // 2 of 4 (false is a value)
+	}
+	for field, pct := range want {
+		if got, ok := emptiness[field]; !ok || !approxEqual(got, pct, 0.01) {
+			t.Errorf("%s should be %.2f%% empty, got %.2f%% (present=%v)", field, pct, got, ok)
+		}
+	}
+}
+
+// TestFieldEmptinessOrdering tests that emptiness is ordered highest-first
+// (ties by name) and marshals as an ordered JSON object.
+func TestFieldEmptinessOrdering(t *testing.T) {
+	fe := sortByValueDesc(map[string]float64{"b": 10, "a": 10, "z": 0, "m": 99.5})
+
+	want := FieldEmptiness{{"m", 99.5}, {"a", 10}, {"b", 10}, {"z", 0}}
+	if len(fe) != len(want) {
+		t.Fatalf("got %v, want %v", fe, want)
+	}
+	for i := range want {
+		if fe[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, fe[i], want[i])
+		}
+	}
+
+	for range 5 { // map iteration is randomized; output must not be
+		data, err := json.Marshal(AnalysisResult{FieldEmptiness: fe})
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		if !strings.Contains(string(data), `"field_emptiness":{"m":99.5,"a":10,"b":10,"z":0}`) {
+			t.Fatalf("unexpected JSON %s", data)
+		}
+	}
+
+	var decoded FieldEmptiness
+	if err := json.Unmarshal([]byte(`{"z":0,"b":10,"m":99.5,"a":10}`), &decoded); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if decoded[0].Field != "m" || decoded[3].Field != "z" {
+		t.Errorf("decoded emptiness should be re-sorted, got %v", decoded)
+	}
+
+	if got, ok := fe.Get("a"); !ok || got != 10 {
+		t.Errorf("Get(a) = %v, %v", got, ok)
+	}
+	if _, ok := fe.Get("missing"); ok {
+		t.Error("Get(missing) should report false")
+	}
+	if m := fe.Map(); len(m) != 4 || m["m"] != 99.5 {
+		t.Errorf("Map() = %v", m)
+	}
+
+	if data, _ := json.Marshal(FieldEmptiness{}); string(data) != "{}" {
+		t.Errorf("empty emptiness should marshal as {}, got %s", data)
+	}
+}
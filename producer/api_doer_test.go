@@ -0,0 +1,34 @@
+package producer
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/helix-tools/sdk-go/v2/helixtest"
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+func TestNewProducerWithAPI(t *testing.T) {
+	api := helixtest.NewMockAPI()
+	api.Handle(http.MethodGet, "/v1/datasets", http.StatusOK, types.DatasetListResponse{
+		Datasets:   []types.Dataset{{ID: "ds-1"}, {ID: "ds-2"}},
+		Pagination: &types.MarketplacePagination{TotalPages: 1},
+	})
+	api.Handle(http.MethodGet, "/v1/datasets/missing", http.StatusNotFound, "not found")
+
+	p := NewProducerWithAPI(types.Config{CustomerID: "company-1"}, api)
+
+	datasets, err := p.ListMyDatasets(context.Background())
+	if err != nil || len(datasets) != 2 {
+		t.Fatalf("ListMyDatasets = %+v, %v", datasets, err)
+	}
+	if got := api.Calls()[0].Path; got != "/v1/datasets?page=1&per_page=100&producer_id=company-1" {
+		t.Errorf("list path = %q", got)
+	}
+
+	// A mocked non-2xx response surfaces as *APIError, as from the API.
+	if _, err := p.GetDatasetStats(context.Background(), "missing"); !isNotFound(err) {
+		t.Errorf("GetDatasetStats err = %v, want a not-found *APIError", err)
+	}
+}
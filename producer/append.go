@@ -0,0 +1,174 @@
+package producer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+// appendAttempts bounds how often AppendToDataset moves to the next part
+// index, or re-reads the manifest, after losing a race with another append.
+const appendAttempts = 5
+
+// AppendToDataset adds the NDJSON records in filePath to datasetID, a
+// sharded dataset (see UploadShardedDataset). Stored objects are immutable,
+// so the records are compressed, encrypted (under the dataset's kms_key_id
+// when it has one) and stored as a new part, which is then appended to the
+// manifest. The dataset's record_count and part_count metadata grow
+// accordingly, and last_append describes the new part (types.AppendRange).
+//
+// Subscribers are notified by the platform when the part is stored. The
+// notification's s3_key is the new part, so consumers can download only the
+// appended records; the part's object metadata carries its record count,
+// along with the S3Metadata the dataset's last part was stored with.
+//
+// Concurrent appends to one dataset are safe for the data. Each part is
+// created with If-None-Match, so appends never overwrite each other's part,
+// and the manifest is replaced with If-Match on the ETag it was read with,
+// re-reading it when another append got there first. Every appended part is
+// listed exactly once, in the order the manifest updates landed. The
+// metadata update that follows is last-writer-wins: under concurrency
+// record_count and last_append can trail the manifest, which is
+// authoritative. Appending while UploadShardedDataset replaces the same
+// dataset is not supported.
+func (p *Producer) AppendToDataset(ctx context.Context, datasetID, filePath string) error {
+	if datasetID == "" {
+		return fmt.Errorf("dataset ID is required")
+	}
+	if p.s3Client == nil || p.BucketName == "" {
+		return fmt.Errorf("appending needs an S3 client and bucket")
+	}
+
+	dataset := &types.Dataset{}
+	path := fmt.Sprintf("/v1/datasets/%s", url.PathEscape(datasetID))
+	if err := p.makeAPIRequest(ctx, http.MethodGet, path, nil, dataset); err != nil {
+		return fmt.Errorf("failed to get dataset %s: %w", datasetID, err)
+	}
+	manifest, etag, err := p.readManifest(ctx, dataset.Name)
+	if err != nil {
+		return err
+	}
+
+	opts := NewUploadOptions(dataset.Name)
+	opts.KMSKeyID, _ = dataset.Metadata["kms_key_id"].(string)
+	if class, ok := dataset.Metadata["storage_class"].(string); ok {
+		opts.StorageClass = s3types.StorageClass(class)
+	}
+	opts.S3Metadata = p.partMetadata(ctx, manifest)
+
+	analysis, err := p.analyzeData(filePath, DefaultAnalysisOptions())
+	if err != nil {
+		return err
+	}
+	processed, err := p.processFile(ctx, filePath, opts)
+	if err != nil {
+		return err
+	}
+
+	part, err := p.putAppendedPart(ctx, dataset.Name, len(manifest.Parts), processed.Data, analysis.RecordCount, opts)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 1; ; attempt++ {
+		manifest.Parts = append(manifest.Parts, part)
+		manifest.TotalSizeBytes += part.SizeBytes
+		err := p.putManifest(ctx, manifest, etag)
+		if err == nil {
+			break
+		}
+		if s3StatusCode(err) != http.StatusPreconditionFailed || attempt == appendAttempts {
+			return fmt.Errorf("part s3://%s/%s stored but not added to the manifest: %w", p.BucketName, part.S3Key, err)
+		}
+		if manifest, etag, err = p.readManifest(ctx, dataset.Name); err != nil {
+			return fmt.Errorf("part s3://%s/%s stored but not added to the manifest: %w", p.BucketName, part.S3Key, err)
+		}
+	}
+	fmt.Printf("✅ Appended %d records to dataset %s as %s\n", analysis.RecordCount, datasetID, part.S3Key)
+
+	appended := types.AppendRange{
+		PartIndex:   len(manifest.Parts) - 1,
+		S3Key:       part.S3Key,
+		RecordCount: part.RecordCount,
+		SizeBytes:   part.SizeBytes,
+		AppendedAt:  time.Now().UTC().Format(time.RFC3339),
+	}
+	metadata := maps.Clone(dataset.Metadata)
+	if metadata == nil {
+		metadata = make(map[string]any)
+	}
+	recordCount, _ := metadata["record_count"].(float64)
+	metadata["record_count"] = int(recordCount) + appended.RecordCount
+	metadata["part_count"] = len(manifest.Parts)
+	metadata["last_append"] = appended
+	if _, err := p.UpdateDataset(ctx, datasetID, types.DatasetUpdateInput{Metadata: metadata}); err != nil {
+		return fmt.Errorf("records appended to dataset %s but its record count could not be updated: %w", datasetID, err)
+	}
+	return nil
+}
+
+// partMetadata returns the user metadata of the manifest's last part, less
+// its record-count, so an appended part carries the S3Metadata the dataset
+// was uploaded with. It returns nil, with a warning when the part cannot be
+// read, as the metadata is not needed to append.
+func (p *Producer) partMetadata(ctx context.Context, manifest *types.Manifest) map[string]string {
+	if len(manifest.Parts) == 0 {
+		return nil
+	}
+	key := manifest.Parts[len(manifest.Parts)-1].S3Key
+	head, err := p.s3Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(p.BucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		fmt.Printf("Warning: Failed to read the metadata of s3://%s/%s; the new part will not carry it: %v\n", p.BucketName, key, err)
+		return nil
+	}
+	metadata := maps.Clone(head.Metadata)
+	delete(metadata, "record-count")
+	return metadata
+}
+
+// putAppendedPart stores data as a new part of datasetName at the first
+// free index from index on, never replacing an existing part.
+func (p *Producer) putAppendedPart(ctx context.Context, datasetName string, index int, data []byte, recordCount int, opts UploadOptions) (types.ManifestPart, error) {
+	metadata := opts.s3Metadata()
+	if metadata == nil {
+		metadata = make(map[string]string, 1)
+	}
+	metadata["record-count"] = strconv.Itoa(recordCount)
+
+	for attempt := 1; ; attempt++ {
+		part := types.NewManifestPart(ShardKey(datasetName, index), data)
+		part.RecordCount = recordCount
+
+		fmt.Printf("📤 Uploading %d bytes to s3://%s/%s...\n", part.SizeBytes, p.BucketName, part.S3Key)
+		_, err := p.s3Client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:        aws.String(p.BucketName),
+			Key:           aws.String(part.S3Key),
+			Body:          bytes.NewReader(data),
+			ContentLength: aws.Int64(part.SizeBytes),
+			ContentType:   aws.String("application/octet-stream"),
+			StorageClass:  opts.storageClass(),
+			Metadata:      metadata,
+			IfNoneMatch:   aws.String("*"),
+		})
+		if err == nil {
+			return part, nil
+		}
+		if s3StatusCode(err) != http.StatusPreconditionFailed || attempt == appendAttempts {
+			return types.ManifestPart{}, fmt.Errorf("failed to put s3://%s/%s: %w", p.BucketName, part.S3Key, err)
+		}
+		index++
+	}
+}
@@ -0,0 +1,108 @@
+package producer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/helix-tools/sdk-go/v2/helixtest"
+	"github.com/helix-tools/sdk-go/v2/internal/awsfake"
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+// racingS3 runs beforeManifestPut once, just before the first conditional
+// manifest write, to stand in for a concurrent append.
+type racingS3 struct {
+	*awsfake.S3
+	beforeManifestPut func()
+}
+
+func (r *racingS3) PutObject(ctx context.Context, in *s3.PutObjectInput, opts ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	if in.IfMatch != nil && strings.HasSuffix(aws.ToString(in.Key), "/manifest.json") && r.beforeManifestPut != nil {
+		race := r.beforeManifestPut
+		r.beforeManifestPut = nil
+		race()
+	}
+	return r.S3.PutObject(ctx, in, opts...)
+}
+
+func newAppendProducer(t *testing.T, store s3API) (*Producer, *helixtest.MockAPI) {
+	t.Helper()
+
+	api := helixtest.NewMockAPI()
+	api.Handle(http.MethodGet, "/v1/datasets/ds-1", http.StatusOK, types.Dataset{
+		ID:       "ds-1",
+		Name:     "sales",
+		Metadata: map[string]any{"record_count": 2, "part_count": 1, "encryption_enabled": true},
+	})
+	api.Handle(http.MethodPatch, "/v1/datasets/ds-1", http.StatusOK, types.Dataset{ID: "ds-1"})
+	p := NewProducerWithAPI(types.Config{CustomerID: "company-1", BucketName: "bucket", KMSKeyID: "key-1"}, api)
+	p.kmsClient = awsfake.NewKMS()
+	p.s3Client = store
+	return p, api
+}
+
+func TestAppendToDataset(t *testing.T) {
+	ctx := context.Background()
+	store := &racingS3{S3: awsfake.NewS3()}
+	p, api := newAppendProducer(t, store)
+
+	first := types.NewManifestPart(ShardKey("sales", 0), []byte("first"))
+	if _, err := p.WriteDatasetManifest(ctx, "sales", []types.ManifestPart{first}); err != nil {
+		t.Fatal(err)
+	}
+	// Another append has stored part 1 but not yet listed it; it lists it
+	// just before this append updates the manifest.
+	concurrent := types.NewManifestPart(ShardKey("sales", 1), []byte("concurrent"))
+	_, _ = store.S3.PutObject(ctx, &s3.PutObjectInput{Bucket: aws.String("bucket"), Key: aws.String(concurrent.S3Key)})
+	store.beforeManifestPut = func() {
+		if _, err := p.WriteDatasetManifest(ctx, "sales", []types.ManifestPart{first, concurrent}); err != nil {
+			t.Error(err)
+		}
+	}
+
+	if err := p.AppendToDataset(ctx, "ds-1", writeUploadFile(t)); err != nil {
+		t.Fatalf("AppendToDataset: %v", err)
+	}
+
+	manifest, _, err := p.readManifest(ctx, "sales")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Parts) != 3 || manifest.Parts[1].S3Key != concurrent.S3Key || manifest.Parts[2].S3Key != ShardKey("sales", 2) {
+		t.Fatalf("manifest parts = %+v, want part 0, the concurrent part 1, then this append's part 2", manifest.Parts)
+	}
+	appended := manifest.Parts[2]
+	stored, ok := store.Object("bucket", appended.S3Key)
+	if !ok || appended.Verify(stored) != nil || appended.RecordCount != 2 {
+		t.Errorf("appended part %+v not stored as listed", appended)
+	}
+
+	calls := api.Calls()
+	patch := calls[len(calls)-1]
+	var update struct {
+		Metadata struct {
+			RecordCount int               `json:"record_count"`
+			PartCount   int               `json:"part_count"`
+			LastAppend  types.AppendRange `json:"last_append"`
+		} `json:"metadata"`
+	}
+	if patch.Method != http.MethodPatch || json.Unmarshal(patch.Body, &update) != nil {
+		t.Fatalf("last call = %s %s, want the metadata PATCH", patch.Method, patch.Path)
+	}
+	if got := update.Metadata; got.RecordCount != 4 || got.PartCount != 3 || got.LastAppend.PartIndex != 2 || got.LastAppend.S3Key != appended.S3Key {
+		t.Errorf("metadata update = %+v, want 4 records in 3 parts, last append part 2", got)
+	}
+}
+
+func TestAppendToDatasetNeedsManifest(t *testing.T) {
+	p, _ := newAppendProducer(t, awsfake.NewS3())
+	err := p.AppendToDataset(context.Background(), "ds-1", writeUploadFile(t))
+	if err == nil || !strings.Contains(err.Error(), "no manifest") {
+		t.Errorf("AppendToDataset = %v, want a no-manifest error", err)
+	}
+}
@@ -0,0 +1,70 @@
+package producer
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/helix-tools/sdk-go/v2/internal/ratelimit"
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+// autoApproveRate caps PollAndAutoApprove's approvals per second, on top of
+// any Config.RequestsPerSecond limit.
+const autoApproveRate = 2
+
+// ErrAutoApproveRunning is returned by PollAndAutoApprove when an earlier
+// call on the same Producer has not finished yet.
+var ErrAutoApproveRunning = errors.New("auto-approve is already running on this producer")
+
+// PollAndAutoApprove lists pending subscription requests and approves those
+// policy accepts, with the notes it returns. Requests policy declines are
+// left pending for a manual decision.
+//
+// It is meant to run on a schedule: approvals are paced, overlapping calls
+// on one Producer return ErrAutoApproveRunning instead of racing, and a
+// request decided elsewhere since the listing (409) is skipped. A failed
+// approval does not stop the run; the failures are joined into the returned
+// error.
+func (p *Producer) PollAndAutoApprove(ctx context.Context, policy func(types.SubscriptionRequest) (approve bool, notes string)) error {
+	if !p.autoApproveMu.TryLock() {
+		return ErrAutoApproveRunning
+	}
+	defer p.autoApproveMu.Unlock()
+
+	pending, err := p.ListSubscriptionRequests(ctx, types.SubscriptionRequestStatusPending)
+	if err != nil {
+		return fmt.Errorf("failed to list pending subscription requests: %w", err)
+	}
+
+	limiter := ratelimit.New(autoApproveRate, 1)
+	var errs []error
+	for _, request := range pending {
+		if request.Status != "" && request.Status != types.SubscriptionRequestStatusPending {
+			continue
+		}
+		approve, notes := policy(request)
+		if !approve {
+			continue
+		}
+		if err := limiter.Wait(ctx); err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+
+		var opts *types.ApproveSubscriptionRequestOptions
+		if notes != "" {
+			opts = &types.ApproveSubscriptionRequestOptions{Notes: &notes}
+		}
+		requestID := cmp.Or(request.RequestID, request.ID)
+		if _, err := p.approveSubscriptionRequest(ctx, requestID, opts); err != nil {
+			var apiErr *APIError
+			if errors.As(err, &apiErr) && apiErr.IsConflict() {
+				continue
+			}
+			errs = append(errs, fmt.Errorf("failed to approve subscription request %s: %w", requestID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,56 @@
+package producer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/helix-tools/sdk-go/v2/helixtest"
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+func TestPollAndAutoApprove(t *testing.T) {
+	api := helixtest.NewMockAPI()
+	api.Handle(http.MethodGet, "/v1/producers/subscription-requests", http.StatusOK, types.SubscriptionRequestsResponse{
+		Requests: []types.SubscriptionRequest{
+			{RequestID: "req-basic", Tier: "basic", Status: "pending"},
+			{RequestID: "req-premium", Tier: "premium", Status: "pending"},
+			{RequestID: "req-taken", Tier: "basic", Status: "pending"},
+			{RequestID: "req-broken", Tier: "basic", Status: "pending"},
+		},
+	})
+	api.Handle(http.MethodPost, "/v1/subscription-requests/req-basic", http.StatusOK, types.SubscriptionRequest{RequestID: "req-basic", Status: "approved"})
+	api.Handle(http.MethodPost, "/v1/subscription-requests/req-taken", http.StatusConflict, map[string]string{"error": "already approved"})
+	api.Handle(http.MethodPost, "/v1/subscription-requests/req-broken", http.StatusInternalServerError, map[string]string{"error": "boom"})
+	p := NewProducerWithAPI(types.Config{CustomerID: "company-1"}, api)
+
+	err := p.PollAndAutoApprove(context.Background(), func(r types.SubscriptionRequest) (bool, string) {
+		return r.Tier == "basic", "open data program"
+	})
+	if err == nil || !strings.Contains(err.Error(), "req-broken") || strings.Contains(err.Error(), "req-taken") {
+		t.Errorf("err = %v, want only the req-broken failure", err)
+	}
+
+	var approved []string
+	for _, call := range api.Calls() {
+		if call.Method == http.MethodPost {
+			approved = append(approved, strings.TrimPrefix(call.Path, "/v1/subscription-requests/"))
+		}
+	}
+	if strings.Join(approved, ",") != "req-basic,req-taken,req-broken" {
+		t.Errorf("approved %v; the premium request should be left pending", approved)
+	}
+}
+
+func TestPollAndAutoApproveOverlap(t *testing.T) {
+	p := NewProducerWithAPI(types.Config{CustomerID: "company-1"}, helixtest.NewMockAPI())
+	p.autoApproveMu.Lock()
+	defer p.autoApproveMu.Unlock()
+
+	err := p.PollAndAutoApprove(context.Background(), func(types.SubscriptionRequest) (bool, string) { return true, "" })
+	if !errors.Is(err, ErrAutoApproveRunning) {
+		t.Errorf("err = %v, want ErrAutoApproveRunning", err)
+	}
+}
@@ -0,0 +1,78 @@
+package producer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/helix-tools/sdk-go/v2/internal/awsfake"
+)
+
+// openEnvelope reverses encryptData:
+// [4-byte key length][wrapped data key][16-byte IV][16-byte tag][ciphertext].
+func openEnvelope(t *testing.T, fakeKMS *awsfake.KMS, data []byte) []byte {
+	t.Helper()
+
+	keyLen := binary.BigEndian.Uint32(data)
+	rest := data[4:]
+	wrapped, rest := rest[:keyLen], rest[keyLen:]
+	iv, tag, ciphertext := rest[:16], rest[16:32], rest[32:]
+
+	key, err := fakeKMS.Decrypt(context.Background(), &kms.DecryptInput{CiphertextBlob: wrapped})
+	if err != nil {
+		t.Fatalf("unwrap data key: %v", err)
+	}
+	block, err := aes.NewCipher(key.Plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := gcm.Open(nil, iv, append(bytes.Clone(ciphertext), tag...), nil)
+	if err != nil {
+		t.Fatalf("open envelope: %v", err)
+	}
+	return plaintext
+}
+
+// TestUploadDatasetEncryptedWithFakes runs an encrypted, compressed upload
+// against the in-memory KMS and S3 fakes and recovers the original file
+// from the uploaded bytes.
+func TestUploadDatasetEncryptedWithFakes(t *testing.T) {
+	srv := newUploadServer(t)
+	p := srv.producer()
+	fakeKMS := awsfake.NewKMS()
+	p.kmsClient = fakeKMS
+	p.s3Client = awsfake.NewS3()
+
+	path := writeUploadFile(t)
+	if _, err := p.UploadDataset(context.Background(), path, NewUploadOptions("catalog-check")); err != nil {
+		t.Fatalf("UploadDataset: %v", err)
+	}
+
+	if encrypts, _ := fakeKMS.Calls(); encrypts != 1 {
+		t.Errorf("KMS Encrypt calls = %d, want 1", encrypts)
+	}
+
+	gr, err := gzip.NewReader(bytes.NewReader(openEnvelope(t, fakeKMS, srv.uploaded)))
+	if err != nil {
+		t.Fatalf("uploaded data is not gzip after decryption: %v", err)
+	}
+	got, err := io.ReadAll(gr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := os.ReadFile(path)
+	if !bytes.Equal(got, want) {
+		t.Errorf("round-tripped data = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,95 @@
+package producer
+
+import (
+	"context"
+	"sync"
+
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+// bulkRequestConcurrency bounds how many subscription requests
+// BulkApproveRequests and BulkRejectRequests process at once.
+const bulkRequestConcurrency = 8
+
+// BulkApproveRequests approves many subscription requests concurrently,
+// attaching notes (when non-empty) to each approval.
+//
+// Every distinct request ID appears in exactly one of the returned maps: the
+// first holds the subscription created by each successful approval (nil if
+// the API did not return one), the second each failed request's error.
+// Requests not started before ctx ends fail with ctx.Err().
+func (p *Producer) BulkApproveRequests(ctx context.Context, requestIDs []string, notes string) (map[string]*types.Subscription, map[string]error) {
+	var opts *types.ApproveSubscriptionRequestOptions
+	if notes != "" {
+		opts = &types.ApproveSubscriptionRequestOptions{Notes: &notes}
+	}
+	return runBulkRequests(ctx, requestIDs, func(id string) (*types.Subscription, error) {
+		response, err := p.approveSubscriptionRequest(ctx, id, opts)
+		if err != nil {
+			return nil, err
+		}
+		return response.Subscription, nil
+	})
+}
+
+// BulkRejectRequests rejects many subscription requests concurrently with the
+// same reason. Results are reported as in BulkApproveRequests, with the
+// updated request in place of the subscription.
+func (p *Producer) BulkRejectRequests(ctx context.Context, requestIDs []string, reason string) (map[string]*types.SubscriptionRequest, map[string]error) {
+	return runBulkRequests(ctx, requestIDs, func(id string) (*types.SubscriptionRequest, error) {
+		return p.RejectSubscriptionRequest(ctx, id, reason)
+	})
+}
+
+// runBulkRequests calls do for each distinct ID with up to
+// bulkRequestConcurrency workers and splits the outcomes into results and
+// errors.
+func runBulkRequests[T any](ctx context.Context, ids []string, do func(id string) (T, error)) (map[string]T, map[string]error) {
+	results := make(map[string]T, len(ids))
+	errs := make(map[string]error)
+	var mu sync.Mutex
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for range min(bulkRequestConcurrency, len(ids)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range jobs {
+				result, err := do(id)
+				mu.Lock()
+				if err != nil {
+					errs[id] = err
+				} else {
+					results[id] = result
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	seen := make(map[string]bool, len(ids))
+feed:
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		select {
+		case jobs <- id:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, id := range ids {
+		_, ok := results[id]
+		if _, failed := errs[id]; !ok && !failed {
+			errs[id] = ctx.Err()
+		}
+	}
+
+	return results, errs
+}
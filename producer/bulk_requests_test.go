@@ -0,0 +1,67 @@
+package producer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/helix-tools/sdk-go/v2/helixtest"
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+func TestBulkApproveRequests(t *testing.T) {
+	api := helixtest.NewMockAPI()
+	api.Handle(http.MethodPost, "/v1/subscription-requests/req-1", http.StatusOK, types.ApproveRequestResponse{
+		Request:      types.SubscriptionRequest{ID: "req-1", Status: "approved"},
+		Subscription: &types.Subscription{ID: "sub-1"},
+	})
+	// A bare request (no subscription in the response) still counts as approved.
+	api.Handle(http.MethodPost, "/v1/subscription-requests/req-2", http.StatusOK, types.SubscriptionRequest{ID: "req-2", Status: "approved"})
+	api.Handle(http.MethodPost, "/v1/subscription-requests/req-3", http.StatusConflict, map[string]string{"error": "already approved"})
+	p := NewProducerWithAPI(types.Config{CustomerID: "company-1"}, api)
+
+	subs, errs := p.BulkApproveRequests(context.Background(), []string{"req-1", "req-2", "req-3", "req-1"}, "welcome")
+
+	if len(subs) != 2 || subs["req-1"] == nil || subs["req-1"].ID != "sub-1" || subs["req-2"] != nil {
+		t.Errorf("subscriptions = %+v", subs)
+	}
+	if _, ok := subs["req-2"]; !ok {
+		t.Error("req-2 missing from the approved results")
+	}
+	if len(errs) != 1 || errs["req-3"] == nil {
+		t.Errorf("errors = %v, want only req-3", errs)
+	}
+
+	calls := api.Calls()
+	if len(calls) != 3 {
+		t.Fatalf("made %d calls, want 3 (duplicate IDs are approved once)", len(calls))
+	}
+	var payload map[string]string
+	if err := json.Unmarshal(calls[0].Body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload["action"] != "approve" || payload["notes"] != "welcome" {
+		t.Errorf("payload = %v", payload)
+	}
+}
+
+func TestBulkRejectRequests(t *testing.T) {
+	api := helixtest.NewMockAPI()
+	api.Handle(http.MethodPost, "/v1/subscription-requests/req-1", http.StatusOK, types.SubscriptionRequest{ID: "req-1", Status: "rejected"})
+	p := NewProducerWithAPI(types.Config{CustomerID: "company-1"}, api)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	reqs, errs := p.BulkRejectRequests(ctx, []string{"req-1"}, "not eligible")
+	if reqs["req-1"] == nil || reqs["req-1"].Status != "rejected" || len(errs) != 0 {
+		t.Fatalf("results = %+v, errors = %v", reqs, errs)
+	}
+
+	cancel()
+	_, errs = p.BulkRejectRequests(ctx, []string{"req-1", "req-2"}, "")
+	for _, id := range []string{"req-1", "req-2"} {
+		if errs[id] == nil {
+			t.Errorf("errs[%s] = nil after cancellation", id)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package producer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// GetCategories returns the dataset categories the catalog knows, for
+// picking UploadOptions.Category. The list is kept on the producer, which
+// then warns about uploads whose category is not in it (see
+// UploadOptions.StrictCategory); call it again to refresh.
+//
+// GET /v1/datasets/categories
+func (p *Producer) GetCategories(ctx context.Context) ([]string, error) {
+	var response struct {
+		Categories []string `json:"categories"`
+	}
+	if err := p.makeAPIRequest(ctx, http.MethodGet, "/v1/datasets/categories", nil, &response); err != nil {
+		return nil, fmt.Errorf("failed to get categories: %w", err)
+	}
+
+	p.categoriesMu.Lock()
+	p.categories = slices.Clone(response.Categories)
+	p.categoriesMu.Unlock()
+
+	return response.Categories, nil
+}
+
+// checkCategory checks opts.Category against the known categories: a
+// warning for an unknown one, or an error with opts.StrictCategory, which
+// also fetches the categories when the producer has none yet. An empty
+// list from the API means categories are open-ended and anything goes.
+func (p *Producer) checkCategory(ctx context.Context, opts UploadOptions) error {
+	p.categoriesMu.Lock()
+	known := p.categories
+	p.categoriesMu.Unlock()
+
+	if known == nil {
+		if !opts.StrictCategory {
+			return nil
+		}
+		var err error
+		if known, err = p.GetCategories(ctx); err != nil {
+			return fmt.Errorf("cannot check category %q: %w", opts.Category, err)
+		}
+	}
+	if len(known) == 0 || slices.Contains(known, opts.Category) {
+		return nil
+	}
+
+	if opts.StrictCategory {
+		return fmt.Errorf("unknown category %q: must be one of %s", opts.Category, strings.Join(known, ", "))
+	}
+	fmt.Printf("Warning: category %q is not one of the known categories (%s)\n", opts.Category, strings.Join(known, ", "))
+	return nil
+}
@@ -0,0 +1,58 @@
+package producer
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/helix-tools/sdk-go/v2/helixtest"
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+func TestCheckCategory(t *testing.T) {
+	ctx := context.Background()
+	api := helixtest.NewMockAPI()
+	api.Handle(http.MethodGet, "/v1/datasets/categories", http.StatusOK, map[string]any{
+		"categories": []string{"finance", "general", "test"},
+	})
+	p := NewProducerWithAPI(types.Config{CustomerID: "company-1"}, api)
+
+	lenient := UploadOptions{Category: "tst"}
+	strict := UploadOptions{Category: "tst", StrictCategory: true}
+
+	if err := p.checkCategory(ctx, lenient); err != nil || len(api.Calls()) != 0 {
+		t.Errorf("lenient check before GetCategories = %v after %d calls, want nil and no calls", err, len(api.Calls()))
+	}
+	if err := p.checkCategory(ctx, strict); err == nil || !strings.Contains(err.Error(), `unknown category "tst"`) {
+		t.Errorf("strict check = %v, want an unknown-category error", err)
+	}
+	if err := p.checkCategory(ctx, lenient); err != nil {
+		t.Errorf("lenient check of an unknown category = %v, want only a warning", err)
+	}
+	if err := p.checkCategory(ctx, UploadOptions{Category: "test", StrictCategory: true}); err != nil {
+		t.Errorf("strict check of a known category = %v", err)
+	}
+	if calls := api.Calls(); len(calls) != 1 {
+		t.Errorf("calls = %v, want the categories fetched once", calls)
+	}
+
+	// An empty list means the catalog takes any category.
+	api.Handle(http.MethodGet, "/v1/datasets/categories", http.StatusOK, map[string]any{"categories": []string{}})
+	if _, err := p.GetCategories(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.checkCategory(ctx, strict); err != nil {
+		t.Errorf("strict check against open-ended categories = %v", err)
+	}
+}
+
+func TestCheckCategoryFetchFailure(t *testing.T) {
+	api := helixtest.NewMockAPI()
+	api.Handle(http.MethodGet, "/v1/datasets/categories", http.StatusInternalServerError, "boom")
+	p := NewProducerWithAPI(types.Config{CustomerID: "company-1"}, api)
+
+	if err := p.checkCategory(context.Background(), UploadOptions{Category: "finance", StrictCategory: true}); err == nil {
+		t.Error("strict check without the category list should fail")
+	}
+}
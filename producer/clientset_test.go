@@ -0,0 +1,59 @@
+package producer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/helix-tools/sdk-go/v2/clientset"
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+// TestNewProducerFromClientSet pins that a producer built on a shared
+// ClientSet still looks up its bucket and KMS key, through the set's SSM
+// client.
+func TestNewProducerFromClientSet(t *testing.T) {
+	var names []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if serveDescribeKey(w, r) {
+			return
+		}
+		if r.Header.Get("X-Amz-Target") != "AmazonSSM.GetParameter" {
+			t.Errorf("unexpected request %s %s", r.Method, r.Header.Get("X-Amz-Target"))
+		}
+		var in struct{ Name string }
+		_ = json.NewDecoder(r.Body).Decode(&in)
+		names = append(names, in.Name)
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		value := "bucket-1"
+		if strings.HasSuffix(in.Name, "/kms_key_id") {
+			value = "key-1"
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"Parameter": map[string]string{"Name": in.Name, "Value": value}})
+	}))
+	defer server.Close()
+
+	awsCfg := aws.Config{
+		Region:       "us-east-1",
+		Credentials:  staticCredsProviderForTests(),
+		BaseEndpoint: aws.String(server.URL),
+	}
+	cs := clientset.FromAWSConfig(types.Config{APIEndpoint: "https://api.test", CustomerID: "company-1", Region: "us-east-1"}, awsCfg)
+
+	p, err := NewProducerFromClientSet(cs)
+	if err != nil {
+		t.Fatalf("NewProducerFromClientSet: %v", err)
+	}
+	if p.BucketName != "bucket-1" || p.KMSKeyID != testKeyARN("key-1") || p.CustomerID != "company-1" || p.APIEndpoint != "https://api.test" {
+		t.Errorf("producer = %+v, want the looked-up bucket and key", p)
+	}
+	if p.kmsClient != cs.KMS || p.s3Client != cs.S3 {
+		t.Error("producer should reuse the ClientSet's KMS and S3 clients")
+	}
+	if len(names) != 2 {
+		t.Errorf("SSM lookups = %v, want one each for the bucket and key", names)
+	}
+}
@@ -0,0 +1,27 @@
+package producer
+
+import (
+	"net/http"
+	"testing"
+)
+
+// countingTransport records CloseIdleConnections calls.
+type countingTransport struct {
+	http.RoundTripper
+	closed int
+}
+
+func (t *countingTransport) CloseIdleConnections() { t.closed++ }
+
+func TestProducerClose(t *testing.T) {
+	p := newTestProducer("http://unused")
+	transport := &countingTransport{RoundTripper: http.DefaultTransport}
+	p.httpClient = &http.Client{Transport: transport}
+
+	if err := p.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if transport.closed != 1 {
+		t.Errorf("CloseIdleConnections called %d times, want 1", transport.closed)
+	}
+}
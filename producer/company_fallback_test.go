@@ -0,0 +1,127 @@
+package producer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/helix-tools/sdk-go/v2/clientset"
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+// newFallbackProducer builds a producer against one server that fakes SSM
+// (from ssm, by parameter suffix; missing names are ParameterNotFound) and
+// GET /v1/companies/company-1 (companyStatus/companyBody). The SSM cache is
+// bypassed, so each call sees only its own ssmValues.
+func newFallbackProducer(t *testing.T, ssmValues map[string]string, companyStatus int, companyBody string) (*Producer, *int, error) {
+	t.Helper()
+
+	companyGets := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if serveDescribeKey(w, r) {
+			return
+		}
+		if r.Header.Get("X-Amz-Target") == "AmazonSSM.GetParameter" {
+			var in struct{ Name string }
+			_ = json.NewDecoder(r.Body).Decode(&in)
+			w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+			for suffix, value := range ssmValues {
+				if strings.HasSuffix(in.Name, "/"+suffix) {
+					_ = json.NewEncoder(w).Encode(map[string]any{"Parameter": map[string]string{"Name": in.Name, "Value": value}})
+					return
+				}
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"__type":"ParameterNotFound","message":"not found"}`))
+			return
+		}
+		if r.Method != http.MethodGet || r.URL.Path != "/v1/companies/company-1" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		companyGets++
+		w.WriteHeader(companyStatus)
+		_, _ = w.Write([]byte(companyBody))
+	}))
+	t.Cleanup(server.Close)
+
+	awsCfg := aws.Config{
+		Region:       "us-east-1",
+		Credentials:  staticCredsProviderForTests(),
+		BaseEndpoint: aws.String(server.URL),
+	}
+	cfg := types.Config{APIEndpoint: server.URL, CustomerID: "company-1", Region: "us-east-1", SSMPathPrefix: "/helix/test/customers", SSMCacheTTL: -1}
+	p, err := NewProducerFromClientSet(clientset.FromAWSConfig(cfg, awsCfg))
+	return p, &companyGets, err
+}
+
+func TestNewProducerCompanyFallback(t *testing.T) {
+	t.Run("SSM values win without a company lookup", func(t *testing.T) {
+		p, gets, err := newFallbackProducer(t, map[string]string{"s3_bucket": "ssm-bucket", "kms_key_id": "ssm-key"}, http.StatusOK, `{}`)
+		if err != nil {
+			t.Fatalf("NewProducerFromClientSet: %v", err)
+		}
+		if p.BucketName != "ssm-bucket" || p.KMSKeyID != testKeyARN("ssm-key") || *gets != 0 {
+			t.Errorf("bucket=%q key=%q company gets=%d, want SSM values and no lookup", p.BucketName, p.KMSKeyID, *gets)
+		}
+	})
+
+	t.Run("missing parameters fall back to the company record", func(t *testing.T) {
+		p, _, err := newFallbackProducer(t, nil, http.StatusOK, `{"_id":"company-1","s3_bucket":"company-bucket","kms_key_id":"company-key"}`)
+		if err != nil {
+			t.Fatalf("NewProducerFromClientSet: %v", err)
+		}
+		if p.BucketName != "company-bucket" || p.KMSKeyID != testKeyARN("company-key") {
+			t.Errorf("bucket=%q key=%q, want the company record values", p.BucketName, p.KMSKeyID)
+		}
+	})
+
+	t.Run("infrastructure details and partial SSM", func(t *testing.T) {
+		p, _, err := newFallbackProducer(t, map[string]string{"s3_bucket": "ssm-bucket"}, http.StatusOK,
+			`{"_id":"company-1","s3_bucket":"company-bucket","infrastructure":{"kms_key_id":"infra-key"}}`)
+		if err != nil {
+			t.Fatalf("NewProducerFromClientSet: %v", err)
+		}
+		if p.BucketName != "ssm-bucket" || p.KMSKeyID != testKeyARN("infra-key") {
+			t.Errorf("bucket=%q key=%q, want the SSM bucket and infrastructure key", p.BucketName, p.KMSKeyID)
+		}
+	})
+
+	t.Run("no bucket anywhere fails", func(t *testing.T) {
+		_, _, err := newFallbackProducer(t, nil, http.StatusNotFound, `{"error":"not found"}`)
+		if err == nil || !strings.Contains(err.Error(), "S3 bucket not found") {
+			t.Errorf("err = %v, want the bucket error", err)
+		}
+	})
+}
+
+// TestNewProducerExplicitInfra pins that Config.BucketName and
+// Config.KMSKeyID skip the parameter lookups entirely; only the key is
+// described.
+func TestNewProducerExplicitInfra(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if serveDescribeKey(w, r) {
+			return
+		}
+		t.Errorf("unexpected request %s %s (%s)", r.Method, r.URL.Path, r.Header.Get("X-Amz-Target"))
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	awsCfg := aws.Config{
+		Region:       "us-east-1",
+		Credentials:  staticCredsProviderForTests(),
+		BaseEndpoint: aws.String(server.URL),
+	}
+	cfg := types.Config{APIEndpoint: server.URL, CustomerID: "company-1", BucketName: "my-bucket", KMSKeyID: "my-key"}
+
+	p, err := NewProducerFromClientSet(clientset.FromAWSConfig(cfg, awsCfg))
+	if err != nil {
+		t.Fatalf("NewProducerFromClientSet: %v", err)
+	}
+	if p.BucketName != "my-bucket" || p.KMSKeyID != testKeyARN("my-key") {
+		t.Errorf("bucket=%q key=%q, want the configured values", p.BucketName, p.KMSKeyID)
+	}
+}
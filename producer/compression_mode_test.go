@@ -0,0 +1,77 @@
+package producer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveCompression(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, data []byte) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	random := make([]byte, 2*compressionSampleSize)
+	_, _ = rand.Read(random)
+
+	text := write("rows.ndjson", bytes.Repeat([]byte(`{"id":1,"name":"row"}`+"\n"), 10000))
+	noise := write("noise.bin", random)
+	empty := write("empty.ndjson", nil)
+
+	p := &Producer{}
+	tests := []struct {
+		path string
+		mode CompressionMode
+		want bool
+	}{
+		{text, CompressionAuto, true},
+		{noise, CompressionAuto, false},
+		{empty, CompressionAuto, false},
+		{noise, CompressionAlways, true},
+		{text, CompressionNever, false},
+		{noise, "", true}, // Compress decides
+	}
+	for _, tt := range tests {
+		opts := NewUploadOptions("sales")
+		opts.CompressionMode = tt.mode
+		got, err := p.resolveCompression(tt.path, opts)
+		if err != nil {
+			t.Fatalf("%s/%q: %v", filepath.Base(tt.path), tt.mode, err)
+		}
+		if got.Compress != tt.want {
+			t.Errorf("%s/%q: Compress = %v, want %v", filepath.Base(tt.path), tt.mode, got.Compress, tt.want)
+		}
+	}
+}
+
+func TestUploadCompressionNever(t *testing.T) {
+	srv := newUploadServer(t)
+	opts := NewUploadOptions("catalog-check")
+	opts.Compress = false
+	opts.CompressionMode = CompressionNever
+
+	if _, err := srv.producer().UploadDataset(context.Background(), writeUploadFile(t), opts); err != nil {
+		t.Fatalf("UploadDataset: %v", err)
+	}
+	metadata, _ := srv.created["metadata"].(map[string]any)
+	if metadata["compression_enabled"] != false {
+		t.Errorf("compression_enabled = %v, want false", metadata["compression_enabled"])
+	}
+	if key, _ := srv.created["s3_key"].(string); strings.HasSuffix(key, ".gz") {
+		t.Errorf("s3_key = %q, want no .gz suffix", key)
+	}
+
+	opts.CompressionMode = "sometimes"
+	if err := opts.Validate(); err == nil || !strings.Contains(err.Error(), "unknown compression mode") {
+		t.Errorf("Validate = %v, want an unknown compression mode error", err)
+	}
+}
@@ -0,0 +1,33 @@
+package producer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// TestUploadDatasetConcurrent shares one Producer across goroutines; run
+// with -race to check the concurrency guarantee on Producer.
+func TestUploadDatasetConcurrent(t *testing.T) {
+	srv := newUploadServer(t)
+	p := srv.producer()
+
+	const uploads = 8
+	var wg sync.WaitGroup
+	for i := range uploads {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			opts := NewUploadOptions(fmt.Sprintf("concurrent-%d", i))
+			if _, err := p.UploadDataset(context.Background(), writeUploadFile(t), opts); err != nil {
+				t.Errorf("upload %d: %v", i, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(srv.keys) != uploads {
+		t.Errorf("catalog registrations = %d, want %d", len(srv.keys), uploads)
+	}
+}
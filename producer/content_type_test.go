@@ -0,0 +1,75 @@
+package producer
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDetectContentType(t *testing.T) {
+	tests := map[string]string{
+		"data.ndjson":      "application/x-ndjson",
+		"data.JSONL":       "application/x-ndjson",
+		"report.csv":       "text/csv",
+		"table.parquet":    "application/vnd.apache.parquet",
+		"no-extension":     "application/x-ndjson",
+		"archive.unknownx": "application/x-ndjson",
+	}
+	for path, want := range tests {
+		if got := detectContentType(path); got != want {
+			t.Errorf("detectContentType(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+// TestUploadRecordsContentType pins that the original file's content type
+// lands in the create payload metadata, and that ContentType overrides it.
+func TestUploadRecordsContentType(t *testing.T) {
+	metadataOf := func(t *testing.T, srv *uploadServer) map[string]any {
+		t.Helper()
+		metadata, _ := srv.created["metadata"].(map[string]any)
+		return metadata
+	}
+
+	t.Run("detected", func(t *testing.T) {
+		srv := newUploadServer(t)
+		if _, err := srv.producer().UploadDataset(context.Background(), writeUploadFile(t), NewUploadOptions("catalog-check")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := metadataOf(t, srv)["content_type"]; got != "application/x-ndjson" {
+			t.Errorf("content_type = %v", got)
+		}
+	})
+
+	t.Run("explicit", func(t *testing.T) {
+		srv := newUploadServer(t)
+		opts := NewUploadOptions("catalog-check")
+		opts.ContentType = "text/csv"
+		if _, err := srv.producer().UploadDataset(context.Background(), writeUploadFile(t), opts); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := metadataOf(t, srv)["content_type"]; got != "text/csv" {
+			t.Errorf("content_type = %v", got)
+		}
+	})
+}
+
+// TestUploadRecordsKMSKeyRegion pins the kms_key_region metadata the
+// consumer uses to reach the key's regional KMS endpoint.
+func TestUploadRecordsKMSKeyRegion(t *testing.T) {
+	for keyID, want := range map[string]string{
+		"test-kms-key": "us-east-1",
+		"arn:aws:kms:eu-west-1:111122223333:key/mrk-1": "eu-west-1",
+	} {
+		srv := newUploadServer(t)
+		p := srv.producer()
+		p.Region = "us-east-1"
+		p.KMSKeyID = keyID
+		if _, err := p.UploadDataset(context.Background(), writeUploadFile(t), NewUploadOptions("catalog-check")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		metadata, _ := srv.created["metadata"].(map[string]any)
+		if got := metadata["kms_key_region"]; got != want {
+			t.Errorf("key %q: kms_key_region = %v, want %s", keyID, got, want)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package producer
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/helix-tools/sdk-go/v2/helixtest"
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+func TestDatasetExists(t *testing.T) {
+	api := helixtest.NewMockAPI()
+	api.Handle(http.MethodGet, "/v1/datasets/ds-1", http.StatusOK, types.Dataset{ID: "ds-1"})
+	api.Handle(http.MethodGet, "/v1/datasets/missing", http.StatusNotFound, "not found")
+	api.Handle(http.MethodGet, "/v1/datasets/broken", http.StatusInternalServerError, "boom")
+	p := NewProducerWithAPI(types.Config{CustomerID: "company-1"}, api)
+
+	if ok, err := p.DatasetExists(context.Background(), "ds-1"); !ok || err != nil {
+		t.Errorf("existing dataset = %v, %v; want true, nil", ok, err)
+	}
+	if ok, err := p.DatasetExists(context.Background(), "missing"); ok || err != nil {
+		t.Errorf("missing dataset = %v, %v; want false, nil", ok, err)
+	}
+	if ok, err := p.DatasetExists(context.Background(), "broken"); ok || err == nil {
+		t.Errorf("server error = %v, %v; want false and an error", ok, err)
+	}
+}
@@ -1,8 +1,11 @@
 package producer
 
 import (
+	"crypto/rand"
+	"fmt"
 	"maps"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -11,6 +14,10 @@ import (
 
 var slugRegex = regexp.MustCompile(`[^a-z0-9-]+`)
 
+// datasetIDRegex is the catalog's dataset ID format, as produced by
+// generateDatasetID: lowercase alphanumerics with inner hyphens, max 128.
+var datasetIDRegex = regexp.MustCompile(`^[a-z0-9](?:[a-z0-9-]{0,126}[a-z0-9])?$`)
+
 func slugify(name string) string {
 	slug := strings.ToLower(name)
 	slug = slugRegex.ReplaceAllString(slug, "-")
@@ -21,14 +28,68 @@ func slugify(name string) string {
 	return slug
 }
 
+// IDFormat is a template for generated dataset IDs. Placeholders:
+// {customer} (producer ID), {slug} (slugified dataset name), {timestamp}
+// (Unix seconds), {rand} (4 random hex chars), and {uuid} (random UUIDv4).
+// Any other text is copied as-is; the rendered ID must still match the
+// catalog ID format.
+type IDFormat string
+
+const (
+	// IDFormatDefault is the deterministic "{customer}-{slug}" format, so
+	// re-uploading a dataset name targets the same ID (matches the Portal).
+	IDFormatDefault IDFormat = "{customer}-{slug}"
+
+	// IDFormatTimestamped gives every upload a fresh ID; {rand} keeps two
+	// uploads of the same name within one second apart.
+	IDFormatTimestamped IDFormat = "{customer}-{slug}-{timestamp}-{rand}"
+
+	// IDFormatUUID is a pure random UUID.
+	IDFormatUUID IDFormat = "{uuid}"
+)
+
 func (p *Producer) generateDatasetID(name string, explicitID *string) string {
+	return p.generateDatasetIDWithFormat(name, explicitID, IDFormatDefault)
+}
+
+// generateDatasetIDWithFormat returns explicitID when set, otherwise renders
+// format (IDFormatDefault when empty).
+func (p *Producer) generateDatasetIDWithFormat(name string, explicitID *string, format IDFormat) string {
 	if explicitID != nil && *explicitID != "" {
 		return *explicitID
 	}
-	// Generate consistent dataset ID without timestamp
-	// This matches Portal behavior and prevents duplicate datasets
-	// Format: {producer_id}-{slugified_name}
-	return p.CustomerID + "-" + slugify(name)
+	if format == "" {
+		format = IDFormatDefault
+	}
+
+	id := string(format)
+	replacements := map[string]func() string{
+		"{customer}":  func() string { return p.CustomerID },
+		"{slug}":      func() string { return slugify(name) },
+		"{timestamp}": func() string { return strconv.FormatInt(time.Now().Unix(), 10) },
+		"{rand}":      func() string { return randomHex(2) },
+		"{uuid}":      newUUID,
+	}
+	for placeholder, value := range replacements {
+		if strings.Contains(id, placeholder) {
+			id = strings.ReplaceAll(id, placeholder, value())
+		}
+	}
+	return id
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b) // crypto/rand.Read never returns an error
+	return fmt.Sprintf("%x", b)
+}
+
+func newUUID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
 }
 
 func defaultPricing() map[string]any {
@@ -78,6 +139,17 @@ func deepMergeMaps(base, overrides map[string]any) map[string]any {
 	return base
 }
 
+// overrideDatasetID returns the explicit dataset ID carried in overrides
+// ("_id" wins over "id") and the key it was found under.
+func overrideDatasetID(overrides map[string]any) (id, key string, ok bool) {
+	for _, k := range []string{"_id", "id"} {
+		if v, isString := overrides[k].(string); isString && v != "" {
+			return v, k, true
+		}
+	}
+	return "", "", false
+}
+
 func cloneOverrides(overrides map[string]any) map[string]any {
 	if overrides == nil {
 		return nil
@@ -99,14 +171,9 @@ func (p *Producer) buildDatasetPayload(
 	overrideCopy := cloneOverrides(overrides)
 
 	var explicitID *string
-	if overrideCopy != nil {
-		if idValue, ok := overrideCopy["_id"].(string); ok && idValue != "" {
-			explicitID = &idValue
-			delete(overrideCopy, "_id")
-		} else if idValue, ok := overrideCopy["id"].(string); ok && idValue != "" {
-			explicitID = &idValue
-			delete(overrideCopy, "id")
-		}
+	if idValue, key, ok := overrideDatasetID(overrideCopy); ok {
+		explicitID = &idValue
+		delete(overrideCopy, key)
 	}
 
 	datasetID := p.generateDatasetID(datasetName, explicitID)
@@ -127,7 +194,7 @@ func (p *Producer) buildDatasetPayload(
 
 	recordCount := 0
 	schema := map[string]any{}
-	fieldEmptiness := map[string]float64{}
+	fieldEmptiness := FieldEmptiness{}
 	if analysis != nil {
 		recordCount = analysis.RecordCount
 		if analysis.Schema != nil {
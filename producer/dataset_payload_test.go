@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/helix-tools/sdk-go/v2/types"
@@ -23,8 +25,8 @@ func TestBuildDatasetPayloadDefaults(t *testing.T) {
 		Schema: map[string]any{
 			"type": "object",
 		},
-		FieldEmptiness: map[string]float64{
-			"name": 0,
+		FieldEmptiness: FieldEmptiness{
+			{Field: "name", Percent: 0},
 		},
 		RecordCount:    2,
 		AnalysisErrors: 0,
@@ -125,3 +127,72 @@ func TestBuildDatasetPayloadOverrides(t *testing.T) {
 		t.Fatalf("override pricing not applied")
 	}
 }
+
+func TestGenerateDatasetIDFormats(t *testing.T) {
+	p := &Producer{CustomerID: "customer-123"}
+
+	t.Run("default stays deterministic", func(t *testing.T) {
+		first := p.generateDatasetID("Daily Sales!", nil)
+		if first != "customer-123-daily-sales" {
+			t.Errorf("unexpected default ID %q", first)
+		}
+		if again := p.generateDatasetIDWithFormat("Daily Sales!", nil, ""); again != first {
+			t.Errorf("empty format must match the default, got %q", again)
+		}
+	})
+
+	t.Run("explicit ID wins over any format", func(t *testing.T) {
+		if got := p.generateDatasetIDWithFormat("x", strptr("ds-1"), IDFormatUUID); got != "ds-1" {
+			t.Errorf("expected explicit ID, got %q", got)
+		}
+	})
+
+	t.Run("slug strips unsafe characters", func(t *testing.T) {
+		got := p.generateDatasetIDWithFormat("../Sales / Q1 <2026>", nil, IDFormatTimestamped)
+		if !strings.HasPrefix(got, "customer-123-sales-q1-2026-") {
+			t.Errorf("unexpected slug in %q", got)
+		}
+		if !datasetIDRegex.MatchString(got) {
+			t.Errorf("generated ID %q does not match the catalog format", got)
+		}
+	})
+
+	t.Run("timestamped IDs are unique across rapid calls", func(t *testing.T) {
+		pattern := regexp.MustCompile(`^customer-123-sales-\d+-[0-9a-f]{4}$`)
+		seen := map[string]bool{}
+		for range 200 {
+			id := p.generateDatasetIDWithFormat("sales", nil, IDFormatTimestamped)
+			if !pattern.MatchString(id) {
+				t.Fatalf("unexpected timestamped ID %q", id)
+			}
+			seen[id] = true
+		}
+		// 200 draws from 65536 suffixes within ~1s: collisions are possible
+		// but vanishingly rare, and far fewer than without the suffix.
+		if len(seen) < 195 {
+			t.Errorf("expected near-unique IDs, got %d distinct of 200", len(seen))
+		}
+	})
+
+	t.Run("UUIDs are unique and well-formed", func(t *testing.T) {
+		pattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+		seen := map[string]bool{}
+		for range 200 {
+			id := p.generateDatasetIDWithFormat("sales", nil, IDFormatUUID)
+			if !pattern.MatchString(id) {
+				t.Fatalf("unexpected UUID %q", id)
+			}
+			seen[id] = true
+		}
+		if len(seen) != 200 {
+			t.Errorf("expected 200 distinct UUIDs, got %d", len(seen))
+		}
+	})
+
+	t.Run("custom template", func(t *testing.T) {
+		got := p.generateDatasetIDWithFormat("Sales", nil, "acme-{slug}-v2")
+		if got != "acme-sales-v2" {
+			t.Errorf("unexpected templated ID %q", got)
+		}
+	})
+}
@@ -0,0 +1,56 @@
+package producer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const statsDataset = `{"_id":"ds-1","stats":{"subscriber_count":4,"download_count":120,"download_count_7d":9,
+	"download_count_30d":31,"view_count":800,"last_downloaded_at":"2026-10-01T12:00:00Z","avg_download_size_mb":2.5}}`
+
+func TestGetDatasetStats(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/datasets/ds-1" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(statsDataset))
+	}))
+	defer server.Close()
+
+	stats, err := newTestProducer(server.URL).GetDatasetStats(context.Background(), "ds-1")
+	if err != nil {
+		t.Fatalf("GetDatasetStats: %v", err)
+	}
+
+	if stats.SubscriberCount != 4 || stats.DownloadCount != 120 || stats.DownloadCount7d != 9 ||
+		stats.DownloadCount30d != 31 || stats.ViewCount != 800 || stats.AvgDownloadSizeMB != 2.5 {
+		t.Errorf("stats = %+v", stats)
+	}
+	if stats.LastDownloadedAt == nil || *stats.LastDownloadedAt != "2026-10-01T12:00:00Z" {
+		t.Errorf("LastDownloadedAt = %v", stats.LastDownloadedAt)
+	}
+}
+
+func TestListMyDatasetsWithStats(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"datasets":[` + statsDataset + `,{"_id":"ds-new"}],"count":2}`))
+	}))
+	defer server.Close()
+
+	datasets, err := newTestProducer(server.URL).ListMyDatasetsWithStats(context.Background())
+	if err != nil {
+		t.Fatalf("ListMyDatasetsWithStats: %v", err)
+	}
+
+	if len(datasets) != 2 || datasets[0].Dataset.ID != "ds-1" || datasets[0].Stats.DownloadCount != 120 {
+		t.Fatalf("datasets = %+v", datasets)
+	}
+	if datasets[1].Stats.DownloadCount != 0 || datasets[1].Stats.LastDownloadedAt != nil {
+		t.Errorf("dataset without stats = %+v, want zero stats", datasets[1].Stats)
+	}
+}
@@ -0,0 +1,136 @@
+package producer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/helix-tools/sdk-go/v2/internal/awsfake"
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+func TestDeleteDatasets(t *testing.T) {
+	var mu sync.Mutex
+	deletes := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/v1/datasets/")
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case id == "ds-gone":
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodGet:
+			_, _ = w.Write([]byte(`{"_id":"` + id + `","s3_key":"datasets/` + id + `/data.ndjson.gz"}`))
+		case r.Method == http.MethodDelete:
+			mu.Lock()
+			deletes[id]++
+			mu.Unlock()
+			if id == "ds-broken" {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	p := newTestProducer(server.URL)
+	fake := &fakeS3{}
+	p.s3Client = fake
+
+	ids := []string{"ds-1", "ds-gone", "ds-broken", "ds-2", "ds-1"}
+	results, err := p.DeleteDatasets(context.Background(), ids, 2)
+	if err != nil {
+		t.Fatalf("DeleteDatasets: %v", err)
+	}
+
+	if len(results) != 4 {
+		t.Errorf("results = %v, want one entry per distinct ID", results)
+	}
+	for _, id := range []string{"ds-1", "ds-2", "ds-gone"} {
+		if err, ok := results[id]; !ok || err != nil {
+			t.Errorf("results[%s] = %v (present=%v), want nil", id, err, ok)
+		}
+	}
+	var apiErr *APIError
+	if !errors.As(results["ds-broken"], &apiErr) || apiErr.StatusCode != http.StatusInternalServerError {
+		t.Errorf("results[ds-broken] = %v, want the 500 *APIError", results["ds-broken"])
+	}
+
+	if deletes["ds-1"] != 1 {
+		t.Errorf("ds-1 deleted %d times, want once despite the duplicate ID", deletes["ds-1"])
+	}
+	slices.Sort(fake.deleted)
+	want := []string{
+		"dme-producer-test/datasets/ds-1/data.ndjson.gz",
+		"dme-producer-test/datasets/ds-2/data.ndjson.gz",
+	}
+	if !slices.Equal(fake.deleted, want) {
+		t.Errorf("S3 deletes = %v, want %v (no object delete when the record delete failed)", fake.deleted, want)
+	}
+}
+
+func TestDeleteDatasetsSharded(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte(`{"_id":"ds-1","s3_key":"datasets/sales/manifest.json","metadata":{"part_count":2}}`))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	p := newTestProducer(server.URL)
+	fake := awsfake.NewS3()
+	p.s3Client = fake
+	ctx := context.Background()
+
+	parts := []types.ManifestPart{
+		types.NewManifestPart(ShardKey("sales", 0), []byte("part 0")),
+		types.NewManifestPart(ShardKey("sales", 1), []byte("part 1")),
+	}
+	for _, part := range parts {
+		if _, err := fake.PutObject(ctx, &s3.PutObjectInput{Bucket: aws.String(p.BucketName), Key: aws.String(part.S3Key), Body: strings.NewReader("part")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := p.WriteDatasetManifest(ctx, "sales", parts); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := p.DeleteDatasets(ctx, []string{"ds-1"}, 1)
+	if err != nil || results["ds-1"] != nil {
+		t.Fatalf("DeleteDatasets = %v, %v", results, err)
+	}
+	for _, key := range []string{ShardKey("sales", 0), ShardKey("sales", 1), ManifestKey("sales")} {
+		if _, ok := fake.Object(p.BucketName, key); ok {
+			t.Errorf("s3://%s/%s still stored", p.BucketName, key)
+		}
+	}
+}
+
+func TestDeleteDatasetsCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results, err := newTestProducer(server.URL).DeleteDatasets(ctx, []string{"ds-1", "ds-2"}, 1)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	for _, id := range []string{"ds-1", "ds-2"} {
+		if !errors.Is(results[id], context.Canceled) {
+			t.Errorf("results[%s] = %v, want context.Canceled", id, results[id])
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package producer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	kmstypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/helix-tools/sdk-go/v2/internal/envelope"
+)
+
+// identityKMS "wraps" a data key by returning it unchanged.
+type identityKMS struct{}
+
+func (identityKMS) Encrypt(_ context.Context, in *kms.EncryptInput, _ ...func(*kms.Options)) (*kms.EncryptOutput, error) {
+	return &kms.EncryptOutput{CiphertextBlob: in.Plaintext, KeyId: in.KeyId}, nil
+}
+
+func (identityKMS) ReEncrypt(_ context.Context, in *kms.ReEncryptInput, _ ...func(*kms.Options)) (*kms.ReEncryptOutput, error) {
+	return &kms.ReEncryptOutput{CiphertextBlob: in.CiphertextBlob, KeyId: in.DestinationKeyId}, nil
+}
+
+func (identityKMS) DescribeKey(_ context.Context, in *kms.DescribeKeyInput, _ ...func(*kms.Options)) (*kms.DescribeKeyOutput, error) {
+	return &kms.DescribeKeyOutput{KeyMetadata: &kmstypes.KeyMetadata{KeyId: in.KeyId, Arn: in.KeyId, Enabled: true}}, nil
+}
+
+func TestEncryptDataRoundTrip(t *testing.T) {
+	p := newTestProducer("http://unused")
+	p.KMSKeyID = "test-kms-key"
+	p.kmsClient = identityKMS{}
+
+	for _, data := range [][]byte{{}, {0x42}, bytes.Repeat([]byte("row\n"), 1<<18)} {
+		sealed, err := p.encryptData(context.Background(), data)
+		if err != nil {
+			t.Fatalf("encryptData(%d bytes): %v", len(data), err)
+		}
+		opened, err := envelope.Open(context.Background(), sealed, func(_ context.Context, key []byte) ([]byte, error) {
+			return key, nil
+		})
+		if err != nil || !bytes.Equal(opened, data) {
+			t.Errorf("round trip of %d bytes: got %d bytes, %v", len(data), len(opened), err)
+		}
+	}
+
+	p.KMSKeyID = ""
+	if _, err := p.encryptData(context.Background(), []byte("x")); err == nil {
+		t.Error("encryptData without a KMS key should fail")
+	}
+}
+
+// TestProcessFileZeroize pins that Config.ZeroizeBuffers clears only the
+// intermediate plaintext buffers, not the sealed data processFile returns.
+func TestProcessFileZeroize(t *testing.T) {
+	p := newTestProducer("http://unused")
+	p.KMSKeyID = "test-kms-key"
+	p.kmsClient = identityKMS{}
+	p.zeroize = true
+
+	file := writeUploadFile(t)
+	processed, err := p.processFile(context.Background(), file, NewUploadOptions("zeroize"))
+	if err != nil {
+		t.Fatalf("processFile: %v", err)
+	}
+	compressed, err := envelope.Open(context.Background(), processed.Data, func(_ context.Context, key []byte) ([]byte, error) {
+		return bytes.Clone(key), nil
+	})
+	if err != nil {
+		t.Fatalf("open processed data: %v", err)
+	}
+	gr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatalf("processed data is not gzip: %v", err)
+	}
+	got, _ := io.ReadAll(gr)
+	if want, _ := os.ReadFile(file); !bytes.Equal(got, want) {
+		t.Errorf("processed data opens to %q, want %q", got, want)
+	}
+}
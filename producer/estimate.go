@@ -0,0 +1,109 @@
+package producer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// estimateSampleSize is how much of the file EstimateUpload compresses to
+// estimate the compression ratio. Files up to this size are compressed
+// whole, so their estimate is exact.
+const estimateSampleSize = 1 << 20
+
+// UploadEstimate is returned by EstimateUpload.
+type UploadEstimate struct {
+	OriginalBytes int64
+
+	// SampleBytes is how much of the file was compressed to estimate
+	// CompressionRatio (all of it for files up to 1 MiB).
+	SampleBytes int64
+
+	// CompressionRatio is compressed/original size for the sample; 1 when
+	// the upload would not be compressed.
+	CompressionRatio float64
+
+	EstimatedCompressedBytes int64
+
+	// EnvelopeOverheadBytes is what encryption adds to the compressed data:
+	// the encrypted data key and the envelope header. It does not depend
+	// on the data size.
+	EnvelopeOverheadBytes int64
+
+	// EstimatedStoredBytes is the size of the object the upload would
+	// store: EstimatedCompressedBytes + EnvelopeOverheadBytes.
+	EstimatedStoredBytes int64
+}
+
+// EstimatedStoredGB is EstimatedStoredBytes in GB (2^30 bytes), the unit
+// storage is usually priced in.
+func (e *UploadEstimate) EstimatedStoredGB() float64 {
+	return float64(e.EstimatedStoredBytes) / (1 << 30)
+}
+
+// Cost estimates what storing the upload costs at ratePerGB, a price per
+// GB (and per period, e.g. per month) in whatever currency the caller
+// uses. The SDK has no pricing of its own.
+func (e *UploadEstimate) Cost(ratePerGB float64) float64 {
+	return e.EstimatedStoredGB() * ratePerGB
+}
+
+// EstimateUpload estimates the size of the object UploadDataset would
+// store for filePath with opts, without creating a catalog record or
+// uploading anything. It compresses a sample from the start of the file at
+// opts.CompressionLevel (honoring opts.CompressionMode) and extrapolates
+// the ratio to the whole file, so the estimate is only as good as the
+// sample is representative.
+//
+// The envelope overhead is measured by sealing an empty payload under the
+// upload's KMS key, which costs one KMS request and fails as the upload
+// would if the key is unusable.
+func (p *Producer) EstimateUpload(ctx context.Context, filePath string, opts UploadOptions) (*UploadEstimate, error) {
+	opts = opts.withDefaults()
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	size, err := checkSourceFile(filePath, opts)
+	if err != nil {
+		return nil, err
+	}
+	opts, err = p.resolveCompression(filePath, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	estimate := &UploadEstimate{OriginalBytes: size, CompressionRatio: 1, EstimatedCompressedBytes: size}
+	if opts.Compress {
+		f, err := os.Open(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open file: %w", err)
+		}
+		defer f.Close()
+
+		sample, err := io.ReadAll(io.LimitReader(f, estimateSampleSize))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file: %w", err)
+		}
+		compressed, err := p.compressData(sample, opts.CompressionLevel)
+		if err != nil {
+			return nil, err
+		}
+
+		estimate.SampleBytes = int64(len(sample))
+		estimate.CompressionRatio = float64(len(compressed)) / float64(len(sample))
+		estimate.EstimatedCompressedBytes = int64(math.Ceil(float64(size) * estimate.CompressionRatio))
+	}
+
+	// GCM ciphertext is as long as the plaintext, so sealing nothing
+	// yields exactly the fixed overhead.
+	envelope, err := p.encryptDataWithKey(ctx, p.uploadKeyID(opts), nil)
+	if err != nil {
+		return nil, fmt.Errorf("encryption failed: %w", err)
+	}
+	estimate.EnvelopeOverheadBytes = int64(len(envelope))
+	estimate.EstimatedStoredBytes = estimate.EstimatedCompressedBytes + estimate.EnvelopeOverheadBytes
+
+	return estimate, nil
+}
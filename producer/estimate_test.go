@@ -0,0 +1,39 @@
+package producer
+
+import (
+	"context"
+	"testing"
+)
+
+// TestEstimateUploadMatchesUpload checks that for a file smaller than the
+// sample the estimate is exact, and that estimating has no side effects.
+func TestEstimateUploadMatchesUpload(t *testing.T) {
+	srv := newUploadServer(t)
+	p := srv.producer()
+	file := writeUploadFile(t)
+
+	estimate, err := p.EstimateUpload(context.Background(), file, NewUploadOptions("estimate"))
+	if err != nil {
+		t.Fatalf("EstimateUpload: %v", err)
+	}
+	if len(srv.keys) != 0 || srv.uploaded != nil {
+		t.Fatal("EstimateUpload created a record or uploaded data")
+	}
+	if estimate.OriginalBytes != 18 || estimate.SampleBytes != 18 {
+		t.Errorf("OriginalBytes, SampleBytes = %d, %d, want 18, 18", estimate.OriginalBytes, estimate.SampleBytes)
+	}
+	if estimate.EnvelopeOverheadBytes <= 0 {
+		t.Errorf("EnvelopeOverheadBytes = %d, want > 0", estimate.EnvelopeOverheadBytes)
+	}
+
+	if _, err := p.UploadDataset(context.Background(), file, NewUploadOptions("estimate")); err != nil {
+		t.Fatalf("UploadDataset: %v", err)
+	}
+	if got := int64(len(srv.uploaded)); got != estimate.EstimatedStoredBytes {
+		t.Errorf("uploaded %d bytes, estimated %d", got, estimate.EstimatedStoredBytes)
+	}
+
+	if got, want := estimate.Cost(2), estimate.EstimatedStoredGB()*2; got != want {
+		t.Errorf("Cost(2) = %v, want %v", got, want)
+	}
+}
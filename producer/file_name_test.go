@@ -0,0 +1,75 @@
+package producer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUploadFileName(t *testing.T) {
+	tests := []struct {
+		name, file, fileName string
+		wantKey, wantFormat  string
+	}{
+		{"ndjson default", "data.ndjson", "", "datasets/catalog-check/data.ndjson.gz", "ndjson"},
+		{"json follows content type", "export.json", "", "datasets/catalog-check/data.json.gz", "json"},
+		{"csv follows content type", "export.csv", "", "datasets/catalog-check/data.csv.gz", "csv"},
+		{"explicit name", "export.json", "customers-2026.jsonl", "datasets/catalog-check/customers-2026.jsonl.gz", "jsonl"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newUploadServer(t)
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte("{\"id\":1}\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			opts := NewUploadOptions("catalog-check")
+			opts.FileName = tt.fileName
+			if _, err := srv.producer().UploadDataset(context.Background(), path, opts); err != nil {
+				t.Fatalf("UploadDataset: %v", err)
+			}
+
+			if got := srv.created["s3_key"]; got != tt.wantKey {
+				t.Errorf("s3_key = %v, want %s", got, tt.wantKey)
+			}
+			metadata, _ := srv.created["metadata"].(map[string]any)
+			if got := metadata["file_format"]; got != tt.wantFormat {
+				t.Errorf("file_format = %v, want %s", got, tt.wantFormat)
+			}
+		})
+	}
+}
+
+func TestUploadOptionsValidateFileName(t *testing.T) {
+	for _, name := range []string{"../escape.csv", "dir/data.csv", ".hidden", "data.csv.gz", "data csv"} {
+		opts := NewUploadOptions("sales")
+		opts.FileName = name
+		if err := opts.Validate(); err == nil || !strings.Contains(err.Error(), "file name") {
+			t.Errorf("Validate(FileName=%q) = %v, want a file name error", name, err)
+		}
+	}
+
+	opts := NewUploadOptions("sales")
+	opts.FileName = "Sales_2026-10.csv"
+	if err := opts.Validate(); err != nil {
+		t.Errorf("Validate(valid FileName) = %v", err)
+	}
+}
+
+func TestDefaultFileName(t *testing.T) {
+	tests := map[string]string{
+		"application/x-ndjson":           "data.ndjson",
+		"text/csv; charset=utf-8":        "data.csv",
+		"application/vnd.apache.parquet": "data.parquet",
+		"application/octet-stream":       "data.ndjson",
+		"":                               "data.ndjson",
+	}
+	for contentType, want := range tests {
+		if got := defaultFileName(contentType); got != want {
+			t.Errorf("defaultFileName(%q) = %q, want %q", contentType, got, want)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package producer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/helix-tools/sdk-go/v2/helixtest"
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+func TestProducerGetSubscription(t *testing.T) {
+	api := helixtest.NewMockAPI()
+	api.Handle(http.MethodGet, "/v1/subscriptions/sub-1", http.StatusOK, types.Subscription{ID: "sub-1"})
+	api.Handle(http.MethodGet, "/v1/subscriptions/missing", http.StatusNotFound, map[string]string{"error": "not found"})
+	p := NewProducerWithAPI(types.Config{CustomerID: "company-1"}, api)
+
+	if sub, err := p.GetSubscription(context.Background(), "sub-1"); err != nil || sub.ID != "sub-1" {
+		t.Fatalf("GetSubscription = %+v, %v", sub, err)
+	}
+
+	_, err := p.GetSubscription(context.Background(), "missing")
+	var notFound *types.NotFoundError
+	if !errors.As(err, &notFound) || !isNotFound(err) {
+		t.Errorf("err = %v, want a *types.NotFoundError wrapping the 404 *APIError", err)
+	}
+}
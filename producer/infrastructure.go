@@ -0,0 +1,103 @@
+package producer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// InfrastructureStatus is CheckInfrastructure's readiness report, one entry
+// per resource the producer's uploads depend on.
+type InfrastructureStatus struct {
+	Bucket ResourceStatus // the producer's bucket, checked with HeadBucket
+	KMSKey ResourceStatus // the producer's KMS key, checked with DescribeKey
+
+	// Topic and Queue are the company's notification topic and queue, as
+	// recorded on the company once provisioning wired them up.
+	Topic ResourceStatus
+	Queue ResourceStatus
+}
+
+// ResourceStatus is the readiness of one resource.
+type ResourceStatus struct {
+	Name  string // bucket name, key ARN, topic ARN or queue URL; empty if unknown
+	Ready bool
+	Err   error // why the resource is not ready
+}
+
+// Ready reports whether every resource is ready.
+func (s *InfrastructureStatus) Ready() bool {
+	return s.Bucket.Ready && s.KMSKey.Ready && s.Topic.Ready && s.Queue.Ready
+}
+
+// CheckInfrastructure checks that the resources provisioned for the
+// producer's company are in place before the first upload, e.g. after
+// client.WaitForCompanyActive: the bucket exists and is accessible, the KMS
+// key is enabled, and the company record names its notification topic and
+// queue. The report is always returned; the error joins the reason of every
+// resource that is not ready, and is nil when all are.
+func (p *Producer) CheckInfrastructure(ctx context.Context) (*InfrastructureStatus, error) {
+	status := &InfrastructureStatus{
+		Bucket: p.checkBucket(ctx),
+		KMSKey: p.checkKMSKey(ctx),
+	}
+
+	company, err := p.getOwnCompany(ctx)
+	if err != nil {
+		err = fmt.Errorf("company record lookup failed: %w", err)
+		status.Topic.Err, status.Queue.Err = err, err
+	} else {
+		status.Topic = recordedResource("notification topic", company.SNSTopicARN)
+		if company.Infrastructure != nil {
+			status.Queue = recordedResource("notification queue", company.Infrastructure.SQSQueueURL)
+		} else {
+			status.Queue = recordedResource("notification queue", "")
+		}
+	}
+
+	var errs []error
+	for _, resource := range []ResourceStatus{status.Bucket, status.KMSKey, status.Topic, status.Queue} {
+		if resource.Err != nil {
+			errs = append(errs, resource.Err)
+		}
+	}
+	return status, errors.Join(errs...)
+}
+
+func (p *Producer) checkBucket(ctx context.Context) ResourceStatus {
+	resource := ResourceStatus{Name: p.BucketName}
+	switch {
+	case p.BucketName == "":
+		resource.Err = errors.New("S3 bucket not configured")
+	case p.s3Client == nil:
+		resource.Err = errors.New("checking the S3 bucket needs an S3 client")
+	default:
+		if _, err := p.s3Client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(p.BucketName)}); err != nil {
+			resource.Err = fmt.Errorf("S3 bucket %s is not accessible: %w", p.BucketName, err)
+		}
+	}
+	resource.Ready = resource.Err == nil
+	return resource
+}
+
+func (p *Producer) checkKMSKey(ctx context.Context) ResourceStatus {
+	if p.KMSKeyID == "" {
+		return ResourceStatus{Err: errors.New("KMS key not configured")}
+	}
+	opts, err := p.resolveUploadKey(ctx, UploadOptions{KMSKeyID: p.KMSKeyID})
+	if err != nil {
+		return ResourceStatus{Name: p.KMSKeyID, Err: err}
+	}
+	return ResourceStatus{Name: opts.KMSKeyID, Ready: true}
+}
+
+// recordedResource is the status of a resource the company record names.
+func recordedResource(what, name string) ResourceStatus {
+	if name == "" {
+		return ResourceStatus{Err: fmt.Errorf("%s not yet recorded on the company", what)}
+	}
+	return ResourceStatus{Name: name, Ready: true}
+}
@@ -0,0 +1,52 @@
+package producer
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	kmstypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/helix-tools/sdk-go/v2/helixtest"
+	"github.com/helix-tools/sdk-go/v2/internal/awsfake"
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+func TestCheckInfrastructure(t *testing.T) {
+	provisioned := types.Company{
+		ID:             "company-1",
+		SNSTopicARN:    "arn:aws:sns:us-east-1:000000000000:company-1",
+		Infrastructure: &types.InfrastructureInfo{SQSQueueURL: "https://queue.example/company-1"},
+	}
+
+	newProducer := func(bucket string, company types.Company) (*Producer, *awsfake.KMS) {
+		api := helixtest.NewMockAPI()
+		api.Handle(http.MethodGet, "/v1/companies/company-1", http.StatusOK, company)
+		p := NewProducerWithAPI(types.Config{CustomerID: "company-1", BucketName: bucket, KMSKeyID: "key-1"}, api)
+		fakeKMS := awsfake.NewKMS()
+		p.kmsClient = fakeKMS
+		p.s3Client = awsfake.NewS3()
+		return p, fakeKMS
+	}
+
+	p, _ := newProducer("bucket-1", provisioned)
+	status, err := p.CheckInfrastructure(context.Background())
+	if err != nil || !status.Ready() {
+		t.Fatalf("CheckInfrastructure = %+v, %v; want everything ready", status, err)
+	}
+	if status.KMSKey.Name != "arn:aws:kms:us-east-1:000000000000:key/key-1" || status.Queue.Name != "https://queue.example/company-1" {
+		t.Errorf("status = %+v, want the key ARN and queue URL named", status)
+	}
+
+	p, fakeKMS := newProducer("missing", types.Company{ID: "company-1"})
+	fakeKMS.SetKeyState("key-1", kmstypes.KeyStatePendingDeletion)
+	status, err = p.CheckInfrastructure(context.Background())
+	if status.Ready() || status.Bucket.Ready || status.KMSKey.Ready || status.Topic.Ready || status.Queue.Ready {
+		t.Errorf("status = %+v, want nothing ready", status)
+	}
+	for _, want := range []string{"bucket missing", "PendingDeletion", "topic", "queue"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("error = %v, want it to mention %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package producer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	kmstypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/helix-tools/sdk-go/v2/internal/awsfake"
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+// testKeyARN is the ARN serveDescribeKey and awsfake.KMS resolve keyID to.
+func testKeyARN(keyID string) string {
+	return "arn:aws:kms:us-east-1:000000000000:key/" + keyID
+}
+
+// serveDescribeKey answers a KMS DescribeKey request to a test server with
+// an enabled key and reports whether r was one.
+func serveDescribeKey(w http.ResponseWriter, r *http.Request) bool {
+	if r.Header.Get("X-Amz-Target") != "TrentService.DescribeKey" {
+		return false
+	}
+	var in struct{ KeyId string }
+	_ = json.NewDecoder(r.Body).Decode(&in)
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	_ = json.NewEncoder(w).Encode(map[string]any{"KeyMetadata": map[string]any{
+		"KeyId":    in.KeyId,
+		"Arn":      testKeyARN(in.KeyId),
+		"Enabled":  true,
+		"KeyState": "Enabled",
+		"KeyUsage": "ENCRYPT_DECRYPT",
+	}})
+	return true
+}
+
+// describeKeyKMS counts DescribeKey calls to awsfake.KMS and can fail them
+// or edit the key they describe.
+type describeKeyKMS struct {
+	*awsfake.KMS
+	calls  int
+	err    error
+	modify func(*kmstypes.KeyMetadata)
+}
+
+func (k *describeKeyKMS) DescribeKey(ctx context.Context, in *kms.DescribeKeyInput, optFns ...func(*kms.Options)) (*kms.DescribeKeyOutput, error) {
+	k.calls++
+	if k.err != nil {
+		return nil, k.err
+	}
+	out, err := k.KMS.DescribeKey(ctx, in, optFns...)
+	if err == nil && k.modify != nil {
+		k.modify(out.KeyMetadata)
+	}
+	return out, err
+}
+
+func TestNewProducerResolvesKMSKey(t *testing.T) {
+	t.Cleanup(ClearSSMCache)
+
+	build := func(keyID string, fakeKMS *describeKeyKMS) (*Producer, error) {
+		cfg := types.Config{CustomerID: "company-1", Region: "us-east-1", BucketName: "bucket-1", KMSKeyID: keyID}
+		return newProducer(cfg, aws.Config{Region: cfg.Region}, nil, fakeKMS, nil)
+	}
+
+	t.Run("resolved to its ARN and cached", func(t *testing.T) {
+		ClearSSMCache()
+		fakeKMS := &describeKeyKMS{KMS: awsfake.NewKMS()}
+		for range 2 {
+			p, err := build("alias/helix", fakeKMS)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.KMSKeyID != testKeyARN("alias/helix") {
+				t.Errorf("KMSKeyID = %q, want the key ARN", p.KMSKeyID)
+			}
+		}
+		if fakeKMS.calls != 1 {
+			t.Errorf("DescribeKey calls for 2 producers = %d, want 1", fakeKMS.calls)
+		}
+	})
+
+	t.Run("unusable keys fail construction", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			keyID  string
+			modify func(*kmstypes.KeyMetadata)
+			want   error
+		}{
+			{"missing", "missing", nil, types.ErrKMSKeyNotFound},
+			{"disabled", "key-1", func(m *kmstypes.KeyMetadata) {
+				m.Enabled, m.KeyState = false, kmstypes.KeyStateDisabled
+			}, types.ErrKMSKeyDisabled},
+			{"pending deletion", "key-1", func(m *kmstypes.KeyMetadata) {
+				m.Enabled, m.KeyState = false, kmstypes.KeyStatePendingDeletion
+			}, types.ErrKMSKeyDisabled},
+			{"signing key", "key-1", func(m *kmstypes.KeyMetadata) {
+				m.KeyUsage = kmstypes.KeyUsageTypeSignVerify
+			}, types.ErrKMSKeyDisabled},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				ClearSSMCache()
+				p, err := build(tt.keyID, &describeKeyKMS{KMS: awsfake.NewKMS(), modify: tt.modify})
+				if !errors.Is(err, tt.want) || p != nil {
+					t.Errorf("newProducer = %v, %v, want %v", p, err, tt.want)
+				}
+			})
+		}
+	})
+
+	t.Run("key used as configured when it cannot be described", func(t *testing.T) {
+		ClearSSMCache()
+		fakeKMS := &describeKeyKMS{KMS: awsfake.NewKMS(), err: errors.New("AccessDeniedException")}
+		p, err := build("key-1", fakeKMS)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.KMSKeyID != "key-1" {
+			t.Errorf("KMSKeyID = %q, want the configured key", p.KMSKeyID)
+		}
+	})
+}
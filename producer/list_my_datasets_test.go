@@ -0,0 +1,151 @@
+package producer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// TestListMyDatasets_WrappedResponse pins the /v1/datasets response shape:
+// the API wraps the list as {"datasets": [...], "count": N}.
+func TestListMyDatasets_WrappedResponse(t *testing.T) {
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/v1/datasets" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		query = r.URL.Query()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"datasets":[{"_id":"ds-1","name":"One"},{"_id":"ds-2","name":"Two"}],"count":2}`))
+	}))
+	defer server.Close()
+
+	p := newTestProducer(server.URL)
+	p.CustomerID = "company-1&x=y"
+
+	datasets, err := p.ListMyDatasets(context.Background())
+	if err != nil {
+		t.Fatalf("ListMyDatasets: %v", err)
+	}
+	if len(datasets) != 2 || datasets[0].ID != "ds-1" || datasets[1].ID != "ds-2" {
+		t.Fatalf("datasets = %+v, want ds-1 and ds-2", datasets)
+	}
+	if got := query.Get("producer_id"); got != "company-1&x=y" {
+		t.Errorf("producer_id = %q, want the escaped customer ID to round-trip", got)
+	}
+}
+
+func TestListMyDatasetsPaged(t *testing.T) {
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"datasets":[{"_id":"ds-3"}],"count":1,"pagination":{"total":3,"page":3,"per_page":1,"total_pages":3}}`))
+	}))
+	defer server.Close()
+
+	p := newTestProducer(server.URL)
+	resp, err := p.ListMyDatasetsPaged(context.Background(), 3, 1)
+	if err != nil {
+		t.Fatalf("ListMyDatasetsPaged: %v", err)
+	}
+
+	if query.Get("page") != "3" || query.Get("per_page") != "1" || query.Get("producer_id") != "test-producer" {
+		t.Errorf("query = %v, want page=3 per_page=1 producer_id=test-producer", query)
+	}
+	if len(resp.Datasets) != 1 || resp.Datasets[0].ID != "ds-3" || resp.Count != 1 {
+		t.Errorf("resp = %+v, want the single ds-3 dataset", resp)
+	}
+	if resp.Pagination == nil || resp.Pagination.TotalPages != 3 {
+		t.Errorf("Pagination = %+v, want total_pages=3", resp.Pagination)
+	}
+
+	// Zero page/perPage leave the parameters to the API defaults.
+	if _, err := p.ListMyDatasetsPaged(context.Background(), 0, 0); err != nil {
+		t.Fatalf("ListMyDatasetsPaged(0, 0): %v", err)
+	}
+	if query.Has("page") || query.Has("per_page") {
+		t.Errorf("query = %v, want no page/per_page for zero values", query)
+	}
+}
+
+func TestListMyDatasets_FollowsPagination(t *testing.T) {
+	var pages []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+		pages = append(pages, page)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"datasets":[{"_id":"ds-%s"}],"count":1,"pagination":{"total":3,"page":%s,"per_page":1,"total_pages":3}}`, page, page)
+	}))
+	defer server.Close()
+
+	datasets, err := newTestProducer(server.URL).ListMyDatasets(context.Background())
+	if err != nil {
+		t.Fatalf("ListMyDatasets: %v", err)
+	}
+
+	if got := strings.Join(pages, ","); got != "1,2,3" {
+		t.Errorf("requested pages %s, want 1,2,3", got)
+	}
+	var ids []string
+	for _, d := range datasets {
+		ids = append(ids, d.ID)
+	}
+	if got := strings.Join(ids, ","); got != "ds-1,ds-2,ds-3" {
+		t.Errorf("datasets = %s, want ds-1,ds-2,ds-3", got)
+	}
+}
+
+func TestListMyDatasets_PageCap(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		// A server that never reaches its last page.
+		_, _ = w.Write([]byte(`{"datasets":[{"_id":"ds"}],"count":1,"pagination":{"total":1000000,"page":1,"per_page":1,"total_pages":1000000}}`))
+	}))
+	defer server.Close()
+
+	_, err := newTestProducer(server.URL).ListMyDatasets(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "exceeded") {
+		t.Fatalf("err = %v, want page cap error", err)
+	}
+	if requests != listMaxPages {
+		t.Errorf("requests = %d, want %d", requests, listMaxPages)
+	}
+}
+
+func TestListMyDatasetsByCategory(t *testing.T) {
+	var queries []url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Query())
+		page := r.URL.Query().Get("page")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"datasets":[{"_id":"ds-%s"}],"count":1,"pagination":{"total":2,"page":%s,"per_page":1,"total_pages":2}}`, page, page)
+	}))
+	defer server.Close()
+
+	p := newTestProducer(server.URL)
+	p.CustomerID = "company-1"
+
+	datasets, err := p.ListMyDatasetsByCategory(context.Background(), "Real Estate & Land")
+	if err != nil {
+		t.Fatalf("ListMyDatasetsByCategory: %v", err)
+	}
+	if len(datasets) != 2 || datasets[0].ID != "ds-1" || datasets[1].ID != "ds-2" {
+		t.Errorf("datasets = %+v, want ds-1, ds-2", datasets)
+	}
+	for _, q := range queries {
+		if q.Get("category") != "Real Estate & Land" || q.Get("producer_id") != "company-1" {
+			t.Errorf("query = %v, want the category and producer_id filters", q)
+		}
+	}
+
+	if _, err := p.ListMyDatasetsByCategory(context.Background(), ""); err == nil {
+		t.Error("ListMyDatasetsByCategory with an empty category succeeded")
+	}
+}
@@ -0,0 +1,116 @@
+package producer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+// ManifestKey returns the S3 key of a dataset's manifest.
+func ManifestKey(datasetName string) string {
+	return fmt.Sprintf("datasets/%s/manifest.json", datasetName)
+}
+
+// WriteDatasetManifest writes the manifest of a dataset stored as several
+// parts to ManifestKey(datasetName) in the producer's bucket. Build parts
+// with types.NewManifestPart from the bytes actually stored, so consumers
+// can verify each part independently.
+func (p *Producer) WriteDatasetManifest(ctx context.Context, datasetName string, parts []types.ManifestPart) (*types.Manifest, error) {
+	if datasetName == "" {
+		return nil, types.ErrDatasetNameRequired
+	}
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("manifest needs at least one part")
+	}
+	if p.BucketName == "" || p.s3Client == nil {
+		return nil, fmt.Errorf("producer has no S3 bucket configured")
+	}
+
+	manifest := &types.Manifest{
+		ManifestVersion: types.ManifestVersion,
+		DatasetName:     datasetName,
+		Parts:           make([]types.ManifestPart, len(parts)),
+		CreatedAt:       time.Now().UTC().Format(time.RFC3339),
+	}
+	for i, part := range parts {
+		if part.S3Key == "" || part.SHA256 == "" {
+			return nil, fmt.Errorf("manifest part %d needs an s3_key and sha256", i)
+		}
+		part.DownloadURL = ""
+		manifest.Parts[i] = part
+		manifest.TotalSizeBytes += part.SizeBytes
+	}
+
+	if err := p.putManifest(ctx, manifest, nil); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// putManifest writes manifest to its key. With ifMatch set, the manifest
+// being replaced must still have that ETag.
+func (p *Producer) putManifest(ctx context.Context, manifest *types.Manifest, ifMatch *string) error {
+	body, err := json.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	key := ManifestKey(manifest.DatasetName)
+	if _, err := p.s3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(p.BucketName),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String("application/json"),
+		IfMatch:     ifMatch,
+	}); err != nil {
+		return fmt.Errorf("failed to write manifest s3://%s/%s: %w", p.BucketName, key, err)
+	}
+	return nil
+}
+
+// readManifest reads a dataset's manifest and returns it with its ETag.
+func (p *Producer) readManifest(ctx context.Context, datasetName string) (*types.Manifest, *string, error) {
+	manifest, etag, err := p.getManifest(ctx, p.BucketName, ManifestKey(datasetName))
+	if s3StatusCode(err) == http.StatusNotFound {
+		return nil, nil, fmt.Errorf("dataset %s has no manifest; only sharded datasets (see UploadShardedDataset) can be appended to", datasetName)
+	}
+	return manifest, etag, err
+}
+
+// getManifest reads the manifest at s3://bucket/key and returns it with
+// its ETag. S3 errors are wrapped, so s3StatusCode sees them.
+func (p *Producer) getManifest(ctx context.Context, bucket, key string) (*types.Manifest, *string, error) {
+	obj, err := p.s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read manifest s3://%s/%s: %w", bucket, key, err)
+	}
+	defer obj.Body.Close()
+
+	var manifest types.Manifest
+	if err := json.NewDecoder(obj.Body).Decode(&manifest); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode manifest s3://%s/%s: %w", bucket, key, err)
+	}
+	return &manifest, obj.ETag, nil
+}
+
+// s3StatusCode is the HTTP status of an S3 response error, or 0 for any
+// other error.
+func s3StatusCode(err error) int {
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) {
+		return respErr.HTTPStatusCode()
+	}
+	return 0
+}
@@ -0,0 +1,67 @@
+package producer
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+func TestWriteDatasetManifest(t *testing.T) {
+	p := newTestProducer("http://unused")
+	fake := &fakeS3{}
+	p.s3Client = fake
+
+	parts := []types.ManifestPart{
+		types.NewManifestPart("datasets/sales/part-0000.ndjson.gz", []byte("first")),
+		types.NewManifestPart("datasets/sales/part-0001.ndjson.gz", []byte("second part")),
+	}
+	parts[0].DownloadURL = "https://example.invalid/presigned"
+
+	manifest, err := p.WriteDatasetManifest(context.Background(), "sales", parts)
+	if err != nil {
+		t.Fatalf("WriteDatasetManifest: %v", err)
+	}
+	if manifest.TotalSizeBytes != 16 || len(manifest.Parts) != 2 || manifest.ManifestVersion != types.ManifestVersion {
+		t.Errorf("manifest = %+v, want 2 parts totalling 16 bytes", manifest)
+	}
+
+	body, ok := fake.puts["dme-producer-test/datasets/sales/manifest.json"]
+	if !ok {
+		t.Fatalf("manifest not written, puts = %v", fake.puts)
+	}
+	if strings.Contains(string(body), "download_url") {
+		t.Errorf("stored manifest must not carry presigned URLs: %s", body)
+	}
+	var stored types.Manifest
+	if err := json.Unmarshal(body, &stored); err != nil {
+		t.Fatalf("stored manifest is not JSON: %v", err)
+	}
+	if stored.DatasetName != "sales" || stored.Parts[1].SHA256 != parts[1].SHA256 {
+		t.Errorf("stored = %+v, want the parts as given", stored)
+	}
+}
+
+func TestWriteDatasetManifest_Validation(t *testing.T) {
+	p := newTestProducer("http://unused")
+	p.s3Client = &fakeS3{}
+	part := types.NewManifestPart("datasets/sales/part-0000.ndjson.gz", []byte("x"))
+
+	tests := []struct {
+		name, dataset string
+		parts         []types.ManifestPart
+	}{
+		{"no name", "", []types.ManifestPart{part}},
+		{"no parts", "sales", nil},
+		{"part without checksum", "sales", []types.ManifestPart{{S3Key: "k"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := p.WriteDatasetManifest(context.Background(), tt.dataset, tt.parts); err == nil {
+				t.Error("expected a validation error")
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package producer
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// reservedMetadataKeys are the dataset metadata keys the SDK sets during an
+// upload. A value a producer puts under one of them in UploadOptions.Metadata
+// is overwritten.
+var reservedMetadataKeys = []string{
+	"analysis_errors",
+	"compressed_size_bytes",
+	"compression_enabled",
+	"content_type", // set UploadOptions.ContentType instead
+	"encrypted_size_bytes",
+	"encryption_enabled",
+	"field_emptiness",
+	"file_format", // set UploadOptions.FileName instead
+	"kms_key_id",  // set UploadOptions.KMSKeyID instead
+	"kms_key_region",
+	"last_append",
+	"original_size_bytes",
+	"part_count",
+	"record_count",
+	"schema",
+	"storage_class", // set UploadOptions.StorageClass instead
+	"truncated_records",
+}
+
+// ErrReservedMetadataKey is returned by the Metadata setters for a key the
+// SDK manages itself (see ReservedMetadataKeys).
+var ErrReservedMetadataKey = errors.New("metadata key is reserved by the SDK")
+
+// ReservedMetadataKeys returns the metadata keys the SDK sets during an
+// upload, sorted. Values set under them in UploadOptions.Metadata are
+// overwritten.
+func ReservedMetadataKeys() []string {
+	return slices.Clone(reservedMetadataKeys)
+}
+
+// IsReservedMetadataKey reports whether the SDK sets key during an upload.
+func IsReservedMetadataKey(key string) bool {
+	return slices.Contains(reservedMetadataKeys, key)
+}
+
+// Metadata builds UploadOptions.Metadata with typed values, refusing keys
+// the SDK would overwrite:
+//
+//	md := producer.NewMetadata()
+//	if err := md.SetString("source", "crm-export"); err != nil { ... }
+//	opts.Metadata = md
+type Metadata map[string]any
+
+// NewMetadata returns an empty Metadata.
+func NewMetadata() Metadata {
+	return Metadata{}
+}
+
+// SetString sets key to a string value.
+func (m Metadata) SetString(key, value string) error {
+	return m.set(key, value)
+}
+
+// SetNumber sets key to a numeric value.
+func (m Metadata) SetNumber(key string, value float64) error {
+	return m.set(key, value)
+}
+
+// SetTime sets key to t as an RFC 3339 UTC timestamp, the format the
+// catalog uses for its own timestamps.
+func (m Metadata) SetTime(key string, t time.Time) error {
+	return m.set(key, t.UTC().Format(time.RFC3339))
+}
+
+func (m Metadata) set(key string, value any) error {
+	if key == "" {
+		return fmt.Errorf("metadata key must not be empty")
+	}
+	if IsReservedMetadataKey(key) {
+		return fmt.Errorf("%w: %q", ErrReservedMetadataKey, key)
+	}
+	m[key] = value
+	return nil
+}
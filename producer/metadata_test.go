@@ -0,0 +1,55 @@
+package producer
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestMetadata(t *testing.T) {
+	md := NewMetadata()
+	if err := md.SetString("source", "crm-export"); err != nil {
+		t.Fatalf("SetString: %v", err)
+	}
+	if err := md.SetNumber("rows_expected", 1200); err != nil {
+		t.Fatalf("SetNumber: %v", err)
+	}
+	ts := time.Date(2026, 10, 16, 9, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+	if err := md.SetTime("exported_at", ts); err != nil {
+		t.Fatalf("SetTime: %v", err)
+	}
+
+	if md["source"] != "crm-export" || md["rows_expected"] != 1200.0 || md["exported_at"] != "2026-10-16T07:30:00Z" {
+		t.Errorf("metadata = %v", md)
+	}
+
+	// Metadata is assignable to UploadOptions.Metadata as is.
+	opts := NewUploadOptions("sales")
+	opts.Metadata = md
+	if len(opts.Metadata) != 3 {
+		t.Errorf("opts.Metadata = %v", opts.Metadata)
+	}
+}
+
+func TestMetadataRejectsReservedKeys(t *testing.T) {
+	md := NewMetadata()
+	for _, key := range ReservedMetadataKeys() {
+		if err := md.SetString(key, "x"); !errors.Is(err, ErrReservedMetadataKey) {
+			t.Errorf("SetString(%q) = %v, want ErrReservedMetadataKey", key, err)
+		}
+	}
+	if err := md.SetNumber("", 1); err == nil {
+		t.Error("empty key should be rejected")
+	}
+	if len(md) != 0 {
+		t.Errorf("rejected keys were stored: %v", md)
+	}
+
+	if !slices.IsSorted(ReservedMetadataKeys()) {
+		t.Error("ReservedMetadataKeys should be sorted")
+	}
+	if !IsReservedMetadataKey("record_count") || IsReservedMetadataKey("encoding") {
+		t.Error("record_count is reserved; encoding is a producer-settable default")
+	}
+}
@@ -8,49 +8,113 @@ package producer
 
 import (
 	"bytes"
+	"cmp"
 	"compress/gzip"
 	"context"
 	"crypto"
-	"crypto/aes"
-	"crypto/cipher"
-	"crypto/rand"
-	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"maps"
+	"mime"
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/helix-tools/sdk-go/v2/client"
+	"github.com/helix-tools/sdk-go/v2/clientset"
 	stscreds "github.com/helix-tools/sdk-go/v2/credentials"
+	helixcrypto "github.com/helix-tools/sdk-go/v2/crypto"
+	"github.com/helix-tools/sdk-go/v2/internal/ratelimit"
 	"github.com/helix-tools/sdk-go/v2/types"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/kms"
+	kmstypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/aws/aws-sdk-go-v2/service/ssm"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 )
 
 // Producer handles uploading and managing datasets on Helix Connect platform.
+//
+// A Producer is safe for concurrent use by multiple goroutines: its fields
+// are set once at construction, and the HTTP and AWS clients it holds are
+// themselves safe for concurrent use.
 type Producer struct {
 	APIEndpoint string
 	BucketName  string
 	CustomerID  string
-	KMSKeyID    string
+	KMSKeyID    string // resolved to its ARN by NewProducer
 	Region      string
 
+	api        types.APIDoer // when set, replaces the signed API round-trip
 	awsConfig  aws.Config
 	httpClient *http.Client
-	kmsClient  *kms.Client
-	s3Client   *s3.Client
+	kmsClient  kmsAPI
+	limiter    *ratelimit.Limiter // nil when Config.RequestsPerSecond is unset
+	s3Client   s3API
+	tempDir    string // Config.TempDir
+	zeroize    bool   // Config.ZeroizeBuffers
+
+	categoriesMu sync.Mutex
+	categories   []string // from GetCategories; nil until fetched
+
+	autoApproveMu sync.Mutex // held while PollAndAutoApprove runs
+}
+
+// kmsAPI is the subset of the KMS client the producer calls.
+// *kms.Client satisfies it; tests substitute a fake.
+type kmsAPI interface {
+	Encrypt(ctx context.Context, params *kms.EncryptInput, optFns ...func(*kms.Options)) (*kms.EncryptOutput, error)
+	ReEncrypt(ctx context.Context, params *kms.ReEncryptInput, optFns ...func(*kms.Options)) (*kms.ReEncryptOutput, error)
+	DescribeKey(ctx context.Context, params *kms.DescribeKeyInput, optFns ...func(*kms.Options)) (*kms.DescribeKeyOutput, error)
+}
+
+// ssmAPI is the SSM call the producer makes. *ssm.Client satisfies it;
+// tests substitute a fake.
+type ssmAPI interface {
+	GetParameter(ctx context.Context, params *ssm.GetParameterInput, optFns ...func(*ssm.Options)) (*ssm.GetParameterOutput, error)
 }
 
+// s3API is the subset of the S3 client the producer calls directly.
+// *s3.Client satisfies it; tests substitute a fake.
+type s3API interface {
+	HeadBucket(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error)
+	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error)
+	CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error)
+	UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error)
+	UploadPartCopy(ctx context.Context, params *s3.UploadPartCopyInput, optFns ...func(*s3.Options)) (*s3.UploadPartCopyOutput, error)
+	CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error)
+	AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error)
+}
+
+const (
+	// rollbackTimeout bounds the cleanup issued when an upload is rolled back.
+	// The rollback runs on a context detached from the caller's cancellation (a
+	// cancelled upload is a prime reason to roll back), so it needs its own limit.
+	rollbackTimeout = 30 * time.Second
+
+	// listPageSize and listMaxPages bound ListMyDatasets' pagination loop.
+	listPageSize = 100
+	listMaxPages = 1000
+)
+
 // APIError represents an error returned by the Helix API with status code.
 type APIError struct {
 	StatusCode int
@@ -66,6 +130,17 @@ func (e *APIError) IsConflict() bool {
 	return e.StatusCode == http.StatusConflict
 }
 
+// IsNotFound returns true if the error is a 404 Not Found.
+func (e *APIError) IsNotFound() bool {
+	return e.StatusCode == http.StatusNotFound
+}
+
+// isNotFound reports whether err is an *APIError with status 404.
+func isNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.IsNotFound()
+}
+
 // UploadOptions contains options for uploading datasets.
 //
 // NOTE: Use NewUploadOptions() to get sane defaults.
@@ -80,6 +155,289 @@ type UploadOptions struct {
 	Encrypt          bool
 	Metadata         map[string]any
 	DatasetOverrides map[string]any
+
+	// ContentType is the MIME type of the uploaded file, recorded in dataset
+	// metadata so consumers can pick a matching file extension. When empty
+	// it is detected from the file extension (default "application/x-ndjson").
+	ContentType string
+
+	// FileName is the name of the stored object under datasets/{name}/,
+	// without the ".gz" the SDK appends when compressing. When empty it
+	// follows the content type: data.ndjson, data.json, data.csv, data.tsv or
+	// data.parquet (data.ndjson for anything else). Its extension is recorded
+	// in metadata as file_format.
+	FileName string
+
+	// DatasetID pins the catalog ID instead of letting the API assign one.
+	// Reusing the ID of an existing dataset updates it in place (and disables
+	// rollback, see RollbackOnRegistrationFailure). The ID must match the
+	// catalog format: lowercase letters, digits, and inner hyphens, at most
+	// 128 characters (e.g. "{customer}-{slug}").
+	DatasetID *string
+
+	// IDFormat generates the catalog ID client-side when DatasetID is unset
+	// (see IDFormat for placeholders). Empty leaves ID assignment to the API.
+	IDFormat IDFormat
+
+	// IdempotencyKey is sent as the Idempotency-Key header on the dataset
+	// create request, so a retried upload whose earlier create actually
+	// succeeded server-side doesn't register a duplicate. When empty, a
+	// deterministic key is derived from the producer ID, dataset name, and
+	// the file's SHA-256.
+	IdempotencyKey string
+
+	// RollbackOnRegistrationFailure deletes the dataset record and the
+	// just-uploaded S3 object when the catalog registration cannot be
+	// confirmed after retries, so a failed upload doesn't leave an orphaned,
+	// billable object behind (default: true). Only objects this upload
+	// created are rolled back: in-place updates (DatasetID, or an explicit
+	// "_id"/"id" in DatasetOverrides) and re-uploads over an existing key are left alone,
+	// since the object may be a valid prior version.
+	RollbackOnRegistrationFailure *bool
+
+	// StorageClass is the S3 storage class of the uploaded object (default:
+	// STANDARD). Rarely downloaded datasets are cheaper in STANDARD_IA or
+	// INTELLIGENT_TIERING; archive classes (GLACIER, DEEP_ARCHIVE) add
+	// retrieval latency for consumers. Recorded in metadata as storage_class.
+	StorageClass s3types.StorageClass
+
+	// S3Metadata is stored as user metadata (x-amz-meta-*) on the uploaded or
+	// copied object, or on every part of a sharded upload and the parts
+	// appended to it later, so the object can be identified in storage tooling
+	// without the catalog (e.g. {"dataset-id": ...}). Re-encrypting a dataset
+	// keeps its object's metadata. Keys must be HTTP header tokens and are
+	// lowercased; values must be printable ASCII; keys and values together
+	// must fit in 2 KB. Like StorageClass it is sent with the create request,
+	// since the presigned PUT carries it as headers the API must sign.
+	S3Metadata map[string]string
+
+	// UploadMode selects how UploadDataset writes the object (default:
+	// UploadModePresigned).
+	UploadMode UploadMode
+
+	// CompressionMode decides whether the file is gzipped. When empty,
+	// Compress decides (and must be true); otherwise it overrides Compress.
+	// The choice is recorded as compression_enabled, which consumers use to
+	// decide whether to decompress.
+	CompressionMode CompressionMode
+
+	// OnComplete, when set, is called with the upload's stats after a
+	// successful upload (not when an earlier attempt's dataset is returned).
+	OnComplete func(stats UploadStats)
+
+	// StrictCategory fails the upload when Category is not one of the
+	// catalog's known categories (GetCategories), fetching them if needed.
+	// Without it an unknown category only logs a warning, and only when the
+	// producer has already fetched the categories.
+	StrictCategory bool
+
+	// Preprocessed marks the source object of UploadDatasetFromS3 as already
+	// in stored form: sealed under KMSKeyID or the producer's KMS key
+	// (crypto.Seal) and gzipped as Compress or CompressionMode say. It is
+	// then copied server side without being read. UploadDataset ignores it.
+	Preprocessed bool
+
+	// KMSKeyID, when set, seals this upload under the given KMS key (ID,
+	// ARN or alias) instead of the producer's, e.g. to keep datasets of
+	// different classifications or tenants under separate keys. The key is
+	// checked with DescribeKey before any work is done, and its ARN is
+	// recorded as the dataset's kms_key_id metadata.
+	KMSKeyID string
+
+	// MaxFileBytes, when positive, fails the upload before anything is
+	// read if the source file is larger (for UploadShardedDataset, if the
+	// shards together are), with a *types.LimitError wrapping
+	// types.ErrFileTooLarge.
+	MaxFileBytes int64
+
+	// MaxRecords, when positive, fails the upload if the source has more
+	// records, with a *types.LimitError wrapping types.ErrTooManyRecords.
+	// It is enforced by the analysis pass, which stops at the first record
+	// over the limit, so nothing is compressed or registered.
+	MaxRecords int
+}
+
+// UploadStats describes a completed upload, to log or alert on slow
+// networks and compare original and stored sizes.
+type UploadStats struct {
+	OriginalBytes  int64         // size of the file read
+	BytesUploaded  int64         // bytes sent to storage, after compression and encryption
+	Duration       time.Duration // the whole upload, catalog registration included
+	ThroughputMBps float64       // MiB/s of the transfer to storage alone
+	Compressed     bool
+	Encrypted      bool
+	Timings        UploadTimings
+}
+
+// CompressionMode decides whether UploadDataset gzips the file.
+type CompressionMode string
+
+const (
+	// CompressionAlways gzips every file.
+	CompressionAlways CompressionMode = "always"
+
+	// CompressionNever uploads the file uncompressed, for data that is
+	// already compressed.
+	CompressionNever CompressionMode = "never"
+
+	// CompressionAuto gzips a sample from the start of the file and
+	// compresses the whole file only if the sample shrinks by at least
+	// minCompressionSavings, so high-entropy data isn't gzipped for nothing.
+	CompressionAuto CompressionMode = "auto"
+)
+
+const (
+	// compressionSampleSize is how much of the file CompressionAuto tries.
+	compressionSampleSize = 64 << 10
+
+	// minCompressionSavings is the fraction CompressionAuto's sample must
+	// shrink by for the file to be compressed.
+	minCompressionSavings = 0.05
+)
+
+// UploadMode selects how UploadDataset writes the processed object to S3.
+type UploadMode string
+
+const (
+	// UploadModePresigned PUTs the object to the presigned URL the API
+	// issues with the dataset record. It needs only API access, no S3
+	// permissions, and is the default.
+	UploadModePresigned UploadMode = "presigned"
+
+	// UploadModeDirect writes the object with the producer's own S3 client
+	// (s3:PutObject on its bucket), for producers whose network can reach
+	// S3 but not the presigned URL's endpoint, or that need the object
+	// written under their own credentials.
+	UploadModeDirect UploadMode = "direct"
+)
+
+// uploadMode resolves UploadMode, defaulting to UploadModePresigned.
+func (o UploadOptions) uploadMode() UploadMode {
+	if o.UploadMode == "" {
+		return UploadModePresigned
+	}
+	return o.UploadMode
+}
+
+// rollbackOnRegistrationFailure resolves RollbackOnRegistrationFailure,
+// defaulting to true when unset.
+func (o UploadOptions) rollbackOnRegistrationFailure() bool {
+	if o.RollbackOnRegistrationFailure == nil {
+		return true
+	}
+	return *o.RollbackOnRegistrationFailure
+}
+
+// isInPlaceUpdate reports whether the upload targets an explicit dataset ID
+// (DatasetID, or "_id"/"id" in DatasetOverrides) rather than creating a new one.
+func (o UploadOptions) isInPlaceUpdate() bool {
+	if o.DatasetID != nil && *o.DatasetID != "" {
+		return true
+	}
+	_, _, ok := overrideDatasetID(o.DatasetOverrides)
+	return ok
+}
+
+// validateDatasetID checks DatasetID against the catalog ID format and
+// rejects a conflicting "_id"/"id" in DatasetOverrides.
+func (o UploadOptions) validateDatasetID() error {
+	if o.DatasetID == nil {
+		return nil
+	}
+	if !datasetIDRegex.MatchString(*o.DatasetID) {
+		return fmt.Errorf("invalid dataset ID %q: must be 1-128 lowercase letters, digits, or inner hyphens", *o.DatasetID)
+	}
+	if id, key, ok := overrideDatasetID(o.DatasetOverrides); ok && id != *o.DatasetID {
+		return fmt.Errorf("dataset ID %q conflicts with DatasetOverrides[%q] = %q", *o.DatasetID, key, id)
+	}
+	return nil
+}
+
+// storageClass resolves StorageClass, defaulting to STANDARD.
+func (o UploadOptions) storageClass() s3types.StorageClass {
+	if o.StorageClass == "" {
+		return s3types.StorageClassStandard
+	}
+	return o.StorageClass
+}
+
+// validateStorageClass rejects a StorageClass S3 doesn't define.
+func (o UploadOptions) validateStorageClass() error {
+	if o.StorageClass == "" || slices.Contains(o.StorageClass.Values(), o.StorageClass) {
+		return nil
+	}
+	return fmt.Errorf("invalid storage class %q: must be one of %v", o.StorageClass, o.StorageClass.Values())
+}
+
+// withDefaults fills the fields UploadDataset defaults when unset: Category
+// "general", DataFreshness daily, CompressionLevel 6.
+func (o UploadOptions) withDefaults() UploadOptions {
+	if o.Category == "" {
+		o.Category = "general"
+	}
+	if o.DataFreshness == "" {
+		o.DataFreshness = types.DataFreshnessDaily
+	}
+	if o.CompressionLevel == 0 {
+		o.CompressionLevel = 6
+	}
+	return o
+}
+
+// Validate reports every problem with the options as UploadDataset would
+// use them (after defaulting an empty Category, DataFreshness and
+// CompressionLevel), joined into one error. UploadDataset calls it before
+// doing any work; callers can use it to check options up front.
+func (o UploadOptions) Validate() error {
+	o = o.withDefaults()
+
+	var errs []error
+	if strings.TrimSpace(o.DatasetName) == "" {
+		errs = append(errs, types.ErrDatasetNameRequired)
+	}
+	if strings.TrimSpace(o.Category) == "" {
+		errs = append(errs, fmt.Errorf("category must not be blank"))
+	}
+	if !o.DataFreshness.IsValid() {
+		errs = append(errs, fmt.Errorf("unknown data freshness %q", o.DataFreshness))
+	}
+	if o.CompressionLevel < gzip.BestSpeed || o.CompressionLevel > gzip.BestCompression {
+		errs = append(errs, fmt.Errorf("compression level %d out of range %d-%d", o.CompressionLevel, gzip.BestSpeed, gzip.BestCompression))
+	}
+	if !o.Encrypt {
+		errs = append(errs, types.ErrEncryptionRequired)
+	}
+	if !o.Compress && o.CompressionMode == "" {
+		errs = append(errs, types.ErrCompressionRequired)
+	}
+	switch o.CompressionMode {
+	case "", CompressionAlways, CompressionNever, CompressionAuto:
+	default:
+		errs = append(errs, fmt.Errorf("unknown compression mode %q", o.CompressionMode))
+	}
+	if err := o.validateDatasetID(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := o.validateStorageClass(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := o.validateS3Metadata(); err != nil {
+		errs = append(errs, err)
+	}
+	if mode := o.uploadMode(); mode != UploadModePresigned && mode != UploadModeDirect {
+		errs = append(errs, fmt.Errorf("unknown upload mode %q", o.UploadMode))
+	}
+	if o.FileName != "" && !fileNameRegex.MatchString(o.FileName) {
+		errs = append(errs, fmt.Errorf("invalid file name %q: use letters, digits, '.', '_' and '-', starting with a letter or digit", o.FileName))
+	}
+	if strings.HasSuffix(strings.ToLower(o.FileName), ".gz") {
+		errs = append(errs, fmt.Errorf("file name %q must not end in .gz; the SDK adds it when compressing", o.FileName))
+	}
+	if o.MaxFileBytes < 0 || o.MaxRecords < 0 {
+		errs = append(errs, fmt.Errorf("MaxFileBytes and MaxRecords must not be negative"))
+	}
+
+	return errors.Join(errs...)
 }
 
 // NewUploadOptions creates UploadOptions with sane defaults.
@@ -126,63 +484,224 @@ func NewProducer(cfg types.Config) (*Producer, error) {
 		return nil, fmt.Errorf("failed to select AWS credentials provider: %w", err)
 	}
 
+	endpointOpt, err := clientset.EndpointOption(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	// Load AWS config.
 	awsCfg, err := config.LoadDefaultConfig(context.Background(),
 		config.WithRegion(cfg.Region),
 		config.WithCredentialsProvider(credProvider),
+		endpointOpt,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load AWS config: %w", err)
 	}
 
-	// Validate credentials.
-	stsClient := sts.NewFromConfig(awsCfg)
-	if _, err = stsClient.GetCallerIdentity(
-		context.Background(),
-		&sts.GetCallerIdentityInput{},
-	); err != nil {
-		return nil, fmt.Errorf("invalid AWS credentials: %w", err)
+	// Validate credentials, unless the caller opted out.
+	if !cfg.SkipCredentialValidation {
+		stsClient := sts.NewFromConfig(awsCfg)
+		if _, err = stsClient.GetCallerIdentity(
+			context.Background(),
+			&sts.GetCallerIdentityInput{},
+		); err != nil {
+			return nil, fmt.Errorf("invalid AWS credentials: %w", err)
+		}
 	}
 
-	// Get producer-specific resources from SSM.
-	ssmClient := ssm.NewFromConfig(awsCfg)
+	return newProducer(cfg, awsCfg, ssm.NewFromConfig(awsCfg), kms.NewFromConfig(awsCfg), s3.NewFromConfig(awsCfg, clientset.S3Options(cfg)))
+}
 
-	// Get S3 bucket name.
-	bucketParamCandidates := ssmParamCandidates(cfg.CustomerID, "s3_bucket")
-	bucketValue, err := getSSMParameterValue(context.Background(), ssmClient, bucketParamCandidates)
-	if err != nil {
-		return nil, fmt.Errorf("S3 bucket not found for producer %s: %w", cfg.CustomerID, err)
+// NewProducerFromClientSet creates a Producer on the shared clients of cs,
+// so a process that also runs a Consumer keeps a single credential provider
+// and connection pool. The credential check, if any, already ran in
+// clientset.New; the producer's bucket and KMS key are still looked up here.
+func NewProducerFromClientSet(cs *clientset.ClientSet) (*Producer, error) {
+	return newProducer(cs.Config, cs.AWSConfig, cs.SSM, cs.KMS, cs.S3)
+}
+
+// NewProducerWithAPI creates a Producer whose API requests go to api instead
+// of being signed and sent to the Helix API, so code built on it can be unit
+// tested without credentials (see package helixtest). It makes no calls
+// itself; BucketName and KMSKeyID are taken from cfg as is. Methods that
+// also call AWS directly, such as an encrypted UploadDataset, fail with a
+// credentials error.
+func NewProducerWithAPI(cfg types.Config, api types.APIDoer) *Producer {
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
 	}
 
-	// Get KMS key ID.
-	kmsKeyID := ""
-	kmsParamCandidates := ssmParamCandidates(cfg.CustomerID, "kms_key_id")
-	kmsValue, err := getSSMParameterValue(context.Background(), ssmClient, kmsParamCandidates)
-	if err != nil {
-		fmt.Printf("Warning: KMS key not found, encryption will be disabled: %v\n", err)
-	} else {
-		kmsKeyID = kmsValue
+	awsCfg := aws.Config{
+		Region: cfg.Region,
+		Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{}, errors.New("no AWS credentials: producer was built with NewProducerWithAPI")
+		}),
 	}
 
 	return &Producer{
 		APIEndpoint: cfg.APIEndpoint,
-		BucketName:  bucketValue,
+		BucketName:  cfg.BucketName,
 		CustomerID:  cfg.CustomerID,
-		KMSKeyID:    kmsKeyID,
+		KMSKeyID:    cfg.KMSKeyID,
 		Region:      cfg.Region,
 
+		api:        api,
 		awsConfig:  awsCfg,
-		httpClient: &http.Client{},
+		httpClient: client.HTTPClientFor(cfg, 0),
 		kmsClient:  kms.NewFromConfig(awsCfg),
-		s3Client:   s3.NewFromConfig(awsCfg),
-	}, nil
+		limiter:    ratelimit.New(cfg.RequestsPerSecond, cfg.Burst),
+		tempDir:    cfg.TempDir,
+		zeroize:    cfg.ZeroizeBuffers,
+	}
+}
+
+// newProducer looks up the producer's bucket and KMS key in SSM, resolves
+// the key to its ARN, and builds the Producer on the given clients.
+func newProducer(cfg types.Config, awsCfg aws.Config, ssmClient ssmAPI, kmsClient kmsAPI, s3Client s3API) (*Producer, error) {
+	p := &Producer{
+		APIEndpoint: cfg.APIEndpoint,
+		CustomerID:  cfg.CustomerID,
+		Region:      cfg.Region,
+
+		awsConfig:  awsCfg,
+		httpClient: client.HTTPClientFor(cfg, 0),
+		kmsClient:  kmsClient,
+		limiter:    ratelimit.New(cfg.RequestsPerSecond, cfg.Burst),
+		s3Client:   s3Client,
+		tempDir:    cfg.TempDir,
+		zeroize:    cfg.ZeroizeBuffers,
+	}
+
+	// Get S3 bucket name and KMS key ID, unless the caller supplied them.
+	bucketValue, kmsValue := cfg.BucketName, cfg.KMSKeyID
+	cacheTTL := cfg.SSMCacheTTL
+	scope, ok := cacheScope(context.Background(), cfg.Region, awsCfg)
+	if !ok {
+		cacheTTL = -1
+	}
+	var bucketErr, kmsErr error
+	if bucketValue == "" {
+		bucketParamCandidates := ssmParamCandidates(cfg.SSMPathPrefix, cfg.CustomerID, "s3_bucket")
+		bucketValue, bucketErr = ssmCache.getParameter(context.Background(), ssmClient, scope, bucketParamCandidates, cacheTTL)
+	}
+	if kmsValue == "" {
+		kmsParamCandidates := ssmParamCandidates(cfg.SSMPathPrefix, cfg.CustomerID, "kms_key_id")
+		kmsValue, kmsErr = ssmCache.getParameter(context.Background(), ssmClient, scope, kmsParamCandidates, cacheTTL)
+	}
+
+	// Right after onboarding the SSM parameters may not be provisioned yet;
+	// the company record carries the same values.
+	if bucketErr != nil || kmsErr != nil {
+		company, err := p.getOwnCompany(context.Background())
+		if err != nil {
+			fmt.Printf("Warning: company record lookup failed: %v\n", err)
+		} else {
+			if bucket := companyS3Bucket(company); bucketErr != nil && bucket != "" {
+				bucketValue, bucketErr = bucket, nil
+				fmt.Printf("S3 bucket resolved from the company record (not in SSM)\n")
+			}
+			if key := companyKMSKeyID(company); kmsErr != nil && key != "" {
+				kmsValue, kmsErr = key, nil
+				fmt.Printf("KMS key resolved from the company record (not in SSM)\n")
+			}
+		}
+	}
+
+	if bucketErr != nil {
+		return nil, fmt.Errorf("S3 bucket not found for producer %s: %w", cfg.CustomerID, bucketErr)
+	}
+	p.BucketName = bucketValue
+
+	if kmsErr != nil {
+		fmt.Printf("Warning: KMS key not found, encryption will be disabled: %v\n", kmsErr)
+		return p, nil
+	}
+	if cfg.SkipCredentialValidation {
+		p.KMSKeyID = kmsValue
+		return p, nil
+	}
+
+	// Canonicalize the key to its ARN, so it compares equal however SSM
+	// or the caller spelled it, and fail now rather than at the first
+	// upload if it cannot encrypt.
+	keyARN, err := kmsKeyCache.get(scope+"\n"+cfg.CustomerID+"\n"+kmsValue, cacheTTL, func() (string, error) {
+		return describeEncryptionKey(context.Background(), kmsClient, kmsValue)
+	})
+	switch {
+	case errors.Is(err, types.ErrKMSKeyNotFound) || errors.Is(err, types.ErrKMSKeyDisabled):
+		return nil, fmt.Errorf("producer %s: %w", cfg.CustomerID, err)
+	case err != nil:
+		// Without kms:DescribeKey the key may still encrypt; uploads will
+		// tell.
+		fmt.Printf("Warning: could not validate KMS key, using it as configured: %v\n", err)
+		p.KMSKeyID = kmsValue
+	default:
+		p.KMSKeyID = keyARN
+	}
+
+	return p, nil
 }
 
-func ssmParamCandidates(customerID, paramName string) []string {
+// getOwnCompany fetches the producer's company record.
+//
+// GET /v1/companies/:id
+func (p *Producer) getOwnCompany(ctx context.Context) (*types.Company, error) {
+	var company types.Company
+	path := fmt.Sprintf("/v1/companies/%s", url.PathEscape(p.CustomerID))
+	if err := p.makeAPIRequest(ctx, http.MethodGet, path, nil, &company); err != nil {
+		return nil, err
+	}
+	return &company, nil
+}
+
+// companyS3Bucket returns the company's bucket, preferring the top-level
+// field over the provisioned infrastructure details.
+func companyS3Bucket(company *types.Company) string {
+	if company.S3Bucket != "" || company.Infrastructure == nil {
+		return company.S3Bucket
+	}
+	return company.Infrastructure.S3Bucket
+}
+
+// companyKMSKeyID is companyS3Bucket for the KMS key.
+func companyKMSKeyID(company *types.Company) string {
+	if company.KMSKeyID != "" || company.Infrastructure == nil {
+		return company.KMSKeyID
+	}
+	return company.Infrastructure.KMSKeyID
+}
+
+// Close releases the producer's idle HTTP connections, both to the API and
+// to AWS. The producer must not be used after Close. It always returns nil;
+// the error is there for future resources that can fail to release.
+func (p *Producer) Close() error {
+	closeIdleConnections(p.httpClient, p.awsConfig.HTTPClient)
+	return nil
+}
+
+// closeIdleConnections closes idle connections on every client that
+// supports it.
+func closeIdleConnections(clients ...any) {
+	for _, client := range clients {
+		if c, ok := client.(interface{ CloseIdleConnections() }); ok {
+			c.CloseIdleConnections()
+		}
+	}
+}
+
+// ssmParamCandidates lists the SSM names to try, in order, for a customer
+// parameter. An explicit prefix (Config.SSMPathPrefix) is the only candidate,
+// so a staging producer never falls back to production parameters.
+func ssmParamCandidates(prefix, customerID, paramName string) []string {
 	if customerID == "" || paramName == "" {
 		return nil
 	}
 
+	if prefix = strings.TrimRight(prefix, "/"); prefix != "" {
+		return []string{fmt.Sprintf("%s/%s/%s", prefix, customerID, paramName)}
+	}
+
 	env := os.Getenv("HELIX_ENVIRONMENT")
 	if env == "" {
 		env = os.Getenv("ENVIRONMENT")
@@ -216,7 +735,8 @@ func ssmParamCandidates(customerID, paramName string) []string {
 	return candidates
 }
 
-func getSSMParameterValue(ctx context.Context, client *ssm.Client, names []string) (string, error) {
+// getSSMParameterValue returns the value of the first of names that exists.
+func getSSMParameterValue(ctx context.Context, client ssmAPI, names []string) (string, error) {
 	var lastErr error
 	for _, name := range names {
 		resp, err := client.GetParameter(ctx, &ssm.GetParameterInput{
@@ -257,78 +777,99 @@ func (p *Producer) compressData(data []byte, level int) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
-// encryptData encrypts data using envelope encryption
-// Process:
-// 1. Generate random data key (32 bytes for AES-256)
-// 2. Encrypt data with the data key
-// 3. Encrypt the data key with KMS
-// 4. Return: [key_length][encrypted_key][iv][tag][encrypted_data]
-func (p *Producer) encryptData(ctx context.Context, data []byte) ([]byte, error) {
-	if p.KMSKeyID == "" {
-		return nil, fmt.Errorf("KMS key not configured, cannot encrypt data")
-	}
+// resolveCompression settles opts.CompressionMode into opts.Compress for
+// filePath, so the dataset record, the S3 key and the processed data all
+// agree on it. For CompressionAuto it gzips up to compressionSampleSize
+// bytes from the start of the file at opts.CompressionLevel.
+func (p *Producer) resolveCompression(filePath string, opts UploadOptions) (UploadOptions, error) {
+	switch opts.CompressionMode {
+	case CompressionAlways:
+		opts.Compress = true
+	case CompressionNever:
+		opts.Compress = false
+	case CompressionAuto:
+		f, err := os.Open(filePath)
+		if err != nil {
+			return opts, fmt.Errorf("failed to open file: %w", err)
+		}
+		defer f.Close()
 
-	// Generate random data key and IV.
-	dataKey := make([]byte, 32) // 256-bit key for AES-256.
-	if _, err := rand.Read(dataKey); err != nil {
-		return nil, fmt.Errorf("failed to generate data key: %w", err)
+		sample, err := io.ReadAll(io.LimitReader(f, compressionSampleSize))
+		if err != nil {
+			return opts, fmt.Errorf("failed to read file: %w", err)
+		}
+		compressed, err := p.compressData(sample, opts.CompressionLevel)
+		if err != nil {
+			return opts, err
+		}
+		opts.Compress = len(sample) > 0 && float64(len(compressed)) <= float64(len(sample))*(1-minCompressionSavings)
+		if !opts.Compress {
+			fmt.Printf("📦 Skipping compression: a %d-byte sample only compressed to %d bytes\n", len(sample), len(compressed))
+		}
 	}
+	return opts, nil
+}
 
-	iv := make([]byte, 16) // 128-bit IV for GCM mode (16 bytes, matches Python SDK).
-	if _, err := rand.Read(iv); err != nil {
-		return nil, fmt.Errorf("failed to generate IV: %w", err)
-	}
+// encryptData encrypts data under the producer's KMS key (see package
+// crypto for the format).
+func (p *Producer) encryptData(ctx context.Context, data []byte) ([]byte, error) {
+	return p.encryptDataWithKey(ctx, p.KMSKeyID, data)
+}
 
-	// Encrypt data with data key using AES-256-GCM.
-	block, err := aes.NewCipher(dataKey)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create cipher: %w", err)
+// encryptDataWithKey is encryptData under keyID.
+func (p *Producer) encryptDataWithKey(ctx context.Context, keyID string, data []byte) ([]byte, error) {
+	return helixcrypto.Seal(ctx, p.kmsClient, keyID, data, helixcrypto.SealOptions{})
+}
+
+// uploadKeyID is the KMS key an upload with opts is sealed under:
+// opts.KMSKeyID, or the producer's key when that is unset.
+func (p *Producer) uploadKeyID(opts UploadOptions) string {
+	return cmp.Or(opts.KMSKeyID, p.KMSKeyID)
+}
+
+// resolveUploadKey checks the key an upload with opts is sealed under. An
+// opts.KMSKeyID is described, must be able to encrypt, and is replaced with
+// the key's ARN, so a typo or a key the producer cannot use fails the
+// upload before the catalog record is created.
+func (p *Producer) resolveUploadKey(ctx context.Context, opts UploadOptions) (UploadOptions, error) {
+	if opts.KMSKeyID == "" {
+		if p.KMSKeyID == "" {
+			return opts, fmt.Errorf("encryption requested but %w", types.ErrKMSKeyNotFound)
+		}
+		return opts, nil
 	}
 
-	// Use 16-byte nonce to match Python's os.urandom(16).
-	aesGCM, err := cipher.NewGCMWithNonceSize(block, 16)
+	keyARN, err := describeEncryptionKey(ctx, p.kmsClient, opts.KMSKeyID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create GCM: %w", err)
+		return opts, err
 	}
+	opts.KMSKeyID = keyARN
+	return opts, nil
+}
 
-	// Encrypt the data (auth tag is automatically appended).
-	encryptedData := aesGCM.Seal(nil, iv, data, nil)
-
-	// Split encrypted data and auth tag
-	authTagSize := aesGCM.Overhead()
-	actualEncryptedData := encryptedData[:len(encryptedData)-authTagSize]
-	authTag := encryptedData[len(encryptedData)-authTagSize:]
-
-	// Encrypt the data key with KMS.
-	encryptOutput, err := p.kmsClient.Encrypt(ctx, &kms.EncryptInput{
-		KeyId:     aws.String(p.KMSKeyID),
-		Plaintext: dataKey,
-	})
+// describeEncryptionKey resolves keyID (a key ID, alias or ARN) to the
+// key's ARN, failing with types.ErrKMSKeyNotFound if it does not exist and
+// types.ErrKMSKeyDisabled if it cannot encrypt: it is disabled, pending
+// deletion, or not an ENCRYPT_DECRYPT key.
+func describeEncryptionKey(ctx context.Context, kmsClient kmsAPI, keyID string) (string, error) {
+	out, err := kmsClient.DescribeKey(ctx, &kms.DescribeKeyInput{KeyId: aws.String(keyID)})
 	if err != nil {
-		return nil, fmt.Errorf("KMS encryption failed: %w", err)
+		var notFound *kmstypes.NotFoundException
+		if errors.As(err, &notFound) {
+			return "", fmt.Errorf("KMS key %s is not accessible (%w): %w", keyID, types.ErrKMSKeyNotFound, err)
+		}
+		return "", fmt.Errorf("KMS key %s is not accessible: %w", keyID, err)
 	}
-
-	// Package: [4 bytes: key length][encrypted key][16 bytes: IV][16 bytes: tag][encrypted data].
-	var result bytes.Buffer
-
-	// Write encrypted key length (4 bytes, big-endian).
-	if err := binary.Write(&result, binary.BigEndian, uint32(len(encryptOutput.CiphertextBlob))); err != nil {
-		return nil, fmt.Errorf("failed to write key length: %w", err)
+	if out.KeyMetadata == nil || aws.ToString(out.KeyMetadata.Arn) == "" {
+		return "", fmt.Errorf("KMS key %s: DescribeKey returned no key ARN", keyID)
 	}
-
-	// Write encrypted key.
-	result.Write(encryptOutput.CiphertextBlob)
-
-	// Write IV (16 bytes).
-	result.Write(iv)
-
-	// Write auth tag (16 bytes).
-	result.Write(authTag)
-
-	// Write encrypted data.
-	result.Write(actualEncryptedData)
-
-	return result.Bytes(), nil
+	if !out.KeyMetadata.Enabled {
+		return "", fmt.Errorf("KMS key %s cannot encrypt: %w (key state is %s)", keyID, types.ErrKMSKeyDisabled, out.KeyMetadata.KeyState)
+	}
+	if usage := out.KeyMetadata.KeyUsage; usage != "" && usage != kmstypes.KeyUsageTypeEncryptDecrypt {
+		return "", fmt.Errorf("KMS key %s cannot encrypt: %w (key usage is %s)", keyID, types.ErrKMSKeyDisabled, usage)
+	}
+	return aws.ToString(out.KeyMetadata.Arn), nil
 }
 
 // CreateDatasetResponse represents the API response when creating a dataset record.
@@ -336,6 +877,10 @@ type CreateDatasetResponse struct {
 	ID        string `json:"id"`
 	UploadURL string `json:"upload_url"`
 	S3Key     string `json:"s3_key"`
+
+	// replayed is set when the API rejected the create as a duplicate
+	// Idempotency-Key: the dataset already exists and only ID is populated.
+	replayed bool
 }
 
 // ProcessedFileData contains the processed (encrypted/compressed) file data and metadata.
@@ -344,23 +889,122 @@ type ProcessedFileData struct {
 	OriginalSize int64
 	Sizes        map[string]any
 	Analysis     *AnalysisResult
+
+	// CompressionTime and EncryptionTime are how long each stage took.
+	CompressionTime time.Duration
+	EncryptionTime  time.Duration
+}
+
+// UploadTimings is the wall-clock duration of each UploadDataset stage.
+// Stages that did not run are zero.
+type UploadTimings struct {
+	CreateRecord time.Duration // analysis + POST /v1/datasets
+	Compression  time.Duration
+	Encryption   time.Duration // includes the KMS data key request
+	Upload       time.Duration // PUT to the presigned URL
+	Registration time.Duration // catalog confirmation, including retries
+	Total        time.Duration
+}
+
+// UploadResult is returned by UploadDatasetWithResult.
+type UploadResult struct {
+	Dataset *types.Dataset
+	Timings UploadTimings
+}
+
+// GetUploadURL creates the catalog record for uploading filePath and returns
+// it with the presigned URL the processed (compressed, encrypted) data is
+// PUT to. It is step 1 of UploadDataset, exposed for callers that drive the
+// upload themselves; the PUT needs no S3 permissions, only the URL. The
+// upload must still be finished with the PUT, or the record stays pending.
+//
+// If the API reports opts.IdempotencyKey (or the derived key) as already
+// used, no URL is issued and an error naming the existing dataset is
+// returned.
+func (p *Producer) GetUploadURL(ctx context.Context, filePath string, opts UploadOptions) (*CreateDatasetResponse, error) {
+	opts = opts.withDefaults()
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if _, err := checkSourceFile(filePath, opts); err != nil {
+		return nil, err
+	}
+	opts, err := p.resolveUploadKey(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	opts, err = p.resolveCompression(filePath, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.createDatasetRecord(ctx, filePath, opts)
+	if err != nil {
+		return nil, err
+	}
+	if resp.replayed {
+		return nil, fmt.Errorf("dataset %s was already created with this idempotency key", resp.ID)
+	}
+	return resp, nil
 }
 
 // createDatasetRecord creates a dataset record in the catalog and retrieves presigned URL.
 // This is step 1 of the new POST-first upload flow.
+//
+// An empty filePath means the data is not local (UploadDatasetFromS3's
+// server-side copy): it is not analyzed, and opts.IdempotencyKey must be set.
 func (p *Producer) createDatasetRecord(ctx context.Context, filePath string, opts UploadOptions) (*CreateDatasetResponse, error) {
+	var record datasetRecord
+	if filePath != "" {
+		record.filePaths = []string{filePath}
+	}
+	return p.createRecord(ctx, opts, record)
+}
+
+// datasetRecord describes what createRecord registers beyond opts.
+type datasetRecord struct {
+	filePaths []string       // analyzed as one dataset; the first names the content type
+	s3Key     string         // the stored object; default datasets/{name}/{file name}
+	fields    map[string]any // extra payload fields, before DatasetOverrides
+	metadata  map[string]any // extra SDK-set metadata
+}
+
+// createRecord is createDatasetRecord for record.
+func (p *Producer) createRecord(ctx context.Context, opts UploadOptions, record datasetRecord) (*CreateDatasetResponse, error) {
+	if err := p.checkCategory(ctx, opts); err != nil {
+		return nil, err
+	}
+
+	var filePath string
+	if len(record.filePaths) > 0 {
+		filePath = record.filePaths[0]
+	}
+
 	// Analyze data before compression/encryption (memory-efficient streaming).
 	var analysis *AnalysisResult
-	analysisResult, err := p.analyzeData(filePath, DefaultAnalysisOptions())
-	if err != nil {
-		fmt.Printf("⚠️  Warning: Data analysis failed, continuing without analysis: %v\n", err)
-	} else {
-		analysis = analysisResult
+	var err error
+	if filePath != "" {
+		analysisOpts := DefaultAnalysisOptions()
+		analysisOpts.MaxRecords = opts.MaxRecords
+		analysisResult, err := p.analyzeFiles(record.filePaths, analysisOpts)
+		if errors.Is(err, types.ErrTooManyRecords) {
+			return nil, fmt.Errorf("%s: %w", filePath, err)
+		}
+		if err != nil {
+			fmt.Printf("⚠️  Warning: Data analysis failed, continuing without analysis: %v\n", err)
+		} else {
+			analysis = analysisResult
+		}
 	}
 
 	// Build initial metadata (sizes will be updated after processing)
 	metadata := make(map[string]any)
 	maps.Copy(metadata, opts.Metadata)
+	for key := range opts.Metadata {
+		if IsReservedMetadataKey(key) {
+			fmt.Printf("Warning: metadata key %q is set by the SDK; the value provided is ignored\n", key)
+		}
+	}
 
 	// Record encryption/compression so the CONSUMER download knows to reverse
 	// them: Consumer.DownloadDataset reads dataset.Metadata["encryption_enabled"]
@@ -371,6 +1015,34 @@ func (p *Producer) createDatasetRecord(ctx context.Context, filePath string, opt
 	metadata["encryption_enabled"] = opts.Encrypt
 	metadata["compression_enabled"] = opts.Compress
 
+	// content_type describes the ORIGINAL file (before compression and
+	// encryption); the consumer uses it to name auto-downloaded files.
+	contentType := opts.ContentType
+	if contentType == "" {
+		contentType = detectContentType(filePath)
+	}
+	metadata["content_type"] = contentType
+	metadata["storage_class"] = string(opts.storageClass())
+
+	// kms_key_region tells the consumer which regional KMS endpoint can
+	// decrypt the data key when the key lives outside its own region;
+	// kms_key_id names a per-upload key for decrypt and re-encrypt tooling.
+	if opts.Encrypt {
+		metadata["kms_key_region"] = helixcrypto.KeyRegion(p.uploadKeyID(opts), p.Region)
+		if opts.KMSKeyID != "" {
+			metadata["kms_key_id"] = opts.KMSKeyID
+		}
+	}
+
+	fileName := opts.FileName
+	if fileName == "" {
+		fileName = defaultFileName(contentType)
+	}
+	if format := fileFormat(fileName); format != "" {
+		metadata["file_format"] = format
+	}
+	maps.Copy(metadata, record.metadata)
+
 	// Add analysis results to metadata if available
 	if analysis != nil {
 		metadata["schema"] = analysis.Schema
@@ -379,6 +1051,9 @@ func (p *Producer) createDatasetRecord(ctx context.Context, filePath string, opt
 		if analysis.AnalysisErrors > 0 {
 			metadata["analysis_errors"] = analysis.AnalysisErrors
 		}
+		if analysis.TruncatedRecords > 0 {
+			metadata["truncated_records"] = analysis.TruncatedRecords
+		}
 	}
 
 	// s3_key MUST be sent, dataset-NAME-keyed, matching Python/TS
@@ -388,14 +1063,18 @@ func (p *Producer) createDatasetRecord(ctx context.Context, filePath string, opt
 	// the s3-event-processor derives dataset_name from the key's FIRST segment,
 	// so a producer-id-keyed object yields dataset_name=<producer_id>, the
 	// findOneAndUpdate never matches, and subscribers are notified with the
-	// wrong name (or not at all). Go always compresses, so the file is
-	// `data.ndjson.gz`. (Found 2026-07-06 by the SDK-only E2E suite: go uploads
-	// landed under datasets/<customer_id>/ while py/ts used datasets/<name>/.)
-	fileName := "data.ndjson"
+	// wrong name (or not at all). (Found 2026-07-06 by the SDK-only E2E
+	// suite: go uploads landed under datasets/<customer_id>/ while py/ts used
+	// datasets/<name>/.) The file name follows UploadOptions.FileName or the
+	// content type, with ".gz" added when the upload is compressed (Compress,
+	// as resolved from CompressionMode).
 	if opts.Compress {
 		fileName += ".gz"
 	}
-	s3Key := fmt.Sprintf("datasets/%s/%s", opts.DatasetName, fileName)
+	s3Key := record.s3Key
+	if s3Key == "" {
+		s3Key = fmt.Sprintf("datasets/%s/%s", opts.DatasetName, fileName)
+	}
 
 	// Build dataset payload (without size, which is set after upload).
 	// s3_bucket_name and access_tier are also REQUIRED by the create validator
@@ -415,35 +1094,201 @@ func (p *Producer) createDatasetRecord(ctx context.Context, filePath string, opt
 		"metadata":       metadata,
 	}
 
+	if opts.DatasetID != nil || opts.IDFormat != "" {
+		datasetID := p.generateDatasetIDWithFormat(opts.DatasetName, opts.DatasetID, opts.IDFormat)
+		if !datasetIDRegex.MatchString(datasetID) {
+			return nil, fmt.Errorf("IDFormat %q produced invalid dataset ID %q", opts.IDFormat, datasetID)
+		}
+		payload["_id"] = datasetID
+	}
+
+	// A non-default storage class is sent as a request field as well as in
+	// metadata: the presigned PUT carries X-Amz-Storage-Class, which S3 only
+	// accepts when the API signed it into the URL.
+	if class := opts.storageClass(); class != s3types.StorageClassStandard {
+		payload["storage_class"] = string(class)
+	}
+	if metadata := opts.s3Metadata(); metadata != nil {
+		payload["s3_metadata"] = metadata
+	}
+
+	maps.Copy(payload, record.fields)
+
 	// Merge dataset overrides
 	if opts.DatasetOverrides != nil {
 		maps.Copy(payload, opts.DatasetOverrides)
 	}
 
+	idempotencyKey := opts.IdempotencyKey
+	if idempotencyKey == "" {
+		if idempotencyKey, err = p.deriveIdempotencyKey(filePath, opts.DatasetName); err != nil {
+			return nil, err
+		}
+	}
+
 	// POST to /v1/datasets to create record and get presigned URL
 	var response CreateDatasetResponse
-	err = p.makeAPIRequest(ctx, "POST", "/v1/datasets", payload, &response)
+	headers := http.Header{"Idempotency-Key": {idempotencyKey}}
+	err = p.makeAPIRequestWithHeaders(ctx, "POST", "/v1/datasets", payload, &response, headers)
 	if err != nil {
+		if id := replayedDatasetID(err, idempotencyKey); id != "" {
+			return &CreateDatasetResponse{ID: id, replayed: true}, nil
+		}
 		return nil, fmt.Errorf("failed to create dataset record: %w", err)
 	}
 
+	// Older API versions don't echo s3_key; keep the key we sent (including a
+	// DatasetOverrides["s3_key"]) so later steps know which object was written.
+	if response.S3Key == "" {
+		response.S3Key, _ = payload["s3_key"].(string)
+	}
+
 	return &response, nil
 }
 
+// contentTypesByExt covers the dataset formats producers commonly upload;
+// other extensions fall back to the system MIME table.
+var contentTypesByExt = map[string]string{
+	".ndjson":  "application/x-ndjson",
+	".jsonl":   "application/x-ndjson",
+	".json":    "application/json",
+	".csv":     "text/csv",
+	".tsv":     "text/tab-separated-values",
+	".parquet": "application/vnd.apache.parquet",
+}
+
+// fileNamesByContentType names the stored object after the uploaded format.
+var fileNamesByContentType = map[string]string{
+	"application/x-ndjson":           "data.ndjson",
+	"application/json":               "data.json",
+	"text/csv":                       "data.csv",
+	"text/tab-separated-values":      "data.tsv",
+	"application/vnd.apache.parquet": "data.parquet",
+}
+
+// fileNameRegex matches a single, non-hidden path element.
+var fileNameRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// defaultFileName returns the stored object name for a content type,
+// "data.ndjson" when it is unknown.
+func defaultFileName(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil {
+		if name, ok := fileNamesByContentType[mediaType]; ok {
+			return name
+		}
+	}
+	return "data.ndjson"
+}
+
+// fileFormat is the file_format metadata for a stored file name: its
+// lower-case extension without the dot.
+func fileFormat(fileName string) string {
+	return strings.ToLower(strings.TrimPrefix(filepath.Ext(fileName), "."))
+}
+
+// detectContentType guesses the MIME type of filePath from its extension,
+// defaulting to NDJSON (the format the upload pipeline analyzes).
+func detectContentType(filePath string) string {
+	ext := strings.ToLower(filepath.Ext(filePath))
+	if contentType, ok := contentTypesByExt[ext]; ok {
+		return contentType
+	}
+	if contentType := mime.TypeByExtension(ext); contentType != "" {
+		return contentType
+	}
+	return "application/x-ndjson"
+}
+
+// deriveIdempotencyKey builds the default Idempotency-Key: a SHA-256 over the
+// producer ID, dataset name, and the SHA-256 of the file contents, so a retry
+// of the same upload always reuses the same key.
+func (p *Producer) deriveIdempotencyKey(filePath, datasetName string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	defer file.Close()
+
+	content := crypto.SHA256.New()
+	if _, err := io.Copy(content, file); err != nil {
+		return "", fmt.Errorf("failed to hash file: %w", err)
+	}
+
+	h := crypto.SHA256.New()
+	fmt.Fprintf(h, "%s\n%s\n%x", p.CustomerID, datasetName, content.Sum(nil))
+
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// replayedDatasetID returns the existing dataset ID from a 409 the API sent
+// because the Idempotency-Key was already used, or "" for any other error
+// (including a plain name conflict).
+func replayedDatasetID(err error, idempotencyKey string) string {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || !apiErr.IsConflict() {
+		return ""
+	}
+
+	var body struct {
+		ID             string `json:"id"`
+		MongoID        string `json:"_id"`
+		DatasetID      string `json:"dataset_id"`
+		IdempotencyKey string `json:"idempotency_key"`
+	}
+	if json.Unmarshal([]byte(apiErr.Body), &body) != nil {
+		return ""
+	}
+
+	switch {
+	case body.IdempotencyKey != "":
+		if body.IdempotencyKey != idempotencyKey {
+			return ""
+		}
+	case !strings.Contains(strings.ToLower(apiErr.Body), "idempotency"):
+		return ""
+	}
+
+	for _, id := range []string{body.DatasetID, body.ID, body.MongoID} {
+		if id != "" {
+			return id
+		}
+	}
+
+	return ""
+}
+
+// checkSourceFile fails with types.ErrEmptyFile when filePath has no data,
+// or a *types.LimitError when it is over opts.MaxFileBytes, so the file is
+// rejected before the catalog record is created. It returns the file size.
+func checkSourceFile(filePath string, opts UploadOptions) (int64, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read file: %w", err)
+	}
+	if info.Size() == 0 {
+		return 0, fmt.Errorf("%w: %s (no data to upload)", types.ErrEmptyFile, filePath)
+	}
+	if opts.MaxFileBytes > 0 && info.Size() > opts.MaxFileBytes {
+		return 0, fmt.Errorf("%s: %w", filePath, &types.LimitError{Observed: info.Size(), Allowed: opts.MaxFileBytes, Err: types.ErrFileTooLarge})
+	}
+	return info.Size(), nil
+}
+
 // processFile reads, compresses, and encrypts the file data.
 // This is step 2 of the new POST-first upload flow.
 func (p *Producer) processFile(ctx context.Context, filePath string, opts UploadOptions) (*ProcessedFileData, error) {
 	// Validate encryption/compression requirements
 	if !opts.Encrypt {
-		return nil, fmt.Errorf("encryption is required for dataset uploads")
+		return nil, types.ErrEncryptionRequired
 	}
 
-	if !opts.Compress {
-		return nil, fmt.Errorf("compression is required for dataset uploads")
+	if !opts.Compress && opts.CompressionMode == "" {
+		return nil, types.ErrCompressionRequired
 	}
 
-	if opts.Encrypt && p.KMSKeyID == "" {
-		return nil, fmt.Errorf("encryption requested but KMS key not found")
+	if opts.Encrypt && p.uploadKeyID(opts) == "" {
+		return nil, fmt.Errorf("encryption requested but %w", types.ErrKMSKeyNotFound)
 	}
 
 	// Read original file
@@ -453,10 +1298,13 @@ func (p *Producer) processFile(ctx context.Context, filePath string, opts Upload
 	}
 
 	originalSize := int64(len(data))
+	if p.zeroize {
+		defer clear(data) // only the sealed copy is returned
+	}
 
 	// Validate file is not empty
 	if originalSize == 0 {
-		return nil, fmt.Errorf("file is empty: %s (no data to upload)", filePath)
+		return nil, fmt.Errorf("%w: %s (no data to upload)", types.ErrEmptyFile, filePath)
 	}
 
 	// Track sizes for metadata
@@ -468,16 +1316,23 @@ func (p *Producer) processFile(ctx context.Context, filePath string, opts Upload
 		"compression_enabled":   opts.Compress,
 	}
 
+	var compressionTime, encryptionTime time.Duration
+
 	// Step 1: Compress FIRST
 	if opts.Compress {
+		start := time.Now()
 		fmt.Printf("📦 Compressing %d bytes with gzip (level %d)...\n", len(data), opts.CompressionLevel)
 
 		compressed, err := p.compressData(data, opts.CompressionLevel)
 		if err != nil {
 			return nil, fmt.Errorf("compression failed: %w", err)
 		}
+		if p.zeroize {
+			defer clear(compressed)
+		}
 
 		data = compressed
+		compressionTime = time.Since(start)
 		sizes["compressed_size_bytes"] = int64(len(data))
 
 		compressionRatio := (1 - float64(len(data))/float64(originalSize)) * 100
@@ -486,29 +1341,98 @@ func (p *Producer) processFile(ctx context.Context, filePath string, opts Upload
 
 	// Step 2: Encrypt SECOND
 	if opts.Encrypt {
+		start := time.Now()
 		fmt.Printf("🔒 Encrypting %d bytes with KMS key...\n", len(data))
 
-		encrypted, err := p.encryptData(ctx, data)
+		encrypted, err := p.encryptDataWithKey(ctx, p.uploadKeyID(opts), data)
 		if err != nil {
 			return nil, fmt.Errorf("encryption failed: %w", err)
 		}
 
 		data = encrypted
+		encryptionTime = time.Since(start)
 		sizes["encrypted_size_bytes"] = int64(len(data))
 
 		fmt.Printf("Encrypted: %d bytes\n", len(data))
 	}
 
 	return &ProcessedFileData{
-		Data:         data,
-		OriginalSize: originalSize,
-		Sizes:        sizes,
+		Data:            data,
+		OriginalSize:    originalSize,
+		Sizes:           sizes,
+		CompressionTime: compressionTime,
+		EncryptionTime:  encryptionTime,
 	}, nil
 }
 
+// UploadViaPresignedURL compresses and encrypts filePath as UploadDataset
+// does and PUTs the result to uploadURL, a presigned URL from GetUploadURL.
+// Together they are UploadDataset's presigned path for callers that issue
+// the URL in one place and upload from another: the uploading side needs
+// no S3 permissions, only the URL and KMS access for encryption. Unlike
+// UploadDataset it does not confirm the catalog registration afterwards.
+func (p *Producer) UploadViaPresignedURL(ctx context.Context, uploadURL, filePath string, opts UploadOptions) error {
+	opts = opts.withDefaults()
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	if uploadURL == "" {
+		return fmt.Errorf("upload URL is required")
+	}
+	if _, err := checkSourceFile(filePath, opts); err != nil {
+		return err
+	}
+	opts, err := p.resolveUploadKey(ctx, opts)
+	if err != nil {
+		return err
+	}
+	opts, err = p.resolveCompression(filePath, opts)
+	if err != nil {
+		return err
+	}
+
+	processedData, err := p.processFile(ctx, filePath, opts)
+	if err != nil {
+		return err
+	}
+	return p.uploadToPresignedURL(ctx, uploadURL, processedData.Data, uploadHeaders(opts))
+}
+
+// uploadObject writes the processed data for createResp as opts.UploadMode
+// selects: to the presigned URL, or with PutObject to the producer's bucket
+// under the record's S3 key.
+func (p *Producer) uploadObject(ctx context.Context, createResp *CreateDatasetResponse, data []byte, opts UploadOptions) error {
+	if opts.uploadMode() != UploadModeDirect {
+		return p.uploadToPresignedURL(ctx, createResp.UploadURL, data, uploadHeaders(opts))
+	}
+
+	if p.s3Client == nil || p.BucketName == "" {
+		return fmt.Errorf("direct upload needs an S3 client and bucket")
+	}
+	if createResp.S3Key == "" {
+		return fmt.Errorf("direct upload needs the dataset's S3 key, which the API did not return")
+	}
+
+	fmt.Printf("📤 Uploading %d bytes to s3://%s/%s...\n", len(data), p.BucketName, createResp.S3Key)
+	if _, err := p.s3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(p.BucketName),
+		Key:           aws.String(createResp.S3Key),
+		Body:          bytes.NewReader(data),
+		ContentLength: aws.Int64(int64(len(data))),
+		ContentType:   aws.String("application/octet-stream"),
+		StorageClass:  opts.storageClass(),
+		Metadata:      opts.s3Metadata(),
+	}); err != nil {
+		return fmt.Errorf("failed to put s3://%s/%s: %w", p.BucketName, createResp.S3Key, err)
+	}
+
+	fmt.Printf("✅ Upload successful\n")
+	return nil
+}
+
 // uploadToPresignedURL uploads the processed data to the presigned URL.
 // This is step 3 of the new POST-first upload flow.
-func (p *Producer) uploadToPresignedURL(ctx context.Context, uploadURL string, data []byte) error {
+func (p *Producer) uploadToPresignedURL(ctx context.Context, uploadURL string, data []byte, headers http.Header) error {
 	fmt.Printf("📤 Uploading %d bytes to presigned URL...\n", len(data))
 
 	req, err := http.NewRequestWithContext(ctx, "PUT", uploadURL, bytes.NewReader(data))
@@ -519,6 +1443,9 @@ func (p *Producer) uploadToPresignedURL(ctx context.Context, uploadURL string, d
 	// Set content type for binary data
 	req.Header.Set("Content-Type", "application/octet-stream")
 	req.ContentLength = int64(len(data))
+	for name, values := range headers {
+		req.Header[name] = values
+	}
 
 	resp, err := p.httpClient.Do(req)
 	if err != nil {
@@ -538,176 +1465,433 @@ func (p *Producer) uploadToPresignedURL(ctx context.Context, uploadURL string, d
 	return nil
 }
 
+// uploadHeaders returns the extra headers for the presigned PUT. They must
+// match what the API signed into the URL from the create request.
+func uploadHeaders(opts UploadOptions) http.Header {
+	headers := http.Header{}
+	if class := opts.storageClass(); class != s3types.StorageClassStandard {
+		headers.Set("X-Amz-Storage-Class", string(class))
+	}
+	opts.setS3MetadataHeaders(headers)
+	return headers
+}
+
 // UploadDataset uploads a dataset with optional encryption and compression.
 // NEW FLOW (POST-first to prevent race conditions):
 // 1. POST to /v1/datasets to create record and get presigned URL
 // 2. Process file (compress + encrypt)
-// 3. PUT to presigned URL
-// 4. Return dataset
+// 3. PUT to presigned URL (PutObject with opts.UploadMode UploadModeDirect)
+// 4. Confirm catalog registration and return the dataset
+//
+// If the object reaches S3 but the catalog registration cannot be confirmed,
+// an error wrapping the underlying API error is returned (never a synthetic
+// dataset), with the dataset ID and S3 key in its message. If this upload
+// created the object, the record and object are then rolled back unless
+// opts.RollbackOnRegistrationFailure is false.
+//
+// The create request carries an Idempotency-Key (opts.IdempotencyKey, or one
+// derived from the producer, dataset name, and file contents). If the API
+// reports the key as already used, the existing dataset is returned once
+// its object is found in S3; if the earlier attempt never stored it (say,
+// its create timed out), the object is written now with PutObject.
 //
 // NOTE: Use NewUploadOptions() to get sane defaults.
 func (p *Producer) UploadDataset(ctx context.Context, filePath string, opts UploadOptions) (*types.Dataset, error) {
-	// Set defaults for fields not specified
-	if opts.Category == "" {
-		opts.Category = "general"
+	result, err := p.UploadDatasetWithResult(ctx, filePath, opts)
+	if err != nil {
+		return nil, err
 	}
+	return result.Dataset, nil
+}
 
-	if opts.DataFreshness == "" {
-		opts.DataFreshness = types.DataFreshnessDaily
-	}
+// UploadDatasetWithResult is UploadDataset, additionally reporting how long
+// each stage took, to tell whether compression, encryption, the S3 upload,
+// or catalog registration dominates a slow upload.
+func (p *Producer) UploadDatasetWithResult(ctx context.Context, filePath string, opts UploadOptions) (*UploadResult, error) {
+	started := time.Now()
+	result := &UploadResult{}
+	opts = opts.withDefaults()
 
-	if opts.CompressionLevel == 0 {
-		opts.CompressionLevel = 6
+	if err := opts.Validate(); err != nil {
+		return nil, err
 	}
-
-	// Validate encryption capability
-	if !opts.Encrypt {
-		return nil, fmt.Errorf("encryption is required for dataset uploads")
+	if _, err := checkSourceFile(filePath, opts); err != nil {
+		return nil, err
 	}
 
-	if !opts.Compress {
-		return nil, fmt.Errorf("compression is required for dataset uploads")
+	opts, err := p.resolveUploadKey(ctx, opts)
+	if err != nil {
+		return nil, err
 	}
 
-	if opts.Encrypt && p.KMSKeyID == "" {
-		return nil, fmt.Errorf("encryption requested but KMS key not found")
+	opts, err = p.resolveCompression(filePath, opts)
+	if err != nil {
+		return nil, err
 	}
 
 	// Step 1: Create dataset record and get presigned URL
+	stageStart := time.Now()
 	createResp, err := p.createDatasetRecord(ctx, filePath, opts)
 	if err != nil {
 		return nil, err
 	}
+	result.Timings.CreateRecord = time.Since(stageStart)
 
-	fmt.Printf("✅ Dataset record created: %s\n", createResp.ID)
+	if createResp.replayed {
+		dataset, err := p.replayedUpload(ctx, createResp)
+		if err != nil {
+			return nil, err
+		}
+		if dataset != nil {
+			result.Dataset = dataset
+			result.Timings.Total = time.Since(started)
+			return result, nil
+		}
+		// A replay carries no presigned URL, so the object the earlier
+		// attempt never stored is written directly.
+		opts.UploadMode = UploadModeDirect
+	} else {
+		fmt.Printf("✅ Dataset record created: %s\n", createResp.ID)
+	}
+
+	// Decide before the PUT: the key is name-based, so re-uploading an
+	// existing dataset overwrites the live object, which must never be
+	// deleted by a rollback.
+	rollback := p.canRollBack(ctx, createResp.S3Key, opts)
 
 	// Step 2: Process file (encrypt/compress)
 	processedData, err := p.processFile(ctx, filePath, opts)
 	if err != nil {
 		return nil, err
 	}
+	result.Timings.Compression = processedData.CompressionTime
+	result.Timings.Encryption = processedData.EncryptionTime
 
-	// Step 3: Upload to presigned URL
-	if err := p.uploadToPresignedURL(ctx, createResp.UploadURL, processedData.Data); err != nil {
+	// Step 3: Upload to presigned URL (or straight to S3, see UploadMode)
+	stageStart = time.Now()
+	if err := p.uploadObject(ctx, createResp, processedData.Data, opts); err != nil {
 		return nil, fmt.Errorf("dataset record created but upload failed: %w", err)
 	}
+	result.Timings.Upload = time.Since(stageStart)
 
-	// Step 4: Return dataset (fetch updated record from API)
-	dataset := &types.Dataset{}
-	err = p.makeAPIRequest(ctx, "GET", fmt.Sprintf("/v1/datasets/%s", url.PathEscape(createResp.ID)), nil, dataset)
+	// Step 4: Confirm catalog registration and return the dataset.
+	stageStart = time.Now()
+	dataset, err := p.confirmCatalogRegistration(ctx, createResp)
 	if err != nil {
-		// If GET fails, construct a basic dataset response
-		fmt.Printf("⚠️  Warning: Failed to fetch dataset details: %v\n", err)
-		return &types.Dataset{
-			ID:           createResp.ID,
-			IDAlias:      createResp.ID,
-			Name:         opts.DatasetName,
-			Description:  opts.Description,
-			ProducerID:   p.CustomerID,
-			Category:     opts.Category,
-			S3Key:        createResp.S3Key,
-			S3BucketName: p.BucketName,
-			S3Bucket:     p.BucketName,
-		}, nil
+		if rollback {
+			return nil, p.rollbackUpload(ctx, createResp, err)
+		}
+		return nil, err
 	}
+	result.Timings.Registration = time.Since(stageStart)
 
-	return dataset, nil
+	result.Dataset = dataset
+	result.Timings.Total = time.Since(started)
+
+	if opts.OnComplete != nil {
+		stats := UploadStats{
+			OriginalBytes: processedData.OriginalSize,
+			BytesUploaded: int64(len(processedData.Data)),
+			Duration:      result.Timings.Total,
+			Compressed:    opts.Compress,
+			Encrypted:     opts.Encrypt,
+			Timings:       result.Timings,
+		}
+		if upload := result.Timings.Upload; upload > 0 {
+			stats.ThroughputMBps = float64(stats.BytesUploaded) / (1024 * 1024) / upload.Seconds()
+		}
+		opts.OnComplete(stats)
+	}
+	return result, nil
 }
 
-// makeAPIRequest makes an authenticated API request.
-func (p *Producer) makeAPIRequest(ctx context.Context, method, path string, body, response any) error {
-	apiURL, err := url.Parse(p.APIEndpoint + path)
-	if err != nil {
-		return fmt.Errorf("invalid API URL: %w", err)
+// replayedUpload handles a create the API rejected as a replay of an earlier
+// attempt's Idempotency-Key. If the earlier attempt also stored the object
+// under the record's S3 key, the existing dataset is returned. If it did not
+// (it failed between the create and the upload, e.g. because the create's
+// response timed out), nil is returned with createResp.S3Key set, and the
+// caller stores the object now.
+func (p *Producer) replayedUpload(ctx context.Context, createResp *CreateDatasetResponse) (*types.Dataset, error) {
+	dataset := &types.Dataset{}
+	path := fmt.Sprintf("/v1/datasets/%s", url.PathEscape(createResp.ID))
+	if err := p.makeAPIRequest(ctx, http.MethodGet, path, nil, dataset); err != nil {
+		return nil, fmt.Errorf("failed to fetch existing dataset %s: %w", createResp.ID, err)
 	}
 
-	var (
-		reqBody  io.Reader
-		jsonData []byte
-	)
+	if dataset.S3Key == "" || p.s3Client == nil || p.BucketName == "" {
+		fmt.Printf("Warning: cannot check that dataset %s was stored by the earlier attempt; assuming it was\n", createResp.ID)
+		return dataset, nil
+	}
+	_, err := p.s3Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(p.BucketName),
+		Key:    aws.String(dataset.S3Key),
+	})
+	switch {
+	case err == nil:
+		fmt.Printf("✅ Dataset already uploaded by an earlier attempt: %s\n", createResp.ID)
+		return dataset, nil
+	case s3StatusCode(err) != http.StatusNotFound:
+		return nil, fmt.Errorf("failed to check s3://%s/%s for dataset %s: %w", p.BucketName, dataset.S3Key, createResp.ID, err)
+	}
 
-	if body != nil {
-		var err error
-		jsonData, err = json.Marshal(body)
-		if err != nil {
-			return fmt.Errorf("failed to marshal request body: %w", err)
-		}
+	fmt.Printf("✅ Dataset created by an earlier attempt that did not store it: %s\n", createResp.ID)
+	createResp.S3Key = dataset.S3Key
+	return nil, nil
+}
 
-		reqBody = bytes.NewReader(jsonData)
+// canRollBack reports whether a failed registration may delete the object
+// at key: rollback must be enabled, the upload must not be an in-place
+// update, and the key must not exist yet. Any probe result other than a 404
+// (including 403 without s3:ListBucket) counts as "exists".
+func (p *Producer) canRollBack(ctx context.Context, key string, opts UploadOptions) bool {
+	if !opts.rollbackOnRegistrationFailure() || opts.isInPlaceUpdate() || p.s3Client == nil {
+		return false
 	}
 
-	req, err := http.NewRequestWithContext(ctx, method, apiURL.String(), reqBody)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+	_, err := p.s3Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(p.BucketName),
+		Key:    aws.String(key),
+	})
+
+	var respErr *awshttp.ResponseError
+	return errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusNotFound
+}
+
+// rollbackUpload undoes a failed upload, then returns regErr. The step-1
+// catalog record is deleted first; the object is only deleted once the record
+// is gone, so the catalog never points at missing data. If either delete
+// fails, the cleanup error is wrapped alongside regErr so operators can clean
+// up manually.
+func (p *Producer) rollbackUpload(ctx context.Context, createResp *CreateDatasetResponse, regErr error) error {
+	rbCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), rollbackTimeout)
+	defer cancel()
+
+	fmt.Printf("🧹 Rolling back upload: deleting dataset %s and s3://%s/%s\n", createResp.ID, p.BucketName, createResp.S3Key)
+
+	if err := p.DeleteDataset(rbCtx, createResp.ID); err != nil && !isNotFound(err) {
+		return fmt.Errorf("%w (rollback failed, dataset %s and s3://%s/%s left in place: %w)",
+			regErr, createResp.ID, p.BucketName, createResp.S3Key, err)
 	}
 
-	if body != nil {
-		req.Header.Set("Content-Type", "application/json")
+	if _, err := p.s3Client.DeleteObject(rbCtx, &s3.DeleteObjectInput{
+		Bucket: aws.String(p.BucketName),
+		Key:    aws.String(createResp.S3Key),
+	}); err != nil {
+		return fmt.Errorf("%w (rollback of s3://%s/%s also failed, manual cleanup required: %w)",
+			regErr, p.BucketName, createResp.S3Key, err)
 	}
 
-	// Sign request with AWS SigV4.
-	creds, err := p.awsConfig.Credentials.Retrieve(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to retrieve credentials: %w", err)
+	fmt.Printf("✅ Rolled back dataset %s and s3://%s/%s\n", createResp.ID, p.BucketName, createResp.S3Key)
+
+	return regErr
+}
+
+// confirmCatalogRegistration fetches the dataset record after the upload;
+// the API client retries transient failures with backoff.
+// This is step 4 of the POST-first upload flow.
+func (p *Producer) confirmCatalogRegistration(ctx context.Context, createResp *CreateDatasetResponse) (*types.Dataset, error) {
+	path := fmt.Sprintf("/v1/datasets/%s", url.PathEscape(createResp.ID))
+
+	dataset := &types.Dataset{}
+	if err := p.makeAPIRequest(ctx, http.MethodGet, path, nil, dataset); err != nil {
+		return nil, fmt.Errorf("file uploaded to S3 but catalog registration failed (dataset_id=%s, s3_key=%s): %w",
+			createResp.ID, createResp.S3Key, err)
 	}
 
-	// Calculate payload hash for SigV4.
-	var payloadHash string
+	return dataset, nil
+}
 
-	if body != nil {
-		// Hash the actual JSON body.
-		h := crypto.SHA256.New()
+// makeAPIRequest makes an authenticated API request.
+func (p *Producer) makeAPIRequest(ctx context.Context, method, path string, body, response any) error {
+	return p.makeAPIRequestWithHeaders(ctx, method, path, body, response, nil)
+}
 
-		h.Write(jsonData)
+// makeAPIRequestWithHeaders is makeAPIRequest with extra request headers,
+// which are set before signing.
+func (p *Producer) makeAPIRequestWithHeaders(ctx context.Context, method, path string, body, response any, headers http.Header) error {
+	if p.api != nil {
+		// headers are transport detail (e.g. the idempotency key) and are
+		// not passed to an APIDoer.
+		err := p.api.Do(ctx, method, path, body, response)
+		var statusErr *types.StatusError
+		if errors.As(err, &statusErr) {
+			return &APIError{StatusCode: statusErr.StatusCode, Body: statusErr.Body}
+		}
+		return err
+	}
 
-		payloadHash = fmt.Sprintf("%x", h.Sum(nil))
-	} else {
-		// Empty payload hash for GET requests.
-		payloadHash = types.EmptyPayloadHash
+	// A response to decode but no body is an error, as it has always been
+	// for the producer's own requests.
+	apiClient := p.APIClient()
+	apiClient.RequireBody = true
+	err := apiClient.RequestWithHeaders(ctx, method, path, body, response, headers)
+	var apiErr *client.APIError
+	if errors.As(err, &apiErr) {
+		return &APIError{StatusCode: apiErr.StatusCode, Body: apiErr.Body}
 	}
+	return err
+}
 
-	signer := v4.NewSigner()
-	if err := signer.SignHTTP(ctx, creds, req, payloadHash, "execute-api", p.Region, time.Now()); err != nil {
-		return fmt.Errorf("failed to sign request: %w", err)
+// APIClient returns a client for the Helix API that signs with the
+// producer's credentials and shares its HTTP client and rate limit, for
+// calling endpoints the SDK does not wrap. It always talks to APIEndpoint,
+// even for a Producer built with NewProducerWithAPI.
+func (p *Producer) APIClient() *client.Client {
+	return &client.Client{
+		Endpoint:   p.APIEndpoint,
+		Region:     p.Region,
+		AWSConfig:  p.awsConfig,
+		HTTPClient: p.httpClient,
+		Limiter:    p.limiter,
+		Retry:      client.DefaultRetryPolicy,
 	}
+}
 
-	// Execute request.
-	resp, err := p.httpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("request failed: %w", err)
+// ListMyDatasets lists all datasets uploaded by this producer, following
+// pagination until the last page. It fails rather than truncating if the
+// listing runs past listMaxPages pages.
+func (p *Producer) ListMyDatasets(ctx context.Context) ([]types.Dataset, error) {
+	return p.listMyDatasets(ctx, "")
+}
+
+// ListMyDatasetsByCategory is ListMyDatasets limited to this producer's
+// datasets in category.
+func (p *Producer) ListMyDatasetsByCategory(ctx context.Context, category string) ([]types.Dataset, error) {
+	if strings.TrimSpace(category) == "" {
+		return nil, fmt.Errorf("category is required")
 	}
+	return p.listMyDatasets(ctx, category)
+}
 
-	defer resp.Body.Close()
+// listMyDatasets pages through this producer's datasets, in category when
+// it is non-empty.
+func (p *Producer) listMyDatasets(ctx context.Context, category string) ([]types.Dataset, error) {
+	var datasets []types.Dataset
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		bodyBytes, _ := io.ReadAll(resp.Body)
+	for page := 1; ; page++ {
+		if page > listMaxPages {
+			return nil, fmt.Errorf("listing datasets exceeded %d pages; use ListMyDatasetsPaged", listMaxPages)
+		}
 
-		return &APIError{
-			StatusCode: resp.StatusCode,
-			Body:       string(bodyBytes),
+		response, err := p.listMyDatasetsPage(ctx, category, page, listPageSize)
+		if err != nil {
+			return nil, err
 		}
-	}
+		datasets = append(datasets, response.Datasets...)
 
-	if response != nil {
-		if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
-			return fmt.Errorf("failed to decode response: %w", err)
+		// No pagination block means the API returned the full listing.
+		if response.Pagination == nil || len(response.Datasets) == 0 || page >= response.Pagination.TotalPages {
+			return datasets, nil
 		}
 	}
+}
 
-	return nil
+// ListMyDatasetsPaged fetches a single page of this producer's datasets.
+// page is 1-based; page or perPage <= 0 leaves that parameter to the API
+// default. Pagination is set when the API returns a pagination block.
+func (p *Producer) ListMyDatasetsPaged(ctx context.Context, page, perPage int) (*types.DatasetListResponse, error) {
+	return p.listMyDatasetsPage(ctx, "", page, perPage)
 }
 
-// ListMyDatasets lists all datasets uploaded by this producer
-func (p *Producer) ListMyDatasets(ctx context.Context) ([]types.Dataset, error) {
-	var datasets []types.Dataset
+// listMyDatasetsPage is ListMyDatasetsPaged, filtered by category when it
+// is non-empty.
+func (p *Producer) listMyDatasetsPage(ctx context.Context, category string, page, perPage int) (*types.DatasetListResponse, error) {
+	q := p.withProducerID(url.Values{})
+	if category != "" {
+		q.Set("category", category)
+	}
+	if page > 0 {
+		q.Set("page", strconv.Itoa(page))
+	}
+	if perPage > 0 {
+		q.Set("per_page", strconv.Itoa(perPage))
+	}
+
+	var response types.DatasetListResponse
+	if err := p.makeAPIRequest(ctx, http.MethodGet, "/v1/datasets?"+q.Encode(), nil, &response); err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+// withProducerID scopes a /v1/datasets query to this producer. url.Values
+// handles the escaping, so customer IDs with reserved characters round-trip.
+func (p *Producer) withProducerID(q url.Values) url.Values {
+	q.Set("producer_id", p.CustomerID)
+	return q
+}
+
+// DatasetWithStats pairs a dataset with its parsed usage stats.
+type DatasetWithStats struct {
+	Dataset types.Dataset
+	Stats   types.DatasetStats
+}
 
-	path := fmt.Sprintf("/v1/datasets?producer_id=%s", url.QueryEscape(p.CustomerID))
+// DatasetExists reports whether the catalog has a dataset with datasetID,
+// for upsert logic with deterministic IDs (UploadOptions.DatasetID or
+// IDFormat): a 404 is false, any other API failure is returned as an error.
+func (p *Producer) DatasetExists(ctx context.Context, datasetID string) (bool, error) {
+	path := fmt.Sprintf("/v1/datasets/%s", url.PathEscape(datasetID))
+	if err := p.makeAPIRequest(ctx, http.MethodGet, path, nil, nil); err != nil {
+		if isNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
 
-	if err := p.makeAPIRequest(ctx, "GET", path, nil, &datasets); err != nil {
+// GetDatasetStats returns the usage stats (subscribers, downloads, views) of
+// one of this producer's datasets.
+func (p *Producer) GetDatasetStats(ctx context.Context, datasetID string) (*types.DatasetStats, error) {
+	var dataset types.Dataset
+	if err := p.makeAPIRequest(ctx, http.MethodGet, fmt.Sprintf("/v1/datasets/%s", url.PathEscape(datasetID)), nil, &dataset); err != nil {
 		return nil, err
 	}
 
-	return datasets, nil
+	stats, err := parseDatasetStats(dataset.Stats)
+	if err != nil {
+		return nil, fmt.Errorf("dataset %s: %w", datasetID, err)
+	}
+	return &stats, nil
+}
+
+// ListMyDatasetsWithStats is ListMyDatasets with each dataset's stats parsed,
+// for an analytics view of which datasets are actually used.
+func (p *Producer) ListMyDatasetsWithStats(ctx context.Context) ([]DatasetWithStats, error) {
+	datasets, err := p.ListMyDatasets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]DatasetWithStats, 0, len(datasets))
+	for _, dataset := range datasets {
+		stats, err := parseDatasetStats(dataset.Stats)
+		if err != nil {
+			return nil, fmt.Errorf("dataset %s: %w", dataset.ID, err)
+		}
+		result = append(result, DatasetWithStats{Dataset: dataset, Stats: stats})
+	}
+	return result, nil
+}
+
+// parseDatasetStats converts a dataset's untyped stats map. A nil map yields
+// zero stats.
+func parseDatasetStats(raw map[string]any) (types.DatasetStats, error) {
+	var stats types.DatasetStats
+	if len(raw) == 0 {
+		return stats, nil
+	}
+
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return stats, fmt.Errorf("failed to encode stats: %w", err)
+	}
+	if err := json.Unmarshal(b, &stats); err != nil {
+		return stats, fmt.Errorf("failed to parse stats: %w", err)
+	}
+	return stats, nil
 }
 
 // GetDatasetSubscribers lists all subscribers for a specific dataset.
@@ -726,6 +1910,24 @@ func (p *Producer) GetDatasetSubscribers(ctx context.Context, datasetID string)
 	return response.Subscriptions, nil
 }
 
+// GetSubscription fetches one subscription to this producer's datasets. A
+// subscription the API doesn't know fails with a *types.NotFoundError.
+//
+// GET /v1/subscriptions/:id
+func (p *Producer) GetSubscription(ctx context.Context, subscriptionID string) (*types.Subscription, error) {
+	path := fmt.Sprintf("/v1/subscriptions/%s", url.PathEscape(subscriptionID))
+
+	var subscription types.Subscription
+	if err := p.makeAPIRequest(ctx, http.MethodGet, path, nil, &subscription); err != nil {
+		if isNotFound(err) {
+			return nil, &types.NotFoundError{Resource: "subscription", ID: subscriptionID, Err: err}
+		}
+		return nil, err
+	}
+
+	return &subscription, nil
+}
+
 // RevokeSubscription revokes a subscription.
 func (p *Producer) RevokeSubscription(ctx context.Context, subscriptionID string) error {
 	path := fmt.Sprintf("/v1/subscriptions/%s/revoke", url.PathEscape(subscriptionID))
@@ -775,6 +1977,17 @@ func (p *Producer) ListSubscriptionRequests(ctx context.Context, status string)
 //
 // Returns the updated subscription request with status "approved".
 func (p *Producer) ApproveSubscriptionRequest(ctx context.Context, requestID string, opts *types.ApproveSubscriptionRequestOptions) (*types.SubscriptionRequest, error) {
+	response, err := p.approveSubscriptionRequest(ctx, requestID, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &response.Request, nil
+}
+
+// approveSubscriptionRequest approves requestID, returning the updated
+// request and, when the API includes it, the subscription it created. Both
+// the {"request", "subscription"} response and a bare request are accepted.
+func (p *Producer) approveSubscriptionRequest(ctx context.Context, requestID string, opts *types.ApproveSubscriptionRequestOptions) (*types.ApproveRequestResponse, error) {
 	path := fmt.Sprintf("/v1/subscription-requests/%s", url.PathEscape(requestID))
 
 	// Use a map to include the optional dataset_id field, which is not part
@@ -791,12 +2004,26 @@ func (p *Producer) ApproveSubscriptionRequest(ctx context.Context, requestID str
 		}
 	}
 
-	var result types.SubscriptionRequest
-	if err := p.makeAPIRequest(ctx, http.MethodPost, path, payloadMap, &result); err != nil {
+	var raw json.RawMessage
+	if err := p.makeAPIRequest(ctx, http.MethodPost, path, payloadMap, &raw); err != nil {
 		return nil, err
 	}
 
-	return &result, nil
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, fmt.Errorf("failed to decode approval response: %w", err)
+	}
+	var response types.ApproveRequestResponse
+	if _, wrapped := fields["request"]; wrapped {
+		err := json.Unmarshal(raw, &response)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode approval response: %w", err)
+		}
+	} else if err := json.Unmarshal(raw, &response.Request); err != nil {
+		return nil, fmt.Errorf("failed to decode approval response: %w", err)
+	}
+
+	return &response, nil
 }
 
 // UpdateDataset updates an existing dataset's metadata.
@@ -865,3 +2092,116 @@ func (p *Producer) RejectSubscriptionRequest(ctx context.Context, requestID stri
 func (p *Producer) DeleteDataset(ctx context.Context, datasetID string) error {
 	return p.makeAPIRequest(ctx, "DELETE", fmt.Sprintf("/v1/datasets/%s", url.PathEscape(datasetID)), nil, nil)
 }
+
+// DeleteDatasets deletes many datasets (catalog record and S3 object, and a
+// sharded dataset's parts) using up to concurrency workers (< 1 means 1).
+// Datasets that are already gone (404) count as deleted.
+//
+// The returned map has an entry for every distinct ID: nil when deleted,
+// otherwise that dataset's error. The error return is non-nil only when ctx
+// ends before every ID was processed; unprocessed IDs then map to ctx.Err().
+func (p *Producer) DeleteDatasets(ctx context.Context, datasetIDs []string, concurrency int) (map[string]error, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make(map[string]error, len(datasetIDs))
+	var mu sync.Mutex
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for range min(concurrency, len(datasetIDs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range jobs {
+				err := p.deleteDatasetAndObject(ctx, id)
+				mu.Lock()
+				results[id] = err
+				mu.Unlock()
+			}
+		}()
+	}
+
+	seen := make(map[string]bool, len(datasetIDs))
+feed:
+	for _, id := range datasetIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		select {
+		case jobs <- id:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	var ctxErr error
+	for _, id := range datasetIDs {
+		if _, done := results[id]; !done {
+			ctxErr = ctx.Err()
+			results[id] = ctxErr
+		}
+	}
+
+	return results, ctxErr
+}
+
+// deleteDatasetAndObject looks up the dataset's S3 location, deletes the
+// catalog record, then the object. For a sharded dataset the object is its
+// manifest, so the parts it lists are deleted first. A missing record is
+// treated as deleted.
+func (p *Producer) deleteDatasetAndObject(ctx context.Context, datasetID string) error {
+	var dataset types.Dataset
+	if err := p.makeAPIRequest(ctx, http.MethodGet, fmt.Sprintf("/v1/datasets/%s", url.PathEscape(datasetID)), nil, &dataset); err != nil {
+		if isNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to look up dataset %s: %w", datasetID, err)
+	}
+
+	hasObject := dataset.S3Key != "" && p.s3Client != nil
+	bucket := cmp.Or(dataset.S3BucketName, dataset.S3Bucket, p.BucketName)
+
+	// Read the manifest while the record still points at it.
+	var parts []types.ManifestPart
+	if _, sharded := dataset.Metadata["part_count"]; sharded && hasObject {
+		manifest, _, err := p.getManifest(ctx, bucket, dataset.S3Key)
+		switch {
+		case err == nil:
+			parts = manifest.Parts
+		case s3StatusCode(err) != http.StatusNotFound:
+			return fmt.Errorf("failed to list the parts of dataset %s: %w", datasetID, err)
+		}
+	}
+
+	if err := p.DeleteDataset(ctx, datasetID); err != nil && !isNotFound(err) {
+		return fmt.Errorf("failed to delete dataset %s: %w", datasetID, err)
+	}
+
+	if !hasObject {
+		return nil
+	}
+	for _, key := range append(partKeys(parts), dataset.S3Key) {
+		if _, err := p.s3Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key),
+		}); err != nil {
+			return fmt.Errorf("dataset %s deleted but s3://%s/%s was not: %w", datasetID, bucket, key, err)
+		}
+	}
+
+	return nil
+}
+
+// partKeys returns the S3 keys of parts.
+func partKeys(parts []types.ManifestPart) []string {
+	keys := make([]string, len(parts))
+	for i, part := range parts {
+		keys[i] = part.S3Key
+	}
+	return keys
+}
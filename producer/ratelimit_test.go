@@ -0,0 +1,41 @@
+package producer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/helix-tools/sdk-go/v2/internal/ratelimit"
+)
+
+// TestMakeAPIRequest_RateLimited pins that makeAPIRequest waits on the
+// limiter before sending, and gives up without sending once ctx expires.
+func TestMakeAPIRequest_RateLimited(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"datasets":[],"count":0}`))
+	}))
+	defer server.Close()
+
+	p := newTestProducer(server.URL)
+	p.limiter = ratelimit.New(0.001, 1) // one request, then effectively never
+
+	if _, err := p.ListMyDatasetsPaged(context.Background(), 1, 10); err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := p.ListMyDatasetsPaged(ctx, 2, 10)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want context.DeadlineExceeded from the limiter", err)
+	}
+	if requests != 1 {
+		t.Errorf("server saw %d requests, want 1", requests)
+	}
+}
@@ -0,0 +1,113 @@
+package producer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	helixcrypto "github.com/helix-tools/sdk-go/v2/crypto"
+	"github.com/helix-tools/sdk-go/v2/helixtest"
+	"github.com/helix-tools/sdk-go/v2/internal/awsfake"
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+func TestReEncryptDataset(t *testing.T) {
+	ctx := context.Background()
+	fakeKMS := awsfake.NewKMS()
+	fakeS3 := awsfake.NewS3()
+	plaintext := []byte(`{"id":1}` + "\n")
+	newKey := "arn:aws:kms:eu-west-1:111122223333:key/new"
+
+	sealed, err := helixcrypto.Seal(ctx, fakeKMS, "old-key", plaintext, helixcrypto.SealOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fakeS3.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:   aws.String("bucket"),
+		Key:      aws.String("datasets/sales/data.ndjson.gz"),
+		Body:     bytes.NewReader(sealed),
+		Metadata: map[string]string{"dataset-id": "ds-1"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	api := helixtest.NewMockAPI()
+	api.Handle(http.MethodGet, "/v1/datasets/ds-1", http.StatusOK, types.Dataset{
+		ID:       "ds-1",
+		S3Key:    "datasets/sales/data.ndjson.gz",
+		Metadata: map[string]any{"encryption_enabled": true, "kms_key_region": "us-east-1", "record_count": 1},
+	})
+	api.Handle(http.MethodPatch, "/v1/datasets/ds-1", http.StatusOK, types.Dataset{ID: "ds-1"})
+	api.Handle(http.MethodGet, "/v1/datasets/plain", http.StatusOK, types.Dataset{ID: "plain", S3Key: "k"})
+
+	p := NewProducerWithAPI(types.Config{CustomerID: "company-1", BucketName: "bucket"}, api)
+	p.kmsClient = fakeKMS
+	p.s3Client = fakeS3
+
+	if err := p.ReEncryptDataset(ctx, "ds-1", newKey); err != nil {
+		t.Fatalf("ReEncryptDataset: %v", err)
+	}
+
+	stored, _ := fakeS3.Object("bucket", "datasets/sales/data.ndjson.gz")
+	if opened, err := helixcrypto.Open(ctx, fakeKMS, stored, helixcrypto.OpenOptions{KeyID: newKey}); err != nil || !bytes.Equal(opened, plaintext) {
+		t.Errorf("object under the new key = %q, %v", opened, err)
+	}
+	if head, err := fakeS3.HeadObject(ctx, &s3.HeadObjectInput{Bucket: aws.String("bucket"), Key: aws.String("datasets/sales/data.ndjson.gz")}); err != nil || head.Metadata["dataset-id"] != "ds-1" {
+		t.Errorf("object metadata after re-encryption = %v, %v; want dataset-id kept", head.Metadata, err)
+	}
+	if _, decrypts := fakeKMS.Calls(); decrypts != 1 || fakeKMS.ReEncrypts() != 1 {
+		t.Errorf("decrypts = %d (the check above), re-encrypts = %d; want 1, 1", decrypts, fakeKMS.ReEncrypts())
+	}
+
+	calls := api.Calls()
+	last := calls[len(calls)-1]
+	var patch struct {
+		Metadata map[string]any `json:"metadata"`
+	}
+	_ = json.Unmarshal(last.Body, &patch)
+	if last.Method != http.MethodPatch || patch.Metadata["kms_key_region"] != "eu-west-1" || patch.Metadata["record_count"] == nil {
+		t.Errorf("last call = %s %s %s, want a PATCH keeping metadata with kms_key_region eu-west-1", last.Method, last.Path, last.Body)
+	}
+
+	if err := p.ReEncryptDataset(ctx, "plain", newKey); err == nil || !strings.Contains(err.Error(), "not encrypted") {
+		t.Errorf("unencrypted dataset err = %v", err)
+	}
+	if err := p.ReEncryptDataset(ctx, "ds-1", ""); err == nil {
+		t.Error("empty key ID should fail")
+	}
+}
+
+func TestReEncryptDatasetObjectChanged(t *testing.T) {
+	ctx := context.Background()
+	fakeKMS := awsfake.NewKMS()
+	store := &rangeRecordingS3{S3: awsfake.NewS3()}
+	p := newRewrapProducer(t, fakeKMS, store, []byte(`{"id":1}`+"\n"))
+
+	replacement := []byte("uploaded meanwhile")
+	store.afterGet = func() {
+		store.afterGet = nil
+		if _, err := store.S3.PutObject(ctx, &s3.PutObjectInput{
+			Bucket: aws.String("bucket"),
+			Key:    aws.String("datasets/sales/data.ndjson"),
+			Body:   bytes.NewReader(replacement),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := p.ReEncryptDataset(ctx, "ds-1", "arn:aws:kms:eu-west-1:111122223333:key/new")
+	var respErr *awshttp.ResponseError
+	if !errors.As(err, &respErr) || respErr.HTTPStatusCode() != http.StatusPreconditionFailed {
+		t.Fatalf("err = %v, want a 412 response error", err)
+	}
+	if stored, _ := store.Object("bucket", "datasets/sales/data.ndjson"); !bytes.Equal(stored, replacement) {
+		t.Errorf("object = %q, want the concurrent upload left in place", stored)
+	}
+}
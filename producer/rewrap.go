@@ -0,0 +1,313 @@
+package producer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	helixcrypto "github.com/helix-tools/sdk-go/v2/crypto"
+	"github.com/helix-tools/sdk-go/v2/types"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+const (
+	// rewrapHeadSize is how much of the object RewrapDatasetKey reads: one
+	// minimum-size multipart part (5 MiB) plus room for the largest wrapped
+	// key KMS returns, so the rewritten first part still meets the minimum.
+	rewrapHeadSize = 5<<20 + 8<<10
+
+	// maxCopyPartSize is the largest range S3 copies in one UploadPartCopy.
+	maxCopyPartSize = 5 << 30
+)
+
+// ReEncryptDataset moves datasetID's stored object to newKMSKeyID, e.g.
+// after rotating the producer's key. Only the object's wrapped data key is
+// re-encrypted, by KMS on the server side, so no plaintext key or data
+// leaves KMS and the bulk ciphertext is copied unchanged. The object is
+// rewritten in place in the producer's bucket, keeping its storage class
+// and user metadata, and the dataset's kms_key_region updated when it
+// changes. The rewrite is conditioned on the
+// ETag read at the start, so an upload that replaces the object meanwhile
+// makes ReEncryptDataset fail (with a 412 response error) instead of being
+// overwritten with the old data.
+//
+// Later uploads still use Config.KMSKeyID; point it at the new key too.
+func (p *Producer) ReEncryptDataset(ctx context.Context, datasetID, newKMSKeyID string) error {
+	if newKMSKeyID == "" {
+		return fmt.Errorf("new KMS key ID is required")
+	}
+	if p.s3Client == nil || p.BucketName == "" {
+		return fmt.Errorf("re-encryption needs an S3 client and bucket")
+	}
+
+	dataset, err := p.encryptedDataset(ctx, datasetID)
+	if err != nil {
+		return err
+	}
+
+	obj, err := p.s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(p.BucketName),
+		Key:    aws.String(dataset.S3Key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get s3://%s/%s: %w", p.BucketName, dataset.S3Key, err)
+	}
+	data, err := io.ReadAll(obj.Body)
+	obj.Body.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read s3://%s/%s: %w", p.BucketName, dataset.S3Key, err)
+	}
+
+	rewrapped, err := helixcrypto.Rewrap(ctx, p.kmsClient, data, newKMSKeyID)
+	if err != nil {
+		return fmt.Errorf("dataset %s: %w", datasetID, err)
+	}
+
+	if _, err := p.s3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(p.BucketName),
+		Key:           aws.String(dataset.S3Key),
+		Body:          bytes.NewReader(rewrapped),
+		ContentLength: aws.Int64(int64(len(rewrapped))),
+		ContentType:   aws.String("application/octet-stream"),
+		StorageClass:  obj.StorageClass,
+		Metadata:      obj.Metadata,
+		IfMatch:       obj.ETag,
+	}); err != nil {
+		return fmt.Errorf("failed to put s3://%s/%s: %w", p.BucketName, dataset.S3Key, err)
+	}
+
+	return p.updateKeyRegion(ctx, dataset, newKMSKeyID)
+}
+
+// RewrapDatasetKey is ReEncryptDataset without moving the data: it reads
+// only the first few MiB of the object (the envelope header and the first
+// multipart part), has KMS re-encrypt the wrapped data key, and rebuilds
+// the object with a multipart upload whose first part is the new header
+// and whose remaining parts are copied server side from the current
+// object. Objects that fit in the first read are simply rewritten.
+//
+// Consistency: readers see either the old or the new object, never a mix;
+// S3 swaps the object in when the upload completes. Every copy and the
+// completion are conditioned on the ETag read at the start, so an upload
+// that replaces the object meanwhile makes the rewrap fail (with a 412
+// response error) instead of splicing the new header onto other data. A
+// failed rewrap aborts its multipart upload and leaves the object as it
+// was. The object's ETag changes, which invalidates consumers'
+// conditional-download state, and S3 stores the object under the new ETag
+// format of a multipart upload.
+func (p *Producer) RewrapDatasetKey(ctx context.Context, datasetID, newKMSKeyID string) error {
+	if newKMSKeyID == "" {
+		return fmt.Errorf("new KMS key ID is required")
+	}
+	if p.s3Client == nil || p.BucketName == "" {
+		return fmt.Errorf("re-encryption needs an S3 client and bucket")
+	}
+
+	dataset, err := p.encryptedDataset(ctx, datasetID)
+	if err != nil {
+		return err
+	}
+	bucket, key := aws.String(p.BucketName), aws.String(dataset.S3Key)
+	location := fmt.Sprintf("s3://%s/%s", p.BucketName, dataset.S3Key)
+
+	obj, err := p.s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: bucket,
+		Key:    key,
+		Range:  aws.String(fmt.Sprintf("bytes=0-%d", rewrapHeadSize-1)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get %s: %w", location, err)
+	}
+	head, err := io.ReadAll(obj.Body)
+	obj.Body.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", location, err)
+	}
+	etag := obj.ETag
+	total, err := objectSize(obj.ContentRange, int64(len(head)))
+	if err != nil {
+		return fmt.Errorf("%s: %w", location, err)
+	}
+
+	newHead, err := helixcrypto.Rewrap(ctx, p.kmsClient, head, newKMSKeyID)
+	if err != nil {
+		return fmt.Errorf("dataset %s: %w", datasetID, err)
+	}
+
+	if int64(len(head)) >= total {
+		if _, err := p.s3Client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:        bucket,
+			Key:           key,
+			Body:          bytes.NewReader(newHead),
+			ContentLength: aws.Int64(int64(len(newHead))),
+			ContentType:   aws.String("application/octet-stream"),
+			StorageClass:  obj.StorageClass,
+			Metadata:      obj.Metadata,
+			IfMatch:       etag,
+		}); err != nil {
+			return fmt.Errorf("failed to put %s: %w", location, err)
+		}
+	} else if err := p.multipartCopy(ctx, bucket, key, obj.StorageClass, obj.Metadata, newHead, s3Source{p.BucketName, dataset.S3Key, aws.ToString(etag)}, int64(len(head)), total, etag); err != nil {
+		return fmt.Errorf("failed to rewrite %s: %w", location, err)
+	}
+
+	return p.updateKeyRegion(ctx, dataset, newKMSKeyID)
+}
+
+// s3Source is an object copied server side, pinned to the ETag it had when
+// it was inspected.
+type s3Source struct {
+	bucket, key, etag string
+}
+
+// copySource is the URL-encoded CopySource naming src.
+func (src s3Source) copySource() *string {
+	return aws.String(src.bucket + "/" + url.PathEscape(src.key))
+}
+
+// multipartCopy writes bucket/key, with class and user metadata, as a
+// multipart upload: head, when not empty, as the first part, then src's bytes from offset to total copied
+// server side in parts of at most maxCopyPartSize. Every copy requires src
+// to still have its ETag, and with ifMatch set the completion requires the
+// same of the object being replaced. The upload is aborted on failure.
+func (p *Producer) multipartCopy(ctx context.Context, bucket, key *string, class s3types.StorageClass, metadata map[string]string, head []byte, src s3Source, offset, total int64, ifMatch *string) (retErr error) {
+	upload, err := p.s3Client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:       bucket,
+		Key:          key,
+		ContentType:  aws.String("application/octet-stream"),
+		StorageClass: class,
+		Metadata:     metadata,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start multipart upload: %w", err)
+	}
+	defer func() {
+		if retErr == nil {
+			return
+		}
+		// Detached from ctx: the abort must run even when ctx is why we failed.
+		abortCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), rollbackTimeout)
+		defer cancel()
+		if _, err := p.s3Client.AbortMultipartUpload(abortCtx, &s3.AbortMultipartUploadInput{
+			Bucket:   bucket,
+			Key:      key,
+			UploadId: upload.UploadId,
+		}); err != nil {
+			fmt.Printf("Warning: Failed to abort multipart upload %s: %v\n", aws.ToString(upload.UploadId), err)
+		}
+	}()
+
+	var parts []s3types.CompletedPart
+	if len(head) > 0 {
+		first, err := p.s3Client.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:        bucket,
+			Key:           key,
+			UploadId:      upload.UploadId,
+			PartNumber:    aws.Int32(1),
+			Body:          bytes.NewReader(head),
+			ContentLength: aws.Int64(int64(len(head))),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to upload header part: %w", err)
+		}
+		parts = append(parts, s3types.CompletedPart{PartNumber: aws.Int32(1), ETag: first.ETag})
+	}
+
+	for start := offset; start < total; start += maxCopyPartSize {
+		end := min(start+maxCopyPartSize, total) - 1
+		partNumber := aws.Int32(int32(len(parts) + 1))
+		copied, err := p.s3Client.UploadPartCopy(ctx, &s3.UploadPartCopyInput{
+			Bucket:            bucket,
+			Key:               key,
+			UploadId:          upload.UploadId,
+			PartNumber:        partNumber,
+			CopySource:        src.copySource(),
+			CopySourceRange:   aws.String(fmt.Sprintf("bytes=%d-%d", start, end)),
+			CopySourceIfMatch: aws.String(src.etag),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to copy bytes %d-%d: %w", start, end, err)
+		}
+		parts = append(parts, s3types.CompletedPart{PartNumber: partNumber, ETag: copied.CopyPartResult.ETag})
+	}
+
+	if _, err := p.s3Client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          bucket,
+		Key:             key,
+		UploadId:        upload.UploadId,
+		MultipartUpload: &s3types.CompletedMultipartUpload{Parts: parts},
+		IfMatch:         ifMatch,
+	}); err != nil {
+		return fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+	return nil
+}
+
+// objectSize returns the full object size from a ranged GET's
+// Content-Range ("bytes 0-99/1234"), or read when there is none (the
+// whole object was returned).
+func objectSize(contentRange *string, read int64) (int64, error) {
+	if contentRange == nil {
+		return read, nil
+	}
+	_, size, ok := strings.Cut(aws.ToString(contentRange), "/")
+	if !ok {
+		return 0, fmt.Errorf("unexpected Content-Range %q", aws.ToString(contentRange))
+	}
+	total, err := strconv.ParseInt(size, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected Content-Range %q", aws.ToString(contentRange))
+	}
+	return total, nil
+}
+
+// encryptedDataset fetches datasetID for re-encryption, failing for a
+// dataset that is not encrypted or has no stored object.
+func (p *Producer) encryptedDataset(ctx context.Context, datasetID string) (*types.Dataset, error) {
+	dataset := &types.Dataset{}
+	path := fmt.Sprintf("/v1/datasets/%s", url.PathEscape(datasetID))
+	if err := p.makeAPIRequest(ctx, http.MethodGet, path, nil, dataset); err != nil {
+		return nil, fmt.Errorf("failed to get dataset %s: %w", datasetID, err)
+	}
+	if encrypted, _ := dataset.Metadata["encryption_enabled"].(bool); !encrypted && !dataset.Encryption {
+		return nil, fmt.Errorf("dataset %s is not encrypted", datasetID)
+	}
+	if dataset.S3Key == "" {
+		return nil, fmt.Errorf("dataset %s has no S3 key", datasetID)
+	}
+	return dataset, nil
+}
+
+// updateKeyRegion records newKMSKeyID's region as the dataset's
+// kms_key_region when it changed, keeping the rest of its metadata. A
+// kms_key_id recorded by a per-upload key is replaced with newKMSKeyID.
+func (p *Producer) updateKeyRegion(ctx context.Context, dataset *types.Dataset, newKMSKeyID string) error {
+	region := helixcrypto.KeyRegion(newKMSKeyID, p.Region)
+	current, _ := dataset.Metadata["kms_key_region"].(string)
+	keyID, hasKeyID := dataset.Metadata["kms_key_id"].(string)
+	if current == region && (!hasKeyID || keyID == newKMSKeyID) {
+		return nil
+	}
+
+	metadata := maps.Clone(dataset.Metadata)
+	if metadata == nil {
+		metadata = make(map[string]any)
+	}
+	metadata["kms_key_region"] = region
+	if hasKeyID {
+		metadata["kms_key_id"] = newKMSKeyID
+	}
+	if _, err := p.UpdateDataset(ctx, dataset.ID, types.DatasetUpdateInput{Metadata: metadata}); err != nil {
+		return fmt.Errorf("dataset %s re-encrypted but its kms_key_region could not be updated: %w", dataset.ID, err)
+	}
+	return nil
+}
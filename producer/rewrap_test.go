@@ -0,0 +1,148 @@
+package producer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	helixcrypto "github.com/helix-tools/sdk-go/v2/crypto"
+	"github.com/helix-tools/sdk-go/v2/helixtest"
+	"github.com/helix-tools/sdk-go/v2/internal/awsfake"
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+// rangeRecordingS3 records the Range of every GetObject and can run hooks
+// after each GetObject and before each UploadPart, to change the object
+// mid-rewrap.
+type rangeRecordingS3 struct {
+	*awsfake.S3
+	mu           sync.Mutex
+	ranges       []string
+	afterGet     func()
+	beforeUpload func()
+}
+
+func (r *rangeRecordingS3) GetObject(ctx context.Context, in *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	r.mu.Lock()
+	r.ranges = append(r.ranges, aws.ToString(in.Range))
+	r.mu.Unlock()
+	out, err := r.S3.GetObject(ctx, in, optFns...)
+	if r.afterGet != nil {
+		r.afterGet()
+	}
+	return out, err
+}
+
+func (r *rangeRecordingS3) UploadPart(ctx context.Context, in *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	if r.beforeUpload != nil {
+		r.beforeUpload()
+	}
+	return r.S3.UploadPart(ctx, in, optFns...)
+}
+
+func newRewrapProducer(t *testing.T, fakeKMS *awsfake.KMS, store *rangeRecordingS3, plaintext []byte) *Producer {
+	t.Helper()
+	ctx := context.Background()
+	sealed, err := helixcrypto.Seal(ctx, fakeKMS, "old-key", plaintext, helixcrypto.SealOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:   aws.String("bucket"),
+		Key:      aws.String("datasets/sales/data.ndjson"),
+		Body:     bytes.NewReader(sealed),
+		Metadata: map[string]string{"dataset-id": "ds-1"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	api := helixtest.NewMockAPI()
+	api.Handle(http.MethodGet, "/v1/datasets/ds-1", http.StatusOK, types.Dataset{
+		ID:       "ds-1",
+		S3Key:    "datasets/sales/data.ndjson",
+		Metadata: map[string]any{"encryption_enabled": true, "kms_key_region": "eu-west-1"},
+	})
+	p := NewProducerWithAPI(types.Config{CustomerID: "company-1", BucketName: "bucket"}, api)
+	p.kmsClient = fakeKMS
+	p.s3Client = store
+	return p
+}
+
+func TestRewrapDatasetKey(t *testing.T) {
+	ctx := context.Background()
+	newKey := "arn:aws:kms:eu-west-1:111122223333:key/new"
+
+	for _, tc := range []struct {
+		name string
+		size int
+	}{
+		{"small object", 1 << 10},
+		{"multipart object", 3*rewrapHeadSize + 123},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeKMS := awsfake.NewKMS()
+			store := &rangeRecordingS3{S3: awsfake.NewS3()}
+			plaintext := bytes.Repeat([]byte("0123456789abcdef"), tc.size/16+1)[:tc.size]
+			p := newRewrapProducer(t, fakeKMS, store, plaintext)
+
+			if err := p.RewrapDatasetKey(ctx, "ds-1", newKey); err != nil {
+				t.Fatalf("RewrapDatasetKey: %v", err)
+			}
+
+			if len(store.ranges) != 1 || store.ranges[0] != "bytes=0-5251071" {
+				t.Errorf("GetObject ranges = %q, want only the header range", store.ranges)
+			}
+			stored, _ := store.Object("bucket", "datasets/sales/data.ndjson")
+			if opened, err := helixcrypto.Open(ctx, fakeKMS, stored, helixcrypto.OpenOptions{KeyID: newKey}); err != nil || !bytes.Equal(opened, plaintext) {
+				t.Errorf("object under the new key: %d bytes, %v; want the %d-byte plaintext", len(opened), err, len(plaintext))
+			}
+			if fakeKMS.ReEncrypts() != 1 {
+				t.Errorf("re-encrypts = %d, want 1", fakeKMS.ReEncrypts())
+			}
+			if store.Uploads() != 0 {
+				t.Errorf("%d multipart uploads left open", store.Uploads())
+			}
+			head, err := store.HeadObject(ctx, &s3.HeadObjectInput{Bucket: aws.String("bucket"), Key: aws.String("datasets/sales/data.ndjson")})
+			if err != nil || head.Metadata["dataset-id"] != "ds-1" {
+				t.Errorf("object metadata after the rewrap = %v, %v; want dataset-id kept", head.Metadata, err)
+			}
+		})
+	}
+}
+
+func TestRewrapDatasetKeyObjectChanged(t *testing.T) {
+	ctx := context.Background()
+	fakeKMS := awsfake.NewKMS()
+	store := &rangeRecordingS3{S3: awsfake.NewS3()}
+	p := newRewrapProducer(t, fakeKMS, store, bytes.Repeat([]byte{'x'}, 2*rewrapHeadSize))
+
+	replacement := []byte("uploaded meanwhile")
+	store.beforeUpload = func() {
+		store.beforeUpload = nil
+		if _, err := store.S3.PutObject(ctx, &s3.PutObjectInput{
+			Bucket: aws.String("bucket"),
+			Key:    aws.String("datasets/sales/data.ndjson"),
+			Body:   bytes.NewReader(replacement),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := p.RewrapDatasetKey(ctx, "ds-1", "arn:aws:kms:eu-west-1:111122223333:key/new")
+	var respErr *awshttp.ResponseError
+	if !errors.As(err, &respErr) || respErr.HTTPStatusCode() != http.StatusPreconditionFailed {
+		t.Fatalf("err = %v, want a 412 response error", err)
+	}
+	if stored, _ := store.Object("bucket", "datasets/sales/data.ndjson"); !bytes.Equal(stored, replacement) {
+		t.Errorf("object = %d bytes, want the concurrent upload left in place", len(stored))
+	}
+	if store.Uploads() != 0 {
+		t.Errorf("%d multipart uploads left open after the failure", store.Uploads())
+	}
+}
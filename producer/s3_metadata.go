@@ -0,0 +1,76 @@
+package producer
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// maxS3MetadataBytes is S3's limit on user-defined metadata: the UTF-8
+// length of every key and value, summed.
+const maxS3MetadataBytes = 2 << 10
+
+// validateS3Metadata rejects S3Metadata S3 would refuse: keys that are not
+// HTTP header tokens, or that collide once S3 lowercases them, values that
+// are not printable ASCII, and more than 2 KB in total.
+func (o UploadOptions) validateS3Metadata() error {
+	size := 0
+	seen := make(map[string]bool, len(o.S3Metadata))
+	for key, value := range o.S3Metadata {
+		if !isHeaderToken(key) {
+			return fmt.Errorf("invalid S3 metadata key %q: must be an HTTP header token", key)
+		}
+		lower := strings.ToLower(key)
+		if seen[lower] {
+			return fmt.Errorf("S3 metadata key %q is given twice with different case", key)
+		}
+		seen[lower] = true
+		for i := 0; i < len(value); i++ {
+			if value[i] < ' ' || value[i] > '~' {
+				return fmt.Errorf("invalid S3 metadata value for %q: must be printable ASCII", key)
+			}
+		}
+		size += len(key) + len(value)
+	}
+	if size > maxS3MetadataBytes {
+		return fmt.Errorf("S3 metadata is %d bytes, over the %d byte limit", size, maxS3MetadataBytes)
+	}
+	return nil
+}
+
+// isHeaderToken reports whether s is a non-empty HTTP token (RFC 9110).
+func isHeaderToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// s3Metadata returns S3Metadata with lowercased keys, as S3 stores them, or
+// nil when there is none.
+func (o UploadOptions) s3Metadata() map[string]string {
+	if len(o.S3Metadata) == 0 {
+		return nil
+	}
+	metadata := make(map[string]string, len(o.S3Metadata))
+	for key, value := range o.S3Metadata {
+		metadata[strings.ToLower(key)] = value
+	}
+	return metadata
+}
+
+// setS3MetadataHeaders adds S3Metadata to headers as X-Amz-Meta-* headers.
+func (o UploadOptions) setS3MetadataHeaders(headers http.Header) {
+	for key, value := range o.s3Metadata() {
+		headers.Set("X-Amz-Meta-"+key, value)
+	}
+}
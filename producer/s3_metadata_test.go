@@ -0,0 +1,170 @@
+package producer
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/helix-tools/sdk-go/v2/internal/awsfake"
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+func TestUploadS3Metadata(t *testing.T) {
+	t.Run("presigned PUT carries the metadata the create request asked for", func(t *testing.T) {
+		srv := newUploadServer(t)
+
+		opts := NewUploadOptions("catalog-check")
+		opts.S3Metadata = map[string]string{"Dataset-ID": "ds-1", "producer-id": "producer-1"}
+		if _, err := srv.producer().UploadDataset(context.Background(), writeUploadFile(t), opts); err != nil {
+			t.Fatalf("UploadDataset: %v", err)
+		}
+
+		requested, _ := srv.created["s3_metadata"].(map[string]any)
+		if requested["dataset-id"] != "ds-1" || requested["producer-id"] != "producer-1" {
+			t.Errorf("s3_metadata request field = %v, want lowercased keys", srv.created["s3_metadata"])
+		}
+		if h := srv.uploadHeader.Get("X-Amz-Meta-Dataset-Id"); h != "ds-1" {
+			t.Errorf("X-Amz-Meta-Dataset-Id = %q, want ds-1", h)
+		}
+		if h := srv.uploadHeader.Get("X-Amz-Meta-Producer-Id"); h != "producer-1" {
+			t.Errorf("X-Amz-Meta-Producer-Id = %q, want producer-1", h)
+		}
+	})
+
+	t.Run("no metadata sends no field or headers", func(t *testing.T) {
+		srv := newUploadServer(t)
+
+		if _, err := srv.producer().UploadDataset(context.Background(), writeUploadFile(t), NewUploadOptions("catalog-check")); err != nil {
+			t.Fatalf("UploadDataset: %v", err)
+		}
+		if _, ok := srv.created["s3_metadata"]; ok {
+			t.Errorf("s3_metadata request field sent without S3Metadata")
+		}
+		for name := range srv.uploadHeader {
+			if strings.HasPrefix(name, "X-Amz-Meta-") {
+				t.Errorf("unexpected header %s on the PUT", name)
+			}
+		}
+	})
+
+	t.Run("direct mode sets the object's metadata", func(t *testing.T) {
+		srv := newUploadServer(t)
+		s3fake := awsfake.NewS3()
+		p := srv.producer()
+		p.BucketName = "producer-bucket"
+		p.s3Client = s3fake
+
+		opts := NewUploadOptions("catalog-check")
+		opts.UploadMode = UploadModeDirect
+		opts.S3Metadata = map[string]string{"Content-SHA256": "abc"}
+		if _, err := p.UploadDataset(context.Background(), writeUploadFile(t), opts); err != nil {
+			t.Fatalf("UploadDataset: %v", err)
+		}
+
+		key, _ := srv.created["s3_key"].(string)
+		head, err := s3fake.HeadObject(context.Background(), &s3.HeadObjectInput{Bucket: aws.String("producer-bucket"), Key: aws.String(key)})
+		if err != nil {
+			t.Fatalf("HeadObject: %v", err)
+		}
+		if head.Metadata["content-sha256"] != "abc" || len(head.Metadata) != 1 {
+			t.Errorf("object metadata = %v, want content-sha256=abc", head.Metadata)
+		}
+	})
+
+	t.Run("a server-side copy sets the object's metadata", func(t *testing.T) {
+		srv := newUploadServer(t)
+		p := srv.producer()
+		store := awsfake.NewS3()
+		p.s3Client = store
+		if _, err := store.PutObject(context.Background(), &s3.PutObjectInput{
+			Bucket:   aws.String("partner-exports"),
+			Key:      aws.String("exports/sales.ndjson.gz"),
+			Body:     strings.NewReader("stored bytes"),
+			Metadata: map[string]string{"source-only": "x"},
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		opts := NewUploadOptions("catalog-check")
+		opts.Preprocessed = true
+		opts.S3Metadata = map[string]string{"Dataset-ID": "ds-1"}
+		if _, err := p.UploadDatasetFromS3(context.Background(), "partner-exports", "exports/sales.ndjson.gz", opts); err != nil {
+			t.Fatalf("UploadDatasetFromS3: %v", err)
+		}
+
+		key, _ := srv.created["s3_key"].(string)
+		head, err := store.HeadObject(context.Background(), &s3.HeadObjectInput{Bucket: aws.String(p.BucketName), Key: aws.String(key)})
+		if err != nil {
+			t.Fatalf("HeadObject: %v", err)
+		}
+		if head.Metadata["dataset-id"] != "ds-1" || len(head.Metadata) != 1 {
+			t.Errorf("copied object metadata = %v, want only dataset-id=ds-1", head.Metadata)
+		}
+	})
+
+	t.Run("an appended part keeps the dataset's metadata", func(t *testing.T) {
+		ctx := context.Background()
+		store := awsfake.NewS3()
+		p, _ := newAppendProducer(t, store)
+		first := types.NewManifestPart(ShardKey("sales", 0), []byte("first"))
+		if _, err := store.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:   aws.String("bucket"),
+			Key:      aws.String(first.S3Key),
+			Body:     strings.NewReader("first"),
+			Metadata: map[string]string{"dataset-id": "ds-1", "record-count": "2"},
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := p.WriteDatasetManifest(ctx, "sales", []types.ManifestPart{first}); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := p.AppendToDataset(ctx, "ds-1", writeUploadFile(t)); err != nil {
+			t.Fatalf("AppendToDataset: %v", err)
+		}
+
+		head, err := store.HeadObject(ctx, &s3.HeadObjectInput{Bucket: aws.String("bucket"), Key: aws.String(ShardKey("sales", 1))})
+		if err != nil {
+			t.Fatalf("HeadObject: %v", err)
+		}
+		if head.Metadata["dataset-id"] != "ds-1" || head.Metadata["record-count"] != "2" || len(head.Metadata) != 2 {
+			t.Errorf("appended part metadata = %v, want dataset-id=ds-1 and its own record-count", head.Metadata)
+		}
+	})
+}
+
+func TestValidateS3Metadata(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata map[string]string
+		wantErr  string
+	}{
+		{"valid", map[string]string{"dataset-id": "ds-1", "x_y.z": "a b/c"}, ""},
+		{"empty key", map[string]string{"": "v"}, "HTTP header token"},
+		{"space in key", map[string]string{"dataset id": "v"}, "HTTP header token"},
+		{"colon in key", map[string]string{"a:b": "v"}, "HTTP header token"},
+		{"case collision", map[string]string{"Dataset-ID": "a", "dataset-id": "b"}, "different case"},
+		{"newline in value", map[string]string{"k": "a\nb"}, "printable ASCII"},
+		{"non-ASCII value", map[string]string{"k": "café"}, "printable ASCII"},
+		{"at the limit", map[string]string{"k": strings.Repeat("v", maxS3MetadataBytes-1)}, ""},
+		{"over the limit", map[string]string{"k": strings.Repeat("v", maxS3MetadataBytes)}, "over the 2048 byte limit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := NewUploadOptions("catalog-check")
+			opts.S3Metadata = tt.metadata
+			err := opts.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
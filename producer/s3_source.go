@@ -0,0 +1,221 @@
+package producer
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/helix-tools/sdk-go/v2/internal/tempfile"
+	"github.com/helix-tools/sdk-go/v2/types"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// ErrSourceAccessDenied is wrapped by UploadDatasetFromS3 errors when the
+// producer's credentials cannot read the source object.
+var ErrSourceAccessDenied = errors.New("access to the source object denied — the producer's credentials need s3:GetObject on it; a bucket in another account must also grant them in its bucket policy, and an object encrypted with that account's KMS key needs kms:Decrypt on the key")
+
+// UploadDatasetFromS3 publishes the object at sourceBucket/sourceKey as a
+// dataset, for data that already lives in S3, possibly in a bucket owned
+// by another account.
+//
+// With opts.Preprocessed the object is already in stored form and is
+// copied server side into the producer's bucket (CopyObject, or a
+// multipart copy above 5 GiB), so no data passes through the client; the
+// copy is pinned to the ETag the object had when it was inspected. Its
+// schema is not analyzed, and the content type is taken from
+// opts.ContentType or the key's extension (ignoring ".gz").
+//
+// Otherwise the object is staged to a temporary file and uploaded through
+// UploadDataset's compress and encrypt pipeline.
+//
+// Either way the catalog record is created, confirmed and rolled back as
+// in UploadDataset. The copy is written with the producer's S3 client
+// whatever opts.UploadMode says. A source the credentials cannot read
+// fails with an error wrapping ErrSourceAccessDenied.
+func (p *Producer) UploadDatasetFromS3(ctx context.Context, sourceBucket, sourceKey string, opts UploadOptions) (*types.Dataset, error) {
+	opts = opts.withDefaults()
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if sourceBucket == "" || sourceKey == "" {
+		return nil, fmt.Errorf("source bucket and key are required")
+	}
+	if p.s3Client == nil {
+		return nil, fmt.Errorf("uploading from S3 needs an S3 client")
+	}
+	opts, err := p.resolveUploadKey(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	head, err := p.s3Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(sourceBucket),
+		Key:    aws.String(sourceKey),
+	})
+	src := s3Source{bucket: sourceBucket, key: sourceKey}
+	if err != nil {
+		return nil, src.readError(err)
+	}
+	src.etag = aws.ToString(head.ETag)
+
+	if !opts.Preprocessed {
+		return p.uploadStagedSource(ctx, src, opts)
+	}
+	return p.copySourceDataset(ctx, src, aws.ToInt64(head.ContentLength), opts)
+}
+
+// copySourceDataset registers src, already in stored form, as a dataset
+// and copies it into the producer's bucket under the record's S3 key.
+func (p *Producer) copySourceDataset(ctx context.Context, src s3Source, size int64, opts UploadOptions) (*types.Dataset, error) {
+	if p.BucketName == "" {
+		return nil, fmt.Errorf("uploading from S3 needs the producer's bucket")
+	}
+
+	// The data isn't read, so compression must be stated, not sampled.
+	switch opts.CompressionMode {
+	case CompressionAlways:
+		opts.Compress = true
+	case CompressionNever:
+		opts.Compress = false
+	case CompressionAuto:
+		return nil, fmt.Errorf("compression mode %q needs to read the data; set Compress or CompressionMode to say whether a preprocessed source is gzipped", CompressionAuto)
+	}
+	if opts.ContentType == "" {
+		opts.ContentType = detectContentType(strings.TrimSuffix(src.key, ".gz"))
+	}
+	if opts.IdempotencyKey == "" {
+		opts.IdempotencyKey = src.idempotencyKey(p.CustomerID, opts.DatasetName)
+	}
+
+	createResp, err := p.createDatasetRecord(ctx, "", opts)
+	if err != nil {
+		return nil, err
+	}
+	if createResp.replayed {
+		dataset, err := p.replayedUpload(ctx, createResp)
+		if dataset != nil || err != nil {
+			return dataset, err
+		}
+	} else {
+		fmt.Printf("✅ Dataset record created: %s\n", createResp.ID)
+	}
+
+	rollback := p.canRollBack(ctx, createResp.S3Key, opts)
+
+	if err := p.copyObject(ctx, src, size, createResp.S3Key, opts.storageClass(), opts.s3Metadata()); err != nil {
+		return nil, fmt.Errorf("dataset record created but copy failed: %w", err)
+	}
+
+	dataset, err := p.confirmCatalogRegistration(ctx, createResp)
+	if err != nil {
+		if rollback {
+			return nil, p.rollbackUpload(ctx, createResp, err)
+		}
+		return nil, err
+	}
+	return dataset, nil
+}
+
+// copyObject copies src (size bytes) to key in the producer's bucket, with
+// class and metadata in place of the source's.
+func (p *Producer) copyObject(ctx context.Context, src s3Source, size int64, key string, class s3types.StorageClass, metadata map[string]string) error {
+	fmt.Printf("📤 Copying %d bytes from s3://%s/%s to s3://%s/%s...\n", size, src.bucket, src.key, p.BucketName, key)
+
+	var err error
+	if size > maxCopyPartSize {
+		err = p.multipartCopy(ctx, aws.String(p.BucketName), aws.String(key), class, metadata, nil, src, 0, size, nil)
+	} else {
+		_, err = p.s3Client.CopyObject(ctx, &s3.CopyObjectInput{
+			Bucket:            aws.String(p.BucketName),
+			Key:               aws.String(key),
+			CopySource:        src.copySource(),
+			CopySourceIfMatch: aws.String(src.etag),
+			ContentType:       aws.String("application/octet-stream"),
+			MetadataDirective: s3types.MetadataDirectiveReplace,
+			Metadata:          metadata,
+			StorageClass:      class,
+		})
+	}
+	if err != nil {
+		var respErr *awshttp.ResponseError
+		if errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusForbidden {
+			return fmt.Errorf("copy denied: reading s3://%s/%s needs s3:GetObject (and kms:Decrypt on a KMS-encrypted source), writing needs s3:PutObject on s3://%s: %w", src.bucket, src.key, p.BucketName, err)
+		}
+		return err
+	}
+
+	fmt.Printf("✅ Copy successful\n")
+	return nil
+}
+
+// uploadStagedSource downloads src to a temporary file, named with the
+// key's extension so the content type is detected as for a local file,
+// and uploads that with UploadDataset.
+func (p *Producer) uploadStagedSource(ctx context.Context, src s3Source, opts UploadOptions) (*types.Dataset, error) {
+	obj, err := p.s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket:  aws.String(src.bucket),
+		Key:     aws.String(src.key),
+		IfMatch: aws.String(src.etag),
+	})
+	if err != nil {
+		return nil, src.readError(err)
+	}
+	defer obj.Body.Close()
+
+	staged, err := tempfile.Create(p.tempDir, "helix-s3-source-*"+path.Ext(src.key))
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(staged.Name())
+
+	n, err := io.Copy(staged, obj.Body)
+	if closeErr := staged.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to stage s3://%s/%s: %w", src.bucket, src.key, tempfile.WriteError(p.tempDir, err))
+	}
+	fmt.Printf("📥 Staged %d bytes from s3://%s/%s\n", n, src.bucket, src.key)
+
+	return p.UploadDataset(ctx, staged.Name(), opts)
+}
+
+// readError explains a failure to read src: access denied wraps
+// ErrSourceAccessDenied, and a missing object or a bucket in another
+// region says so.
+func (src s3Source) readError(err error) error {
+	location := fmt.Sprintf("s3://%s/%s", src.bucket, src.key)
+
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) {
+		switch respErr.HTTPStatusCode() {
+		case http.StatusForbidden:
+			return fmt.Errorf("%w (%s): %w", ErrSourceAccessDenied, location, err)
+		case http.StatusNotFound:
+			return fmt.Errorf("source object %s not found: %w", location, err)
+		case http.StatusMovedPermanently:
+			return fmt.Errorf("source bucket %s is in a different region than the producer's S3 client: %w", src.bucket, err)
+		}
+	}
+	return fmt.Errorf("failed to read %s: %w", location, err)
+}
+
+// idempotencyKey is the default Idempotency-Key for copying src into
+// datasetName: a SHA-256 over the producer ID, dataset name, and the
+// object's location and ETag, standing in for the file hash a local upload
+// uses.
+func (src s3Source) idempotencyKey(customerID, datasetName string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\ns3://%s/%s\n%s", customerID, datasetName, src.bucket, src.key, src.etag)
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
@@ -0,0 +1,129 @@
+package producer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	helixcrypto "github.com/helix-tools/sdk-go/v2/crypto"
+	"github.com/helix-tools/sdk-go/v2/internal/awsfake"
+)
+
+func putSource(t *testing.T, store *awsfake.S3, key string, body []byte) {
+	t.Helper()
+	if _, err := store.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket: aws.String("partner-exports"),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(body),
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUploadDatasetFromS3Preprocessed(t *testing.T) {
+	srv := newUploadServer(t)
+	p := srv.producer()
+	fakeKMS := awsfake.NewKMS()
+	store := awsfake.NewS3()
+	p.kmsClient = fakeKMS
+	p.s3Client = store
+
+	compressed, err := p.compressData([]byte(`{"id":1}`+"\n"), 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := helixcrypto.Seal(context.Background(), fakeKMS, p.KMSKeyID, compressed, helixcrypto.SealOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	putSource(t, store, "exports/sales.csv.gz", sealed)
+
+	opts := NewUploadOptions("catalog-check")
+	opts.Preprocessed = true
+	if _, err := p.UploadDatasetFromS3(context.Background(), "partner-exports", "exports/sales.csv.gz", opts); err != nil {
+		t.Fatalf("UploadDatasetFromS3: %v", err)
+	}
+
+	if copied, _ := store.Object(p.BucketName, "datasets/catalog-check/data.csv.gz"); !bytes.Equal(copied, sealed) {
+		t.Errorf("copied object = %d bytes, want the %d source bytes unchanged", len(copied), len(sealed))
+	}
+	if srv.uploaded != nil {
+		t.Error("the object was PUT to the presigned URL, want a server-side copy")
+	}
+	if encrypts, _ := fakeKMS.Calls(); encrypts != 1 {
+		t.Errorf("KMS Encrypt calls = %d, want only the test's own Seal", encrypts)
+	}
+	metadata, _ := srv.created["metadata"].(map[string]any)
+	if metadata["content_type"] != "text/csv" || metadata["compression_enabled"] != true || metadata["schema"] != nil {
+		t.Errorf("metadata = %v, want text/csv, compressed and not analyzed", metadata)
+	}
+	if len(srv.keys) != 1 || srv.keys[0] == "" {
+		t.Errorf("Idempotency-Keys = %q, want one derived key", srv.keys)
+	}
+
+	opts.CompressionMode = CompressionAuto
+	if _, err := p.UploadDatasetFromS3(context.Background(), "partner-exports", "exports/sales.csv.gz", opts); err == nil {
+		t.Error("CompressionAuto with a preprocessed source should fail")
+	}
+}
+
+func TestUploadDatasetFromS3Transformed(t *testing.T) {
+	srv := newUploadServer(t)
+	p := srv.producer()
+	fakeKMS := awsfake.NewKMS()
+	store := awsfake.NewS3()
+	p.kmsClient = fakeKMS
+	p.s3Client = store
+
+	source := []byte(`{"id":1,"name":"a"}` + "\n" + `{"id":2,"name":"b"}` + "\n")
+	putSource(t, store, "exports/sales.ndjson", source)
+
+	if _, err := p.UploadDatasetFromS3(context.Background(), "partner-exports", "exports/sales.ndjson", NewUploadOptions("catalog-check")); err != nil {
+		t.Fatalf("UploadDatasetFromS3: %v", err)
+	}
+
+	gr, err := gzip.NewReader(bytes.NewReader(openEnvelope(t, fakeKMS, srv.uploaded)))
+	if err != nil {
+		t.Fatalf("uploaded data is not gzip after decryption: %v", err)
+	}
+	if got, _ := io.ReadAll(gr); !bytes.Equal(got, source) {
+		t.Errorf("round-tripped data = %q, want %q", got, source)
+	}
+	if metadata, _ := srv.created["metadata"].(map[string]any); metadata["record_count"] == nil {
+		t.Errorf("metadata = %v, want the staged file analyzed", metadata)
+	}
+}
+
+// forbiddenS3 answers HeadObject with the 403 a bucket in another account
+// returns when its policy doesn't grant the producer access.
+type forbiddenS3 struct{ *awsfake.S3 }
+
+func (forbiddenS3) HeadObject(context.Context, *s3.HeadObjectInput, ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	return nil, &awshttp.ResponseError{ResponseError: &smithyhttp.ResponseError{
+		Response: &smithyhttp.Response{Response: &http.Response{StatusCode: http.StatusForbidden}},
+		Err:      errors.New("Forbidden"),
+	}}
+}
+
+func TestUploadDatasetFromS3AccessDenied(t *testing.T) {
+	srv := newUploadServer(t)
+	p := srv.producer()
+	p.s3Client = forbiddenS3{awsfake.NewS3()}
+
+	_, err := p.UploadDatasetFromS3(context.Background(), "partner-exports", "exports/sales.ndjson", NewUploadOptions("catalog-check"))
+	if !errors.Is(err, ErrSourceAccessDenied) || !strings.Contains(err.Error(), "s3://partner-exports/exports/sales.ndjson") {
+		t.Errorf("err = %v, want ErrSourceAccessDenied naming the source", err)
+	}
+	if srv.created != nil {
+		t.Error("a dataset record was created for an unreadable source")
+	}
+}
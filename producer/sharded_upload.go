@@ -0,0 +1,218 @@
+package producer
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/helix-tools/sdk-go/v2/internal/tempfile"
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+// ShardKey returns the S3 key of part index (from 0) of a sharded dataset.
+func ShardKey(datasetName string, index int) string {
+	return fmt.Sprintf("datasets/%s/part-%04d.ndjson.gz", datasetName, index)
+}
+
+// UploadShardedDataset uploads filePaths, the NDJSON shards of one dataset,
+// as a single catalog entry. Each shard is compressed and encrypted as by
+// UploadDataset and stored at ShardKey(opts.DatasetName, i), in filePaths
+// order; the dataset's stored object is the manifest listing them (see
+// WriteDatasetManifest), which consumers' DownloadDataset reassembles into
+// the records of every part. The record's size_bytes is the parts' total stored
+// size, and its record_count, schema and field emptiness cover all shards.
+//
+// Parts are always gzipped and are written with the producer's S3 client,
+// so it needs s3:PutObject on its bucket; opts.UploadMode is ignored. As
+// with UploadDataset the record is created before anything is stored, and
+// the manifest is written last, so consumers never see a manifest naming a
+// missing part. Rollback follows opts.RollbackOnRegistrationFailure and
+// removes the parts as well.
+//
+// When opts.IdempotencyKey is empty, it is derived from the producer ID,
+// dataset name, and the shards' contents in order.
+func (p *Producer) UploadShardedDataset(ctx context.Context, filePaths []string, opts UploadOptions) (*types.Dataset, error) {
+	opts = opts.withDefaults()
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if len(filePaths) == 0 {
+		return nil, fmt.Errorf("sharded upload needs at least one file")
+	}
+	switch opts.CompressionMode {
+	case "", CompressionAlways:
+		opts.Compress = true
+	default:
+		return nil, fmt.Errorf("compression mode %q is not supported for sharded uploads; parts are always gzipped", opts.CompressionMode)
+	}
+	if p.s3Client == nil || p.BucketName == "" {
+		return nil, fmt.Errorf("sharded upload needs an S3 client and bucket")
+	}
+	var totalSize int64
+	for _, filePath := range filePaths {
+		size, err := checkSourceFile(filePath, UploadOptions{})
+		if err != nil {
+			return nil, err
+		}
+		totalSize += size
+	}
+	if opts.MaxFileBytes > 0 && totalSize > opts.MaxFileBytes {
+		return nil, &types.LimitError{Observed: totalSize, Allowed: opts.MaxFileBytes, Err: types.ErrFileTooLarge}
+	}
+
+	opts, err := p.resolveUploadKey(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.ContentType == "" {
+		opts.ContentType = "application/x-ndjson"
+	}
+	if opts.IdempotencyKey == "" {
+		if opts.IdempotencyKey, err = p.deriveShardedIdempotencyKey(filePaths, opts.DatasetName); err != nil {
+			return nil, err
+		}
+	}
+
+	// Process every shard before creating the record, which needs the total
+	// stored size. Processed parts wait in a temporary directory.
+	stageDir, err := tempfile.MkdirTemp(p.tempDir, "helix-shards-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(stageDir)
+
+	parts := make([]types.ManifestPart, len(filePaths))
+	var originalSize, storedSize int64
+	for i, filePath := range filePaths {
+		processed, err := p.processFile(ctx, filePath, opts)
+		if err != nil {
+			return nil, fmt.Errorf("shard %s: %w", filePath, err)
+		}
+		if err := os.WriteFile(filepath.Join(stageDir, fmt.Sprint(i)), processed.Data, 0o600); err != nil {
+			return nil, fmt.Errorf("failed to stage shard %s: %w", filePath, tempfile.WriteError(p.tempDir, err))
+		}
+		parts[i] = types.NewManifestPart(ShardKey(opts.DatasetName, i), processed.Data)
+		originalSize += processed.OriginalSize
+		storedSize += parts[i].SizeBytes
+	}
+
+	createResp, err := p.createRecord(ctx, opts, datasetRecord{
+		filePaths: filePaths,
+		s3Key:     ManifestKey(opts.DatasetName),
+		fields:    map[string]any{"size_bytes": storedSize},
+		metadata: map[string]any{
+			"part_count":          len(parts),
+			"original_size_bytes": originalSize,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if createResp.replayed {
+		dataset, err := p.replayedUpload(ctx, createResp)
+		if dataset != nil || err != nil {
+			return dataset, err
+		}
+	} else {
+		fmt.Printf("✅ Dataset record created: %s (%d parts)\n", createResp.ID, len(parts))
+	}
+
+	// As in UploadDatasetWithResult, decide before writing: re-uploading an
+	// existing sharded dataset overwrites its live parts and manifest.
+	rollback := p.canRollBack(ctx, createResp.S3Key, opts)
+	fail := func(err error) (*types.Dataset, error) {
+		if rollback {
+			return nil, p.rollbackShardedUpload(ctx, createResp, parts, err)
+		}
+		return nil, err
+	}
+
+	for i, part := range parts {
+		if err := p.putPart(ctx, filepath.Join(stageDir, fmt.Sprint(i)), part, opts); err != nil {
+			return fail(fmt.Errorf("dataset record created but upload failed: %w", err))
+		}
+	}
+
+	if _, err := p.WriteDatasetManifest(ctx, opts.DatasetName, parts); err != nil {
+		return fail(fmt.Errorf("dataset record created but upload failed: %w", err))
+	}
+
+	dataset, err := p.confirmCatalogRegistration(ctx, createResp)
+	if err != nil {
+		return fail(err)
+	}
+	return dataset, nil
+}
+
+// putPart writes the staged part at stagedPath to its key.
+func (p *Producer) putPart(ctx context.Context, stagedPath string, part types.ManifestPart, opts UploadOptions) error {
+	file, err := os.Open(stagedPath)
+	if err != nil {
+		return fmt.Errorf("failed to read staged part: %w", err)
+	}
+	defer file.Close()
+
+	fmt.Printf("📤 Uploading %d bytes to s3://%s/%s...\n", part.SizeBytes, p.BucketName, part.S3Key)
+	if _, err := p.s3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(p.BucketName),
+		Key:           aws.String(part.S3Key),
+		Body:          file,
+		ContentLength: aws.Int64(part.SizeBytes),
+		ContentType:   aws.String("application/octet-stream"),
+		StorageClass:  opts.storageClass(),
+		Metadata:      opts.s3Metadata(),
+	}); err != nil {
+		return fmt.Errorf("failed to put s3://%s/%s: %w", p.BucketName, part.S3Key, err)
+	}
+	return nil
+}
+
+// rollbackShardedUpload is rollbackUpload for a sharded dataset: once the
+// record and manifest are gone, the parts are deleted too.
+func (p *Producer) rollbackShardedUpload(ctx context.Context, createResp *CreateDatasetResponse, parts []types.ManifestPart, regErr error) error {
+	if err := p.rollbackUpload(ctx, createResp, regErr); err != regErr {
+		return err
+	}
+
+	rbCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), rollbackTimeout)
+	defer cancel()
+
+	for _, part := range parts {
+		if _, err := p.s3Client.DeleteObject(rbCtx, &s3.DeleteObjectInput{
+			Bucket: aws.String(p.BucketName),
+			Key:    aws.String(part.S3Key),
+		}); err != nil {
+			return fmt.Errorf("%w (rollback of s3://%s/%s also failed, manual cleanup required: %w)",
+				regErr, p.BucketName, part.S3Key, err)
+		}
+	}
+	return regErr
+}
+
+// deriveShardedIdempotencyKey is deriveIdempotencyKey over several files:
+// for a single file the two keys are the same.
+func (p *Producer) deriveShardedIdempotencyKey(filePaths []string, datasetName string) (string, error) {
+	h := crypto.SHA256.New()
+	fmt.Fprintf(h, "%s\n%s", p.CustomerID, datasetName)
+
+	for _, filePath := range filePaths {
+		file, err := os.Open(filePath)
+		if err != nil {
+			return "", fmt.Errorf("failed to read file: %w", err)
+		}
+		content := crypto.SHA256.New()
+		_, err = io.Copy(content, file)
+		file.Close()
+		if err != nil {
+			return "", fmt.Errorf("failed to hash file: %w", err)
+		}
+		fmt.Fprintf(h, "\n%x", content.Sum(nil))
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
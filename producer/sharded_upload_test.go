@@ -0,0 +1,169 @@
+package producer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/helix-tools/sdk-go/v2/internal/awsfake"
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+// writeShards writes one NDJSON file per content string.
+func writeShards(t *testing.T, contents ...string) []string {
+	t.Helper()
+
+	dir := t.TempDir()
+	paths := make([]string, len(contents))
+	for i, content := range contents {
+		paths[i] = filepath.Join(dir, fmt.Sprintf("shard-%d.ndjson", i))
+		if err := os.WriteFile(paths[i], []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return paths
+}
+
+func TestUploadShardedDataset(t *testing.T) {
+	srv := newUploadServer(t)
+	p := srv.producer()
+	fakeKMS := awsfake.NewKMS()
+	fakeS3 := awsfake.NewS3()
+	p.kmsClient, p.s3Client = fakeKMS, fakeS3
+
+	shards := []string{"{\"id\":1,\"a\":\"x\"}\n{\"id\":2}\n", "{\"id\":3,\"b\":true}"}
+	paths := writeShards(t, shards...)
+	dataset, err := p.UploadShardedDataset(context.Background(), paths, NewUploadOptions("catalog-check"))
+	if err != nil || dataset.ID != "ds-1" {
+		t.Fatalf("UploadShardedDataset = %+v, %v", dataset, err)
+	}
+
+	body, ok := fakeS3.Object(p.BucketName, "datasets/catalog-check/manifest.json")
+	if !ok {
+		t.Fatal("manifest not written")
+	}
+	var manifest types.Manifest
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		t.Fatalf("manifest is not JSON: %v", err)
+	}
+	if len(manifest.Parts) != 2 || manifest.Parts[1].S3Key != "datasets/catalog-check/part-0001.ndjson.gz" {
+		t.Fatalf("manifest parts = %+v, want part-0000 and part-0001", manifest.Parts)
+	}
+
+	for i, part := range manifest.Parts {
+		stored, ok := fakeS3.Object(p.BucketName, part.S3Key)
+		if !ok {
+			t.Fatalf("part %s not written", part.S3Key)
+		}
+		if err := part.Verify(stored); err != nil {
+			t.Errorf("part %d: %v", i, err)
+		}
+		gr, err := gzip.NewReader(bytes.NewReader(openEnvelope(t, fakeKMS, stored)))
+		if err != nil {
+			t.Fatalf("part %d is not gzip after decryption: %v", i, err)
+		}
+		if got, _ := io.ReadAll(gr); string(got) != shards[i] {
+			t.Errorf("part %d = %q, want %q", i, got, shards[i])
+		}
+	}
+
+	if srv.created["s3_key"] != "datasets/catalog-check/manifest.json" {
+		t.Errorf("s3_key = %v, want the manifest key", srv.created["s3_key"])
+	}
+	if got := srv.created["size_bytes"]; got != float64(manifest.TotalSizeBytes) {
+		t.Errorf("size_bytes = %v, want the parts' total %d", got, manifest.TotalSizeBytes)
+	}
+	metadata, _ := srv.created["metadata"].(map[string]any)
+	if metadata["record_count"] != float64(3) || metadata["part_count"] != float64(2) {
+		t.Errorf("metadata = %v, want record_count 3 across 2 parts", metadata)
+	}
+	if schema, _ := metadata["schema"].(map[string]any); schema == nil || !bytes.Contains(mustJSON(t, schema), []byte(`"b"`)) {
+		t.Errorf("schema = %v, want fields from every shard", metadata["schema"])
+	}
+
+	single, err := p.deriveShardedIdempotencyKey(paths[:1], "catalog-check")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := p.deriveIdempotencyKey(paths[0], "catalog-check"); single != want {
+		t.Errorf("one-shard key = %s, want UploadDataset's %s", single, want)
+	}
+	if srv.keys[0] == single {
+		t.Error("the idempotency key must cover every shard")
+	}
+}
+
+func TestUploadShardedDatasetRollsBackParts(t *testing.T) {
+	srv := newUploadServer(t, http.StatusInternalServerError)
+	p := srv.producer()
+	fakeS3 := awsfake.NewS3()
+	p.kmsClient, p.s3Client = awsfake.NewKMS(), fakeS3
+
+	paths := writeShards(t, "{\"id\":1}\n", "{\"id\":2}\n")
+	if _, err := p.UploadShardedDataset(context.Background(), paths, NewUploadOptions("catalog-check")); err == nil {
+		t.Fatal("expected the registration failure")
+	}
+	if srv.deletes != 1 {
+		t.Errorf("record deletes = %d, want 1", srv.deletes)
+	}
+	for _, key := range []string{ManifestKey("catalog-check"), ShardKey("catalog-check", 0), ShardKey("catalog-check", 1)} {
+		if _, ok := fakeS3.Object(p.BucketName, key); ok {
+			t.Errorf("%s left behind after rollback", key)
+		}
+	}
+}
+
+func TestUploadShardedDatasetValidation(t *testing.T) {
+	p := newTestProducer("http://unused")
+	p.s3Client = awsfake.NewS3()
+	ctx := context.Background()
+
+	if _, err := p.UploadShardedDataset(ctx, nil, NewUploadOptions("catalog-check")); err == nil {
+		t.Error("expected an error for no shards")
+	}
+	opts := NewUploadOptions("catalog-check")
+	opts.CompressionMode = CompressionNever
+	if _, err := p.UploadShardedDataset(ctx, writeShards(t, "{}\n"), opts); err == nil {
+		t.Error("expected an error for uncompressed parts")
+	}
+}
+
+func mustJSON(t *testing.T, v any) []byte {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// TestUploadShardedDatasetTempDir pins that shards are staged in
+// Config.TempDir and removed afterwards, and that an unusable TempDir fails
+// before the record is created.
+func TestUploadShardedDatasetTempDir(t *testing.T) {
+	srv := newUploadServer(t)
+	p := srv.producer()
+	p.kmsClient, p.s3Client = awsfake.NewKMS(), awsfake.NewS3()
+
+	p.tempDir = filepath.Join(t.TempDir(), "missing")
+	_, err := p.UploadShardedDataset(context.Background(), writeShards(t, "{}\n"), NewUploadOptions("catalog-check"))
+	if err == nil || !strings.Contains(err.Error(), "Config.TempDir") || len(srv.keys) != 0 {
+		t.Fatalf("UploadShardedDataset = %v (records %v), want a Config.TempDir error and no record", err, srv.keys)
+	}
+
+	p.tempDir = t.TempDir()
+	if _, err := p.UploadShardedDataset(context.Background(), writeShards(t, "{}\n"), NewUploadOptions("catalog-check")); err != nil {
+		t.Fatalf("UploadShardedDataset: %v", err)
+	}
+	if left, _ := os.ReadDir(p.tempDir); len(left) != 0 {
+		t.Errorf("TempDir holds %d entries after the upload, want none", len(left))
+	}
+}
@@ -0,0 +1,45 @@
+package producer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+// TestNewProducerSkipCredentialValidation builds a producer without any
+// network call when the STS check is skipped and the bucket and key are
+// given, and fails construction on the check otherwise.
+func TestNewProducerSkipCredentialValidation(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.Error(w, "<ErrorResponse><Error><Code>InvalidClientTokenId</Code></Error></ErrorResponse>", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	cfg := types.Config{
+		APIEndpoint:              srv.URL,
+		AWSAccessKeyID:           "AKIDEXAMPLE",
+		AWSSecretAccessKey:       "secret",
+		CustomerID:               "company-1",
+		Region:                   "us-east-1",
+		AWSEndpointURL:           srv.URL,
+		BucketName:               "bucket",
+		KMSKeyID:                 "key",
+		SkipCredentialValidation: true,
+	}
+	if _, err := NewProducer(cfg); err != nil {
+		t.Fatalf("NewProducer with SkipCredentialValidation: %v", err)
+	}
+	if n := requests.Load(); n != 0 {
+		t.Errorf("construction made %d requests, want none", n)
+	}
+
+	cfg.SkipCredentialValidation = false
+	if _, err := NewProducer(cfg); err == nil {
+		t.Error("NewProducer succeeded although the credential check failed")
+	}
+}
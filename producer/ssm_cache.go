@@ -0,0 +1,114 @@
+package producer
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// defaultSSMCacheTTL is how long a parameter stays cached when
+// Config.SSMCacheTTL is zero.
+const defaultSSMCacheTTL = 5 * time.Minute
+
+// ssmCache holds the parameters producers read at construction, shared by
+// every producer in the process.
+var ssmCache = newParameterCache()
+
+// kmsKeyCache holds the ARNs producers resolved their KMS key to at
+// construction, keyed by cache scope, customer and configured key, with
+// the same TTL. Only keys that can encrypt are cached.
+var kmsKeyCache = newParameterCache()
+
+// parameterCache caches resolved SSM parameter lookups until they expire.
+// Only successful lookups are cached, so a parameter provisioned after a
+// failed lookup is found by the next producer.
+type parameterCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedParameter
+	now     func() time.Time
+}
+
+type cachedParameter struct {
+	value   string
+	expires time.Time
+}
+
+func newParameterCache() *parameterCache {
+	return &parameterCache{entries: make(map[string]cachedParameter), now: time.Now}
+}
+
+// getParameter is getSSMParameterValue, answered from the cache when an
+// unexpired lookup of the same names in the same scope (see cacheScope) is
+// cached. ttl is Config.SSMCacheTTL: zero means defaultSSMCacheTTL,
+// negative bypasses the cache. Concurrent misses may each call SSM; the
+// last one wins.
+func (c *parameterCache) getParameter(ctx context.Context, client ssmAPI, scope string, names []string, ttl time.Duration) (string, error) {
+	return c.get(scope+"\n"+strings.Join(names, "\n"), ttl, func() (string, error) {
+		return getSSMParameterValue(ctx, client, names)
+	})
+}
+
+// get returns the value cached under key, or calls lookup and caches its
+// result if it succeeds. ttl is as for getParameter.
+func (c *parameterCache) get(key string, ttl time.Duration, lookup func() (string, error)) (string, error) {
+	if ttl < 0 {
+		return lookup()
+	}
+	if ttl == 0 {
+		ttl = defaultSSMCacheTTL
+	}
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && c.now().Before(entry.expires) {
+		return entry.value, nil
+	}
+
+	value, err := lookup()
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	c.entries[key] = cachedParameter{value: value, expires: c.now().Add(ttl)}
+	c.mu.Unlock()
+	return value, nil
+}
+
+// cacheScope returns what cached lookups are keyed by besides their names:
+// the region, the endpoint and the access key of the credentials, so
+// producers in different accounts, or talking to different endpoints (a
+// local emulator, a test server), never see each other's values. ok is
+// false when the credentials cannot be retrieved; such lookups bypass the
+// cache.
+func cacheScope(ctx context.Context, region string, awsCfg aws.Config) (scope string, ok bool) {
+	scope = region + "\n" + aws.ToString(awsCfg.BaseEndpoint)
+	if awsCfg.Credentials == nil {
+		return scope, true
+	}
+	creds, err := awsCfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return "", false
+	}
+	return scope + "\n" + creds.AccessKeyID, true
+}
+
+// clear drops every cached parameter.
+func (c *parameterCache) clear() {
+	c.mu.Lock()
+	clear(c.entries)
+	c.mu.Unlock()
+}
+
+// ClearSSMCache drops the parameters and resolved KMS key ARNs cached by
+// producer construction (see types.Config.SSMCacheTTL), so the next
+// producer reads them again, e.g. after a customer's bucket or KMS key was
+// re-provisioned.
+func ClearSSMCache() {
+	ssmCache.clear()
+	kmsKeyCache.clear()
+}
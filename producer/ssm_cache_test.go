@@ -0,0 +1,152 @@
+package producer
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/helix-tools/sdk-go/v2/internal/awsfake"
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+// countingSSM answers GetParameter from params and counts the calls.
+type countingSSM struct {
+	mu     sync.Mutex
+	params map[string]string
+	calls  int
+}
+
+func (c *countingSSM) GetParameter(_ context.Context, in *ssm.GetParameterInput, _ ...func(*ssm.Options)) (*ssm.GetParameterOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls++
+	value, ok := c.params[aws.ToString(in.Name)]
+	if !ok {
+		return nil, &ssmtypes.ParameterNotFound{Message: aws.String("not found")}
+	}
+	return &ssm.GetParameterOutput{Parameter: &ssmtypes.Parameter{Value: aws.String(value)}}, nil
+}
+
+func TestNewProducerCachesSSMParameters(t *testing.T) {
+	t.Cleanup(ClearSSMCache)
+	ClearSSMCache()
+
+	fake := &countingSSM{params: map[string]string{
+		"/helix/test/customers/company-1/s3_bucket":  "company-1-bucket",
+		"/helix/test/customers/company-1/kms_key_id": "company-1-key",
+	}}
+	cfg := types.Config{CustomerID: "company-1", Region: "us-east-1", SSMPathPrefix: "/helix/test/customers"}
+
+	for range 3 {
+		p, err := newProducer(cfg, aws.Config{Region: cfg.Region}, fake, awsfake.NewKMS(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.BucketName != "company-1-bucket" || p.KMSKeyID != testKeyARN("company-1-key") {
+			t.Fatalf("bucket, key = %q, %q", p.BucketName, p.KMSKeyID)
+		}
+	}
+	if fake.calls != 2 {
+		t.Errorf("GetParameter calls for 3 producers = %d, want 2 (bucket and key, once)", fake.calls)
+	}
+
+	cfg.SSMCacheTTL = -1
+	if _, err := newProducer(cfg, aws.Config{Region: cfg.Region}, fake, awsfake.NewKMS(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if fake.calls != 4 {
+		t.Errorf("GetParameter calls with the cache disabled = %d, want 4", fake.calls)
+	}
+
+	ClearSSMCache()
+	cfg.SSMCacheTTL = 0
+	if _, err := newProducer(cfg, aws.Config{Region: cfg.Region}, fake, awsfake.NewKMS(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if fake.calls != 6 {
+		t.Errorf("GetParameter calls after ClearSSMCache = %d, want 6", fake.calls)
+	}
+}
+
+// TestNewProducerSSMCacheScope pins that producers talking to different
+// endpoints, or with different credentials, don't share cached parameters.
+func TestNewProducerSSMCacheScope(t *testing.T) {
+	t.Cleanup(ClearSSMCache)
+	ClearSSMCache()
+
+	cfg := types.Config{CustomerID: "company-1", Region: "us-east-1", SSMPathPrefix: "/helix/test/customers"}
+	newFake := func(bucket string) *countingSSM {
+		return &countingSSM{params: map[string]string{
+			"/helix/test/customers/company-1/s3_bucket":  bucket,
+			"/helix/test/customers/company-1/kms_key_id": "company-1-key",
+		}}
+	}
+	build := func(awsCfg aws.Config, fake *countingSSM) *Producer {
+		t.Helper()
+		p, err := newProducer(cfg, awsCfg, fake, awsfake.NewKMS(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	base := aws.Config{Region: cfg.Region, Credentials: credentials.NewStaticCredentialsProvider("AKIDONE", "secret", "")}
+	build(base, newFake("account-one-bucket"))
+
+	otherEndpoint := base.Copy()
+	otherEndpoint.BaseEndpoint = aws.String("http://localhost:4566")
+	if p := build(otherEndpoint, newFake("emulator-bucket")); p.BucketName != "emulator-bucket" {
+		t.Errorf("bucket for another endpoint = %q, want its own value", p.BucketName)
+	}
+
+	otherCreds := base.Copy()
+	otherCreds.Credentials = credentials.NewStaticCredentialsProvider("AKIDTWO", "secret", "")
+	if p := build(otherCreds, newFake("account-two-bucket")); p.BucketName != "account-two-bucket" {
+		t.Errorf("bucket for other credentials = %q, want its own value", p.BucketName)
+	}
+
+	same := newFake("unused")
+	if p := build(base, same); p.BucketName != "account-one-bucket" || same.calls != 0 {
+		t.Errorf("bucket = %q after %d calls, want the cached account-one-bucket", p.BucketName, same.calls)
+	}
+}
+
+func TestParameterCacheExpiry(t *testing.T) {
+	cache := newParameterCache()
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	fake := &countingSSM{params: map[string]string{"/p": "v1"}}
+	ctx := context.Background()
+
+	get := func() string {
+		t.Helper()
+		value, err := cache.getParameter(ctx, fake, "us-east-1", []string{"/p"}, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return value
+	}
+
+	get()
+	fake.params["/p"] = "v2"
+	if got := get(); got != "v1" || fake.calls != 1 {
+		t.Errorf("cached value = %q after %d calls, want v1 after 1", got, fake.calls)
+	}
+
+	now = now.Add(time.Minute)
+	if got := get(); got != "v2" || fake.calls != 2 {
+		t.Errorf("value after the TTL = %q after %d calls, want v2 after 2", got, fake.calls)
+	}
+
+	if _, err := cache.getParameter(ctx, fake, "eu-west-1", []string{"/missing"}, time.Minute); err == nil {
+		t.Fatal("missing parameter should fail")
+	}
+	if _, err := cache.getParameter(ctx, fake, "eu-west-1", []string{"/missing"}, time.Minute); err == nil || fake.calls != 4 {
+		t.Errorf("failed lookups must not be cached: err = %v, calls = %d", err, fake.calls)
+	}
+}
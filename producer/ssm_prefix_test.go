@@ -0,0 +1,42 @@
+package producer
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSSMParamCandidates(t *testing.T) {
+	t.Setenv("HELIX_ENVIRONMENT", "staging")
+	t.Setenv("HELIX_SSM_CUSTOMER_PREFIX", "")
+
+	t.Run("explicit prefix is the only candidate", func(t *testing.T) {
+		got := ssmParamCandidates("/helix/dev/customers/", "company-1", "s3_bucket")
+		if want := []string{"/helix/dev/customers/company-1/s3_bucket"}; !slices.Equal(got, want) {
+			t.Errorf("candidates = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		got := ssmParamCandidates("", "company-1", "kms_key_id")
+		want := []string{
+			"/helix-tools/staging/customers/company-1/kms_key_id",
+			"/helix/staging/customers/company-1/kms_key_id",
+			"/helix/customers/company-1/kms_key_id",
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("candidates = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("environment prefix first", func(t *testing.T) {
+		t.Setenv("HELIX_SSM_CUSTOMER_PREFIX", "/custom")
+		got := ssmParamCandidates("", "company-1", "s3_bucket")
+		if len(got) != 4 || got[0] != "/custom/company-1/s3_bucket" {
+			t.Errorf("candidates = %v, want /custom first", got)
+		}
+	})
+
+	if got := ssmParamCandidates("/helix/dev/customers", "", "s3_bucket"); got != nil {
+		t.Errorf("candidates without a customer ID = %v, want nil", got)
+	}
+}
@@ -0,0 +1,62 @@
+package producer
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestUploadStorageClass(t *testing.T) {
+	t.Run("default is STANDARD and sends no header", func(t *testing.T) {
+		srv := newUploadServer(t)
+
+		if _, err := srv.producer().UploadDataset(context.Background(), writeUploadFile(t), NewUploadOptions("catalog-check")); err != nil {
+			t.Fatalf("UploadDataset: %v", err)
+		}
+
+		metadata, _ := srv.created["metadata"].(map[string]any)
+		if metadata["storage_class"] != "STANDARD" {
+			t.Errorf("metadata storage_class = %v, want STANDARD", metadata["storage_class"])
+		}
+		if _, ok := srv.created["storage_class"]; ok {
+			t.Errorf("storage_class request field sent for the default class")
+		}
+		if h := srv.uploadHeader.Get("X-Amz-Storage-Class"); h != "" {
+			t.Errorf("X-Amz-Storage-Class = %q, want absent", h)
+		}
+	})
+
+	t.Run("explicit class is requested, recorded, and sent on the PUT", func(t *testing.T) {
+		srv := newUploadServer(t)
+
+		opts := NewUploadOptions("catalog-check")
+		opts.StorageClass = s3types.StorageClassStandardIa
+		if _, err := srv.producer().UploadDataset(context.Background(), writeUploadFile(t), opts); err != nil {
+			t.Fatalf("UploadDataset: %v", err)
+		}
+
+		metadata, _ := srv.created["metadata"].(map[string]any)
+		if metadata["storage_class"] != "STANDARD_IA" || srv.created["storage_class"] != "STANDARD_IA" {
+			t.Errorf("storage_class = %v (metadata %v), want STANDARD_IA", srv.created["storage_class"], metadata["storage_class"])
+		}
+		if h := srv.uploadHeader.Get("X-Amz-Storage-Class"); h != "STANDARD_IA" {
+			t.Errorf("X-Amz-Storage-Class = %q, want STANDARD_IA", h)
+		}
+	})
+
+	t.Run("unknown class is rejected before any request", func(t *testing.T) {
+		srv := newUploadServer(t)
+
+		opts := NewUploadOptions("catalog-check")
+		opts.StorageClass = "COLD_STORAGE"
+		_, err := srv.producer().UploadDataset(context.Background(), writeUploadFile(t), opts)
+		if err == nil || !strings.Contains(err.Error(), "invalid storage class") {
+			t.Fatalf("err = %v, want invalid storage class", err)
+		}
+		if len(srv.keys) != 0 {
+			t.Errorf("create was sent %d times, want 0", len(srv.keys))
+		}
+	})
+}
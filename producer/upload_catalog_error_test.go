@@ -1,58 +1,246 @@
 package producer
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
-	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
 )
 
-// TestUploadCatalogFailureReturnsError verifies that when catalog registration
-// or update fails after S3 upload, UploadDataset returns a non-nil error
-// (not a synthetic dataset with nil error). This is parity with the TS SDK
-// fix (commit 05d3df3).
+// uploadServer fakes every endpoint UploadDataset talks to: KMS Encrypt
+// (JSON protocol, routed by X-Amz-Target), the catalog API, and the
+// presigned PUT.
+type uploadServer struct {
+	*httptest.Server
+
+	mu sync.Mutex // serializes the handler for concurrent uploads
+
+	createStatus int // when non-zero, POST /v1/datasets fails with this status and createBody
+	createBody   string
+	replayKeys   bool           // answer a repeated Idempotency-Key with the API's replay 409
+	getStatuses  []int          // successive GET /v1/datasets/ds-1 statuses; the last repeats
+	deleteStatus int            // status for DELETE /v1/datasets/ds-1
+	created      map[string]any // body of POST /v1/datasets
+	keys         []string       // Idempotency-Key of each POST /v1/datasets
+	uploaded     []byte         // body of the presigned PUT
+	uploadHeader http.Header    // headers of the presigned PUT
+	gets         int
+	deletes      int
+}
+
+// newUploadServer returns a server whose catalog GET answers with
+// getStatuses in turn (200 when empty).
+func newUploadServer(t *testing.T, getStatuses ...int) *uploadServer {
+	t.Helper()
+
+	s := &uploadServer{getStatuses: getStatuses, deleteStatus: http.StatusNoContent}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		switch {
+		case r.Header.Get("X-Amz-Target") == "TrentService.Encrypt":
+			w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+			_, _ = w.Write([]byte(`{"CiphertextBlob":"d3JhcHBlZC1rZXk=","KeyId":"test-kms-key"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/datasets":
+			key := r.Header.Get("Idempotency-Key")
+			if s.replayKeys && slices.Contains(s.keys, key) {
+				s.keys = append(s.keys, key)
+				w.WriteHeader(http.StatusConflict)
+				_, _ = w.Write([]byte(`{"error":"idempotency key already used","dataset_id":"ds-1"}`))
+				return
+			}
+			s.keys = append(s.keys, key)
+			if s.createStatus != 0 {
+				w.WriteHeader(s.createStatus)
+				_, _ = w.Write([]byte(s.createBody))
+				return
+			}
+			_ = json.NewDecoder(r.Body).Decode(&s.created)
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]string{"id": "ds-1", "upload_url": s.URL + "/upload"})
+		case r.Method == http.MethodPut && r.URL.Path == "/upload":
+			s.uploaded, _ = io.ReadAll(r.Body)
+			s.uploadHeader = r.Header.Clone()
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/datasets/ds-1":
+			s.gets++
+			if status := s.getStatus(); status != http.StatusOK {
+				http.Error(w, "internal server error", status)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"_id":"ds-1","name":"catalog-check","s3_key":"datasets/catalog-check/data.ndjson.gz"}`))
+		case r.Method == http.MethodDelete && r.URL.Path == "/v1/datasets/ds-1":
+			s.deletes++
+			w.WriteHeader(s.deleteStatus)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *uploadServer) getStatus() int {
+	switch {
+	case len(s.getStatuses) == 0:
+		return http.StatusOK
+	case s.gets <= len(s.getStatuses):
+		return s.getStatuses[s.gets-1]
+	default:
+		return s.getStatuses[len(s.getStatuses)-1]
+	}
+}
+
+// producer returns a test producer whose API and KMS calls hit the server.
+func (s *uploadServer) producer() *Producer {
+	p := newTestProducer(s.URL)
+	p.KMSKeyID = "test-kms-key"
+	p.kmsClient = kms.NewFromConfig(p.awsConfig, func(o *kms.Options) {
+		o.BaseEndpoint = aws.String(s.URL)
+	})
+	return p
+}
+
+// writeUploadFile writes a small NDJSON file for UploadDataset to consume.
+func writeUploadFile(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "data.ndjson")
+	if err := os.WriteFile(path, []byte("{\"id\":1}\n{\"id\":2}\n"), 0o644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+	return path
+}
+
+// TestUploadCatalogFailureReturnsError drives UploadDataset end-to-end: when
+// the object reaches S3 but the catalog fetch fails, it must return nil and an
+// error that unwraps to *APIError — never a synthetic dataset.
 func TestUploadCatalogFailureReturnsError(t *testing.T) {
-	t.Run("registration failure wraps error", func(t *testing.T) {
-		innerErr := &APIError{StatusCode: 500, Body: "internal server error"}
-		err := fmt.Errorf("file uploaded to S3 but catalog registration failed: %w", innerErr)
+	t.Run("registration failure returns nil dataset and wrapped error", func(t *testing.T) {
+		srv := newUploadServer(t, http.StatusInternalServerError)
 
+		dataset, err := srv.producer().UploadDataset(context.Background(), writeUploadFile(t), NewUploadOptions("catalog-check"))
 		if err == nil {
-			t.Fatal("expected non-nil error for catalog registration failure")
+			t.Fatalf("expected error, got dataset %+v", dataset)
+		}
+		if dataset != nil {
+			t.Errorf("expected nil dataset on failure, got %+v", dataset)
 		}
-		if !strings.Contains(err.Error(), "catalog registration failed") {
-			t.Errorf("error should mention catalog registration, got: %s", err.Error())
+		if len(srv.uploaded) == 0 {
+			t.Error("expected the object to be uploaded before the catalog fetch")
+		}
+		if !strings.Contains(err.Error(), "catalog registration failed") ||
+			!strings.Contains(err.Error(), "datasets/catalog-check/data.ndjson.gz") {
+			t.Errorf("error should name the failed step and S3 key, got: %s", err)
 		}
 		var apiErr *APIError
-		if !errors.As(err, &apiErr) {
-			t.Error("wrapped error should be unwrappable to *APIError")
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusInternalServerError {
+			t.Errorf("expected wrapped *APIError 500, got %T: %v", err, err)
+		}
+	})
+
+	t.Run("error names an overridden s3_key", func(t *testing.T) {
+		srv := newUploadServer(t, http.StatusInternalServerError)
+
+		opts := NewUploadOptions("catalog-check")
+		opts.DatasetOverrides = map[string]any{"s3_key": "datasets/custom/key.ndjson.gz"}
+
+		_, err := srv.producer().UploadDataset(context.Background(), writeUploadFile(t), opts)
+		if err == nil || !strings.Contains(err.Error(), "s3_key=datasets/custom/key.ndjson.gz") {
+			t.Errorf("error should carry the overridden S3 key, got: %v", err)
+		}
+	})
+
+	t.Run("success returns the registered dataset", func(t *testing.T) {
+		srv := newUploadServer(t, http.StatusOK)
+
+		dataset, err := srv.producer().UploadDataset(context.Background(), writeUploadFile(t), NewUploadOptions("catalog-check"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if dataset.ID != "ds-1" {
+			t.Errorf("unexpected dataset: %+v", dataset)
 		}
 	})
+}
+
+// TestAPIErrorIsConflict pins 409 detection on *APIError.
+func TestAPIErrorIsConflict(t *testing.T) {
+	apiErr := &APIError{StatusCode: 409, Body: "conflict"}
+	if !apiErr.IsConflict() {
+		t.Error("409 should be detected as conflict")
+	}
+
+	apiErr500 := &APIError{StatusCode: 500, Body: "server error"}
+	if apiErr500.IsConflict() {
+		t.Error("500 should not be detected as conflict")
+	}
+}
 
-	t.Run("update failure wraps error", func(t *testing.T) {
-		innerErr := &APIError{StatusCode: 500, Body: "internal server error"}
-		err := fmt.Errorf("file uploaded to S3 but catalog update failed: %w", innerErr)
+// TestConfirmCatalogRegistration drives the real step-4 path of UploadDataset:
+// once the object is in S3, a failed catalog fetch must return an error that
+// unwraps to *APIError and names the S3 key — never a synthetic dataset.
+func TestConfirmCatalogRegistration(t *testing.T) {
+	createResp := &CreateDatasetResponse{
+		ID:    "ds-1",
+		S3Key: "datasets/orphan-check/data.ndjson.gz",
+	}
 
+	t.Run("catalog failure returns wrapped error with S3 key", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		dataset, err := newTestProducer(server.URL).confirmCatalogRegistration(context.Background(), createResp)
 		if err == nil {
-			t.Fatal("expected non-nil error for catalog update failure")
+			t.Fatalf("expected error, got dataset %+v", dataset)
+		}
+		if dataset != nil {
+			t.Errorf("expected nil dataset on failure, got %+v", dataset)
+		}
+		if !strings.Contains(err.Error(), "file uploaded to S3 but catalog registration failed") {
+			t.Errorf("error should mention catalog registration, got: %s", err)
 		}
-		if !strings.Contains(err.Error(), "catalog update failed") {
-			t.Errorf("error should mention catalog update, got: %s", err.Error())
+		if !strings.Contains(err.Error(), createResp.S3Key) {
+			t.Errorf("error should carry the S3 key %q, got: %s", createResp.S3Key, err)
 		}
 		var apiErr *APIError
-		if !errors.As(err, &apiErr) {
-			t.Error("wrapped error should be unwrappable to *APIError")
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusInternalServerError {
+			t.Errorf("expected wrapped *APIError 500, got %T: %v", err, err)
 		}
 	})
 
-	t.Run("conflict error is detectable", func(t *testing.T) {
-		apiErr := &APIError{StatusCode: 409, Body: "conflict"}
-		if !apiErr.IsConflict() {
-			t.Error("409 should be detected as conflict")
-		}
+	t.Run("success returns the registered dataset", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet || r.URL.Path != "/v1/datasets/ds-1" {
+				t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"_id":"ds-1","name":"orphan-check"}`))
+		}))
+		defer server.Close()
 
-		apiErr500 := &APIError{StatusCode: 500, Body: "server error"}
-		if apiErr500.IsConflict() {
-			t.Error("500 should not be detected as conflict")
+		dataset, err := newTestProducer(server.URL).confirmCatalogRegistration(context.Background(), createResp)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if dataset.ID != "ds-1" || dataset.Name != "orphan-check" {
+			t.Errorf("unexpected dataset: %+v", dataset)
 		}
 	})
 }
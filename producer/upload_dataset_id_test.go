@@ -0,0 +1,128 @@
+package producer
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestValidateDatasetID(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      UploadOptions
+		wantError string
+	}{
+		{"unset", UploadOptions{}, ""},
+		{"generated format", UploadOptions{DatasetID: strptr("company-123456-daily-sales")}, ""},
+		{"single char", UploadOptions{DatasetID: strptr("a")}, ""},
+		{"empty", UploadOptions{DatasetID: strptr("")}, "invalid dataset ID"},
+		{"uppercase", UploadOptions{DatasetID: strptr("Company-1")}, "invalid dataset ID"},
+		{"slash", UploadOptions{DatasetID: strptr("a/b")}, "invalid dataset ID"},
+		{"leading hyphen", UploadOptions{DatasetID: strptr("-a")}, "invalid dataset ID"},
+		{"trailing hyphen", UploadOptions{DatasetID: strptr("a-")}, "invalid dataset ID"},
+		{"too long", UploadOptions{DatasetID: strptr(strings.Repeat("a", 129))}, "invalid dataset ID"},
+		{
+			"matching override",
+			UploadOptions{DatasetID: strptr("ds-1"), DatasetOverrides: map[string]any{"_id": "ds-1"}},
+			"",
+		},
+		{
+			"conflicting override",
+			UploadOptions{DatasetID: strptr("ds-1"), DatasetOverrides: map[string]any{"id": "ds-2"}},
+			"conflicts with DatasetOverrides",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.validateDatasetID()
+			if tt.wantError == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("expected error containing %q, got %v", tt.wantError, err)
+			}
+		})
+	}
+}
+
+// TestUploadDatasetID pins that DatasetID reaches the create payload as _id,
+// counts as an in-place update, and is validated before any network call.
+func TestUploadDatasetID(t *testing.T) {
+	t.Run("sent as _id", func(t *testing.T) {
+		srv := newUploadServer(t)
+
+		opts := NewUploadOptions("catalog-check")
+		opts.DatasetID = strptr("test-producer-catalog-check")
+
+		if _, err := srv.producer().UploadDataset(context.Background(), writeUploadFile(t), opts); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := srv.created["_id"]; got != "test-producer-catalog-check" {
+			t.Errorf("expected _id in create payload, got %v", got)
+		}
+		if !opts.isInPlaceUpdate() {
+			t.Error("an explicit DatasetID must count as an in-place update")
+		}
+	})
+
+	t.Run("invalid ID fails before the create request", func(t *testing.T) {
+		srv := newUploadServer(t)
+
+		opts := NewUploadOptions("catalog-check")
+		opts.DatasetID = strptr("Not Valid")
+
+		if _, err := srv.producer().UploadDataset(context.Background(), writeUploadFile(t), opts); err == nil {
+			t.Fatal("expected a validation error")
+		}
+		if len(srv.keys) != 0 {
+			t.Errorf("no create request should be sent, got %d", len(srv.keys))
+		}
+	})
+}
+
+// TestUploadIDFormat pins that IDFormat renders a client-side _id and that a
+// template producing an invalid ID is rejected before the create request.
+func TestUploadIDFormat(t *testing.T) {
+	t.Run("rendered ID is sent", func(t *testing.T) {
+		srv := newUploadServer(t)
+
+		opts := NewUploadOptions("catalog-check")
+		opts.IDFormat = IDFormatDefault
+
+		if _, err := srv.producer().UploadDataset(context.Background(), writeUploadFile(t), opts); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := srv.created["_id"]; got != "test-producer-catalog-check" {
+			t.Errorf("expected rendered _id, got %v", got)
+		}
+	})
+
+	t.Run("unset leaves ID assignment to the API", func(t *testing.T) {
+		srv := newUploadServer(t)
+
+		if _, err := srv.producer().UploadDataset(context.Background(), writeUploadFile(t), NewUploadOptions("catalog-check")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, ok := srv.created["_id"]; ok {
+			t.Errorf("no _id expected, got %v", srv.created["_id"])
+		}
+	})
+
+	t.Run("invalid template is rejected", func(t *testing.T) {
+		srv := newUploadServer(t)
+
+		opts := NewUploadOptions("catalog-check")
+		opts.IDFormat = "{customer}/{unknown}"
+
+		_, err := srv.producer().UploadDataset(context.Background(), writeUploadFile(t), opts)
+		if err == nil || !strings.Contains(err.Error(), "invalid dataset ID") {
+			t.Fatalf("expected an invalid ID error, got %v", err)
+		}
+		if len(srv.keys) != 0 {
+			t.Errorf("no create request should be sent, got %d", len(srv.keys))
+		}
+	})
+}
@@ -0,0 +1,225 @@
+package producer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestDeriveIdempotencyKey pins that the default key is stable for a retry of
+// the same upload and changes with the producer, name, or content.
+func TestDeriveIdempotencyKey(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write test file: %v", err)
+		}
+		return path
+	}
+	a := write("a.ndjson", "{\"id\":1}\n")
+	sameAsA := write("a-copy.ndjson", "{\"id\":1}\n")
+	b := write("b.ndjson", "{\"id\":2}\n")
+
+	p := newTestProducer("http://unused.invalid")
+	key := func(path, name string) string {
+		t.Helper()
+		k, err := p.deriveIdempotencyKey(path, name)
+		if err != nil {
+			t.Fatalf("deriveIdempotencyKey: %v", err)
+		}
+		return k
+	}
+
+	base := key(a, "ds")
+	if len(base) != 64 {
+		t.Errorf("expected a hex SHA-256 key, got %q", base)
+	}
+	if got := key(sameAsA, "ds"); got != base {
+		t.Errorf("same producer/name/content must reuse the key: %q != %q", got, base)
+	}
+	if key(b, "ds") == base {
+		t.Error("different content must change the key")
+	}
+	if key(a, "other") == base {
+		t.Error("different dataset name must change the key")
+	}
+
+	other := newTestProducer("http://unused.invalid")
+	other.CustomerID = "another-producer"
+	if k, _ := other.deriveIdempotencyKey(a, "ds"); k == base {
+		t.Error("different producer must change the key")
+	}
+
+	if _, err := p.deriveIdempotencyKey(filepath.Join(dir, "missing.ndjson"), "ds"); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+// TestUploadIdempotencyKey drives UploadDataset and pins the Idempotency-Key
+// header and the duplicate-key handling.
+func TestUploadIdempotencyKey(t *testing.T) {
+	t.Run("derived key is sent and stable across retries", func(t *testing.T) {
+		srv := newUploadServer(t)
+		p := srv.producer()
+		file := writeUploadFile(t)
+
+		for range 2 {
+			if _, err := p.UploadDataset(context.Background(), file, NewUploadOptions("catalog-check")); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		if len(srv.keys) != 2 || srv.keys[0] == "" || srv.keys[0] != srv.keys[1] {
+			t.Errorf("expected the same non-empty key on both attempts, got %q", srv.keys)
+		}
+	})
+
+	t.Run("caller-supplied key wins", func(t *testing.T) {
+		srv := newUploadServer(t)
+
+		opts := NewUploadOptions("catalog-check")
+		opts.IdempotencyKey = "caller-key-1"
+
+		if _, err := srv.producer().UploadDataset(context.Background(), writeUploadFile(t), opts); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(srv.keys) != 1 || srv.keys[0] != "caller-key-1" {
+			t.Errorf("expected caller key, got %q", srv.keys)
+		}
+	})
+
+	t.Run("duplicate key returns the existing dataset", func(t *testing.T) {
+		srv := newUploadServer(t)
+		srv.createStatus = http.StatusConflict
+		srv.createBody = `{"error":"idempotency key already used","dataset_id":"ds-1"}`
+		s3fake := &fakeS3{exists: true}
+		p := srv.producer()
+		p.s3Client = s3fake
+
+		dataset, err := p.UploadDataset(context.Background(), writeUploadFile(t), NewUploadOptions("catalog-check"))
+		if err != nil {
+			t.Fatalf("duplicate key should be treated as success, got %v", err)
+		}
+		if dataset.ID != "ds-1" {
+			t.Errorf("expected the existing dataset, got %+v", dataset)
+		}
+		if s3fake.heads != 1 {
+			t.Errorf("HeadObject calls = %d, want 1 to check the object was stored", s3fake.heads)
+		}
+		if srv.uploaded != nil || s3fake.puts != nil {
+			t.Error("a replayed create whose object is stored must not upload again")
+		}
+	})
+
+	t.Run("duplicate key without a stored object uploads it", func(t *testing.T) {
+		srv := newUploadServer(t)
+		srv.createStatus = http.StatusConflict
+		srv.createBody = `{"error":"idempotency key already used","dataset_id":"ds-1"}`
+		s3fake := &fakeS3{}
+		p := srv.producer()
+		p.s3Client = s3fake
+
+		dataset, err := p.UploadDataset(context.Background(), writeUploadFile(t), NewUploadOptions("catalog-check"))
+		if err != nil {
+			t.Fatalf("UploadDataset: %v", err)
+		}
+		if dataset.ID != "ds-1" {
+			t.Errorf("expected the existing dataset, got %+v", dataset)
+		}
+		if body := s3fake.puts["dme-producer-test/datasets/catalog-check/data.ndjson.gz"]; len(body) == 0 {
+			t.Errorf("PutObject calls = %v, want the missing object stored under the record's key", s3fake.puts)
+		}
+	})
+}
+
+// dropFirstCreate passes every request through but reports the first
+// dataset create as timed out after the server has handled it, like a
+// create whose response is lost.
+type dropFirstCreate struct {
+	dropped bool
+}
+
+func (d *dropFirstCreate) RoundTrip(r *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(r)
+	if err == nil && !d.dropped && r.Method == http.MethodPost && r.URL.Path == "/v1/datasets" {
+		d.dropped = true
+		resp.Body.Close()
+		return nil, context.DeadlineExceeded
+	}
+	return resp, err
+}
+
+// TestUploadRetryAfterCreateTimeout covers a create that succeeded on the
+// server but timed out on the client before the upload: the caller's retry
+// is answered as a replay, and must still store the object.
+func TestUploadRetryAfterCreateTimeout(t *testing.T) {
+	srv := newUploadServer(t)
+	srv.replayKeys = true
+	s3fake := &fakeS3{}
+	p := srv.producer()
+	p.s3Client = s3fake
+	p.httpClient = &http.Client{Transport: &dropFirstCreate{}}
+
+	file := writeUploadFile(t)
+	if _, err := p.UploadDataset(context.Background(), file, NewUploadOptions("catalog-check")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("first attempt err = %v, want the create timeout", err)
+	}
+	if s3fake.puts != nil {
+		t.Fatalf("the timed-out attempt stored %v", s3fake.puts)
+	}
+
+	dataset, err := p.UploadDataset(context.Background(), file, NewUploadOptions("catalog-check"))
+	if err != nil {
+		t.Fatalf("retried UploadDataset: %v", err)
+	}
+	if dataset.ID != "ds-1" {
+		t.Errorf("dataset = %+v, want ds-1", dataset)
+	}
+	if len(srv.keys) != 2 || srv.keys[0] != srv.keys[1] {
+		t.Errorf("create keys = %q, want the timed-out create retried with the same key", srv.keys)
+	}
+	if body := s3fake.puts["dme-producer-test/datasets/catalog-check/data.ndjson.gz"]; len(body) == 0 {
+		t.Errorf("PutObject calls = %v, want the object stored after the replay", s3fake.puts)
+	}
+
+	t.Run("plain name conflict is still an error", func(t *testing.T) {
+		srv := newUploadServer(t)
+		srv.createStatus = http.StatusConflict
+		srv.createBody = `{"error":"dataset with this name already exists","_id":"ds-1"}`
+
+		_, err := srv.producer().UploadDataset(context.Background(), writeUploadFile(t), NewUploadOptions("catalog-check"))
+		if err == nil {
+			t.Fatal("expected a conflict error")
+		}
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || !apiErr.IsConflict() {
+			t.Errorf("expected a wrapped 409 *APIError, got %v", err)
+		}
+	})
+}
+
+func TestReplayedDatasetID(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"matching key field", &APIError{StatusCode: 409, Body: `{"idempotency_key":"k","id":"ds-1"}`}, "ds-1"},
+		{"idempotency message", &APIError{StatusCode: 409, Body: `{"message":"Idempotency-Key reused","_id":"ds-2"}`}, "ds-2"},
+		{"other key", &APIError{StatusCode: 409, Body: `{"idempotency_key":"other","id":"ds-1"}`}, ""},
+		{"name conflict", &APIError{StatusCode: 409, Body: `{"error":"name taken","id":"ds-1"}`}, ""},
+		{"not a conflict", &APIError{StatusCode: 500, Body: `{"message":"idempotency store down"}`}, ""},
+		{"no id", &APIError{StatusCode: 409, Body: `{"message":"idempotency key reused"}`}, ""},
+		{"not JSON", &APIError{StatusCode: 409, Body: `idempotency`}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := replayedDatasetID(tt.err, "k"); got != tt.want {
+				t.Errorf("replayedDatasetID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
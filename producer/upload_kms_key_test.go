@@ -0,0 +1,96 @@
+package producer
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	kmstypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/helix-tools/sdk-go/v2/internal/awsfake"
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+// TestUploadDatasetPerUploadKMSKey seals an upload under
+// UploadOptions.KMSKeyID and records the key's ARN in the metadata.
+func TestUploadDatasetPerUploadKMSKey(t *testing.T) {
+	srv := newUploadServer(t)
+	p := srv.producer()
+	fakeKMS := awsfake.NewKMS()
+	p.kmsClient = fakeKMS
+
+	opts := NewUploadOptions("catalog-check")
+	opts.KMSKeyID = "tenant-key"
+	if _, err := p.UploadDataset(context.Background(), writeUploadFile(t), opts); err != nil {
+		t.Fatalf("UploadDataset: %v", err)
+	}
+
+	const arn = "arn:aws:kms:us-east-1:000000000000:key/tenant-key"
+	metadata, _ := srv.created["metadata"].(map[string]any)
+	if got := metadata["kms_key_id"]; got != arn {
+		t.Errorf("metadata kms_key_id = %v, want %s", got, arn)
+	}
+
+	keyLen := binary.BigEndian.Uint32(srv.uploaded)
+	wrapped := srv.uploaded[4 : 4+keyLen]
+	if _, err := fakeKMS.Decrypt(context.Background(), &kms.DecryptInput{CiphertextBlob: wrapped, KeyId: aws.String(arn)}); err != nil {
+		t.Errorf("data key was not wrapped under %s: %v", arn, err)
+	}
+}
+
+// TestUploadDatasetDefaultKMSKey keeps the producer's key, and records no
+// kms_key_id, when UploadOptions.KMSKeyID is unset.
+func TestUploadDatasetDefaultKMSKey(t *testing.T) {
+	srv := newUploadServer(t)
+	p := srv.producer()
+	fakeKMS := awsfake.NewKMS()
+	p.kmsClient = fakeKMS
+
+	if _, err := p.UploadDataset(context.Background(), writeUploadFile(t), NewUploadOptions("catalog-check")); err != nil {
+		t.Fatalf("UploadDataset: %v", err)
+	}
+
+	metadata, _ := srv.created["metadata"].(map[string]any)
+	if got, ok := metadata["kms_key_id"]; ok {
+		t.Errorf("metadata kms_key_id = %v, want unset", got)
+	}
+	keyLen := binary.BigEndian.Uint32(srv.uploaded)
+	wrapped := srv.uploaded[4 : 4+keyLen]
+	if _, err := fakeKMS.Decrypt(context.Background(), &kms.DecryptInput{CiphertextBlob: wrapped, KeyId: aws.String(p.KMSKeyID)}); err != nil {
+		t.Errorf("data key was not wrapped under the producer's key: %v", err)
+	}
+}
+
+// TestUploadDatasetUnusableKMSKey fails before creating the catalog record
+// when the per-upload key is missing or disabled.
+func TestUploadDatasetUnusableKMSKey(t *testing.T) {
+	for _, tc := range []struct {
+		key, want string
+		is        error
+	}{
+		{"missing", "not accessible", types.ErrKMSKeyNotFound},
+		{"disabled-key", "Disabled", types.ErrKMSKeyDisabled},
+	} {
+		srv := newUploadServer(t)
+		p := srv.producer()
+		fakeKMS := awsfake.NewKMS()
+		fakeKMS.SetKeyState("disabled-key", kmstypes.KeyStateDisabled)
+		p.kmsClient = fakeKMS
+
+		opts := NewUploadOptions("catalog-check")
+		opts.KMSKeyID = tc.key
+		_, err := p.UploadDataset(context.Background(), writeUploadFile(t), opts)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("key %s: error = %v, want one containing %q", tc.key, err, tc.want)
+		}
+		if !errors.Is(err, tc.is) {
+			t.Errorf("key %s: error = %v, want %v", tc.key, err, tc.is)
+		}
+		if len(srv.keys) != 0 {
+			t.Errorf("key %s: catalog record created despite the unusable key", tc.key)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package producer
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+func TestUploadOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*UploadOptions)
+		want   []string // substrings of the error; none means valid
+	}{
+		{"defaults", func(*UploadOptions) {}, nil},
+		{"zero values are defaulted", func(o *UploadOptions) {
+			o.Category, o.DataFreshness, o.CompressionLevel = "", "", 0
+		}, nil},
+		{"blank name", func(o *UploadOptions) { o.DatasetName = "  " }, []string{"dataset name is required"}},
+		{"blank category", func(o *UploadOptions) { o.Category = " " }, []string{"category must not be blank"}},
+		{"unknown freshness", func(o *UploadOptions) { o.DataFreshness = "realtime" }, []string{`unknown data freshness "realtime"`}},
+		{"compression level", func(o *UploadOptions) { o.CompressionLevel = 10 }, []string{"compression level 10 out of range 1-9"}},
+		{"bad dataset ID", func(o *UploadOptions) { o.DatasetID = strptr("Bad ID") }, []string{"invalid dataset ID"}},
+		{"every problem at once", func(o *UploadOptions) {
+			o.DatasetName, o.Encrypt, o.Compress = "", false, false
+		}, []string{"dataset name is required", "encryption is required", "compression is required"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := NewUploadOptions("sales")
+			tt.modify(&opts)
+
+			err := opts.Validate()
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Errorf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Validate() = nil, want %q", tt.want)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate() = %v, want it to mention %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+// TestUploadDatasetValidatesFirst pins that invalid options fail before any
+// request is made.
+func TestUploadDatasetValidatesFirst(t *testing.T) {
+	p := &Producer{CustomerID: "test-producer", KMSKeyID: "key"}
+	opts := NewUploadOptions("sales")
+	opts.DataFreshness = types.DataFreshness("sometimes")
+
+	_, err := p.UploadDataset(context.Background(), "/does/not/exist.ndjson", opts)
+	if err == nil || !strings.Contains(err.Error(), "unknown data freshness") {
+		t.Errorf("err = %v, want the validation error", err)
+	}
+}
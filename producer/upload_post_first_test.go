@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -11,6 +12,8 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/helix-tools/sdk-go/v2/types"
 )
 
 // TestProcessFileCompression tests file compression logic.
@@ -69,6 +72,9 @@ func TestProcessFileEmptyFile(t *testing.T) {
 	if !strings.Contains(err.Error(), "file is empty") {
 		t.Errorf("expected 'file is empty' error, got: %v", err)
 	}
+	if !errors.Is(err, types.ErrEmptyFile) {
+		t.Errorf("expected types.ErrEmptyFile, got: %v", err)
+	}
 }
 
 // TestProcessFileMissingFile tests that missing files return appropriate error.
@@ -132,7 +138,7 @@ func TestUploadToPresignedURL(t *testing.T) {
 		}
 
 		testData := []byte("test data content")
-		err := p.uploadToPresignedURL(context.Background(), server.URL, testData)
+		err := p.uploadToPresignedURL(context.Background(), server.URL, testData, nil)
 		if err != nil {
 			t.Fatalf("uploadToPresignedURL failed: %v", err)
 		}
@@ -156,7 +162,7 @@ func TestUploadToPresignedURL(t *testing.T) {
 		}
 
 		testData := []byte("test data")
-		err := p.uploadToPresignedURL(context.Background(), server.URL, testData)
+		err := p.uploadToPresignedURL(context.Background(), server.URL, testData, nil)
 		if err == nil {
 			t.Fatal("expected error for failed upload")
 		}
@@ -185,7 +191,7 @@ func TestUploadToPresignedURL(t *testing.T) {
 		}
 
 		// Should still succeed even with empty data (edge case)
-		err := p.uploadToPresignedURL(context.Background(), server.URL, []byte{})
+		err := p.uploadToPresignedURL(context.Background(), server.URL, []byte{}, nil)
 		if err != nil {
 			t.Errorf("uploadToPresignedURL should handle empty data: %v", err)
 		}
@@ -216,6 +222,9 @@ func TestUploadDatasetValidation(t *testing.T) {
 		if !strings.Contains(err.Error(), "encryption is required") {
 			t.Errorf("expected 'encryption is required', got: %v", err)
 		}
+		if !errors.Is(err, types.ErrEncryptionRequired) {
+			t.Errorf("expected types.ErrEncryptionRequired, got: %v", err)
+		}
 	})
 
 	t.Run("compression required", func(t *testing.T) {
@@ -229,6 +238,9 @@ func TestUploadDatasetValidation(t *testing.T) {
 		if !strings.Contains(err.Error(), "compression is required") {
 			t.Errorf("expected 'compression is required', got: %v", err)
 		}
+		if !errors.Is(err, types.ErrCompressionRequired) {
+			t.Errorf("expected types.ErrCompressionRequired, got: %v", err)
+		}
 	})
 
 	t.Run("KMS key required for encryption", func(t *testing.T) {
@@ -243,6 +255,9 @@ func TestUploadDatasetValidation(t *testing.T) {
 		if !strings.Contains(err.Error(), "KMS key not found") {
 			t.Errorf("expected 'KMS key not found', got: %v", err)
 		}
+		if !errors.Is(err, types.ErrKMSKeyNotFound) {
+			t.Errorf("expected types.ErrKMSKeyNotFound, got: %v", err)
+		}
 	})
 }
 
@@ -0,0 +1,262 @@
+package producer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/helix-tools/sdk-go/v2/client"
+)
+
+// fakeS3 answers HeadObject from exists, GetObject from puts, and records
+// DeleteObject and PutObject calls.
+type fakeS3 struct {
+	mu        sync.Mutex
+	exists    bool
+	heads     int
+	deleted   []string
+	deleteErr error
+	puts      map[string][]byte // body of each PutObject, by bucket/key
+}
+
+func (f *fakeS3) HeadBucket(_ context.Context, _ *s3.HeadBucketInput, _ ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	return &s3.HeadBucketOutput{}, nil
+}
+
+func (f *fakeS3) HeadObject(_ context.Context, _ *s3.HeadObjectInput, _ ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	f.heads++
+	if f.exists {
+		return &s3.HeadObjectOutput{}, nil
+	}
+	return nil, &awshttp.ResponseError{ResponseError: &smithyhttp.ResponseError{
+		Response: &smithyhttp.Response{Response: &http.Response{StatusCode: http.StatusNotFound}},
+		Err:      errors.New("NotFound"),
+	}}
+}
+
+func (f *fakeS3) GetObject(_ context.Context, in *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	body, ok := f.puts[aws.ToString(in.Bucket)+"/"+aws.ToString(in.Key)]
+	if !ok {
+		return nil, &awshttp.ResponseError{ResponseError: &smithyhttp.ResponseError{
+			Response: &smithyhttp.Response{Response: &http.Response{StatusCode: http.StatusNotFound}},
+			Err:      errors.New("NoSuchKey"),
+		}}
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(strings.NewReader(string(body)))}, nil
+}
+
+func (f *fakeS3) DeleteObject(_ context.Context, in *s3.DeleteObjectInput, _ ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deleted = append(f.deleted, aws.ToString(in.Bucket)+"/"+aws.ToString(in.Key))
+	if f.deleteErr != nil {
+		return nil, f.deleteErr
+	}
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func (f *fakeS3) PutObject(_ context.Context, in *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	body, err := io.ReadAll(in.Body)
+	if err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.puts == nil {
+		f.puts = make(map[string][]byte)
+	}
+	f.puts[aws.ToString(in.Bucket)+"/"+aws.ToString(in.Key)] = body
+	return &s3.PutObjectOutput{}, nil
+}
+
+// The copy and multipart calls are only used by RewrapDatasetKey and
+// UploadDatasetFromS3, which are tested against awsfake.S3.
+var errMultipartUnsupported = errors.New("fakeS3: copies and multipart uploads not supported")
+
+func (f *fakeS3) CopyObject(context.Context, *s3.CopyObjectInput, ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	return nil, errMultipartUnsupported
+}
+
+func (f *fakeS3) CreateMultipartUpload(context.Context, *s3.CreateMultipartUploadInput, ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	return nil, errMultipartUnsupported
+}
+
+func (f *fakeS3) UploadPart(context.Context, *s3.UploadPartInput, ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	return nil, errMultipartUnsupported
+}
+
+func (f *fakeS3) UploadPartCopy(context.Context, *s3.UploadPartCopyInput, ...func(*s3.Options)) (*s3.UploadPartCopyOutput, error) {
+	return nil, errMultipartUnsupported
+}
+
+func (f *fakeS3) CompleteMultipartUpload(context.Context, *s3.CompleteMultipartUploadInput, ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	return nil, errMultipartUnsupported
+}
+
+func (f *fakeS3) AbortMultipartUpload(context.Context, *s3.AbortMultipartUploadInput, ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	return nil, errMultipartUnsupported
+}
+
+func boolptr(b bool) *bool { return &b }
+
+const rollbackObject = "dme-producer-test/datasets/catalog-check/data.ndjson.gz"
+
+// TestUploadRollback drives UploadDataset with a catalog fetch that keeps
+// failing and pins when the record and object are (and are not) rolled back.
+func TestUploadRollback(t *testing.T) {
+	upload := func(t *testing.T, srv *uploadServer, fake *fakeS3, opts UploadOptions) error {
+		t.Helper()
+		p := srv.producer()
+		p.s3Client = fake
+		_, err := p.UploadDataset(context.Background(), writeUploadFile(t), opts)
+		if err == nil {
+			t.Fatal("expected registration error")
+		}
+		return err
+	}
+
+	t.Run("new object is rolled back after retries", func(t *testing.T) {
+		srv := newUploadServer(t, http.StatusInternalServerError)
+		fake := &fakeS3{}
+
+		err := upload(t, srv, fake, NewUploadOptions("catalog-check"))
+		if srv.gets != client.DefaultRetryPolicy.MaxAttempts {
+			t.Errorf("expected %d catalog fetches, got %d", client.DefaultRetryPolicy.MaxAttempts, srv.gets)
+		}
+		if srv.deletes != 1 {
+			t.Errorf("expected the dataset record to be deleted, got %d deletes", srv.deletes)
+		}
+		if len(fake.deleted) != 1 || fake.deleted[0] != rollbackObject {
+			t.Errorf("expected one delete of the uploaded object, got %v", fake.deleted)
+		}
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusInternalServerError {
+			t.Errorf("expected the registration *APIError back, got %v", err)
+		}
+	})
+
+	t.Run("transient failure recovers without rollback", func(t *testing.T) {
+		srv := newUploadServer(t, http.StatusServiceUnavailable, http.StatusOK)
+		fake := &fakeS3{}
+
+		p := srv.producer()
+		p.s3Client = fake
+		dataset, err := p.UploadDataset(context.Background(), writeUploadFile(t), NewUploadOptions("catalog-check"))
+		if err != nil || dataset.ID != "ds-1" {
+			t.Fatalf("expected success after retry, got %+v, %v", dataset, err)
+		}
+		if srv.deletes != 0 || len(fake.deleted) != 0 {
+			t.Errorf("nothing should be rolled back, got %d record / %v object deletes", srv.deletes, fake.deleted)
+		}
+	})
+
+	t.Run("existing object is never deleted", func(t *testing.T) {
+		srv := newUploadServer(t, http.StatusInternalServerError)
+		fake := &fakeS3{exists: true}
+
+		_ = upload(t, srv, fake, NewUploadOptions("catalog-check"))
+		if srv.deletes != 0 || len(fake.deleted) != 0 {
+			t.Errorf("re-upload over a live key must not roll back, got %d record / %v object deletes", srv.deletes, fake.deleted)
+		}
+	})
+
+	t.Run("record delete failure leaves the object in place", func(t *testing.T) {
+		srv := newUploadServer(t, http.StatusInternalServerError)
+		srv.deleteStatus = http.StatusInternalServerError
+		fake := &fakeS3{}
+
+		err := upload(t, srv, fake, NewUploadOptions("catalog-check"))
+		if len(fake.deleted) != 0 {
+			t.Errorf("object must stay while its record exists, got %v", fake.deleted)
+		}
+		if !strings.Contains(err.Error(), "left in place") {
+			t.Errorf("error should say the rollback left data in place, got: %s", err)
+		}
+	})
+
+	t.Run("object delete failure reports both errors", func(t *testing.T) {
+		srv := newUploadServer(t, http.StatusInternalServerError)
+		deleteErr := errors.New("AccessDenied: s3:DeleteObject")
+		fake := &fakeS3{deleteErr: deleteErr}
+
+		err := upload(t, srv, fake, NewUploadOptions("catalog-check"))
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || !errors.Is(err, deleteErr) {
+			t.Fatalf("expected both errors wrapped, got %v", err)
+		}
+		if !strings.Contains(err.Error(), "manual cleanup required") {
+			t.Errorf("error should tell operators to clean up, got: %s", err)
+		}
+	})
+
+	t.Run("disabled via option", func(t *testing.T) {
+		srv := newUploadServer(t, http.StatusInternalServerError)
+		fake := &fakeS3{}
+
+		opts := NewUploadOptions("catalog-check")
+		opts.RollbackOnRegistrationFailure = boolptr(false)
+
+		_ = upload(t, srv, fake, opts)
+		if fake.heads != 0 || srv.deletes != 0 || len(fake.deleted) != 0 {
+			t.Errorf("rollback disabled, got %d heads, %d record / %v object deletes", fake.heads, srv.deletes, fake.deleted)
+		}
+	})
+
+	t.Run("skipped for in-place updates", func(t *testing.T) {
+		srv := newUploadServer(t, http.StatusInternalServerError)
+		fake := &fakeS3{}
+
+		opts := NewUploadOptions("catalog-check")
+		opts.DatasetOverrides = map[string]any{"_id": "ds-existing"}
+
+		_ = upload(t, srv, fake, opts)
+		if srv.deletes != 0 || len(fake.deleted) != 0 {
+			t.Errorf("in-place update must not roll back, got %d record / %v object deletes", srv.deletes, fake.deleted)
+		}
+	})
+}
+
+// TestRollbackUploadDetachedContext pins that a cancelled upload still
+// cleans up: the rollback runs on a context detached from the caller's.
+func TestRollbackUploadDetachedContext(t *testing.T) {
+	srv := newUploadServer(t)
+	fake := &fakeS3{}
+	p := srv.producer()
+	p.s3Client = fake
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	regErr := context.Canceled
+	createResp := &CreateDatasetResponse{ID: "ds-1", S3Key: "datasets/catalog-check/data.ndjson.gz"}
+	if err := p.rollbackUpload(ctx, createResp, regErr); !errors.Is(err, regErr) {
+		t.Fatalf("expected the registration error back, got %v", err)
+	}
+	if srv.deletes != 1 || len(fake.deleted) != 1 {
+		t.Errorf("expected rollback despite cancelled ctx, got %d record / %v object deletes", srv.deletes, fake.deleted)
+	}
+}
+
+// TestConfirmCatalogRegistrationNoRetryOn4xx pins that definitive client
+// errors are not retried.
+func TestConfirmCatalogRegistrationNoRetryOn4xx(t *testing.T) {
+	srv := newUploadServer(t, http.StatusForbidden)
+
+	_, err := srv.producer().confirmCatalogRegistration(context.Background(), &CreateDatasetResponse{ID: "ds-1"})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if srv.gets != 1 {
+		t.Errorf("expected a single fetch for a 403, got %d", srv.gets)
+	}
+}
@@ -0,0 +1,28 @@
+package producer
+
+import (
+	"context"
+	"testing"
+)
+
+func TestUploadOnComplete(t *testing.T) {
+	srv := newUploadServer(t)
+	opts := NewUploadOptions("catalog-check")
+
+	var stats []UploadStats
+	opts.OnComplete = func(s UploadStats) { stats = append(stats, s) }
+	if _, err := srv.producer().UploadDataset(context.Background(), writeUploadFile(t), opts); err != nil {
+		t.Fatalf("UploadDataset: %v", err)
+	}
+
+	if len(stats) != 1 {
+		t.Fatalf("OnComplete calls = %d, want 1", len(stats))
+	}
+	got := stats[0]
+	if got.OriginalBytes != 18 || got.BytesUploaded != int64(len(srv.uploaded)) {
+		t.Errorf("sizes = %d original, %d uploaded; want 18, %d", got.OriginalBytes, got.BytesUploaded, len(srv.uploaded))
+	}
+	if !got.Compressed || !got.Encrypted || got.Duration <= 0 || got.Duration != got.Timings.Total {
+		t.Errorf("stats = %+v", got)
+	}
+}
@@ -0,0 +1,36 @@
+package producer
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestUploadDatasetWithResultTimings(t *testing.T) {
+	srv := newUploadServer(t, http.StatusOK)
+
+	result, err := srv.producer().UploadDatasetWithResult(context.Background(), writeUploadFile(t), NewUploadOptions("catalog-check"))
+	if err != nil {
+		t.Fatalf("UploadDatasetWithResult: %v", err)
+	}
+	if result.Dataset == nil {
+		t.Fatal("expected the registered dataset")
+	}
+
+	tm := result.Timings
+	for name, d := range map[string]time.Duration{
+		"CreateRecord": tm.CreateRecord,
+		"Compression":  tm.Compression,
+		"Encryption":   tm.Encryption,
+		"Upload":       tm.Upload,
+		"Registration": tm.Registration,
+	} {
+		if d == 0 {
+			t.Errorf("Timings.%s = 0, want the stage measured", name)
+		}
+	}
+	if stages := tm.CreateRecord + tm.Compression + tm.Encryption + tm.Upload + tm.Registration; tm.Total < stages {
+		t.Errorf("Total %v < sum of stages %v", tm.Total, stages)
+	}
+}
@@ -0,0 +1,91 @@
+package producer
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestGetUploadURL(t *testing.T) {
+	srv := newUploadServer(t)
+
+	resp, err := srv.producer().GetUploadURL(context.Background(), writeUploadFile(t), NewUploadOptions("catalog-check"))
+	if err != nil {
+		t.Fatalf("GetUploadURL: %v", err)
+	}
+	if resp.ID != "ds-1" || resp.UploadURL != srv.URL+"/upload" {
+		t.Errorf("response = %+v, want ds-1 with the presigned URL", resp)
+	}
+	if srv.created["name"] != "catalog-check" || len(srv.keys) != 1 || srv.keys[0] == "" {
+		t.Errorf("create payload = %v, keys = %q", srv.created, srv.keys)
+	}
+	if srv.uploaded != nil {
+		t.Error("GetUploadURL should not upload anything")
+	}
+}
+
+func TestGetUploadURLReplayed(t *testing.T) {
+	srv := newUploadServer(t)
+	srv.createStatus = http.StatusConflict
+	srv.createBody = `{"error":"idempotency key already used","dataset_id":"ds-1"}`
+
+	opts := NewUploadOptions("catalog-check")
+	opts.IdempotencyKey = "key-1"
+	_, err := srv.producer().GetUploadURL(context.Background(), writeUploadFile(t), opts)
+	if err == nil || !strings.Contains(err.Error(), "dataset ds-1 was already created") {
+		t.Errorf("err = %v, want an error naming the existing dataset", err)
+	}
+}
+
+func TestUploadViaPresignedURL(t *testing.T) {
+	srv := newUploadServer(t)
+	p := srv.producer()
+
+	opts := NewUploadOptions("catalog-check")
+	opts.StorageClass = "STANDARD_IA"
+	if err := p.UploadViaPresignedURL(context.Background(), srv.URL+"/upload", writeUploadFile(t), opts); err != nil {
+		t.Fatalf("UploadViaPresignedURL: %v", err)
+	}
+	if len(srv.uploaded) == 0 || srv.uploadHeader.Get("X-Amz-Storage-Class") != "STANDARD_IA" {
+		t.Errorf("uploaded %d bytes with storage class %q", len(srv.uploaded), srv.uploadHeader.Get("X-Amz-Storage-Class"))
+	}
+	if len(srv.keys) != 0 {
+		t.Error("UploadViaPresignedURL should not create a dataset record")
+	}
+
+	if err := p.UploadViaPresignedURL(context.Background(), "", writeUploadFile(t), opts); err == nil {
+		t.Error("empty upload URL should fail")
+	}
+}
+
+func TestUploadModeDirect(t *testing.T) {
+	srv := newUploadServer(t)
+	s3fake := &fakeS3{}
+	p := srv.producer()
+	p.BucketName = "producer-bucket"
+	p.s3Client = s3fake
+
+	opts := NewUploadOptions("catalog-check")
+	opts.UploadMode = UploadModeDirect
+	if _, err := p.UploadDataset(context.Background(), writeUploadFile(t), opts); err != nil {
+		t.Fatalf("UploadDataset: %v", err)
+	}
+	if srv.uploaded != nil {
+		t.Error("direct mode should not PUT to the presigned URL")
+	}
+	key, _ := srv.created["s3_key"].(string)
+	if body := s3fake.puts["producer-bucket/"+key]; len(body) == 0 {
+		t.Errorf("PutObject calls = %v, want one for %s", s3fake.puts, key)
+	}
+
+	p.s3Client = nil
+	if _, err := p.UploadDataset(context.Background(), writeUploadFile(t), opts); err == nil || !strings.Contains(err.Error(), "direct upload needs an S3 client") {
+		t.Errorf("err = %v, want a missing S3 client error", err)
+	}
+
+	opts.UploadMode = "sideways"
+	if err := opts.Validate(); err == nil || !strings.Contains(err.Error(), "unknown upload mode") {
+		t.Errorf("Validate = %v, want an unknown upload mode error", err)
+	}
+}
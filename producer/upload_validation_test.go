@@ -1,10 +1,14 @@
 package producer
 
 import (
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/helix-tools/sdk-go/v2/types"
 )
 
 // TestEmptyFileValidation tests that empty files are rejected with a clear error.
@@ -73,3 +77,68 @@ func TestNonEmptyFileValidation(t *testing.T) {
 		t.Errorf("expected %d bytes, got %d", len(content), len(data))
 	}
 }
+
+// TestUploadDatasetEmptyFile rejects an empty file in UploadDataset itself,
+// before any catalog record is created.
+func TestUploadDatasetEmptyFile(t *testing.T) {
+	emptyFile := filepath.Join(t.TempDir(), "empty.ndjson")
+	if err := os.WriteFile(emptyFile, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := newUploadServer(t)
+	_, err := srv.producer().UploadDataset(context.Background(), emptyFile, NewUploadOptions("catalog-check"))
+	if !errors.Is(err, types.ErrEmptyFile) || !strings.Contains(err.Error(), emptyFile) {
+		t.Errorf("UploadDataset = %v, want types.ErrEmptyFile naming %s", err, emptyFile)
+	}
+	if len(srv.keys) != 0 {
+		t.Error("catalog record created for an empty file")
+	}
+}
+
+func TestUploadDatasetLimits(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     func(*UploadOptions)
+		sentinel error
+		observed int64
+		allowed  int64
+	}{
+		{"MaxFileBytes", func(o *UploadOptions) { o.MaxFileBytes = 10 }, types.ErrFileTooLarge, 18, 10},
+		{"MaxRecords", func(o *UploadOptions) { o.MaxRecords = 1 }, types.ErrTooManyRecords, 2, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newUploadServer(t)
+			opts := NewUploadOptions("limits")
+			tt.opts(&opts)
+
+			_, err := srv.producer().UploadDataset(context.Background(), writeUploadFile(t), opts)
+			if !errors.Is(err, tt.sentinel) {
+				t.Fatalf("UploadDataset = %v, want %v", err, tt.sentinel)
+			}
+			var limitErr *types.LimitError
+			if !errors.As(err, &limitErr) || limitErr.Observed != tt.observed || limitErr.Allowed != tt.allowed {
+				t.Errorf("LimitError = %+v, want observed %d, allowed %d", limitErr, tt.observed, tt.allowed)
+			}
+			if len(srv.keys) != 0 {
+				t.Error("catalog record created for a file over the limit")
+			}
+		})
+	}
+}
+
+func TestAnalyzeReaderMaxRecords(t *testing.T) {
+	opts := DefaultAnalysisOptions()
+	opts.MaxRecords = 2
+
+	p := &Producer{}
+	if _, err := p.analyzeReader(strings.NewReader("{\"id\":1}\n{\"id\":2}\n"), opts); err != nil {
+		t.Fatalf("analyzeReader at the limit: %v", err)
+	}
+	_, err := p.analyzeReader(strings.NewReader("{\"id\":1}\n{\"id\":2}\n{\"id\":3}\n{\"id\":4}\n"), opts)
+	var limitErr *types.LimitError
+	if !errors.As(err, &limitErr) || limitErr.Observed != 3 || !errors.Is(err, types.ErrTooManyRecords) {
+		t.Errorf("analyzeReader = %v, want to stop at record 3 with types.ErrTooManyRecords", err)
+	}
+}
@@ -112,7 +112,7 @@ func main() {
 		DatasetName:      datasetName,
 		Description:      "End-to-end test dataset from Golang Producer SDK",
 		Category:         "test",
-		DataFreshness:    "realtime",
+		DataFreshness:    "hourly",
 		Encrypt:          true,
 		Compress:         true,
 		CompressionLevel: 9, // Maximum compression for test
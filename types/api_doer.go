@@ -0,0 +1,43 @@
+package types
+
+import (
+	"context"
+	"fmt"
+)
+
+// APIDoer performs one Helix API round-trip: it sends body (JSON-encoded,
+// when non-nil) to method and path, relative to the API endpoint, and
+// decodes a 2xx JSON response into result when result is non-nil. A non-2xx
+// response is reported as a *StatusError.
+//
+// Producer and Consumer sign and send API requests themselves; an APIDoer
+// replaces that round-trip in unit tests (see package helixtest).
+type APIDoer interface {
+	Do(ctx context.Context, method, path string, body, result any) error
+}
+
+// StatusError is a non-2xx API response returned by an APIDoer.
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("API request failed: %d - %s", e.StatusCode, e.Body)
+}
+
+// NotFoundError reports that the API has no Resource with ID (a 404).
+// Err is the underlying API error.
+type NotFoundError struct {
+	Resource string
+	ID       string
+	Err      error
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s %s not found", e.Resource, e.ID)
+}
+
+func (e *NotFoundError) Unwrap() error {
+	return e.Err
+}
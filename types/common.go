@@ -1,6 +1,11 @@
 // Package types defines common types used across the SDK.
 package types
 
+import (
+	"net/http"
+	"time"
+)
+
 // EmptyPayloadHash is the SHA256 hash of an empty payload.
 const EmptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
 
@@ -56,6 +61,127 @@ type Config struct {
 	// requested explicitly, so no existing caller can silently start
 	// minting STS sessions.
 	CredentialMode CredentialMode
+
+	// SkipCredentialValidation skips the STS identity check NewProducer,
+	// NewConsumer and clientset.New make at construction, and the
+	// producer's check of its KMS key. It saves network round trips at
+	// startup (e.g. on serverless cold starts) and the need for STS access,
+	// at the cost of bad credentials or an unusable key surfacing only at
+	// the first real operation, as that operation's error. The key is then
+	// used as configured rather than as its ARN. Off by default.
+	SkipCredentialValidation bool
+
+	// RequestsPerSecond, when > 0, paces Helix API requests with a token
+	// bucket so batch operations stay under the customer's API rate limit
+	// instead of bursting into 429s. Zero (the default) disables limiting.
+	RequestsPerSecond float64
+
+	// Burst is the number of requests allowed back-to-back before
+	// RequestsPerSecond pacing applies. Values < 1 mean 1.
+	Burst int
+
+	// HTTPClient, when set, sends the Helix API requests and dataset
+	// uploads and downloads instead of the SDK's own client, e.g. to add a
+	// proxy or tracing transport. MaxIdleConnsPerHost is then ignored.
+	HTTPClient *http.Client
+
+	// MaxIdleConnsPerHost is how many idle connections per host the SDK's
+	// HTTP client keeps for reuse (it always attempts HTTP/2). Zero means
+	// 100, enough for highly parallel batch uploads; net/http's own default
+	// is 2.
+	MaxIdleConnsPerHost int
+
+	// AWSEndpointURL, when set, sends every AWS call the SDK makes to this
+	// URL instead of the regional AWS endpoints, e.g. "http://localhost:4566"
+	// for a local emulator such as LocalStack. It must be an absolute http
+	// or https URL; construction fails otherwise.
+	AWSEndpointURL string
+
+	// FIPSMode requires FIPS 140 validated crypto and sends AWS calls (KMS,
+	// S3, SQS, SSM, STS) to the AWS FIPS endpoints. The SDK's encryption
+	// uses the standard crypto packages, which run in a validated module
+	// only when the program is built or run for it: with Go's FIPS 140-3
+	// module (GODEBUG=fips140=on at run time, or GOFIPS140=latest at build
+	// time) or with BoringCrypto (GOEXPERIMENT=boringcrypto). Without one,
+	// NewProducer, NewConsumer and clientset.New fail rather than fall back
+	// to standard crypto. GODEBUG=fips140=only is not supported: the
+	// encryption format's 16-byte IV, shared with the other SDKs, is chosen
+	// by the SDK, which that mode forbids. Not every region has FIPS
+	// endpoints. Off by default.
+	FIPSMode bool
+
+	// S3ForcePathStyle addresses objects as {endpoint}/{bucket}/{key} instead
+	// of {bucket}.{endpoint}/{key}. Local emulators usually need it, since
+	// bucket subdomains of localhost don't resolve.
+	S3ForcePathStyle bool
+
+	// TrackViews makes Consumer.GetDatasetDetails record a dataset view
+	// (Consumer.RecordDatasetView) in the background, so producers see
+	// accurate view counts. Off by default.
+	TrackViews bool
+
+	// SSMPathPrefix is the SSM parameter path under which the producer's
+	// per-customer settings live, e.g. "/helix/staging/customers"; the
+	// producer reads {prefix}/{CustomerID}/s3_bucket and .../kms_key_id.
+	// When empty it tries HELIX_SSM_CUSTOMER_PREFIX, then the standard
+	// per-environment prefixes, then "/helix/customers".
+	SSMPathPrefix string
+
+	// SSMCacheTTL is how long parameters the producer reads at construction
+	// stay cached, process-wide, so services that build a producer per
+	// request or tenant don't read them from SSM every time (and risk
+	// throttling). Entries are keyed by region, endpoint and credentials,
+	// so producers for different accounts or endpoints don't share them.
+	// Zero means the default of 5 minutes; a negative value disables the
+	// cache. See producer.ClearSSMCache.
+	SSMCacheTTL time.Duration
+
+	// BucketName and KMSKeyID, when set, are used by the producer instead
+	// of being looked up at construction, so a producer can be built
+	// without parameter-store access. Either may be set alone. Either way,
+	// the key (an ID, alias or ARN) is resolved to its ARN at construction
+	// (see SkipCredentialValidation).
+	BucketName string
+	KMSKeyID   string
+
+	// DecryptKMSKeyID, when set, is passed to KMS as the key to decrypt
+	// downloaded data keys with, instead of letting KMS infer it from the
+	// ciphertext; useful when the consumer holds grants on several keys or
+	// must name the key for a cross-region call. Consumer only.
+	DecryptKMSKeyID string
+
+	// DatasetCacheTTL, when > 0, makes the consumer cache dataset metadata
+	// for this long (Consumer.GetDatasetCached), so repeated downloads of a
+	// dataset don't each fetch its record first. Zero disables the cache.
+	// Consumer only.
+	DatasetCacheTTL time.Duration
+
+	// DatasetCacheSize caps how many datasets the metadata cache holds.
+	// Zero means 1000.
+	DatasetCacheSize int
+
+	// MaxDecompressedBytes caps how large a compressed download may expand
+	// to, so a small crafted archive cannot exhaust memory. Zero means the
+	// consumer's default of 2 GiB; raise it for known-large datasets.
+	MaxDecompressedBytes int64
+
+	// TempDir is where the consumer and producer put temporary files:
+	// large downloads before they are decrypted, objects staged from S3,
+	// and processed shards. Zero means os.TempDir(). Point it at a data
+	// volume where /tmp is small or read-only. Files are created readable
+	// by their owner only and removed whether the operation succeeds or
+	// fails.
+	TempDir string
+
+	// ZeroizeBuffers makes consumer downloads and producer uploads
+	// overwrite the buffers that held plaintext dataset content with zeros
+	// once they are done with them, rather than leaving them to the garbage
+	// collector. (Data keys are always zeroed after use.) It is best-effort defense in
+	// depth: Go may already have copied the data while growing a buffer,
+	// the gzip and HTTP layers keep their own internal buffers, and the
+	// bytes written to the output file are of course not affected. Off by
+	// default.
+	ZeroizeBuffers bool
 }
 
 // DataFreshness enumerates allowed dataset update cadences.
@@ -74,6 +200,17 @@ const (
 	DataFreshnessOnDemand        DataFreshness = "on-demand"
 )
 
+// IsValid reports whether f is one of the DataFreshness constants.
+func (f DataFreshness) IsValid() bool {
+	switch f {
+	case DataFreshnessTwoTimesPerDay, DataFreshnessFourTimesPerDay, DataFreshnessHourly,
+		DataFreshnessDaily, DataFreshnessWeekly, DataFreshnessMonthly, DataFreshnessQuarterly,
+		DataFreshnessYearly, DataFreshnessOnce, DataFreshnessOnDemand:
+		return true
+	}
+	return false
+}
+
 // DatasetStatus is the canonical lifecycle state of a dataset.
 // Canonical contract values: active, inactive, archived.
 type DatasetStatus = string
@@ -102,6 +239,26 @@ type DatasetUpdateInput struct {
 	Metadata      map[string]any `json:"metadata,omitempty"`
 }
 
+// DatasetStats is the typed form of a dataset's stats map: usage counters
+// maintained by the catalog. Counters the API omits are zero.
+type DatasetStats struct {
+	SubscriberCount   int64   `json:"subscriber_count"`
+	DownloadCount     int64   `json:"download_count"`
+	DownloadCount7d   int64   `json:"download_count_7d"`
+	DownloadCount30d  int64   `json:"download_count_30d"`
+	ViewCount         int64   `json:"view_count"`
+	LastDownloadedAt  *string `json:"last_downloaded_at"`
+	AvgDownloadSizeMB float64 `json:"avg_download_size_mb"`
+}
+
+// DatasetListResponse is returned by GET /v1/datasets. Pagination is only
+// present when the API paginates the listing (page/per_page requested).
+type DatasetListResponse struct {
+	Datasets   []Dataset              `json:"datasets"`
+	Count      int                    `json:"count"`
+	Pagination *MarketplacePagination `json:"pagination,omitempty"`
+}
+
 // Dataset represents a dataset in the catalog
 type Dataset struct {
 	ID            string        `json:"_id"`
@@ -0,0 +1,20 @@
+package types
+
+import "testing"
+
+func TestDataFreshnessIsValid(t *testing.T) {
+	for _, f := range []DataFreshness{
+		DataFreshnessTwoTimesPerDay, DataFreshnessFourTimesPerDay, DataFreshnessHourly,
+		DataFreshnessDaily, DataFreshnessWeekly, DataFreshnessMonthly, DataFreshnessQuarterly,
+		DataFreshnessYearly, DataFreshnessOnce, DataFreshnessOnDemand,
+	} {
+		if !f.IsValid() {
+			t.Errorf("%q should be valid", f)
+		}
+	}
+	for _, f := range []DataFreshness{"", "realtime", "Daily"} {
+		if f.IsValid() {
+			t.Errorf("%q should be invalid", f)
+		}
+	}
+}
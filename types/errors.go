@@ -0,0 +1,69 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sentinel errors for common failure modes. The SDK wraps them with the
+// details of the failure, so match them with errors.Is rather than by
+// message.
+var (
+	// ErrDatasetNameRequired is returned for an upload without a dataset
+	// name.
+	ErrDatasetNameRequired = errors.New("dataset name is required")
+
+	// ErrEncryptionRequired is returned for an upload that turns
+	// encryption off; every dataset is stored encrypted.
+	ErrEncryptionRequired = errors.New("encryption is required for dataset uploads")
+
+	// ErrCompressionRequired is returned for an upload that turns
+	// compression off without choosing a CompressionMode.
+	ErrCompressionRequired = errors.New("compression is required for dataset uploads")
+
+	// ErrKMSKeyNotFound is returned when the producer has no encryption key
+	// configured, or the key it names does not exist.
+	ErrKMSKeyNotFound = errors.New("KMS key not found")
+
+	// ErrKMSKeyDisabled is returned when the encryption key exists but
+	// cannot encrypt, e.g. it is disabled or pending deletion.
+	ErrKMSKeyDisabled = errors.New("KMS key is not enabled")
+
+	// ErrEmptyFile is returned for an upload of a file with no data.
+	ErrEmptyFile = errors.New("file is empty")
+
+	// ErrFileTooLarge is returned (in a *LimitError) for an upload whose
+	// source exceeds its MaxFileBytes.
+	ErrFileTooLarge = errors.New("file exceeds the size limit")
+
+	// ErrTooManyRecords is returned (in a *LimitError) for an upload whose
+	// source exceeds its MaxRecords.
+	ErrTooManyRecords = errors.New("file exceeds the record limit")
+
+	// ErrDecryptionFailed is returned when a downloaded dataset cannot be
+	// decrypted. The underlying cause is wrapped as well, e.g.
+	// consumer.ErrKMSAccessDenied.
+	ErrDecryptionFailed = errors.New("decryption failed")
+)
+
+// LimitError reports an upload source over one of its limits. Err is
+// ErrFileTooLarge (Observed and Allowed are bytes) or ErrTooManyRecords
+// (records; the count stops at the first record over the limit, so
+// Observed is Allowed+1).
+type LimitError struct {
+	Observed int64
+	Allowed  int64
+	Err      error
+}
+
+func (e *LimitError) Error() string {
+	unit := "bytes"
+	if errors.Is(e.Err, ErrTooManyRecords) {
+		unit = "records"
+	}
+	return fmt.Sprintf("%v: %d %s, limit is %d", e.Err, e.Observed, unit, e.Allowed)
+}
+
+func (e *LimitError) Unwrap() error {
+	return e.Err
+}
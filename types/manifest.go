@@ -0,0 +1,61 @@
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// ManifestVersion is the manifest format written by this SDK.
+const ManifestVersion = 1
+
+// Manifest lists the objects (parts) of a dataset stored as several shards.
+// It is written alongside the data at datasets/{name}/manifest.json.
+type Manifest struct {
+	ManifestVersion int            `json:"manifest_version"`
+	DatasetName     string         `json:"dataset_name"`
+	Parts           []ManifestPart `json:"parts"`
+	TotalSizeBytes  int64          `json:"total_size_bytes"`
+	CreatedAt       string         `json:"created_at"`
+}
+
+// ManifestPart is one object of a sharded dataset. Size and SHA256 describe
+// the stored object bytes, so each part can be verified on its own.
+type ManifestPart struct {
+	S3Key       string `json:"s3_key"`
+	SizeBytes   int64  `json:"size_bytes"`
+	SHA256      string `json:"sha256"`
+	RecordCount int    `json:"record_count,omitempty"`
+	// DownloadURL is a presigned URL for the part, filled in by the API when
+	// a consumer fetches the manifest. Never written by producers.
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+// AppendRange describes the part one append added to a sharded dataset:
+// consumers can download just that part to pick up the new records. It is
+// recorded as the dataset's last_append metadata.
+type AppendRange struct {
+	PartIndex   int    `json:"part_index"` // position in the manifest's parts
+	S3Key       string `json:"s3_key"`
+	RecordCount int    `json:"record_count"`
+	SizeBytes   int64  `json:"size_bytes"`
+	AppendedAt  string `json:"appended_at"`
+}
+
+// NewManifestPart describes the stored bytes of one part.
+func NewManifestPart(s3Key string, data []byte) ManifestPart {
+	sum := sha256.Sum256(data)
+	return ManifestPart{S3Key: s3Key, SizeBytes: int64(len(data)), SHA256: hex.EncodeToString(sum[:])}
+}
+
+// Verify checks data against the part's recorded size and checksum.
+func (p ManifestPart) Verify(data []byte) error {
+	if int64(len(data)) != p.SizeBytes {
+		return fmt.Errorf("part %s: size %d, manifest says %d", p.S3Key, len(data), p.SizeBytes)
+	}
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); got != p.SHA256 {
+		return fmt.Errorf("part %s: sha256 %s, manifest says %s", p.S3Key, got, p.SHA256)
+	}
+	return nil
+}
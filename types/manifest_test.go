@@ -0,0 +1,20 @@
+package types
+
+import "testing"
+
+func TestManifestPartVerify(t *testing.T) {
+	part := NewManifestPart("datasets/sales/part-0000.ndjson.gz", []byte("hello"))
+	if part.SizeBytes != 5 || part.SHA256 != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" {
+		t.Fatalf("part = %+v", part)
+	}
+
+	if err := part.Verify([]byte("hello")); err != nil {
+		t.Errorf("Verify(matching) = %v", err)
+	}
+	if err := part.Verify([]byte("hell")); err == nil {
+		t.Error("Verify should reject a size mismatch")
+	}
+	if err := part.Verify([]byte("HELLO")); err == nil {
+		t.Error("Verify should reject a checksum mismatch")
+	}
+}
@@ -0,0 +1,13 @@
+package types
+
+import "encoding/json"
+
+// WebhookEvent is the envelope of an event pushed to a company's
+// CompanySettings.WebhookURL. Verify the payload with
+// webhook.VerifySignature before trusting it.
+type WebhookEvent struct {
+	Type      string          `json:"type"`
+	DatasetID string          `json:"dataset_id,omitempty"`
+	Timestamp string          `json:"timestamp"`
+	Data      json.RawMessage `json:"data,omitempty"` // event-specific body, decoded by the caller
+}
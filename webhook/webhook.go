@@ -0,0 +1,87 @@
+// Package webhook verifies and decodes the events Helix Connect pushes to a
+// company's webhook URL (CompanySettings.WebhookURL), an alternative to
+// polling the SQS notification queue.
+//
+// A receiver should verify the raw request body before parsing it:
+//
+//	body, _ := io.ReadAll(r.Body)
+//	if err := webhook.VerifySignature(body, r.Header.Get(webhook.SignatureHeader), secret); err != nil {
+//		http.Error(w, "invalid signature", http.StatusUnauthorized)
+//		return
+//	}
+//	event, err := webhook.ParseEvent(body)
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/helix-tools/sdk-go/v2/types"
+)
+
+// SignatureHeader is the HTTP header carrying the payload signature.
+const SignatureHeader = "X-Helix-Signature"
+
+// signaturePrefix is the optional algorithm prefix on the header value.
+const signaturePrefix = "sha256="
+
+var (
+	// ErrMissingSignature is returned when the signature header is empty.
+	ErrMissingSignature = errors.New("webhook: missing signature")
+
+	// ErrInvalidSignature is returned when the signature does not match the
+	// payload, i.e. the payload was not sent by Helix or was altered.
+	ErrInvalidSignature = errors.New("webhook: invalid signature")
+)
+
+// VerifySignature checks that signatureHeader is the hex HMAC-SHA256 of
+// payload keyed with secret, optionally prefixed with "sha256=". The
+// comparison is constant-time. payload must be the raw request body, before
+// any JSON decoding.
+func VerifySignature(payload []byte, signatureHeader, secret string) error {
+	if secret == "" {
+		return errors.New("webhook: secret is required")
+	}
+
+	signature := strings.TrimSpace(signatureHeader)
+	if signature == "" {
+		return ErrMissingSignature
+	}
+	signature = strings.TrimPrefix(signature, signaturePrefix)
+
+	got, err := hex.DecodeString(signature)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	if !hmac.Equal(got, Sign(payload, secret)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Sign returns the HMAC-SHA256 of payload keyed with secret. It is exposed
+// for tests that need to produce signed payloads.
+func Sign(payload []byte, secret string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// ParseEvent decodes a webhook payload into its event envelope. It does not
+// verify the signature; call VerifySignature first.
+func ParseEvent(payload []byte) (*types.WebhookEvent, error) {
+	var event types.WebhookEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("webhook: failed to parse event: %w", err)
+	}
+	if event.Type == "" {
+		return nil, errors.New("webhook: event has no type")
+	}
+	return &event, nil
+}
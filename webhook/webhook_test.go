@@ -0,0 +1,59 @@
+package webhook
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestVerifySignature(t *testing.T) {
+	payload := []byte(`{"type":"dataset.updated","dataset_id":"ds-1","timestamp":"2026-01-01T00:00:00Z"}`)
+	valid := hex.EncodeToString(Sign(payload, "s3cret"))
+
+	tests := []struct {
+		name    string
+		payload []byte
+		header  string
+		secret  string
+		wantErr error
+	}{
+		{"bare hex", payload, valid, "s3cret", nil},
+		{"prefixed", payload, "sha256=" + valid, "s3cret", nil},
+		{"missing", payload, "", "s3cret", ErrMissingSignature},
+		{"wrong secret", payload, valid, "other", ErrInvalidSignature},
+		{"altered payload", append([]byte(" "), payload...), valid, "s3cret", ErrInvalidSignature},
+		{"not hex", payload, "sha256=zz", "s3cret", ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifySignature(tt.payload, tt.header, tt.secret)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifySignature = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := VerifySignature(payload, valid, ""); err == nil {
+		t.Error("expected an error for an empty secret")
+	}
+}
+
+func TestParseEvent(t *testing.T) {
+	event, err := ParseEvent([]byte(`{"type":"dataset.updated","dataset_id":"ds-1","timestamp":"2026-01-01T00:00:00Z","data":{"version":"2"}}`))
+	if err != nil {
+		t.Fatalf("ParseEvent: %v", err)
+	}
+	if event.Type != "dataset.updated" || event.DatasetID != "ds-1" || event.Timestamp != "2026-01-01T00:00:00Z" {
+		t.Errorf("event = %+v", event)
+	}
+	if string(event.Data) != `{"version":"2"}` {
+		t.Errorf("Data = %s, want the raw data object", event.Data)
+	}
+
+	if _, err := ParseEvent([]byte(`not json`)); err == nil {
+		t.Error("expected an error for invalid JSON")
+	}
+	if _, err := ParseEvent([]byte(`{"dataset_id":"ds-1"}`)); err == nil {
+		t.Error("expected an error for an event without a type")
+	}
+}